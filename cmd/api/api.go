@@ -23,6 +23,7 @@ import (
 	"io/ioutil"
 	"log"
 	"net/http"
+	"os"
 	"path"
 	"path/filepath"
 	"strings"
@@ -38,20 +39,20 @@ import (
 	"github.com/k0sproject/k0s/internal/util"
 	"github.com/k0sproject/k0s/pkg/apis/v1beta1"
 	"github.com/k0sproject/k0s/pkg/config"
+	"github.com/k0sproject/k0s/pkg/encryption"
 	"github.com/k0sproject/k0s/pkg/etcd"
 	"github.com/k0sproject/k0s/pkg/kubernetes"
 )
 
 type CmdOpts config.CLIOptions
 
-const (
-	workerRole     = "worker"
-	controllerRole = "controller"
-)
-
-var allowedUsageByRole = map[string]string{
-	workerRole:     "usage-bootstrap-api-worker-calls",
-	controllerRole: "usage-controller-join",
+// apiOpts holds the hardening settings of the API server
+var apiOpts struct {
+	rateLimit        float64
+	rateBurst        int
+	lockoutThreshold int
+	lockoutDuration  time.Duration
+	auditLogPath     string
 }
 
 func NewAPICmd() *cobra.Command {
@@ -60,7 +61,7 @@ func NewAPICmd() *cobra.Command {
 		Short: "Run the controller api",
 		RunE: func(cmd *cobra.Command, args []string) error {
 			c := CmdOpts(config.GetCmdOpts())
-			cfg, err := config.GetYamlFromFile(c.CfgFile, c.K0sVars)
+			cfg, err := config.GetYamlFromFile(c.CfgFile, c.CfgDir, c.K0sVars)
 			if err != nil {
 				return err
 			}
@@ -70,6 +71,11 @@ func NewAPICmd() *cobra.Command {
 	}
 	cmd.SilenceUsage = true
 	cmd.PersistentFlags().AddFlagSet(config.GetPersistentFlagSet())
+	cmd.Flags().Float64Var(&apiOpts.rateLimit, "rate-limit", 5, "allowed API calls per second per source address")
+	cmd.Flags().IntVar(&apiOpts.rateBurst, "rate-limit-burst", 20, "maximum burst of API calls per source address")
+	cmd.Flags().IntVar(&apiOpts.lockoutThreshold, "lockout-threshold", 10, "number of consecutive invalid tokens after which a source address is locked out, 0 disables the lockout")
+	cmd.Flags().DurationVar(&apiOpts.lockoutDuration, "lockout-duration", 5*time.Minute, "how long a source address stays locked out")
+	cmd.Flags().StringVar(&apiOpts.auditLogPath, "audit-log-path", "", "file to write the API audit log to (default: process output)")
 	return cmd
 }
 
@@ -80,29 +86,28 @@ func (c *CmdOpts) startAPI() error {
 		return err
 	}
 	c.KubeClient = kc
-	prefix := "/v1beta1"
-	router := mux.NewRouter()
 
-	if c.ClusterConfig.Spec.Storage.Type == v1beta1.EtcdStorageType {
-		// Only mount the etcd handler if we're running on etcd storage
-		// by default the mux will return 404 back which the caller should handle
-		router.Path(prefix + "/etcd/members").Methods("POST").Handler(
-			c.controllerHandler(c.etcdHandler()),
-		)
-	}
-
-	if c.ClusterConfig.Spec.Storage.IsJoinable() {
-		router.Path(prefix + "/ca").Methods("GET").Handler(
-			c.controllerHandler(c.caHandler()),
-		)
+	stopCh := make(chan struct{})
+	defer close(stopCh)
 
+	tokens := newTokenCache(kc)
+	if err := tokens.Start(stopCh); err != nil {
+		return err
+	}
+	limiter := newSourceLimiter(apiOpts.rateLimit, apiOpts.rateBurst, apiOpts.lockoutThreshold, apiOpts.lockoutDuration)
+	go limiter.runGC(stopCh)
+	audit, err := newAuditLogger(apiOpts.auditLogPath)
+	if err != nil {
+		return err
 	}
-	router.Path(prefix + "/calico/kubeconfig").Methods("GET").Handler(
-		c.workerHandler(c.kubeConfigHandler()),
-	)
+	auth := &authenticator{tokens: tokens, limiter: limiter}
+
+	routes := c.routes()
+	router := mux.NewRouter()
+	routes.mount(router, auth)
 
 	srv := &http.Server{
-		Handler:      router,
+		Handler:      audit.middleware(auth.rateLimit(router)),
 		Addr:         fmt.Sprintf(":%d", c.ClusterConfig.Spec.API.K0sAPIPort),
 		WriteTimeout: 15 * time.Second,
 		ReadTimeout:  15 * time.Second,
@@ -116,6 +121,58 @@ func (c *CmdOpts) startAPI() error {
 	return nil
 }
 
+// routes returns the versioned routes served with the current cluster config
+func (c *CmdOpts) routes() *registry {
+	routes := &registry{}
+	if c.ClusterConfig.Spec.Storage.Type == v1beta1.EtcdStorageType && !c.ClusterConfig.Spec.Storage.Etcd.IsExternalClusterUsed() {
+		// Only mount the etcd handler if we're running etcd
+		// by default the mux will return 404 back which the caller should handle
+		routes.add(route{
+			version:  v1beta1.ControlAPIVersion,
+			path:     "/etcd/members",
+			method:   http.MethodPost,
+			summary:  "Adds a new member to the etcd cluster",
+			role:     controllerRole,
+			request:  v1beta1.EtcdRequest{},
+			response: v1beta1.EtcdResponse{},
+			handler:  c.etcdHandler(),
+		})
+	}
+
+	if c.ClusterConfig.Spec.Storage.IsJoinable() {
+		routes.add(route{
+			version:  v1beta1.ControlAPIVersion,
+			path:     "/ca",
+			method:   http.MethodGet,
+			summary:  "Returns the cluster CA, service account and encryption keys for joining controllers",
+			role:     controllerRole,
+			response: v1beta1.CaResponse{},
+			handler:  c.caHandler(),
+		})
+	}
+	routes.add(route{
+		version:     v1beta1.ControlAPIVersion,
+		path:        "/calico/kubeconfig",
+		method:      http.MethodGet,
+		summary:     "Returns the kubeconfig for calico on windows workers",
+		role:        workerRole,
+		response:    "",
+		contentType: "application/yaml",
+		handler:     c.kubeConfigHandler(),
+	})
+	routes.add(route{
+		version:  v1beta1.ControlAPIVersion,
+		path:     "/worker/join",
+		method:   http.MethodPost,
+		summary:  "Joins a worker node, returning its node scoped kubelet bootstrap config and cluster settings",
+		role:     workerRole,
+		request:  v1beta1.WorkerJoinRequest{},
+		response: v1beta1.WorkerJoinResponse{},
+		handler:  c.workerJoinHandler(),
+	})
+	return routes
+}
+
 func (c *CmdOpts) etcdHandler() http.Handler {
 	return http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
 		ctx := req.Context()
@@ -125,20 +182,20 @@ func (c *CmdOpts) etcdHandler() http.Handler {
 			sendError(err, resp)
 			return
 		}
-		logrus.Infof("etcd API, adding new member: %s", etcdReq.PeerAddress)
+		logrus.Infof("etcd API, adding new member: %s (learner: %t)", etcdReq.PeerAddress, etcdReq.Learner)
 		err = etcdReq.Validate()
 		if err != nil {
 			sendError(err, resp)
 			return
 		}
 
-		etcdClient, err := etcd.NewClient(c.K0sVars.CertRootDir, c.K0sVars.EtcdCertDir)
+		etcdClient, err := etcd.NewClient(c.K0sVars.CertRootDir, c.K0sVars.EtcdCertDir, c.ClusterConfig.Spec.Storage.Etcd)
 		if err != nil {
 			sendError(err, resp)
 			return
 		}
 
-		memberList, err := etcdClient.AddMember(ctx, etcdReq.Node, etcdReq.PeerAddress)
+		memberList, err := etcdClient.AddMember(ctx, etcdReq.Node, etcdReq.PeerAddress, etcdReq.Learner)
 		if err != nil {
 			sendError(err, resp)
 			return
@@ -268,75 +325,17 @@ func (c *CmdOpts) caHandler() http.Handler {
 		}
 		caResp.SAPub = saPub
 
-		resp.Header().Set("content-type", "application/json")
-		if err := json.NewEncoder(resp).Encode(caResp); err != nil {
+		encryptionKeys, err := ioutil.ReadFile(encryption.KeysPath(c.K0sVars.CertRootDir))
+		if err != nil && !os.IsNotExist(err) {
 			sendError(err, resp)
 			return
 		}
-	})
-}
-
-/** The token is in form of xyz.foobar where:
-- xyz: the token "ID" in kube api
-- foobar: the token itself
-We need to validate:
-- that we find a secret with the ID
-- that the token matches whats inside the secret
-*/
-func (c *CmdOpts) isValidToken(token string, role string) bool {
-	parts := strings.Split(token, ".")
-	logrus.Debugf("token parts: %v", parts)
-	if len(parts) != 2 {
-		return false
-	}
-
-	secretName := fmt.Sprintf("bootstrap-token-%s", parts[0])
-	secret, err := c.KubeClient.CoreV1().Secrets("kube-system").Get(context.TODO(), secretName, v1.GetOptions{})
-	if err != nil {
-		logrus.Errorf("failed to get bootstrap token: %s", err.Error())
-		return false
-	}
-
-	if string(secret.Data["token-secret"]) != parts[1] {
-		return false
-	}
-
-	usageValue, ok := secret.Data[allowedUsageByRole[role]]
-	if !ok || string(usageValue) != "true" {
-		return false
-	}
-
-	return true
-}
-
-func (c *CmdOpts) authMiddleware(next http.Handler, role string) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		auth := r.Header.Get("Authorization")
-		if auth == "" {
-			sendError(fmt.Errorf("go away"), w, http.StatusUnauthorized)
-			return
-		}
+		caResp.EncryptionKeys = encryptionKeys
 
-		parts := strings.Split(auth, "Bearer ")
-		if len(parts) == 2 {
-			token := parts[1]
-			if !c.isValidToken(token, role) {
-				sendError(fmt.Errorf("go away"), w, http.StatusUnauthorized)
-				return
-			}
-		} else {
-			sendError(fmt.Errorf("go away"), w, http.StatusUnauthorized)
+		resp.Header().Set("content-type", "application/json")
+		if err := json.NewEncoder(resp).Encode(caResp); err != nil {
+			sendError(err, resp)
 			return
 		}
-
-		next.ServeHTTP(w, r)
 	})
 }
-
-func (c *CmdOpts) controllerHandler(next http.Handler) http.Handler {
-	return c.authMiddleware(next, controllerRole)
-}
-
-func (c *CmdOpts) workerHandler(next http.Handler) http.Handler {
-	return c.authMiddleware(next, workerRole)
-}
@@ -0,0 +1,128 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type auditEntry struct {
+	Endpoint   string `json:"endpoint"`
+	Method     string `json:"method"`
+	TokenID    string `json:"tokenID"`
+	RemoteAddr string `json:"remoteAddr"`
+	Result     string `json:"result"`
+	Status     int    `json:"status"`
+}
+
+// newTestServer returns the API handler chain with a single worker route and the buffer the audit log goes to
+func newTestServer(t *testing.T, limiter *sourceLimiter) (http.Handler, *bytes.Buffer) {
+	tokens, _ := startTokenCache(t, tokenSecret("abcdef", "0123456789abcdef", time.Time{}))
+	auth := &authenticator{tokens: tokens, limiter: limiter}
+
+	out := &bytes.Buffer{}
+	l := logrus.New()
+	l.SetFormatter(&logrus.JSONFormatter{})
+	l.SetOutput(out)
+	audit := &auditLogger{log: l}
+
+	routes := &registry{}
+	routes.add(route{
+		version: "v1beta1",
+		path:    "/test",
+		method:  http.MethodGet,
+		role:    workerRole,
+		handler: http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+			_, _ = resp.Write([]byte("ok"))
+		}),
+	})
+	router := mux.NewRouter()
+	routes.mount(router, auth)
+	return audit.middleware(auth.rateLimit(router)), out
+}
+
+func call(h http.Handler, path, token, remoteAddr string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	req.RemoteAddr = remoteAddr
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func lastAuditEntry(t *testing.T, out *bytes.Buffer) auditEntry {
+	lines := bytes.Split(bytes.TrimSpace(out.Bytes()), []byte("\n"))
+	var entry auditEntry
+	require.NoError(t, json.Unmarshal(lines[len(lines)-1], &entry))
+	return entry
+}
+
+func TestAuditRecord(t *testing.T) {
+	limiter, _ := newTestLimiter(100, 100, 2, time.Minute)
+	h, out := newTestServer(t, limiter)
+
+	rec := call(h, "/v1beta1/test", "abcdef.0123456789abcdef", "10.0.0.1:40000")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, auditEntry{
+		Endpoint:   "/v1beta1/test",
+		Method:     http.MethodGet,
+		TokenID:    "abcdef",
+		RemoteAddr: "10.0.0.1:40000",
+		Result:     auditResultAllowed,
+		Status:     http.StatusOK,
+	}, lastAuditEntry(t, out))
+
+	rec = call(h, "/v1beta1/test", "ghijkl.0123456789abcdef", "10.0.0.2:40000")
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	entry := lastAuditEntry(t, out)
+	assert.Equal(t, "ghijkl", entry.TokenID)
+	assert.Equal(t, "10.0.0.2:40000", entry.RemoteAddr)
+	assert.Equal(t, auditResultUnauthorized, entry.Result)
+
+	call(h, "/v1beta1/test", "ghijkl.0123456789abcdef", "10.0.0.2:40001")
+	rec = call(h, "/v1beta1/test", "abcdef.0123456789abcdef", "10.0.0.2:40002")
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code, "locked out sources must be rejected even with a valid token")
+	entry = lastAuditEntry(t, out)
+	assert.Equal(t, auditResultLockedOut, entry.Result)
+	assert.Empty(t, entry.TokenID, "the token isn't looked at for locked out sources")
+
+	rec = call(h, "/v1beta1/missing", "", "10.0.0.1:40000")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	entry = lastAuditEntry(t, out)
+	assert.Equal(t, "/v1beta1/missing", entry.Endpoint)
+	assert.Equal(t, http.StatusText(http.StatusNotFound), entry.Result)
+}
+
+func TestAuditRecordRateLimited(t *testing.T) {
+	limiter, _ := newTestLimiter(1, 1, 0, time.Minute)
+	h, out := newTestServer(t, limiter)
+
+	assert.Equal(t, http.StatusOK, call(h, "/v1beta1/test", "abcdef.0123456789abcdef", "10.0.0.1:40000").Code)
+	assert.Equal(t, http.StatusTooManyRequests, call(h, "/v1beta1/test", "abcdef.0123456789abcdef", "10.0.0.1:40000").Code)
+	assert.Equal(t, auditResultRateLimited, lastAuditEntry(t, out).Result)
+}
@@ -0,0 +1,93 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	workerRole     = "worker"
+	controllerRole = "controller"
+)
+
+var allowedUsageByRole = map[string]string{
+	workerRole:     "usage-bootstrap-api-worker-calls",
+	controllerRole: "usage-controller-join",
+}
+
+// authenticator guards the API handlers with bearer token auth, per source rate limiting and lockout
+type authenticator struct {
+	tokens  *tokenCache
+	limiter *sourceLimiter
+}
+
+// rateLimit rejects calls from sources that are locked out or exceed their rate
+func (a *authenticator) rateLimit(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		source := sourceAddress(r)
+		record := auditRecordFrom(r)
+		if a.limiter.IsLockedOut(source) {
+			record.result = auditResultLockedOut
+			sendError(fmt.Errorf("too many invalid tokens from %s, try again later", source), w, http.StatusTooManyRequests)
+			return
+		}
+		if !a.limiter.Allow(source) {
+			record.result = auditResultRateLimited
+			sendError(fmt.Errorf("rate limit exceeded for %s", source), w, http.StatusTooManyRequests)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (a *authenticator) authMiddleware(next http.Handler, role string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		source := sourceAddress(r)
+		record := auditRecordFrom(r)
+
+		token := ""
+		auth := r.Header.Get("Authorization")
+		if parts := strings.Split(auth, "Bearer "); len(parts) == 2 {
+			token = parts[1]
+		}
+		record.tokenID = tokenID(token)
+
+		if token == "" || !a.tokens.isValidToken(token, allowedUsageByRole[role]) {
+			logrus.Warnf("invalid %s token presented by %s", role, source)
+			a.limiter.RecordFailure(source)
+			record.result = auditResultUnauthorized
+			sendError(fmt.Errorf("go away"), w, http.StatusUnauthorized)
+			return
+		}
+
+		a.limiter.RecordSuccess(source)
+		record.result = auditResultAllowed
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (a *authenticator) controllerHandler(next http.Handler) http.Handler {
+	return a.authMiddleware(next, controllerRole)
+}
+
+func (a *authenticator) workerHandler(next http.Handler) http.Handler {
+	return a.authMiddleware(next, workerRole)
+}
@@ -0,0 +1,145 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/k0sproject/k0s/pkg/apis/v1beta1"
+	"github.com/k0sproject/k0s/pkg/build"
+	"github.com/k0sproject/k0s/pkg/jsonschema"
+)
+
+const bearerAuthScheme = "bootstrapToken"
+
+// openAPIDocument is a minimal OpenAPI v3 document
+type openAPIDocument struct {
+	OpenAPI    string                          `json:"openapi"`
+	Info       openAPIInfo                     `json:"info"`
+	Paths      map[string]map[string]operation `json:"paths"`
+	Components openAPIComponents               `json:"components"`
+}
+
+type openAPIInfo struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+type openAPIComponents struct {
+	SecuritySchemes map[string]securityScheme `json:"securitySchemes"`
+}
+
+type securityScheme struct {
+	Type        string `json:"type"`
+	Scheme      string `json:"scheme"`
+	Description string `json:"description,omitempty"`
+}
+
+type operation struct {
+	Summary     string                `json:"summary,omitempty"`
+	Tags        []string              `json:"tags,omitempty"`
+	Security    []map[string][]string `json:"security,omitempty"`
+	RequestBody *requestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]response   `json:"responses"`
+}
+
+type requestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]mediaType `json:"content"`
+}
+
+type response struct {
+	Description string               `json:"description"`
+	Content     map[string]mediaType `json:"content,omitempty"`
+}
+
+type mediaType struct {
+	Schema *jsonschema.Schema `json:"schema"`
+}
+
+// openAPI generates the OpenAPI document describing all the registered routes
+func (r *registry) openAPI() openAPIDocument {
+	doc := openAPIDocument{
+		OpenAPI: "3.0.3",
+		Info: openAPIInfo{
+			Title:   "k0s control API",
+			Version: build.Version,
+		},
+		Paths: map[string]map[string]operation{},
+		Components: openAPIComponents{
+			SecuritySchemes: map[string]securityScheme{
+				bearerAuthScheme: {
+					Type:        "http",
+					Scheme:      "bearer",
+					Description: "k0s join token secret in the form <token-id>.<token-secret>",
+				},
+			},
+		},
+	}
+
+	errorContent := map[string]mediaType{
+		"application/json": {Schema: jsonschema.Reflect(v1beta1.ErrorResponse{}, "json")},
+	}
+
+	for _, rt := range r.routes {
+		op := operation{
+			Summary: rt.summary,
+			Tags:    []string{rt.version},
+			Responses: map[string]response{
+				strconv.Itoa(http.StatusOK): {
+					Description: "OK",
+					Content:     map[string]mediaType{rt.contentType: {Schema: jsonschema.Reflect(rt.response, "json")}},
+				},
+				"default": {Description: "Error", Content: errorContent},
+			},
+		}
+		if rt.role != "" {
+			op.Security = []map[string][]string{{bearerAuthScheme: {}}}
+			op.Responses[strconv.Itoa(http.StatusUnauthorized)] = response{Description: "Invalid or missing " + rt.role + " token", Content: errorContent}
+			op.Responses[strconv.Itoa(http.StatusTooManyRequests)] = response{Description: "Rate limited or locked out", Content: errorContent}
+		}
+		if rt.request != nil {
+			op.RequestBody = &requestBody{
+				Required: true,
+				Content:  map[string]mediaType{"application/json": {Schema: jsonschema.Reflect(rt.request, "json")}},
+			}
+		}
+		path := rt.fullPath()
+		if doc.Paths[path] == nil {
+			doc.Paths[path] = map[string]operation{}
+		}
+		doc.Paths[path][strings.ToLower(rt.method)] = op
+	}
+
+	doc.Paths["/version"] = map[string]operation{"get": {
+		Summary: "Lists the API versions served by the controller",
+		Responses: map[string]response{strconv.Itoa(http.StatusOK): {
+			Description: "OK",
+			Content:     map[string]mediaType{"application/json": {Schema: jsonschema.Reflect(v1beta1.VersionResponse{}, "json")}},
+		}},
+	}}
+	doc.Paths["/healthz"] = map[string]operation{"get": {
+		Summary: "Health check",
+		Responses: map[string]response{strconv.Itoa(http.StatusOK): {
+			Description: "OK",
+			Content:     map[string]mediaType{"text/plain": {Schema: &jsonschema.Schema{Type: "string"}}},
+		}},
+	}}
+
+	return doc
+}
@@ -0,0 +1,146 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package api
+
+import (
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// sourceLimiter rate limits API calls per source address and locks out
+// sources that keep presenting invalid tokens
+type sourceLimiter struct {
+	limit            rate.Limit
+	burst            int
+	lockoutThreshold int
+	lockoutDuration  time.Duration
+
+	mu      sync.Mutex
+	sources map[string]*sourceState
+	// now returns the current time, replaced in tests
+	now func() time.Time
+}
+
+type sourceState struct {
+	limiter     *rate.Limiter
+	failures    int
+	lockedUntil time.Time
+	lastSeen    time.Time
+}
+
+func newSourceLimiter(limit float64, burst int, lockoutThreshold int, lockoutDuration time.Duration) *sourceLimiter {
+	return &sourceLimiter{
+		limit:            rate.Limit(limit),
+		burst:            burst,
+		lockoutThreshold: lockoutThreshold,
+		lockoutDuration:  lockoutDuration,
+		sources:          make(map[string]*sourceState),
+		now:              time.Now,
+	}
+}
+
+// get returns the state for the given source, creating it if needed. Must be called with the lock held.
+func (l *sourceLimiter) get(source string, now time.Time) *sourceState {
+	s, ok := l.sources[source]
+	if !ok {
+		s = &sourceState{limiter: rate.NewLimiter(l.limit, l.burst)}
+		l.sources[source] = s
+	}
+	s.lastSeen = now
+	return s
+}
+
+// IsLockedOut returns true if the source is currently locked out
+func (l *sourceLimiter) IsLockedOut(source string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+	return now.Before(l.get(source, now).lockedUntil)
+}
+
+// Allow returns true if the source is allowed to make a call right now
+func (l *sourceLimiter) Allow(source string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+	return l.get(source, now).limiter.AllowN(now, 1)
+}
+
+// RecordFailure records an invalid token for the source and locks it out once the threshold is reached
+func (l *sourceLimiter) RecordFailure(source string) {
+	if l.lockoutThreshold <= 0 {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+	s := l.get(source, now)
+	s.failures++
+	if s.failures >= l.lockoutThreshold {
+		s.failures = 0
+		s.lockedUntil = now.Add(l.lockoutDuration)
+	}
+}
+
+// RecordSuccess resets the failure count of the source
+func (l *sourceLimiter) RecordSuccess(source string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.get(source, l.now()).failures = 0
+}
+
+// gc drops the state of sources not seen within the given idle period
+func (l *sourceLimiter) gc(idle time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+	for source, s := range l.sources {
+		if now.Sub(s.lastSeen) > idle && now.After(s.lockedUntil) {
+			delete(l.sources, source)
+		}
+	}
+}
+
+// runGC periodically drops idle sources until stopCh is closed
+func (l *sourceLimiter) runGC(stopCh <-chan struct{}) {
+	idle := 2 * l.lockoutDuration
+	if idle < time.Minute {
+		idle = time.Minute
+	}
+	ticker := time.NewTicker(idle)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			l.gc(idle)
+		case <-stopCh:
+			return
+		}
+	}
+}
+
+// sourceAddress returns the remote host of the request without the port
+func sourceAddress(req *http.Request) string {
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		return req.RemoteAddr
+	}
+	return host
+}
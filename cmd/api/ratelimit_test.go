@@ -0,0 +1,114 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package api
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeClock struct {
+	t time.Time
+}
+
+func (c *fakeClock) now() time.Time { return c.t }
+
+func (c *fakeClock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+func newTestLimiter(limit float64, burst int, threshold int, duration time.Duration) (*sourceLimiter, *fakeClock) {
+	clock := &fakeClock{t: time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)}
+	l := newSourceLimiter(limit, burst, threshold, duration)
+	l.now = clock.now
+	return l, clock
+}
+
+func TestLockoutThreshold(t *testing.T) {
+	l, _ := newTestLimiter(5, 20, 3, time.Minute)
+
+	l.RecordFailure("10.0.0.1")
+	l.RecordFailure("10.0.0.1")
+	assert.False(t, l.IsLockedOut("10.0.0.1"), "must not lock out below the threshold")
+
+	l.RecordFailure("10.0.0.1")
+	assert.True(t, l.IsLockedOut("10.0.0.1"), "must lock out at the threshold")
+	assert.False(t, l.IsLockedOut("10.0.0.2"), "must not lock out other sources")
+}
+
+func TestLockoutExpiry(t *testing.T) {
+	l, clock := newTestLimiter(5, 20, 1, time.Minute)
+
+	l.RecordFailure("10.0.0.1")
+	assert.True(t, l.IsLockedOut("10.0.0.1"))
+
+	clock.advance(59 * time.Second)
+	assert.True(t, l.IsLockedOut("10.0.0.1"), "must stay locked out for the lockout duration")
+
+	clock.advance(2 * time.Second)
+	assert.False(t, l.IsLockedOut("10.0.0.1"), "must be released after the lockout duration")
+
+	l.RecordFailure("10.0.0.1")
+	assert.True(t, l.IsLockedOut("10.0.0.1"), "must lock out again on new failures")
+}
+
+func TestLockoutDisabled(t *testing.T) {
+	l, _ := newTestLimiter(5, 20, 0, time.Minute)
+	for i := 0; i < 100; i++ {
+		l.RecordFailure("10.0.0.1")
+	}
+	assert.False(t, l.IsLockedOut("10.0.0.1"))
+}
+
+func TestRecordSuccessResetsFailures(t *testing.T) {
+	l, _ := newTestLimiter(5, 20, 3, time.Minute)
+
+	l.RecordFailure("10.0.0.1")
+	l.RecordFailure("10.0.0.1")
+	l.RecordSuccess("10.0.0.1")
+	l.RecordFailure("10.0.0.1")
+	l.RecordFailure("10.0.0.1")
+	assert.False(t, l.IsLockedOut("10.0.0.1"), "failures before a success must not count")
+
+	l.RecordFailure("10.0.0.1")
+	assert.True(t, l.IsLockedOut("10.0.0.1"))
+}
+
+func TestBurstLimiting(t *testing.T) {
+	l, clock := newTestLimiter(1, 3, 0, time.Minute)
+
+	for i := 0; i < 3; i++ {
+		assert.True(t, l.Allow("10.0.0.1"), "call %d within the burst must be allowed", i)
+	}
+	assert.False(t, l.Allow("10.0.0.1"), "call exceeding the burst must be rejected")
+	assert.True(t, l.Allow("10.0.0.2"), "other sources must have their own burst")
+
+	clock.advance(time.Second)
+	assert.True(t, l.Allow("10.0.0.1"), "the limit must refill over time")
+	assert.False(t, l.Allow("10.0.0.1"))
+}
+
+func TestGCKeepsLockedOutSources(t *testing.T) {
+	l, clock := newTestLimiter(5, 20, 1, time.Hour)
+
+	l.RecordFailure("10.0.0.1")
+	l.Allow("10.0.0.2")
+	clock.advance(10 * time.Minute)
+	l.gc(time.Minute)
+
+	assert.Contains(t, l.sources, "10.0.0.1")
+	assert.NotContains(t, l.sources, "10.0.0.2")
+}
@@ -0,0 +1,122 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/gorilla/mux"
+
+	"github.com/k0sproject/k0s/pkg/apis/v1beta1"
+	"github.com/k0sproject/k0s/pkg/build"
+)
+
+// route describes a single versioned endpoint of the k0s API
+type route struct {
+	version string
+	path    string
+	method  string
+	summary string
+	// role is the token role allowed to call the route
+	role string
+	// request and response are sample values of the JSON bodies, used to describe the route in the OpenAPI document
+	request     interface{}
+	response    interface{}
+	contentType string
+	handler     http.Handler
+}
+
+// fullPath returns the path of the route including the version prefix
+func (r route) fullPath() string {
+	return "/" + r.version + r.path
+}
+
+// registry holds all the versioned routes the API serves
+type registry struct {
+	routes []route
+}
+
+func (r *registry) add(rt route) {
+	if rt.contentType == "" {
+		rt.contentType = "application/json"
+	}
+	r.routes = append(r.routes, rt)
+}
+
+// versions returns the sorted list of API versions having at least one route
+func (r *registry) versions() []string {
+	seen := map[string]struct{}{}
+	versions := []string{}
+	for _, rt := range r.routes {
+		if _, ok := seen[rt.version]; ok {
+			continue
+		}
+		seen[rt.version] = struct{}{}
+		versions = append(versions, rt.version)
+	}
+	sort.Strings(versions)
+	return versions
+}
+
+// mount registers the routes and the unversioned discovery endpoints on the router
+func (r *registry) mount(router *mux.Router, auth *authenticator) {
+	for _, rt := range r.routes {
+		var h http.Handler
+		switch rt.role {
+		case controllerRole:
+			h = auth.controllerHandler(rt.handler)
+		case workerRole:
+			h = auth.workerHandler(rt.handler)
+		default:
+			h = rt.handler
+		}
+		router.Path(rt.fullPath()).Methods(rt.method).Handler(h)
+	}
+
+	router.Path("/healthz").Methods(http.MethodGet).HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		resp.Header().Set("Content-Type", "text/plain")
+		_, _ = resp.Write([]byte("ok"))
+	})
+	router.Path("/version").Methods(http.MethodGet).HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		sendJSON(resp, http.StatusOK, v1beta1.VersionResponse{
+			K0sVersion:  build.Version,
+			APIVersions: r.versions(),
+		})
+	})
+	router.Path("/openapi/v3").Methods(http.MethodGet).HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		sendJSON(resp, http.StatusOK, r.openAPI())
+	})
+
+	router.NotFoundHandler = http.HandlerFunc(r.notFound)
+	router.MethodNotAllowedHandler = http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		sendError(fmt.Errorf("method %s is not allowed for %s", req.Method, req.URL.Path), resp, http.StatusMethodNotAllowed)
+	})
+}
+
+// notFound explains why a route is missing, so that clients of another k0s version get a meaningful error
+func (r *registry) notFound(resp http.ResponseWriter, req *http.Request) {
+	version := strings.SplitN(strings.TrimPrefix(req.URL.Path, "/"), "/", 2)[0]
+	for _, v := range r.versions() {
+		if v == version {
+			sendError(fmt.Errorf("%s is not served by this controller, it might be disabled by the cluster configuration", req.URL.Path), resp, http.StatusNotFound)
+			return
+		}
+	}
+	sendError(fmt.Errorf("API version %q is not supported by this controller (k0s %s), supported versions: %s", version, build.Version, strings.Join(r.versions(), ", ")), resp, http.StatusNotFound)
+}
@@ -0,0 +1,103 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/k0sproject/k0s/pkg/apis/v1beta1"
+	"github.com/k0sproject/k0s/pkg/constant"
+)
+
+func TestOpenAPICoversAllRoutes(t *testing.T) {
+	c := &CmdOpts{ClusterConfig: v1beta1.DefaultClusterConfig(constant.CfgVars{})}
+	routes := c.routes()
+	require.NotEmpty(t, routes.routes)
+
+	doc := routes.openAPI()
+	for _, rt := range routes.routes {
+		ops, ok := doc.Paths[rt.fullPath()]
+		require.True(t, ok, "path %s missing from the OpenAPI document", rt.fullPath())
+		op, ok := ops[strings.ToLower(rt.method)]
+		require.True(t, ok, "%s %s missing from the OpenAPI document", rt.method, rt.fullPath())
+		assert.Equal(t, rt.summary, op.Summary)
+		assert.NotNil(t, op.Responses["200"].Content[rt.contentType].Schema, "%s %s has no response schema", rt.method, rt.fullPath())
+		if rt.role != "" {
+			assert.NotEmpty(t, op.Security, "%s %s must require a token", rt.method, rt.fullPath())
+		}
+		if rt.request != nil {
+			assert.NotNil(t, op.RequestBody, "%s %s has no request body", rt.method, rt.fullPath())
+		}
+	}
+	assert.Contains(t, doc.Paths, "/version")
+	assert.Contains(t, doc.Paths, "/healthz")
+
+	// the document must be serializable as served
+	_, err := json.Marshal(doc)
+	assert.NoError(t, err)
+}
+
+func TestRoutesFollowStorage(t *testing.T) {
+	cfg := v1beta1.DefaultClusterConfig(constant.CfgVars{})
+	cfg.Spec.Storage.Type = v1beta1.KineStorageType
+	cfg.Spec.Storage.Kine = &v1beta1.KineConfig{DataSource: "sqlite:///var/lib/k0s/db/state.db"}
+	c := &CmdOpts{ClusterConfig: cfg}
+
+	var paths []string
+	for _, rt := range c.routes().routes {
+		paths = append(paths, rt.fullPath())
+	}
+	assert.NotContains(t, paths, "/v1beta1/etcd/members")
+	assert.Contains(t, paths, "/v1beta1/worker/join")
+}
+
+func TestDiscoveryAndNotFound(t *testing.T) {
+	limiter, _ := newTestLimiter(100, 100, 0, 0)
+	h, _ := newTestServer(t, limiter)
+
+	rec := call(h, "/version", "", "10.0.0.1:40000")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var versions v1beta1.VersionResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &versions))
+	assert.Equal(t, []string{"v1beta1"}, versions.APIVersions)
+
+	rec = call(h, "/v1beta1/missing", "", "10.0.0.1:40000")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.Contains(t, rec.Body.String(), "is not served by this controller")
+
+	rec = call(h, "/v2/test", "", "10.0.0.1:40000")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.Contains(t, rec.Body.String(), `API version \"v2\" is not supported`)
+}
+
+func TestRegistryVersions(t *testing.T) {
+	routes := &registry{}
+	for _, v := range []string{"v1beta2", "v1beta1", "v1beta2"} {
+		routes.add(route{version: v, path: "/x", method: http.MethodGet, handler: http.NotFoundHandler()})
+	}
+	assert.Equal(t, []string{"v1beta1", "v1beta2"}, routes.versions())
+
+	router := mux.NewRouter()
+	routes.mount(router, &authenticator{})
+	assert.Equal(t, "application/json", routes.routes[0].contentType)
+}
@@ -0,0 +1,116 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package api
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/client-go/informers"
+	k8s "k8s.io/client-go/kubernetes"
+	listerv1 "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+const tokenNamespace = "kube-system"
+
+// tokenCache validates bootstrap tokens against an informer backed cache
+// of the token secrets instead of hitting the kube API on every call
+type tokenCache struct {
+	factory informers.SharedInformerFactory
+	lister  listerv1.SecretNamespaceLister
+	synced  cache.InformerSynced
+}
+
+func newTokenCache(client k8s.Interface) *tokenCache {
+	factory := informers.NewSharedInformerFactoryWithOptions(client, 10*time.Minute,
+		informers.WithNamespace(tokenNamespace),
+		informers.WithTweakListOptions(func(opts *metav1.ListOptions) {
+			opts.FieldSelector = fields.OneTermEqualSelector("type", string(corev1.SecretTypeBootstrapToken)).String()
+		}),
+	)
+	secrets := factory.Core().V1().Secrets()
+	return &tokenCache{
+		factory: factory,
+		lister:  secrets.Lister().Secrets(tokenNamespace),
+		synced:  secrets.Informer().HasSynced,
+	}
+}
+
+// Start starts the informers and waits for the initial sync
+func (t *tokenCache) Start(stopCh <-chan struct{}) error {
+	t.factory.Start(stopCh)
+	if !cache.WaitForCacheSync(stopCh, t.synced) {
+		return fmt.Errorf("failed to sync bootstrap token cache")
+	}
+	return nil
+}
+
+// tokenID returns the ID part of the token, or an empty string if the token is malformed
+func tokenID(token string) string {
+	parts := strings.Split(token, ".")
+	if len(parts) != 2 {
+		return ""
+	}
+	return parts[0]
+}
+
+/** The token is in form of xyz.foobar where:
+- xyz: the token "ID" in kube api
+- foobar: the token itself
+We need to validate:
+- that we find a secret with the ID
+- that the token matches whats inside the secret
+- that the token has not expired
+- that the token is allowed to be used for the given usage
+*/
+func (t *tokenCache) isValidToken(token string, usage string) bool {
+	parts := strings.Split(token, ".")
+	if len(parts) != 2 {
+		return false
+	}
+
+	secretName := fmt.Sprintf("bootstrap-token-%s", parts[0])
+	secret, err := t.lister.Get(secretName)
+	if err != nil {
+		logrus.Debugf("failed to get bootstrap token %s: %s", parts[0], err.Error())
+		return false
+	}
+
+	if subtle.ConstantTimeCompare(secret.Data["token-secret"], []byte(parts[1])) != 1 {
+		return false
+	}
+
+	if expiration, ok := secret.Data["expiration"]; ok {
+		expiry, err := time.Parse(time.RFC3339, string(expiration))
+		if err != nil || time.Now().After(expiry) {
+			return false
+		}
+	}
+
+	usageValue, ok := secret.Data[usage]
+	if !ok || string(usageValue) != "true" {
+		return false
+	}
+
+	return true
+}
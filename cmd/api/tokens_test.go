@@ -0,0 +1,102 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package api
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func tokenSecret(id, secret string, expiry time.Time) *corev1.Secret {
+	s := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "bootstrap-token-" + id, Namespace: tokenNamespace},
+		Type:       corev1.SecretTypeBootstrapToken,
+		Data: map[string][]byte{
+			"token-id":                         []byte(id),
+			"token-secret":                     []byte(secret),
+			"usage-bootstrap-api-worker-calls": []byte("true"),
+		},
+	}
+	if !expiry.IsZero() {
+		s.Data["expiration"] = []byte(expiry.Format(time.RFC3339))
+	}
+	return s
+}
+
+func startTokenCache(t *testing.T, objects ...*corev1.Secret) (*tokenCache, *fake.Clientset) {
+	client := fake.NewSimpleClientset()
+	for _, o := range objects {
+		_, err := client.CoreV1().Secrets(tokenNamespace).Create(context.TODO(), o, metav1.CreateOptions{})
+		require.NoError(t, err)
+	}
+	stopCh := make(chan struct{})
+	t.Cleanup(func() { close(stopCh) })
+	tokens := newTokenCache(client)
+	require.NoError(t, tokens.Start(stopCh))
+	return tokens, client
+}
+
+func TestTokenValidation(t *testing.T) {
+	tokens, _ := startTokenCache(t, tokenSecret("abcdef", "0123456789abcdef", time.Time{}))
+
+	assert.True(t, tokens.isValidToken("abcdef.0123456789abcdef", "usage-bootstrap-api-worker-calls"))
+	assert.False(t, tokens.isValidToken("abcdef.0123456789abcdeX", "usage-bootstrap-api-worker-calls"), "wrong secret")
+	assert.False(t, tokens.isValidToken("abcdef.0123456789abcdef", "usage-controller-join"), "wrong usage")
+	assert.False(t, tokens.isValidToken("fedcba.0123456789abcdef", "usage-bootstrap-api-worker-calls"), "unknown token")
+	assert.False(t, tokens.isValidToken("abcdef0123456789abcdef", "usage-bootstrap-api-worker-calls"), "malformed token")
+}
+
+func TestTokenExpiry(t *testing.T) {
+	tokens, _ := startTokenCache(t,
+		tokenSecret("abcdef", "0123456789abcdef", time.Now().Add(-time.Minute)),
+		tokenSecret("ghijkl", "0123456789abcdef", time.Now().Add(time.Hour)),
+	)
+
+	assert.False(t, tokens.isValidToken("abcdef.0123456789abcdef", "usage-bootstrap-api-worker-calls"), "expired token")
+	assert.True(t, tokens.isValidToken("ghijkl.0123456789abcdef", "usage-bootstrap-api-worker-calls"))
+}
+
+func TestTokenCacheInvalidation(t *testing.T) {
+	tokens, client := startTokenCache(t, tokenSecret("abcdef", "0123456789abcdef", time.Time{}))
+	require.True(t, tokens.isValidToken("abcdef.0123456789abcdef", "usage-bootstrap-api-worker-calls"))
+
+	// an invalidated token must be rejected once the informer sees the change
+	_, err := client.CoreV1().Secrets(tokenNamespace).Update(context.TODO(),
+		tokenSecret("abcdef", "0123456789abcdef", time.Now().Add(-time.Second)), metav1.UpdateOptions{})
+	require.NoError(t, err)
+	assert.Eventually(t, func() bool {
+		return !tokens.isValidToken("abcdef.0123456789abcdef", "usage-bootstrap-api-worker-calls")
+	}, 5*time.Second, 10*time.Millisecond, "expired token must be rejected")
+
+	_, err = client.CoreV1().Secrets(tokenNamespace).Update(context.TODO(),
+		tokenSecret("abcdef", "0123456789abcdef", time.Time{}), metav1.UpdateOptions{})
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		return tokens.isValidToken("abcdef.0123456789abcdef", "usage-bootstrap-api-worker-calls")
+	}, 5*time.Second, 10*time.Millisecond)
+
+	require.NoError(t, client.CoreV1().Secrets(tokenNamespace).Delete(context.TODO(), "bootstrap-token-abcdef", metav1.DeleteOptions{}))
+	assert.Eventually(t, func() bool {
+		return !tokens.isValidToken("abcdef.0123456789abcdef", "usage-bootstrap-api-worker-calls")
+	}, 5*time.Second, 10*time.Millisecond, "deleted token must be rejected")
+}
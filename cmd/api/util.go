@@ -16,9 +16,12 @@ limitations under the License.
 package api
 
 import (
+	"encoding/json"
 	"net/http"
 
 	"github.com/sirupsen/logrus"
+
+	"github.com/k0sproject/k0s/pkg/apis/v1beta1"
 )
 
 func sendError(err error, resp http.ResponseWriter, status ...int) {
@@ -27,10 +30,17 @@ func sendError(err error, resp http.ResponseWriter, status ...int) {
 		code = status[0]
 	}
 	logrus.Error(err)
-	resp.Header().Set("Content-Type", "text/plain")
+	sendJSON(resp, code, v1beta1.ErrorResponse{
+		Code:    code,
+		Reason:  http.StatusText(code),
+		Message: err.Error(),
+	})
+}
+
+func sendJSON(resp http.ResponseWriter, code int, body interface{}) {
+	resp.Header().Set("Content-Type", "application/json")
 	resp.WriteHeader(code)
-	if _, err := resp.Write([]byte(err.Error())); err != nil {
-		sendError(err, resp)
-		return
+	if err := json.NewEncoder(resp).Encode(body); err != nil {
+		logrus.Errorf("failed to write response: %s", err.Error())
 	}
 }
@@ -0,0 +1,162 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	"github.com/k0sproject/k0s/pkg/apis/v1beta1"
+	"github.com/k0sproject/k0s/pkg/constant"
+	"github.com/k0sproject/k0s/pkg/token"
+)
+
+// nodeBootstrapTokenExpiry is how long a joining node has to complete the kubelet TLS bootstrap
+const nodeBootstrapTokenExpiry = time.Hour
+
+// workerJoinHandler hands out a node scoped kubelet bootstrap config together with the cluster settings the worker needs
+func (c *CmdOpts) workerJoinHandler() http.Handler {
+	return http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		ctx := req.Context()
+		var joinReq v1beta1.WorkerJoinRequest
+		if err := json.NewDecoder(req.Body).Decode(&joinReq); err != nil {
+			sendError(err, resp, http.StatusBadRequest)
+			return
+		}
+		if err := joinReq.Validate(); err != nil {
+			sendError(err, resp, http.StatusBadRequest)
+			return
+		}
+
+		nodeName := strings.ToLower(joinReq.Hostname)
+		logrus.Infof("worker API, joining node %s (%s/%s)", nodeName, joinReq.OS, joinReq.Arch)
+		if status, err := c.checkNodeName(ctx, nodeName); err != nil {
+			sendError(err, resp, status)
+			return
+		}
+		if err := validateNodeLabels(joinReq.Labels); err != nil {
+			sendError(err, resp, http.StatusBadRequest)
+			return
+		}
+
+		profile := joinReq.Profile
+		if profile == "" {
+			profile = "default"
+			if joinReq.OS == "windows" {
+				profile = "default-windows"
+			}
+		}
+		kubeletConfig, err := c.workerProfile(ctx, profile)
+		if err != nil {
+			status := http.StatusInternalServerError
+			if apierrors.IsNotFound(err) {
+				status = http.StatusBadRequest
+				err = fmt.Errorf("unknown worker profile %q", profile)
+			}
+			sendError(err, resp, status)
+			return
+		}
+
+		dnsAddress, err := c.ClusterConfig.Spec.Network.DNSAddress()
+		if err != nil {
+			sendError(err, resp)
+			return
+		}
+
+		caCert, err := ioutil.ReadFile(filepath.Join(c.K0sVars.CertRootDir, "ca.crt"))
+		if err != nil {
+			sendError(err, resp)
+			return
+		}
+
+		tokenString, err := token.NewManagerForClient(c.KubeClient).CreateForNode(nodeBootstrapTokenExpiry, nodeName)
+		if err != nil {
+			sendError(fmt.Errorf("failed to create bootstrap token for node %s: %w", nodeName, err), resp)
+			return
+		}
+		apiAddress := c.ClusterConfig.Spec.API.APIAddressURL()
+		kubeconfig, err := token.NodeBootstrapKubeconfig(apiAddress, caCert, tokenString)
+		if err != nil {
+			sendError(err, resp)
+			return
+		}
+
+		sendJSON(resp, http.StatusOK, v1beta1.WorkerJoinResponse{
+			Kubeconfig:    kubeconfig,
+			Profile:       profile,
+			KubeletConfig: kubeletConfig,
+			APIAddress:    apiAddress,
+			ClusterDNS:    dnsAddress,
+			ClusterDomain: c.ClusterConfig.Spec.Network.ClusterDomain,
+			ServiceCIDR:   c.ClusterConfig.Spec.Network.ServiceCIDR,
+			PodCIDR:       c.ClusterConfig.Spec.Network.PodCIDR,
+			CACert:        caCert,
+		})
+	})
+}
+
+// checkNodeName enforces the node name policy: the name must be a valid node name
+// and must not be taken by an already registered node
+func (c *CmdOpts) checkNodeName(ctx context.Context, nodeName string) (int, error) {
+	if errs := validation.IsDNS1123Subdomain(nodeName); len(errs) > 0 {
+		return http.StatusBadRequest, fmt.Errorf("invalid node name %q: %s", nodeName, strings.Join(errs, ", "))
+	}
+	_, err := c.KubeClient.CoreV1().Nodes().Get(ctx, nodeName, v1.GetOptions{})
+	if err == nil {
+		return http.StatusConflict, fmt.Errorf("node %s is already registered, delete it before joining a new node with the same name", nodeName)
+	}
+	if !apierrors.IsNotFound(err) {
+		return http.StatusInternalServerError, err
+	}
+	return http.StatusOK, nil
+}
+
+func validateNodeLabels(labels map[string]string) error {
+	for k, v := range labels {
+		if errs := validation.IsQualifiedName(k); len(errs) > 0 {
+			return fmt.Errorf("invalid label key %q: %s", k, strings.Join(errs, ", "))
+		}
+		if errs := validation.IsValidLabelValue(v); len(errs) > 0 {
+			return fmt.Errorf("invalid value for label %q: %s", k, strings.Join(errs, ", "))
+		}
+	}
+	return nil
+}
+
+// workerProfile returns the resolved kubelet config of the given worker profile
+func (c *CmdOpts) workerProfile(ctx context.Context, profile string) (string, error) {
+	cmName := fmt.Sprintf("kubelet-config-%s-%s", profile, constant.KubernetesMajorMinorVersion)
+	cm, err := c.KubeClient.CoreV1().ConfigMaps("kube-system").Get(ctx, cmName, v1.GetOptions{})
+	if err != nil {
+		return "", err
+	}
+	config := cm.Data["kubelet"]
+	if config == "" {
+		return "", fmt.Errorf("no config found with key 'kubelet' in %s", cmName)
+	}
+	return config, nil
+}
@@ -0,0 +1,152 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/k0sproject/k0s/pkg/apis/v1beta1"
+	"github.com/k0sproject/k0s/pkg/constant"
+	"github.com/k0sproject/k0s/pkg/token"
+)
+
+func kubeletConfigMap(profile string) *corev1.ConfigMap {
+	return &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("kubelet-config-%s-%s", profile, constant.KubernetesMajorMinorVersion),
+			Namespace: "kube-system",
+		},
+		Data: map[string]string{"kubelet": "kind: KubeletConfiguration"},
+	}
+}
+
+func newTestWorkerAPI(t *testing.T, objects ...runtime.Object) (*CmdOpts, *fake.Clientset) {
+	k0sVars := constant.GetConfig(t.TempDir())
+	k0sVars.CertRootDir = t.TempDir()
+	require.NoError(t, ioutil.WriteFile(filepath.Join(k0sVars.CertRootDir, "ca.crt"), []byte("ca"), 0600))
+
+	clusterConfig := v1beta1.DefaultClusterConfig(k0sVars)
+	clusterConfig.Spec.Network.ClusterDomain = "k0s.example"
+
+	client := fake.NewSimpleClientset(objects...)
+	return &CmdOpts{
+		ClusterConfig: clusterConfig,
+		K0sVars:       k0sVars,
+		KubeClient:    client,
+	}, client
+}
+
+func join(c *CmdOpts, joinReq v1beta1.WorkerJoinRequest) *httptest.ResponseRecorder {
+	body, _ := json.Marshal(joinReq)
+	req := httptest.NewRequest(http.MethodPost, "/v1beta1/worker/join", bytes.NewReader(body))
+	rec := httptest.NewRecorder()
+	c.workerJoinHandler().ServeHTTP(rec, req)
+	return rec
+}
+
+func TestWorkerJoin(t *testing.T) {
+	c, client := newTestWorkerAPI(t, kubeletConfigMap("default"))
+
+	rec := join(c, v1beta1.WorkerJoinRequest{Hostname: "Worker-0", OS: "linux", Arch: "amd64", Labels: map[string]string{"foo": "bar"}})
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	var joinResp v1beta1.WorkerJoinResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &joinResp))
+	assert.Equal(t, "default", joinResp.Profile)
+	assert.Equal(t, "kind: KubeletConfiguration", joinResp.KubeletConfig)
+	assert.Equal(t, "k0s.example", joinResp.ClusterDomain)
+	assert.Equal(t, "10.96.0.10", joinResp.ClusterDNS)
+	assert.Equal(t, c.ClusterConfig.Spec.Network.ServiceCIDR, joinResp.ServiceCIDR)
+	assert.Equal(t, c.ClusterConfig.Spec.Network.PodCIDR, joinResp.PodCIDR)
+	assert.Equal(t, []byte("ca"), joinResp.CACert)
+
+	secrets, err := client.CoreV1().Secrets("kube-system").List(context.TODO(), metav1.ListOptions{})
+	require.NoError(t, err)
+	require.Len(t, secrets.Items, 1)
+	secret := secrets.Items[0]
+	assert.Equal(t, "worker-0", secret.Labels[token.NodeNameLabel], "the token must be scoped to the lower cased node name")
+	assert.Equal(t, token.NodeBootstrapGroup("worker-0"), secret.StringData["auth-extra-groups"])
+	assert.Contains(t, string(joinResp.Kubeconfig), "token: "+secret.StringData["token-id"]+"."+secret.StringData["token-secret"])
+}
+
+func TestWorkerJoinErrors(t *testing.T) {
+	existing := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "worker-0"}}
+
+	for _, test := range []struct {
+		name   string
+		req    v1beta1.WorkerJoinRequest
+		status int
+	}{
+		{"missing_arch", v1beta1.WorkerJoinRequest{Hostname: "worker-1", OS: "linux"}, http.StatusBadRequest},
+		{"invalid_node_name", v1beta1.WorkerJoinRequest{Hostname: "worker_1", OS: "linux", Arch: "amd64"}, http.StatusBadRequest},
+		{"existing_node", v1beta1.WorkerJoinRequest{Hostname: "worker-0", OS: "linux", Arch: "amd64"}, http.StatusConflict},
+		{"invalid_label", v1beta1.WorkerJoinRequest{Hostname: "worker-1", OS: "linux", Arch: "amd64", Labels: map[string]string{"foo/bar/baz": "x"}}, http.StatusBadRequest},
+		{"unknown_profile", v1beta1.WorkerJoinRequest{Hostname: "worker-1", OS: "linux", Arch: "amd64", Profile: "gpu"}, http.StatusBadRequest},
+		{"no_windows_profile", v1beta1.WorkerJoinRequest{Hostname: "worker-1", OS: "windows", Arch: "amd64"}, http.StatusBadRequest},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			c, client := newTestWorkerAPI(t, existing, kubeletConfigMap("default"))
+
+			rec := join(c, test.req)
+			assert.Equal(t, test.status, rec.Code, rec.Body.String())
+
+			secrets, err := client.CoreV1().Secrets("kube-system").List(context.TODO(), metav1.ListOptions{})
+			require.NoError(t, err)
+			assert.Empty(t, secrets.Items, "no token must be created for a rejected join")
+		})
+	}
+}
+
+func TestCheckNodeName(t *testing.T) {
+	c, _ := newTestWorkerAPI(t, &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "worker-0"}})
+
+	for _, test := range []struct {
+		nodeName string
+		status   int
+	}{
+		{"worker-1", http.StatusOK},
+		{"worker-1.example.com", http.StatusOK},
+		{"worker-0", http.StatusConflict},
+		{"Worker-1", http.StatusBadRequest},
+		{"worker_1", http.StatusBadRequest},
+		{"", http.StatusBadRequest},
+	} {
+		t.Run(test.nodeName, func(t *testing.T) {
+			status, err := c.checkNodeName(context.TODO(), test.nodeName)
+			assert.Equal(t, test.status, status)
+			if test.status == http.StatusOK {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
@@ -42,7 +42,7 @@ func NewBackupCmd() *cobra.Command {
 		Short: "Back-Up k0s configuration. Must be run as root (or with sudo)",
 		RunE: func(cmd *cobra.Command, args []string) error {
 			c := CmdOpts(config.GetCmdOpts())
-			cfg, err := config.GetYamlFromFile(c.CfgFile, c.K0sVars)
+			cfg, err := config.GetYamlFromFile(c.CfgFile, c.CfgDir, c.K0sVars)
 			if err != nil {
 				return err
 			}
@@ -92,7 +92,7 @@ func (c *CmdOpts) backup() error {
 
 func preRunValidateConfig(cmd *cobra.Command, args []string) error {
 	c := CmdOpts(config.GetCmdOpts())
-	_, err := config.ValidateYaml(c.CfgFile, c.K0sVars)
+	_, err := config.ValidateYaml(c.CfgFile, c.CfgDir, c.K0sVars)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,134 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package config
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/k0sproject/k0s/internal/util"
+	"github.com/k0sproject/k0s/pkg/apis/v1beta1"
+	"github.com/k0sproject/k0s/pkg/component/controller"
+	"github.com/k0sproject/k0s/pkg/component/worker"
+	"github.com/k0sproject/k0s/pkg/config"
+	"github.com/k0sproject/k0s/pkg/flags"
+)
+
+// argsSingleNode tells if the flags are printed for a single node controller, which doesn't run konnectivity
+var argsSingleNode bool
+
+var argsComponents = []string{
+	"containerd",
+	"etcd",
+	"kine",
+	"konnectivity-server",
+	"kube-apiserver",
+	"kube-controller-manager",
+	"kube-scheduler",
+	"kubelet",
+}
+
+func configArgsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "args <component>",
+		Short: "Print the flags k0s runs a component with",
+		Long: fmt.Sprintf(`Print the flags k0s runs a component with, the flags k0s sets merged with the extra args of the
+config, or of the --kubelet-extra-args and --containerd-extra-args flags for the worker components.
+Conflicts between the extra args and the flags k0s sets are reported as errors. Use --single for
+the flags of a controller started with --single.
+
+Components: %s
+
+Example:
+   k0s config args kube-apiserver --config /etc/k0s/k0s.yaml
+   k0s config args kubelet --kubelet-extra-args="--v=4 --node-ip=10.0.0.1"`, strings.Join(argsComponents, ", ")),
+		Args:      cobra.ExactArgs(1),
+		ValidArgs: argsComponents,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c := CmdOpts(config.GetCmdOpts())
+			c.Logging = util.MapMerge(c.CmdLogLevels, c.DefaultLogLevels)
+			_, cfg, err := config.LoadComposed(c.CfgFile, c.CfgDir, c.K0sVars)
+			if err != nil {
+				return err
+			}
+
+			componentArgs, err := c.componentArgs(args[0], cfg)
+			if err != nil {
+				return err
+			}
+			for _, arg := range componentArgs.ToArgs() {
+				fmt.Fprintln(cmd.OutOrStdout(), arg)
+			}
+			return nil
+		},
+	}
+	cmd.PersistentFlags().AddFlagSet(config.GetPersistentFlagSet())
+	cmd.Flags().AddFlagSet(config.GetWorkerFlags())
+	cmd.Flags().BoolVar(&argsSingleNode, "single", false, "print the flags of a single node controller")
+	return cmd
+}
+
+func (c *CmdOpts) componentArgs(name string, cfg *v1beta1.ClusterConfig) (flags.Args, error) {
+	switch name {
+	case "kube-apiserver":
+		api := &controller.APIServer{ClusterConfig: cfg, K0sVars: c.K0sVars, LogLevel: c.Logging[name], EnableKonnectivity: !argsSingleNode}
+		return api.Args()
+	case "kube-controller-manager":
+		cm := &controller.Manager{ClusterConfig: cfg, K0sVars: c.K0sVars, LogLevel: c.Logging[name]}
+		return cm.Args()
+	case "kube-scheduler":
+		scheduler := &controller.Scheduler{ClusterConfig: cfg, K0sVars: c.K0sVars, LogLevel: c.Logging[name]}
+		return scheduler.Args()
+	case "konnectivity-server":
+		if argsSingleNode {
+			return nil, fmt.Errorf("konnectivity-server isn't run on single node controllers")
+		}
+		// the server count is only known at runtime, it's the count of the controllers
+		konnectivity := &controller.Konnectivity{ClusterConfig: cfg, K0sVars: c.K0sVars, LogLevel: c.Logging[name]}
+		return konnectivity.Args(1)
+	case "etcd":
+		if cfg.Spec.Storage.Type != v1beta1.EtcdStorageType {
+			return nil, fmt.Errorf("the storage type is %s, not etcd", cfg.Spec.Storage.Type)
+		}
+		if cfg.Spec.Storage.Etcd.IsExternalClusterUsed() {
+			return nil, fmt.Errorf("etcd is not managed by k0s, an external cluster is configured")
+		}
+		etcd := &controller.Etcd{Config: cfg.Spec.Storage.Etcd, K0sVars: c.K0sVars, LogLevel: c.Logging[name]}
+		return etcd.Args()
+	case "kine":
+		if cfg.Spec.Storage.Type != v1beta1.KineStorageType {
+			return nil, fmt.Errorf("the storage type is %s, not kine", cfg.Spec.Storage.Type)
+		}
+		kine := &controller.Kine{Config: cfg.Spec.Storage.Kine, K0sVars: c.K0sVars}
+		return kine.Args()
+	case "kubelet":
+		kubelet := &worker.Kubelet{
+			CRISocket:           c.CriSocket,
+			EnableCloudProvider: c.CloudProvider,
+			K0sVars:             c.K0sVars,
+			LogLevel:            c.Logging[name],
+			Labels:              c.Labels,
+			ExtraArgs:           c.KubeletExtraArgs,
+		}
+		return kubelet.Args()
+	case "containerd":
+		containerd := &worker.ContainerD{K0sVars: c.K0sVars, LogLevel: c.Logging[name], ExtraArgs: c.ContainerdExtraArgs}
+		return containerd.Args()
+	}
+	return nil, fmt.Errorf("unknown component %q, expected one of: %s", name, strings.Join(argsComponents, ", "))
+}
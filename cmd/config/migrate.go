@@ -0,0 +1,81 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/k0sproject/k0s/pkg/apis/v1beta2"
+	"github.com/k0sproject/k0s/pkg/config"
+)
+
+type CmdOpts config.CLIOptions
+
+var inPlace bool
+
+func configMigrateCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "migrate",
+		Short: "Migrate the config file to the newest config version",
+		Long: `Migrate the config file to the newest config version. Only the keys renamed in the newest version
+are changed, the settings omitted from the file keep getting their defaults.
+
+Example:
+   k0s config migrate --config k0s.yaml > k0s.v1beta2.yaml
+   k0s config migrate --config k0s.yaml --in-place`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c := CmdOpts(config.GetCmdOpts())
+			var data []byte
+			var err error
+			switch c.CfgFile {
+			case "":
+				return fmt.Errorf("no config file given, use --config")
+			case "-":
+				if inPlace {
+					return fmt.Errorf("can't migrate stdin in place")
+				}
+				data, err = ioutil.ReadAll(os.Stdin)
+			default:
+				data, err = ioutil.ReadFile(c.CfgFile)
+			}
+			if err != nil {
+				return fmt.Errorf("failed to read the config: %w", err)
+			}
+
+			migrated, err := v1beta2.Migrate(data)
+			if err != nil {
+				return fmt.Errorf("can't migrate the config: %w", err)
+			}
+
+			if !inPlace {
+				_, err = cmd.OutOrStdout().Write(migrated)
+				return err
+			}
+			info, err := os.Stat(c.CfgFile)
+			if err != nil {
+				return err
+			}
+			return ioutil.WriteFile(c.CfgFile, migrated, info.Mode())
+		},
+	}
+	cmd.Flags().BoolVar(&inPlace, "in-place", false, "rewrite the config file instead of printing the migrated config")
+	cmd.PersistentFlags().AddFlagSet(config.GetPersistentFlagSet())
+	return cmd
+}
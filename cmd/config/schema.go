@@ -0,0 +1,46 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/k0sproject/k0s/pkg/apis/v1beta1"
+)
+
+func configSchemaCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "schema",
+		Short: "Print the JSON Schema of the k0s config file",
+		Long: `Print the JSON Schema of the k0s config file. Editors and CI pipelines can use it to validate
+the config before it's deployed to the nodes.
+
+Example:
+   k0s config schema > k0s.schema.json`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			data, err := json.MarshalIndent(v1beta1.ClusterConfigSchema(), "", "  ")
+			if err != nil {
+				return err
+			}
+			fmt.Fprintln(cmd.OutOrStdout(), string(data))
+			return nil
+		},
+	}
+	return cmd
+}
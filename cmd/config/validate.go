@@ -0,0 +1,57 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package config
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/k0sproject/k0s/pkg/component/controller"
+	"github.com/k0sproject/k0s/pkg/config"
+)
+
+func configValidateCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "validate",
+		Short: "Validate the config and the extra args of the control plane components",
+		Long: `Validate the config composed of the config file, the config directory and the environment overrides.
+The flag names of the extra args are checked against the flags the component binaries installed in the
+data directory know, the components whose binary isn't installed yet are skipped.
+
+Example:
+   k0s config validate --config /etc/k0s/k0s.yaml`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c := CmdOpts(config.GetCmdOpts())
+			_, cfg, err := config.LoadComposed(c.CfgFile, c.CfgDir, c.K0sVars)
+			if err != nil {
+				return err
+			}
+			if errs := controller.ValidateExtraArgs(cfg.Spec, c.K0sVars.BinDir); len(errs) > 0 {
+				messages := make([]string, len(errs))
+				for i, err := range errs {
+					messages[i] = err.Error()
+				}
+				return fmt.Errorf("invalid extra args:\n%s", strings.Join(messages, "\n"))
+			}
+			fmt.Fprintln(cmd.OutOrStdout(), "config is valid")
+			return nil
+		},
+	}
+	cmd.PersistentFlags().AddFlagSet(config.GetPersistentFlagSet())
+	return cmd
+}
@@ -0,0 +1,68 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package config
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/k0sproject/k0s/pkg/config"
+)
+
+var effective bool
+
+func configViewCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "view",
+		Short: "Print the config composed of the config file, the config directory and the environment overrides",
+		Long: `Print the config composed of the config file, the fragments of the config directory and the
+K0S_CONFIG_* environment overrides, with the origin of each value as a comment. With --effective the
+whole config k0s uses is printed, the values not set by any of the sources are marked as defaults.
+
+Example:
+   k0s config view --config /etc/k0s/k0s.yaml --config-dir /etc/k0s/conf.d
+   K0S_CONFIG_SPEC_API_ADDRESS=10.0.0.1 k0s config view --config k0s.yaml --effective`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c := CmdOpts(config.GetCmdOpts())
+			composed, cfg, err := config.LoadComposed(c.CfgFile, c.CfgDir, c.K0sVars)
+			if err != nil {
+				return err
+			}
+			if composed == nil {
+				if !effective {
+					return fmt.Errorf("no config sources given, use --config, --config-dir or the %s* environment variables", config.EnvOverridePrefix)
+				}
+				composed = &config.ComposedConfig{}
+			}
+
+			var data []byte
+			if effective {
+				data, err = composed.AnnotatedEffective(cfg)
+			} else {
+				data, err = composed.Annotated()
+			}
+			if err != nil {
+				return err
+			}
+			_, err = cmd.OutOrStdout().Write(data)
+			return err
+		},
+	}
+	cmd.Flags().BoolVar(&effective, "effective", false, "print the whole config including the defaults")
+	cmd.PersistentFlags().AddFlagSet(config.GetPersistentFlagSet())
+	return cmd
+}
@@ -24,6 +24,7 @@ import (
 	"os/signal"
 	"path"
 	"path/filepath"
+	"strings"
 	"syscall"
 	"time"
 
@@ -41,6 +42,7 @@ import (
 	"github.com/k0sproject/k0s/pkg/component/controller"
 	"github.com/k0sproject/k0s/pkg/config"
 	"github.com/k0sproject/k0s/pkg/constant"
+	"github.com/k0sproject/k0s/pkg/encryption"
 	"github.com/k0sproject/k0s/pkg/kubernetes"
 	"github.com/k0sproject/k0s/pkg/performance"
 	"github.com/k0sproject/k0s/pkg/telemetry"
@@ -81,7 +83,7 @@ func NewControllerCmd() *cobra.Command {
 				c.K0sVars.DefaultStorageType = "kine"
 			}
 			c.Logging = util.MapMerge(c.CmdLogLevels, c.DefaultLogLevels)
-			cfg, err := config.GetYamlFromFile(c.CfgFile, c.K0sVars)
+			cfg, err := config.GetYamlFromFile(c.CfgFile, c.CfgDir, c.K0sVars)
 			if err != nil {
 				return err
 			}
@@ -125,9 +127,48 @@ func writeCerts(caData v1beta1.CaResponse, certRootDir string) error {
 			return fmt.Errorf("failed to write %s: %w", f.path, err)
 		}
 	}
+	if len(caData.EncryptionKeys) > 0 {
+		return writeEncryptionKeys(caData.EncryptionKeys, certRootDir)
+	}
+	return nil
+}
+
+func writeEncryptionKeys(data []byte, certRootDir string) error {
+	keysPath := encryption.KeysPath(certRootDir)
+	if err := util.InitDirectory(filepath.Dir(keysPath), constant.EncryptionKeysDirMode); err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(keysPath, data, constant.CertSecureMode); err != nil {
+		return fmt.Errorf("failed to write %s: %w", keysPath, err)
+	}
 	return nil
 }
 
+// syncEncryptionKeys fetches the encryption keys from the controller this controller joined when there are
+// none yet or the fetched ones are newer, e.g. because the encryption got enabled after joining or the keys
+// have been rotated. Failing that, kube-apiserver refuses to start until the keys are copied over.
+func (c *CmdOpts) syncEncryptionKeys() {
+	if c.ClusterConfig.Spec.API.Encryption == nil && !util.FileExists(encryption.KeysPath(c.K0sVars.CertRootDir)) {
+		return
+	}
+	if _, err := controller.SyncEncryptionKeys(c.K0sVars.CertRootDir, c.fetchEncryptionKeys); err != nil {
+		logrus.Warnf("failed to fetch the encryption keys: %v", err)
+	}
+}
+
+// fetchEncryptionKeys returns the keys file of the controller this controller joined
+func (c *CmdOpts) fetchEncryptionKeys() ([]byte, error) {
+	joinClient, err := token.JoinClientFromToken(c.TokenArg)
+	if err != nil {
+		return nil, err
+	}
+	caData, err := joinClient.GetCA()
+	if err != nil {
+		return nil, err
+	}
+	return caData.EncryptionKeys, nil
+}
+
 func joinController(tokenArg string, certRootDir string) (*token.JoinClient, error) {
 	joinClient, err := token.JoinClientFromToken(tokenArg)
 	if err != nil {
@@ -151,7 +192,11 @@ func joinController(tokenArg string, certRootDir string) (*token.JoinClient, err
 func (c *CmdOpts) startController() error {
 	existingCNI := c.existingCNIProvider()
 	if existingCNI != "" && existingCNI != c.ClusterConfig.Spec.Network.Provider {
-		return fmt.Errorf("cannot change CNI from %s to %s", existingCNI, c.ClusterConfig.Spec.Network.Provider)
+		if !c.EnableDynamicConfig {
+			return fmt.Errorf("cannot change CNI from %s to %s, use `k0s network offline-migrate` with the dynamic config enabled to switch the network provider", existingCNI, c.ClusterConfig.Spec.Network.Provider)
+		}
+		// the network provider stored in the cluster takes precedence over the config file
+		logrus.Warnf("the config file sets the network provider to %s but %s is deployed, use `k0s network offline-migrate` to switch the network provider", c.ClusterConfig.Spec.Network.Provider, existingCNI)
 	}
 	perfTimer := performance.NewTimer("controller-start").Buffer().Start()
 
@@ -174,6 +219,8 @@ func (c *CmdOpts) startController() error {
 		if err != nil {
 			return fmt.Errorf("failed to join controller: %w", err)
 		}
+	} else if c.TokenArg != "" {
+		c.syncEncryptionKeys()
 	}
 	componentManager.AddSync(&controller.Certificates{
 		ClusterSpec: c.ClusterConfig.Spec,
@@ -198,6 +245,10 @@ func (c *CmdOpts) startController() error {
 			K0sVars: c.K0sVars,
 		}
 	case v1beta1.EtcdStorageType:
+		if c.ClusterConfig.Spec.Storage.Etcd.IsExternalClusterUsed() {
+			logrus.Infof("Using external etcd cluster: %s", strings.Join(c.ClusterConfig.Spec.Storage.Etcd.ExternalCluster.Endpoints, ","))
+			break
+		}
 		storageBackend = &controller.Etcd{
 			CertManager: certificateManager,
 			Config:      c.ClusterConfig.Spec.Storage.Etcd,
@@ -209,7 +260,15 @@ func (c *CmdOpts) startController() error {
 		return fmt.Errorf("invalid storage type: %s", c.ClusterConfig.Spec.Storage.Type)
 	}
 	logrus.Infof("Using storage backend %s", c.ClusterConfig.Spec.Storage.Type)
-	componentManager.Add(storageBackend)
+	if storageBackend != nil {
+		componentManager.Add(storageBackend)
+	}
+	if _, ok := storageBackend.(*controller.Etcd); ok {
+		componentManager.Add(&controller.EtcdMaintainer{
+			Config:  c.ClusterConfig.Spec.Storage.Etcd,
+			K0sVars: c.K0sVars,
+		})
+	}
 
 	// common factory to get the admin kube client that's needed in many components
 	adminClientFactory := kubernetes.NewAdminClientFactory(c.K0sVars)
@@ -220,8 +279,19 @@ func (c *CmdOpts) startController() error {
 		LogLevel:           c.Logging["kube-apiserver"],
 		Storage:            storageBackend,
 		EnableKonnectivity: !c.SingleNode,
+		// the controllers started without a join token initialize the cluster
+		GenerateEncryptionKeys: c.TokenArg == "",
 	})
 
+	encryptionKeys := &controller.EncryptionKeys{
+		K0sVars:           c.K0sVars,
+		KubeClientFactory: adminClientFactory,
+	}
+	if c.TokenArg != "" {
+		encryptionKeys.FetchKeys = c.fetchEncryptionKeys
+	}
+	componentManager.Add(encryptionKeys)
+
 	if c.ClusterConfig.Spec.API.ExternalAddress != "" {
 		componentManager.Add(&controller.K0sLease{
 			ClusterConfig:     c.ClusterConfig,
@@ -247,19 +317,23 @@ func (c *CmdOpts) startController() error {
 		K0sVars:       c.K0sVars,
 	})
 
-	// One leader elector per controller
+	// One leader elector per controller. Whenever the storage allows more controllers to join the
+	// cluster, the controllers elect the leader through their local API server, regardless of how
+	// they're exposed to the rest of the cluster.
 	var leaderElector controller.LeaderElector
-	if c.ClusterConfig.Spec.API.ExternalAddress != "" {
+	if !c.SingleNode && c.ClusterConfig.Spec.Storage.IsJoinable() {
 		leaderElector = controller.NewLeaderElector(c.ClusterConfig, adminClientFactory)
 	} else {
 		leaderElector = &controller.DummyLeaderElector{Leader: true}
 	}
 	componentManager.Add(leaderElector)
+	componentManager.SetLeaderElection(leaderElector)
 
-	componentManager.Add(&applier.Manager{K0sVars: c.K0sVars, KubeClientFactory: adminClientFactory, LeaderElector: leaderElector})
+	componentManager.Add(&applier.Manager{K0sVars: c.K0sVars, KubeClientFactory: adminClientFactory})
 	if !c.SingleNode {
 		componentManager.Add(&controller.K0SControlAPI{
 			ConfigPath: c.CfgFile,
+			ConfigDir:  c.CfgDir,
 			K0sVars:    c.K0sVars,
 		})
 	}
@@ -275,13 +349,27 @@ func (c *CmdOpts) startController() error {
 	if c.ClusterConfig.Spec.API.ExternalAddress != "" {
 		componentManager.Add(controller.NewEndpointReconciler(
 			c.ClusterConfig,
-			leaderElector,
+			adminClientFactory,
+		))
+	}
+
+	if etcdConf := c.ClusterConfig.Spec.Storage.Etcd; c.ClusterConfig.Spec.Storage.Type == v1beta1.EtcdStorageType &&
+		etcdConf.IsLearnerEnabled() && !etcdConf.Learner.ManualPromotion {
+		componentManager.Add(&controller.EtcdLearnerPromoter{
+			Config:  etcdConf,
+			K0sVars: c.K0sVars,
+		})
+	}
+
+	if c.ClusterConfig.Spec.Storage.Type == v1beta1.EtcdStorageType && c.ClusterConfig.Spec.Storage.Etcd.IsMemberCleanupEnabled() {
+		componentManager.Add(controller.NewEtcdMemberReconciler(
+			c.ClusterConfig,
+			c.K0sVars,
 			adminClientFactory,
 		))
 	}
 
 	componentManager.Add(controller.NewCSRApprover(c.ClusterConfig,
-		leaderElector,
 		adminClientFactory))
 
 	if c.EnableK0sCloudProvider {
@@ -330,6 +418,17 @@ func (c *CmdOpts) startController() error {
 		ch <- syscall.SIGTERM
 	}
 
+	// the cluster wide config stored in the cluster takes precedence over the config file
+	var clusterConfigReconciler *controller.ClusterConfigReconciler
+	if c.EnableDynamicConfig {
+		clusterConfigReconciler = controller.NewClusterConfigReconciler(c.ClusterConfig, adminClientFactory)
+		cfg, err := clusterConfigReconciler.Load(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to load the cluster config: %w", err)
+		}
+		c.ClusterConfig = cfg
+	}
+
 	// in-cluster component reconcilers
 	reconcilers, err := c.createClusterReconcilers(adminClientFactory, leaderElector)
 	if err != nil {
@@ -347,6 +446,16 @@ func (c *CmdOpts) startController() error {
 		}
 	}
 
+	// watch the cluster config once the reconcilers are running so that they get all the changes
+	var dynamicConfig component.Component
+	if clusterConfigReconciler != nil {
+		clusterConfigReconciler.SetReconcilers(reconcilers)
+		dynamicConfig = component.LeaderScoped(leaderElector, clusterConfigReconciler)
+		if err = dynamicConfig.Run(); err != nil {
+			logrus.Errorf("failed to start the cluster config reconciler: %s", err.Error())
+		}
+	}
+
 	perfTimer.Checkpoint("started-reconcilers")
 
 	if err == nil && c.EnableWorker {
@@ -369,6 +478,12 @@ func (c *CmdOpts) startController() error {
 	<-ctx.Done()
 	logrus.Debug("Context done in main")
 
+	if dynamicConfig != nil {
+		if err := dynamicConfig.Stop(); err != nil {
+			logrus.Warningf("failed to stop the cluster config reconciler: %s", err.Error())
+		}
+	}
+
 	// Stop all reconcilers first
 	for _, reconciler := range reconcilers {
 		if err := reconciler.Stop(); err != nil {
@@ -409,18 +524,10 @@ func (c *CmdOpts) createClusterReconcilers(cf kubernetes.ClientFactory, leaderEl
 	}
 
 	logrus.Infof("initializing network reconciler for provider %s", c.ClusterConfig.Spec.Network.Provider)
-	switch c.ClusterConfig.Spec.Network.Provider {
-	case "custom":
+	if c.ClusterConfig.Spec.Network.Provider == "custom" {
 		logrus.Warnf("network provider set to custom, k0s will not manage it")
-	case "calico":
-		err = c.initCalico(reconcilers)
-	case "kuberouter":
-		err = c.initKubeRouter(reconcilers)
-	}
-	if err != nil {
-		logrus.Warnf("failed to initialize network reconciler: %s", err.Error())
-		return reconcilers, err
 	}
+	reconcilers["networkProvider"] = controller.NewNetworkProvider(c.ClusterConfig, c.K0sVars, cf, leaderElector)
 
 	manifestsSaver, err := controller.NewManifestsSaver("helm", c.K0sVars.DataDir)
 	if err != nil {
@@ -428,7 +535,7 @@ func (c *CmdOpts) createClusterReconcilers(cf kubernetes.ClientFactory, leaderEl
 		return reconcilers, err
 	}
 	reconcilers["crd"] = controller.NewCRD(manifestsSaver)
-	reconcilers["helmAddons"] = controller.NewHelmAddons(c.ClusterConfig, manifestsSaver, c.K0sVars, cf, leaderElector)
+	reconcilers["helmAddons"] = component.LeaderScoped(leaderElector, controller.NewHelmAddons(c.ClusterConfig, manifestsSaver, c.K0sVars, cf))
 
 	metricServer, err := controller.NewMetricServer(c.ClusterConfig, c.K0sVars, cf)
 	if err != nil {
@@ -454,49 +561,17 @@ func (c *CmdOpts) createClusterReconcilers(cf kubernetes.ClientFactory, leaderEl
 	return reconcilers, nil
 }
 
-func (c *CmdOpts) initCalico(reconcilers map[string]component.Component) error {
-	calicoSaver, err := controller.NewManifestsSaver("calico", c.K0sVars.DataDir)
-	if err != nil {
-		logrus.Warnf("failed to initialize reconcilers manifests saver: %s", err.Error())
-		return err
-	}
-	calicoInitSaver, err := controller.NewManifestsSaver("calico_init", c.K0sVars.DataDir)
-	if err != nil {
-		logrus.Warnf("failed to initialize reconcilers manifests saver: %s", err.Error())
-		return err
-	}
-	calico, err := controller.NewCalico(c.ClusterConfig, calicoInitSaver, calicoSaver)
-	if err != nil {
-		logrus.Warnf("failed to initialize calico reconciler: %s", err.Error())
-		return err
-	}
-	reconcilers["calico"] = calico
-
-	return nil
-}
-
-func (c *CmdOpts) initKubeRouter(reconcilers map[string]component.Component) error {
-	mfSaver, err := controller.NewManifestsSaver("kuberouter", c.K0sVars.DataDir)
-	if err != nil {
-		logrus.Warnf("failed to initialize kube-router manifests saver: %s", err.Error())
-		return err
-	}
-	kubeRouter, err := controller.NewKubeRouter(c.ClusterConfig, mfSaver)
-	if err != nil {
-		logrus.Warnf("failed to initialize kube-router reconciler: %s", err.Error())
-		return err
-	}
-	reconcilers["kube-router"] = kubeRouter
-
-	return nil
-}
-
 func (c *CmdOpts) existingCNIProvider() string {
 	calicoManifestPath := path.Join(c.K0sVars.ManifestsDir, "calico", "calico-DaemonSet-calico-node.yaml")
 	if util.FileExists(calicoManifestPath) {
 		return "calico"
 	}
 
+	ciliumManifestPath := path.Join(c.K0sVars.ManifestsDir, "cilium", "cilium.yaml")
+	if util.FileExists(ciliumManifestPath) {
+		return "cilium"
+	}
+
 	kubeRouterManifestPath := path.Join(c.K0sVars.ManifestsDir, "kuberouter", "kube-router.yaml")
 	if util.FileExists(kubeRouterManifestPath) {
 		return "kuberouter"
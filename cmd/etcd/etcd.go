@@ -16,12 +16,17 @@ limitations under the License.
 package etcd
 
 import (
+	"encoding/json"
 	"fmt"
+	"io"
 
+	"github.com/olekukonko/tablewriter"
 	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
 
 	"github.com/k0sproject/k0s/pkg/apis/v1beta1"
 	"github.com/k0sproject/k0s/pkg/config"
+	"github.com/k0sproject/k0s/pkg/etcd"
 )
 
 type CmdOpts config.CLIOptions
@@ -32,7 +37,7 @@ func NewEtcdCmd() *cobra.Command {
 		Short: "Manage etcd cluster",
 		PreRunE: func(cmd *cobra.Command, args []string) error {
 			c := CmdOpts(config.GetCmdOpts())
-			cfg, err := config.GetYamlFromFile(c.CfgFile, c.K0sVars)
+			cfg, err := config.GetYamlFromFile(c.CfgFile, c.CfgDir, c.K0sVars)
 			if err != nil {
 				return err
 			}
@@ -46,6 +51,68 @@ func NewEtcdCmd() *cobra.Command {
 	cmd.SilenceUsage = true
 	cmd.AddCommand(etcdLeaveCmd())
 	cmd.AddCommand(etcdListCmd())
+	cmd.AddCommand(etcdPromoteCmd())
+	cmd.AddCommand(etcdSnapshotCmd())
+	cmd.AddCommand(etcdStatusCmd())
 	cmd.PersistentFlags().AddFlagSet(config.GetPersistentFlagSet())
 	return cmd
 }
+
+// newClient loads the config and connects to the etcd cluster of the controller, the tests replace it
+var newClient = func() (*etcd.Client, *v1beta1.ClusterConfig, error) {
+	c := CmdOpts(config.GetCmdOpts())
+	cfg, err := config.GetYamlFromFile(c.CfgFile, c.CfgDir, c.K0sVars)
+	if err != nil {
+		return nil, nil, err
+	}
+	client, err := etcd.NewClient(c.K0sVars.CertRootDir, c.K0sVars.EtcdCertDir, cfg.Spec.Storage.Etcd)
+	if err != nil {
+		return nil, nil, fmt.Errorf("can't connect to the etcd: %w", err)
+	}
+	return client, cfg, nil
+}
+
+// printOutput writes the value as json or yaml to w, or else calls printTable
+func printOutput(w io.Writer, out string, v interface{}, printTable func()) error {
+	switch out {
+	case "json":
+		data, err := json.MarshalIndent(v, "", "   ")
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(w, string(data))
+	case "yaml":
+		data, err := yaml.Marshal(v)
+		if err != nil {
+			return err
+		}
+		fmt.Fprint(w, string(data))
+	case "", "table":
+		printTable()
+	default:
+		return fmt.Errorf("unsupported output %q, use table, json or yaml", out)
+	}
+	return nil
+}
+
+func newTable(w io.Writer, header ...string) *tablewriter.Table {
+	table := tablewriter.NewWriter(w)
+	table.SetHeader(header)
+	table.SetAutoWrapText(false)
+	table.SetAutoFormatHeaders(true)
+	table.SetHeaderAlignment(tablewriter.ALIGN_LEFT)
+	table.SetAlignment(tablewriter.ALIGN_LEFT)
+	table.SetCenterSeparator("")
+	table.SetColumnSeparator("")
+	table.SetRowSeparator("")
+	table.SetHeaderLine(false)
+	table.SetBorder(false)
+	table.SetTablePadding("\t") // pad with tabs
+	table.SetNoWhiteSpace(true)
+	return table
+}
+
+// formatBytes formats the size in MiB, which is precise enough for etcd databases
+func formatBytes(size int64) string {
+	return fmt.Sprintf("%.1f MiB", float64(size)/(1024*1024))
+}
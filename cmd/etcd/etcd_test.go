@@ -0,0 +1,151 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package etcd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+
+	"github.com/k0sproject/k0s/internal/testutil"
+	"github.com/k0sproject/k0s/pkg/apis/v1beta1"
+	"github.com/k0sproject/k0s/pkg/constant"
+	"github.com/k0sproject/k0s/pkg/etcd"
+)
+
+// useTestEtcd makes the commands connect to an etcd member run by the test, with the peer port of the member
+func useTestEtcd(t *testing.T) (peerURL string) {
+	clientURL, peerURL := testutil.StartEtcd(t)
+	u, err := url.Parse(peerURL)
+	require.NoError(t, err)
+	peerPort, err := strconv.Atoi(u.Port())
+	require.NoError(t, err)
+
+	orig := newClient
+	t.Cleanup(func() { newClient = orig })
+	newClient = func() (*etcd.Client, *v1beta1.ClusterConfig, error) {
+		cfg := v1beta1.DefaultClusterConfig(constant.CfgVars{})
+		cfg.Spec.Storage.Etcd.PeerAddress = "127.0.0.1"
+		cfg.Spec.Storage.Etcd.PeerPort = peerPort
+		client, err := etcd.NewClient("", "", &v1beta1.EtcdConfig{ExternalCluster: &v1beta1.ExternalCluster{Endpoints: []string{clientURL}}})
+		return client, cfg, err
+	}
+	return peerURL
+}
+
+func TestEtcdStatusCmd(t *testing.T) {
+	peerURL := useTestEtcd(t)
+
+	var out bytes.Buffer
+	cmd := etcdStatusCmd()
+	cmd.SetOut(&out)
+	cmd.SetArgs([]string{"-o", "json"})
+	require.NoError(t, cmd.Execute())
+
+	var statuses []etcd.MemberStatus
+	require.NoError(t, json.Unmarshal(out.Bytes(), &statuses), out.String())
+	require.Len(t, statuses, 1)
+	assert.Equal(t, peerURL, statuses[0].PeerURL)
+	assert.True(t, statuses[0].IsLeader)
+	assert.True(t, statuses[0].Healthy)
+	assert.NotZero(t, statuses[0].DBSize)
+
+	out.Reset()
+	cmd = etcdStatusCmd()
+	cmd.SetOut(&out)
+	cmd.SetArgs([]string{})
+	require.NoError(t, cmd.Execute())
+	assert.Contains(t, out.String(), "DB IN USE")
+	assert.Contains(t, out.String(), peerURL)
+	assert.Contains(t, out.String(), "healthy (")
+
+	cmd = etcdStatusCmd()
+	cmd.SetOut(&out)
+	cmd.SetErr(&bytes.Buffer{})
+	cmd.SetArgs([]string{"-o", "xml"})
+	assert.Error(t, cmd.Execute())
+}
+
+func TestEtcdSnapshotCmd(t *testing.T) {
+	useTestEtcd(t)
+	path := filepath.Join(t.TempDir(), "snapshot.db")
+	client, _, err := newClient()
+	require.NoError(t, err)
+	defer client.Close()
+	_, err = client.KV().Put(context.Background(), "/test", "value")
+	require.NoError(t, err)
+
+	var out bytes.Buffer
+	cmd := etcdSnapshotSaveCmd()
+	cmd.SetOut(&out)
+	cmd.SetArgs([]string{path})
+	require.NoError(t, cmd.Execute())
+	assert.Equal(t, "Snapshot saved at "+path+"\n", out.String())
+
+	out.Reset()
+	cmd = etcdSnapshotStatusCmd()
+	cmd.SetOut(&out)
+	cmd.SetArgs([]string{path, "-o", "yaml"})
+	require.NoError(t, cmd.Execute())
+	var status snapshotStatus
+	require.NoError(t, yaml.Unmarshal(out.Bytes(), &status), out.String())
+	assert.NotZero(t, status.TotalKey)
+	assert.NotZero(t, status.Revision)
+
+	cmd = etcdSnapshotStatusCmd()
+	cmd.SetOut(&out)
+	cmd.SetErr(&bytes.Buffer{})
+	cmd.SetArgs([]string{filepath.Join(t.TempDir(), "missing.db")})
+	assert.Error(t, cmd.Execute())
+}
+
+func TestEtcdPromoteCmd(t *testing.T) {
+	useTestEtcd(t)
+	client, cfg, err := newClient()
+	require.NoError(t, err)
+	defer client.Close()
+	// the learner never starts, so it never catches up with the leader
+	_, err = client.AddMember(context.Background(), "learner", cfg.Spec.Storage.Etcd.PeerURL("127.0.0.2"), true)
+	require.NoError(t, err)
+
+	for _, test := range []struct {
+		peerAddress string
+		err         string
+	}{
+		{"127.0.0.2", "hasn't caught up with the leader yet"},
+		{"127.0.0.1", "is already a voting member"},
+		{"127.0.0.3", "peer not found"},
+	} {
+		t.Run(test.peerAddress, func(t *testing.T) {
+			cmd := etcdPromoteCmd()
+			cmd.SetOut(&bytes.Buffer{})
+			cmd.SetErr(&bytes.Buffer{})
+			cmd.SetArgs([]string{"--peer-address", test.peerAddress})
+			err := cmd.Execute()
+			if assert.Error(t, err) {
+				assert.Contains(t, err.Error(), test.err)
+			}
+		})
+	}
+}
@@ -33,12 +33,15 @@ func etcdLeaveCmd() *cobra.Command {
 		Short: "Sign off a given etc node from etcd cluster",
 		RunE: func(cmd *cobra.Command, args []string) error {
 			c := CmdOpts(config.GetCmdOpts())
-			cfg, err := config.GetYamlFromFile(c.CfgFile, c.K0sVars)
+			cfg, err := config.GetYamlFromFile(c.CfgFile, c.CfgDir, c.K0sVars)
 			if err != nil {
 				return err
 			}
 			c.ClusterConfig = cfg
 			ctx := context.Background()
+			if etcdPeerAddress == "" && c.ClusterConfig.Spec.Storage.Etcd.IsExternalClusterUsed() {
+				return fmt.Errorf("can't leave etcd cluster: the controller is not a member of the external cluster, use --peer-address to remove a member")
+			}
 			if etcdPeerAddress == "" {
 				etcdPeerAddress = c.ClusterConfig.Spec.Storage.Etcd.PeerAddress
 			}
@@ -46,8 +49,8 @@ func etcdLeaveCmd() *cobra.Command {
 				return fmt.Errorf("can't leave etcd cluster: peer address is empty, check the config file or use cli argument")
 			}
 
-			peerURL := fmt.Sprintf("https://%s:2380", etcdPeerAddress)
-			etcdClient, err := etcd.NewClient(c.K0sVars.CertRootDir, c.K0sVars.EtcdCertDir)
+			peerURL := c.ClusterConfig.Spec.Storage.Etcd.PeerURL(etcdPeerAddress)
+			etcdClient, err := etcd.NewClient(c.K0sVars.CertRootDir, c.K0sVars.EtcdCertDir, c.ClusterConfig.Spec.Storage.Etcd)
 			if err != nil {
 				return fmt.Errorf("can't connect to the etcd: %v", err)
 			}
@@ -0,0 +1,74 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package etcd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"go.etcd.io/etcd/etcdserver/api/v3rpc/rpctypes"
+
+	"github.com/k0sproject/k0s/pkg/config"
+)
+
+func etcdPromoteCmd() *cobra.Command {
+	var peerAddress string
+	cmd := &cobra.Command{
+		Use:   "promote",
+		Short: "Promote an etcd learner to a voting member",
+		Long: `Promote the etcd learner of a controller joined with spec.storage.etcd.learner to a voting member.
+Learners don't serve requests, run the command on a controller that is already a voting member.`,
+		Example: `k0s etcd promote --peer-address 10.0.0.5`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, cfg, err := newClient()
+			if err != nil {
+				return err
+			}
+			defer client.Close()
+
+			if peerAddress == "" {
+				peerAddress = cfg.Spec.Storage.Etcd.PeerAddress
+			}
+			peerURL := cfg.Spec.Storage.Etcd.PeerURL(peerAddress)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			defer cancel()
+			peerID, err := client.GetPeerIDByAddress(ctx, peerURL)
+			if err != nil {
+				return err
+			}
+			err = client.PromoteMember(ctx, peerID)
+			switch {
+			case errors.Is(err, rpctypes.ErrMemberLearnerNotReady):
+				return fmt.Errorf("the learner %s hasn't caught up with the leader yet, retry later", peerURL)
+			case errors.Is(err, rpctypes.ErrMemberNotLearner):
+				return fmt.Errorf("%s is already a voting member", peerURL)
+			case err != nil:
+				return fmt.Errorf("failed to promote %s: %w", peerURL, err)
+			}
+
+			logrus.WithField("peerID", peerID).Infof("Successfully promoted %s", peerURL)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&peerAddress, "peer-address", "", "etcd peer address of the learner (default: the peer address of this controller)")
+	cmd.PersistentFlags().AddFlagSet(config.GetPersistentFlagSet())
+	return cmd
+}
@@ -0,0 +1,105 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package etcd
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/k0sproject/k0s/pkg/config"
+	"github.com/k0sproject/k0s/pkg/etcd"
+)
+
+func etcdSnapshotCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "snapshot",
+		Short: "Save and inspect etcd snapshots",
+	}
+	cmd.AddCommand(etcdSnapshotSaveCmd())
+	cmd.AddCommand(etcdSnapshotStatusCmd())
+	cmd.PersistentFlags().AddFlagSet(config.GetPersistentFlagSet())
+	return cmd
+}
+
+func etcdSnapshotSaveCmd() *cobra.Command {
+	var timeout time.Duration
+	cmd := &cobra.Command{
+		Use:     "save <file>",
+		Short:   "Save a snapshot of the etcd database to the file",
+		Example: `k0s etcd snapshot save /tmp/etcd-snapshot.db`,
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, _, err := newClient()
+			if err != nil {
+				return err
+			}
+			defer client.Close()
+
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			defer cancel()
+			if err := client.SaveSnapshot(ctx, args[0]); err != nil {
+				return fmt.Errorf("failed to save the etcd snapshot: %w", err)
+			}
+			fmt.Fprintln(cmd.OutOrStdout(), "Snapshot saved at", args[0])
+			return nil
+		},
+	}
+	cmd.Flags().DurationVar(&timeout, "timeout", 5*time.Minute, "how long to wait for the snapshot")
+	cmd.PersistentFlags().AddFlagSet(config.GetPersistentFlagSet())
+	return cmd
+}
+
+// snapshotStatus is etcd's snapshot status with yaml field names
+type snapshotStatus struct {
+	Hash      uint32 `json:"hash" yaml:"hash"`
+	Revision  int64  `json:"revision" yaml:"revision"`
+	TotalKey  int    `json:"totalKey" yaml:"totalKey"`
+	TotalSize int64  `json:"totalSize" yaml:"totalSize"`
+}
+
+func etcdSnapshotStatusCmd() *cobra.Command {
+	var out string
+	cmd := &cobra.Command{
+		Use:     "status <file>",
+		Short:   "Show the hash, revision, key count and size of an etcd snapshot",
+		Example: `k0s etcd snapshot status /tmp/etcd-snapshot.db`,
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			s, err := etcd.SnapshotStatus(args[0])
+			if err != nil {
+				return fmt.Errorf("failed to read the etcd snapshot: %w", err)
+			}
+			status := snapshotStatus(s)
+			return printOutput(cmd.OutOrStdout(), out, status, func() {
+				table := newTable(cmd.OutOrStdout(), "Hash", "Revision", "Total keys", "Total size")
+				table.Append([]string{
+					strconv.FormatUint(uint64(status.Hash), 16),
+					strconv.FormatInt(status.Revision, 10),
+					strconv.Itoa(status.TotalKey),
+					formatBytes(status.TotalSize),
+				})
+				table.Render()
+			})
+		},
+	}
+	cmd.Flags().StringVarP(&out, "out", "o", "table", "sets type of output to table, json or yaml")
+	cmd.PersistentFlags().AddFlagSet(config.GetPersistentFlagSet())
+	return cmd
+}
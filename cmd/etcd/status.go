@@ -0,0 +1,83 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package etcd
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/k0sproject/k0s/pkg/config"
+)
+
+func etcdStatusCmd() *cobra.Command {
+	var out string
+	cmd := &cobra.Command{
+		Use:   "status",
+		Short: "Show the status of the etcd cluster members",
+		Long: `Show the leader and learner state, raft term and index, database size, quota usage, NOSPACE alarm
+and health check latency of the etcd cluster members. The members only listening on the loopback interface of other controllers can't be
+reached, only their membership is shown.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, cfg, err := newClient()
+			if err != nil {
+				return err
+			}
+			defer client.Close()
+
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			defer cancel()
+			var quota int64
+			if etcdConf := cfg.Spec.Storage.Etcd; !etcdConf.IsExternalClusterUsed() {
+				quota = etcdConf.GetQuotaBackendBytes()
+			}
+			statuses, err := client.MemberStatuses(ctx, quota)
+			if err != nil {
+				return err
+			}
+
+			return printOutput(cmd.OutOrStdout(), out, statuses, func() {
+				table := newTable(cmd.OutOrStdout(), "Name", "ID", "Peer URL", "Leader", "Learner", "Raft term", "Raft index", "DB size", "DB in use", "Quota", "Health")
+				for _, s := range statuses {
+					row := []string{s.Name, s.ID, s.PeerURL, strconv.FormatBool(s.IsLeader), strconv.FormatBool(s.IsLearner), "", "", "", "", "", s.Error}
+					if s.RaftTerm != 0 {
+						row[5] = strconv.FormatUint(s.RaftTerm, 10)
+						row[6] = strconv.FormatUint(s.RaftIndex, 10)
+						row[7] = formatBytes(s.DBSize)
+						row[8] = formatBytes(s.DBSizeInUse)
+						if quota > 0 {
+							row[9] = strconv.Itoa(s.QuotaPercent) + "%"
+						}
+					}
+					if s.Healthy {
+						row[10] = "healthy (" + s.HealthLatency + ")"
+					}
+					if s.NoSpaceAlarm {
+						row[10] = strings.TrimPrefix(row[10]+", NOSPACE alarm", ", ")
+					}
+					table.Append(row)
+				}
+				table.Render()
+			})
+		},
+	}
+	cmd.Flags().StringVarP(&out, "out", "o", "table", "sets type of output to table, json or yaml")
+	cmd.PersistentFlags().AddFlagSet(config.GetPersistentFlagSet())
+	return cmd
+}
@@ -54,7 +54,7 @@ func (c *CmdOpts) setup(role string, args []string) error {
 		return fmt.Errorf("file %s does not exist", c.CfgFile)
 	}
 	if role == "controller" {
-		cfg, err := config.GetYamlFromFile(c.CfgFile, c.K0sVars)
+		cfg, err := config.GetYamlFromFile(c.CfgFile, c.CfgDir, c.K0sVars)
 		if err != nil {
 			return err
 		}
@@ -101,7 +101,7 @@ func (c *CmdOpts) convertFileParamsToAbsolute() (err error) {
 
 func preRunValidateConfig(_ *cobra.Command, _ []string) error {
 	c := CmdOpts(config.GetCmdOpts())
-	_, err := config.ValidateYaml(c.CfgFile, c.K0sVars)
+	_, err := config.ValidateYaml(c.CfgFile, c.CfgDir, c.K0sVars)
 	if err != nil {
 		return err
 	}
@@ -26,9 +26,7 @@ func installWorkerCmd() *cobra.Command {
 		Use:   "worker",
 		Short: "Helper command for setting up k0s as a worker node on a brand-new system. Must be run as root (or with sudo)",
 		Example: `Worker subcommand allows you to pass in all available worker parameters. 
-All default values of worker command will be passed to the service stub unless overriden.
-
-Windows flags like "--api-server", "--cidr-range" and "--cluster-dns" will be ignored since install command doesn't yet support Windows services`,
+All default values of worker command will be passed to the service stub unless overriden.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			c := CmdOpts(config.GetCmdOpts())
 			if err := c.convertFileParamsToAbsolute(); err != nil {
@@ -23,17 +23,19 @@ import (
 	"github.com/cloudflare/cfssl/log"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
-	"html/template"
 	"io/ioutil"
 	"os"
 	"path"
+	"text/template"
 
+	"github.com/k0sproject/k0s/pkg/apis/v1beta1"
 	"github.com/k0sproject/k0s/pkg/certificate"
 	"github.com/k0sproject/k0s/pkg/config"
 )
 
 var (
 	groups string
+	oidc   oidcOptions
 
 	userKubeconfigTemplate = template.Must(template.New("kubeconfig").Parse(`
 apiVersion: v1
@@ -63,30 +65,44 @@ func kubeconfigCreateCmd() *cobra.Command {
 		Use:   "create [username]",
 		Short: "Create a kubeconfig for a user",
 		Long: `Create a kubeconfig with a signed certificate and public key for a given user (and optionally user groups)
-Note: A certificate once signed cannot be revoked for a particular user`,
+Note: A certificate once signed cannot be revoked for a particular user
+
+With --oidc, create a kubeconfig authenticating with the OIDC issuer of spec.api.authentication.oidc instead,
+using the kubectl oidc-login plugin (https://github.com/int128/kubelogin). The username is then only the
+name of the user in the kubeconfig, the user is the one logging in to the issuer.`,
 		Example: `	Command to create a kubeconfig for a user:
 	CLI argument:
 	$ k0s kubeconfig create [username]
 
 	optionally add groups:
-	$ k0s kubeconfig create [username] --groups [groups]`,
+	$ k0s kubeconfig create [username] --groups [groups]
+
+	Command to create an OIDC kubeconfig:
+	$ k0s kubeconfig create --oidc --oidc-extra-scopes groups`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			// disable cfssl log
 			log.Level = log.LevelFatal
 
+			c := CmdOpts(config.GetCmdOpts())
+			if oidc.enabled {
+				username := "oidc"
+				if len(args) > 0 {
+					username = args[0]
+				}
+				return c.createOIDCKubeconfig(username, oidc)
+			}
+
 			if len(args) == 0 {
 				return fmt.Errorf("username is mandatory")
 			}
 			var username = args[0]
-			c := CmdOpts(config.GetCmdOpts())
 			clusterAPIURL, err := c.getAPIURL()
-
 			if err != nil {
 				return fmt.Errorf("failed to fetch cluster's API Address: %w", err)
 			}
-			caCert, err := ioutil.ReadFile(path.Join(c.K0sVars.CertRootDir, "ca.crt"))
+			caCert, err := c.readCACert()
 			if err != nil {
-				return fmt.Errorf("failed to read cluster ca certificate: %w, check if the control plane is initialized on this node", err)
+				return err
 			}
 			caCertPath, caCertKey := path.Join(c.K0sVars.CertRootDir, "ca.crt"), path.Join(c.K0sVars.CertRootDir, "ca.key")
 			userReq := certificate.Request{
@@ -132,17 +148,33 @@ Note: A certificate once signed cannot be revoked for a particular user`,
 		},
 	}
 	cmd.Flags().StringVar(&groups, "groups", "", "Specify groups")
+	cmd.Flags().BoolVar(&oidc.enabled, "oidc", false, "create a kubeconfig authenticating with the OIDC issuer of the cluster")
+	cmd.Flags().StringVar(&oidc.clientSecret, "oidc-client-secret", "", "client secret passed to the OIDC issuer, for the issuers requiring it")
+	cmd.Flags().StringSliceVar(&oidc.extraScopes, "oidc-extra-scopes", nil, "scopes requested from the OIDC issuer in addition to openid, e.g. groups or email")
 	cmd.PersistentFlags().AddFlagSet(config.GetPersistentFlagSet())
 	return cmd
 }
 
-func (c *CmdOpts) getAPIURL() (string, error) {
+func (c *CmdOpts) getClusterConfig() (*v1beta1.ClusterConfig, error) {
 	// Disable logrus
 	logrus.SetLevel(logrus.FatalLevel)
 
-	clusterConfig, err := config.GetYamlFromFile(c.CfgFile, c.K0sVars)
+	_, clusterConfig, err := config.LoadComposed(c.CfgFile, c.CfgDir, c.K0sVars)
+	return clusterConfig, err
+}
+
+func (c *CmdOpts) getAPIURL() (string, error) {
+	clusterConfig, err := c.getClusterConfig()
 	if err != nil {
 		return "", err
 	}
 	return clusterConfig.Spec.API.APIAddressURL(), nil
 }
+
+func (c *CmdOpts) readCACert() ([]byte, error) {
+	caCert, err := ioutil.ReadFile(path.Join(c.K0sVars.CertRootDir, "ca.crt"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cluster ca certificate: %w, check if the control plane is initialized on this node", err)
+	}
+	return caCert, nil
+}
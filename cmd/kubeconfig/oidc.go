@@ -0,0 +1,122 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package kubeconfig
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"text/template"
+
+	"github.com/k0sproject/k0s/pkg/apis/v1beta1"
+)
+
+type oidcOptions struct {
+	enabled      bool
+	clientSecret string
+	extraScopes  []string
+}
+
+var oidcKubeconfigTemplate = template.Must(template.New("kubeconfig").Parse(`
+apiVersion: v1
+clusters:
+- cluster:
+    server: {{.JoinURL}}
+    certificate-authority-data: {{.CACert}}
+  name: k0s
+contexts:
+- context:
+    cluster: k0s
+    user: {{.User}}
+  name: k0s
+current-context: k0s
+kind: Config
+preferences: {}
+users:
+- name: {{.User}}
+  user:
+    exec:
+      apiVersion: client.authentication.k8s.io/v1beta1
+      command: kubectl
+      args:
+      - oidc-login
+      - get-token
+{{- range .Args }}
+      - {{ printf "%q" . }}
+{{- end }}
+`))
+
+// createOIDCKubeconfig writes a kubeconfig getting the ID token from the OIDC issuer with the kubectl
+// oidc-login exec plugin
+func (c *CmdOpts) createOIDCKubeconfig(username string, opts oidcOptions) error {
+	clusterConfig, err := c.getClusterConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load the cluster config: %w", err)
+	}
+	authn := clusterConfig.Spec.API.Authentication
+	if authn == nil || authn.OIDC == nil {
+		return fmt.Errorf("the OIDC authentication is not configured, see spec.api.authentication.oidc")
+	}
+	caCert, err := c.readCACert()
+	if err != nil {
+		return err
+	}
+	args, err := oidcLoginArgs(authn.OIDC, opts)
+	if err != nil {
+		return err
+	}
+
+	data := struct {
+		CACert  string
+		User    string
+		JoinURL string
+		Args    []string
+	}{
+		CACert:  base64.StdEncoding.EncodeToString(caCert),
+		User:    username,
+		JoinURL: clusterConfig.Spec.API.APIAddressURL(),
+		Args:    args,
+	}
+	return oidcKubeconfigTemplate.Execute(os.Stdout, &data)
+}
+
+// oidcLoginArgs builds the flags of kubectl oidc-login. The issuer CA is embedded in the kubeconfig as
+// the file path is only valid on the controllers.
+func oidcLoginArgs(spec *v1beta1.OIDCSpec, opts oidcOptions) ([]string, error) {
+	args := []string{
+		"--oidc-issuer-url=" + spec.IssuerURL,
+		"--oidc-client-id=" + spec.ClientID,
+	}
+	if opts.clientSecret != "" {
+		args = append(args, "--oidc-client-secret="+opts.clientSecret)
+	}
+	for _, scope := range opts.extraScopes {
+		args = append(args, "--oidc-extra-scope="+scope)
+	}
+	ca := spec.CA
+	if spec.CAFile != "" {
+		data, err := ioutil.ReadFile(spec.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the OIDC issuer CA: %w", err)
+		}
+		ca = string(data)
+	}
+	if ca != "" {
+		args = append(args, "--certificate-authority-data="+base64.StdEncoding.EncodeToString([]byte(ca)))
+	}
+	return args, nil
+}
@@ -0,0 +1,47 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package kubeconfig
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/k0sproject/k0s/pkg/apis/v1beta1"
+)
+
+func TestOIDCLoginArgs(t *testing.T) {
+	spec := &v1beta1.OIDCSpec{IssuerURL: "https://issuer.example.com", ClientID: "k0s", CA: "ca"}
+	args, err := oidcLoginArgs(spec, oidcOptions{clientSecret: "secret", extraScopes: []string{"groups", "email"}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"--oidc-issuer-url=https://issuer.example.com",
+		"--oidc-client-id=k0s",
+		"--oidc-client-secret=secret",
+		"--oidc-extra-scope=groups",
+		"--oidc-extra-scope=email",
+		"--certificate-authority-data=" + base64.StdEncoding.EncodeToString([]byte("ca")),
+	}, args)
+
+	args, err = oidcLoginArgs(&v1beta1.OIDCSpec{IssuerURL: "https://issuer.example.com", ClientID: "k0s"}, oidcOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"--oidc-issuer-url=https://issuer.example.com", "--oidc-client-id=k0s"}, args)
+
+	_, err = oidcLoginArgs(&v1beta1.OIDCSpec{CAFile: "/nonexistent/ca.pem"}, oidcOptions{})
+	assert.Error(t, err)
+}
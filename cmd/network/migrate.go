@@ -0,0 +1,378 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package network
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/dynamic"
+	k8s "k8s.io/client-go/kubernetes"
+	"k8s.io/kubectl/pkg/drain"
+
+	"github.com/k0sproject/k0s/pkg/apis/v1beta1"
+	"github.com/k0sproject/k0s/pkg/component/worker"
+	"github.com/k0sproject/k0s/pkg/config"
+	"github.com/k0sproject/k0s/pkg/kubernetes"
+)
+
+// migratedFromAnnotation is set on the cluster config for the time of the migration, so that an
+// interrupted migration can be resumed
+const migratedFromAnnotation = "k0s.k0sproject.io/network-migrated-from"
+
+// The node annotations set for the time of the migration. nodeCordonedAnnotation keeps whether the node
+// was cordoned before the migration cordoned it, nodeMigratedAnnotation is set to the new provider once
+// the node is migrated, so that a resumed migration skips it.
+const (
+	nodeCordonedAnnotation = "k0s.k0sproject.io/network-migration-cordoned"
+	nodeMigratedAnnotation = "k0s.k0sproject.io/network-migrated-to"
+)
+
+// providerPods are the label selectors of the pods each network provider runs on every node
+var providerPods = map[string]string{
+	"calico":     "k8s-app=calico-node",
+	"cilium":     "k8s-app=cilium",
+	"kuberouter": "k8s-app=kube-router",
+}
+
+var clusterConfigGVR = schema.GroupVersionResource{
+	Group:    v1beta1.ClusterConfigGroup,
+	Version:  v1beta1.ClusterConfigVersion,
+	Resource: v1beta1.ClusterConfigResource,
+}
+
+func networkOfflineMigrateCmd() *cobra.Command {
+	var (
+		to           string
+		force        bool
+		drainTimeout time.Duration
+		timeout      time.Duration
+	)
+	cmd := &cobra.Command{
+		Use:   "offline-migrate",
+		Short: "Switch the cluster to another network provider offline, with a pod network outage",
+		Long: `Switch the cluster between the kube-router, Calico and Cilium network providers offline. The network
+provider is changed in the cluster config stored in the cluster, the controllers remove the previous
+provider from all the nodes at once and deploy the new one. The nodes are then migrated one at a time:
+the node is drained, the worker removes the CNI config, the state, the network interfaces, the iptables
+rules and the ipsets of the previous provider, and the pods left on the node are restarted so that they
+get their network from the new provider.
+
+This is an offline migration: the previous provider isn't kept running on the nodes not migrated yet, so
+the pod network of every node is down from the moment the previous provider is removed until the node is
+migrated. Run it in a maintenance window.
+
+The controllers must be run with --enable-dynamic-config and the command must be run on a controller.
+An interrupted migration is resumed by running the command again with the same provider, the nodes
+already migrated are skipped.
+
+Example:
+   k0s network offline-migrate --to calico
+   k0s network offline-migrate --to cilium
+   k0s network offline-migrate --to kuberouter --force --drain-timeout 10m`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c := CmdOpts(config.GetCmdOpts())
+			if !v1beta1.IsMigratableNetworkProvider(to) {
+				return fmt.Errorf("unsupported network provider %q, must be one of %s", to, strings.Join(v1beta1.MigratableNetworkProviders, ", "))
+			}
+
+			clientFactory := kubernetes.NewAdminClientFactory(c.K0sVars)
+			client, err := clientFactory.GetClient()
+			if err != nil {
+				return err
+			}
+			dynamicClient, err := clientFactory.GetDynamicClient()
+			if err != nil {
+				return err
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			defer cancel()
+			m := &migration{
+				client:        client,
+				dynamicClient: dynamicClient,
+				to:            to,
+				drainer: &drain.Helper{
+					Ctx:                 ctx,
+					Client:              client,
+					Force:               force,
+					GracePeriodSeconds:  -1,
+					IgnoreAllDaemonSets: true,
+					DeleteEmptyDirData:  true,
+					Timeout:             drainTimeout,
+					Out:                 os.Stdout,
+					ErrOut:              os.Stderr,
+				},
+			}
+			return m.run(ctx)
+		},
+	}
+	cmd.Flags().StringVar(&to, "to", "", fmt.Sprintf("network provider to migrate to, one of %s", strings.Join(v1beta1.MigratableNetworkProviders, ", ")))
+	cmd.Flags().BoolVar(&force, "force", false, "drain the nodes even if there are pods not managed by a controller")
+	cmd.Flags().DurationVar(&drainTimeout, "drain-timeout", 5*time.Minute, "how long to wait for a node to be drained")
+	cmd.Flags().DurationVar(&timeout, "timeout", time.Hour, "how long the whole migration may take")
+	_ = cmd.MarkFlagRequired("to")
+	cmd.PersistentFlags().AddFlagSet(config.GetPersistentFlagSet())
+	return cmd
+}
+
+type migration struct {
+	client        k8s.Interface
+	dynamicClient dynamic.Interface
+	drainer       *drain.Helper
+	from, to      string
+}
+
+func (m *migration) run(ctx context.Context) error {
+	if err := m.switchProvider(ctx); err != nil {
+		return err
+	}
+
+	logrus.Infof("Waiting for %s to be removed and %s to be deployed", m.from, m.to)
+	if err := m.waitForProviders(ctx); err != nil {
+		return fmt.Errorf("the network provider hasn't been replaced: %w", err)
+	}
+
+	nodes, err := m.client.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to list the nodes: %w", err)
+	}
+	sort.Slice(nodes.Items, func(i, j int) bool { return nodes.Items[i].Name < nodes.Items[j].Name })
+	for i := range nodes.Items {
+		node := &nodes.Items[i]
+		if node.Labels[corev1.LabelOSStable] == "windows" {
+			logrus.Warnf("Skipping the windows node %s, its network has to be migrated manually", node.Name)
+			continue
+		}
+		if node.Annotations[nodeMigratedAnnotation] == m.to {
+			logrus.Infof("Node %s is already migrated", node.Name)
+			continue
+		}
+		logrus.Infof("Migrating node %s", node.Name)
+		if err := m.migrateNode(ctx, node); err != nil {
+			return fmt.Errorf("failed to migrate node %s, run the command again to resume the migration: %w", node.Name, err)
+		}
+	}
+
+	for _, node := range nodes.Items {
+		if _, ok := node.Annotations[nodeMigratedAnnotation]; !ok {
+			continue
+		}
+		patch := fmt.Sprintf(`{"metadata":{"annotations":{%q:null}}}`, nodeMigratedAnnotation)
+		if _, err := m.client.CoreV1().Nodes().Patch(ctx, node.Name, types.MergePatchType, []byte(patch), metav1.PatchOptions{}); err != nil {
+			return fmt.Errorf("failed to finish the migration of node %s: %w", node.Name, err)
+		}
+	}
+	patch := fmt.Sprintf(`{"metadata":{"annotations":{%q:null}}}`, migratedFromAnnotation)
+	if _, err := m.clusterConfig().Patch(ctx, v1beta1.ClusterConfigName, types.MergePatchType, []byte(patch), metav1.PatchOptions{}); err != nil {
+		return fmt.Errorf("failed to finish the migration: %w", err)
+	}
+	logrus.Infof("Migrated the cluster network from %s to %s", m.from, m.to)
+	return nil
+}
+
+func (m *migration) clusterConfig() dynamic.ResourceInterface {
+	return m.dynamicClient.Resource(clusterConfigGVR).Namespace(v1beta1.ClusterConfigNamespace)
+}
+
+// switchProvider changes the network provider in the cluster config and waits for the controllers to
+// reconcile the change
+func (m *migration) switchProvider(ctx context.Context) error {
+	obj, err := m.clusterConfig().Get(ctx, v1beta1.ClusterConfigName, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return fmt.Errorf("no cluster config stored in the cluster, the controllers must be run with --enable-dynamic-config")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get the cluster config: %w", err)
+	}
+
+	current, _, err := unstructured.NestedString(obj.Object, "spec", "network", "provider")
+	if err != nil {
+		return fmt.Errorf("invalid cluster config: %w", err)
+	}
+	if current == m.to {
+		m.from = obj.GetAnnotations()[migratedFromAnnotation]
+		if m.from == "" {
+			return fmt.Errorf("the cluster already uses %s", m.to)
+		}
+		logrus.Infof("Resuming the migration from %s to %s", m.from, m.to)
+		return nil
+	}
+	if !v1beta1.IsMigratableNetworkProvider(current) {
+		return fmt.Errorf("can't migrate from the network provider %s", current)
+	}
+	m.from = current
+
+	logrus.Infof("Switching the network provider from %s to %s", m.from, m.to)
+	patch := fmt.Sprintf(`{"metadata":{"annotations":{%q:%q}},"spec":{"network":{"provider":%q}}}`, migratedFromAnnotation, m.from, m.to)
+	obj, err = m.clusterConfig().Patch(ctx, v1beta1.ClusterConfigName, types.MergePatchType, []byte(patch), metav1.PatchOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to update the cluster config: %w", err)
+	}
+
+	generation := obj.GetGeneration()
+	return wait.PollImmediateUntil(2*time.Second, func() (bool, error) {
+		obj, err := m.clusterConfig().Get(ctx, v1beta1.ClusterConfigName, metav1.GetOptions{})
+		if err != nil {
+			return false, nil
+		}
+		observed, _, _ := unstructured.NestedInt64(obj.Object, "status", "observedGeneration")
+		if observed < generation {
+			return false, nil
+		}
+		if msg, _, _ := unstructured.NestedString(obj.Object, "status", "error"); msg != "" {
+			return false, fmt.Errorf("the controllers failed to apply the cluster config: %s", msg)
+		}
+		return true, nil
+	}, ctx.Done())
+}
+
+// waitForProviders waits for the pods of the previous provider to be gone and the ones of the new provider to be created
+func (m *migration) waitForProviders(ctx context.Context) error {
+	return wait.PollImmediateUntil(2*time.Second, func() (bool, error) {
+		old, err := m.providerPods(ctx, m.from, "")
+		if err != nil || len(old) > 0 {
+			return false, nil
+		}
+		pods, err := m.providerPods(ctx, m.to, "")
+		if err != nil {
+			return false, nil
+		}
+		return len(pods) > 0, nil
+	}, ctx.Done())
+}
+
+func (m *migration) providerPods(ctx context.Context, provider, nodeName string) ([]corev1.Pod, error) {
+	opts := metav1.ListOptions{LabelSelector: providerPods[provider]}
+	if nodeName != "" {
+		opts.FieldSelector = fields.OneTermEqualSelector("spec.nodeName", nodeName).String()
+	}
+	pods, err := m.client.CoreV1().Pods(metav1.NamespaceSystem).List(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return pods.Items, nil
+}
+
+// migrateNode drains the node, has the worker clean up the previous provider and restarts the
+// pods left on the node
+func (m *migration) migrateNode(ctx context.Context, node *corev1.Node) error {
+	nodes := m.client.CoreV1().Nodes()
+	// an interrupted migration already cordoned the node, the annotation tells how it was before
+	cordoned := node.Spec.Unschedulable
+	if value, ok := node.Annotations[nodeCordonedAnnotation]; ok {
+		cordoned = value == "true"
+	} else {
+		patch := fmt.Sprintf(`{"metadata":{"annotations":{%q:%q}}}`, nodeCordonedAnnotation, strconv.FormatBool(cordoned))
+		var err error
+		if node, err = nodes.Patch(ctx, node.Name, types.MergePatchType, []byte(patch), metav1.PatchOptions{}); err != nil {
+			return fmt.Errorf("failed to record the cordon state of the node: %w", err)
+		}
+	}
+	if err := drain.RunCordonOrUncordon(m.drainer, node, true); err != nil {
+		return fmt.Errorf("failed to cordon the node: %w", err)
+	}
+	if err := drain.RunNodeDrain(m.drainer, node.Name); err != nil {
+		return fmt.Errorf("failed to drain the node: %w", err)
+	}
+
+	patch := fmt.Sprintf(`{"metadata":{"annotations":{%q:%q}}}`, worker.NetworkCleanupAnnotation, m.from)
+	if _, err := nodes.Patch(ctx, node.Name, types.MergePatchType, []byte(patch), metav1.PatchOptions{}); err != nil {
+		return fmt.Errorf("failed to request the network cleanup: %w", err)
+	}
+	logrus.Infof("Waiting for the worker to clean up %s", m.from)
+	err := wait.PollImmediateUntil(2*time.Second, func() (bool, error) {
+		n, err := nodes.Get(ctx, node.Name, metav1.GetOptions{})
+		if err != nil {
+			return false, nil
+		}
+		_, pending := n.Annotations[worker.NetworkCleanupAnnotation]
+		return !pending, nil
+	}, ctx.Done())
+	if err != nil {
+		return fmt.Errorf("the worker hasn't cleaned up %s: %w", m.from, err)
+	}
+
+	// the pods of the daemon sets are left running by the drain and still use the previous network
+	pods, err := m.client.CoreV1().Pods(metav1.NamespaceAll).List(ctx, metav1.ListOptions{
+		FieldSelector: fields.OneTermEqualSelector("spec.nodeName", node.Name).String(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list the pods of the node: %w", err)
+	}
+	for _, pod := range pods.Items {
+		if pod.Spec.HostNetwork || pod.DeletionTimestamp != nil {
+			continue
+		}
+		logrus.Infof("Restarting pod %s/%s", pod.Namespace, pod.Name)
+		if err := m.client.CoreV1().Pods(pod.Namespace).Delete(ctx, pod.Name, metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+			return fmt.Errorf("failed to delete pod %s/%s: %w", pod.Namespace, pod.Name, err)
+		}
+	}
+
+	logrus.Infof("Waiting for %s to be ready on the node", m.to)
+	err = wait.PollImmediateUntil(2*time.Second, func() (bool, error) {
+		pods, err := m.providerPods(ctx, m.to, node.Name)
+		if err != nil || len(pods) == 0 {
+			return false, nil
+		}
+		for _, pod := range pods {
+			if !podReady(&pod) {
+				return false, nil
+			}
+		}
+		return true, nil
+	}, ctx.Done())
+	if err != nil {
+		return fmt.Errorf("%s isn't ready on the node: %w", m.to, err)
+	}
+
+	// the node is uncordoned and marked as migrated at once, so that a resumed migration doesn't drain it again
+	// nor leave it cordoned
+	patch = fmt.Sprintf(`{"metadata":{"annotations":{%q:null,%q:%q}},"spec":{"unschedulable":null}}`, nodeCordonedAnnotation, nodeMigratedAnnotation, m.to)
+	if cordoned {
+		logrus.Infof("Node %s was cordoned before the migration, leaving it cordoned", node.Name)
+		patch = fmt.Sprintf(`{"metadata":{"annotations":{%q:null,%q:%q}}}`, nodeCordonedAnnotation, nodeMigratedAnnotation, m.to)
+	}
+	if _, err := nodes.Patch(ctx, node.Name, types.MergePatchType, []byte(patch), metav1.PatchOptions{}); err != nil {
+		return fmt.Errorf("failed to uncordon the node: %w", err)
+	}
+	return nil
+}
+
+func podReady(pod *corev1.Pod) bool {
+	for _, cond := range pod.Status.Conditions {
+		if cond.Type == corev1.PodReady {
+			return cond.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
@@ -0,0 +1,102 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package network
+
+import (
+	"context"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/kubectl/pkg/drain"
+
+	"github.com/k0sproject/k0s/pkg/component/worker"
+)
+
+func TestMigrateNode(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		cordoned string
+		keep     bool
+	}{
+		{"resumed on a node cordoned by the migration", "false", false},
+		{"resumed on a node cordoned before the migration", "true", true},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			node := &corev1.Node{
+				ObjectMeta: metav1.ObjectMeta{Name: "worker-0", Annotations: map[string]string{nodeCordonedAnnotation: test.cordoned}},
+				Spec:       corev1.NodeSpec{Unschedulable: true},
+			}
+			daemonSet := &appsv1.DaemonSet{ObjectMeta: metav1.ObjectMeta{Name: "calico-node", Namespace: metav1.NamespaceSystem}}
+			isController := true
+			calicoNode := &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:            "calico-node-abcde",
+					Namespace:       metav1.NamespaceSystem,
+					Labels:          map[string]string{"k8s-app": "calico-node"},
+					OwnerReferences: []metav1.OwnerReference{{APIVersion: "apps/v1", Kind: "DaemonSet", Name: "calico-node", Controller: &isController}},
+				},
+				Spec:   corev1.PodSpec{NodeName: "worker-0", HostNetwork: true},
+				Status: corev1.PodStatus{Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}}},
+			}
+			client := fake.NewSimpleClientset(node, daemonSet, calicoNode)
+
+			ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+			defer cancel()
+			// the worker cleans up the previous provider and removes the annotation
+			go func() {
+				for ctx.Err() == nil {
+					n, err := client.CoreV1().Nodes().Get(ctx, "worker-0", metav1.GetOptions{})
+					if err == nil && n.Annotations[worker.NetworkCleanupAnnotation] != "" {
+						patch := `{"metadata":{"annotations":{"` + worker.NetworkCleanupAnnotation + `":null}}}`
+						_, _ = client.CoreV1().Nodes().Patch(ctx, "worker-0", types.MergePatchType, []byte(patch), metav1.PatchOptions{})
+					}
+					time.Sleep(100 * time.Millisecond)
+				}
+			}()
+
+			m := &migration{
+				client: client,
+				from:   "kuberouter",
+				to:     "calico",
+				drainer: &drain.Helper{
+					Ctx:                 ctx,
+					Client:              client,
+					GracePeriodSeconds:  -1,
+					IgnoreAllDaemonSets: true,
+					Timeout:             10 * time.Second,
+					Out:                 ioutil.Discard,
+					ErrOut:              ioutil.Discard,
+				},
+			}
+			require.NoError(t, m.migrateNode(ctx, node))
+
+			n, err := client.CoreV1().Nodes().Get(ctx, "worker-0", metav1.GetOptions{})
+			require.NoError(t, err)
+			assert.Equal(t, test.keep, n.Spec.Unschedulable)
+			assert.Equal(t, "calico", n.Annotations[nodeMigratedAnnotation])
+			assert.NotContains(t, n.Annotations, nodeCordonedAnnotation)
+			assert.NotContains(t, n.Annotations, worker.NetworkCleanupAnnotation)
+		})
+	}
+}
@@ -67,7 +67,7 @@ func (c *CmdOpts) reset() error {
 	}
 
 	// Get Cleanup Config
-	cfg, err := cleanup.NewConfig(c.K0sVars, c.CfgFile, c.WorkerOptions.CriSocket)
+	cfg, err := cleanup.NewConfig(c.K0sVars, c.CfgFile, c.CfgDir, c.WorkerOptions.CriSocket)
 	if err != nil {
 		logger.Fatalf("failed to configure cleanup: %v", err)
 		return err
@@ -81,7 +81,7 @@ func (c *CmdOpts) reset() error {
 
 func preRunValidateConfig(_ *cobra.Command, _ []string) error {
 	c := CmdOpts(config.GetCmdOpts())
-	_, err := config.ValidateYaml(c.CfgFile, c.K0sVars)
+	_, err := config.ValidateYaml(c.CfgFile, c.CfgDir, c.K0sVars)
 	if err != nil {
 		return err
 	}
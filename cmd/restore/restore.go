@@ -47,7 +47,7 @@ func NewRestoreCmd() *cobra.Command {
 			if len(args) != 1 {
 				return fmt.Errorf("path to backup archive expected")
 			}
-			cfg, err := config.GetYamlFromFile(c.CfgFile, c.K0sVars)
+			cfg, err := config.GetYamlFromFile(c.CfgFile, c.CfgDir, c.K0sVars)
 			if err != nil {
 				return err
 			}
@@ -106,7 +106,7 @@ func (c *CmdOpts) restore(path string) error {
 // TODO Need to move to some common place, now it's defined in restore and backup commands
 func preRunValidateConfig(_ *cobra.Command, _ []string) error {
 	c := CmdOpts(config.GetCmdOpts())
-	_, err := config.ValidateYaml(c.CfgFile, c.K0sVars)
+	_, err := config.ValidateYaml(c.CfgFile, c.CfgDir, c.K0sVars)
 	if err != nil {
 		return err
 	}
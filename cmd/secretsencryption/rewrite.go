@@ -0,0 +1,42 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package secretsencryption
+
+import (
+	"context"
+
+	"github.com/spf13/cobra"
+
+	"github.com/k0sproject/k0s/pkg/config"
+)
+
+func rewriteCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "rewrite",
+		Short: "Rewrite the encrypted resources with the current encryption key",
+		Long: `Rewrite all the objects of the resources ever encrypted at rest, so that they're stored again with
+the current encryption key, or in plaintext if the encryption has been disabled since.
+
+Example:
+   k0s secrets-encryption rewrite`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c := CmdOpts(config.GetCmdOpts())
+			return c.rewrite(context.Background())
+		},
+	}
+	cmd.PersistentFlags().AddFlagSet(config.GetPersistentFlagSet())
+	return cmd
+}
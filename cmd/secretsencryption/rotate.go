@@ -0,0 +1,173 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package secretsencryption
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/util/wait"
+	k8s "k8s.io/client-go/kubernetes"
+
+	"github.com/k0sproject/k0s/pkg/config"
+	"github.com/k0sproject/k0s/pkg/encryption"
+	"github.com/k0sproject/k0s/pkg/kubernetes"
+)
+
+func rotateCmd() *cobra.Command {
+	var (
+		rewrite bool
+		timeout time.Duration
+	)
+	cmd := &cobra.Command{
+		Use:   "rotate",
+		Short: "Add a new encryption key and rewrite the Secrets with it",
+		Long: `Add a new encryption key, make it the one the resources are encrypted with once all the controllers
+have it, and rewrite all the encrypted resources with it. The previous keys are kept, so that the
+resources can be read during the rotation.
+
+The rotation runs in two phases. The new key is first added as a decryption only key, and the command
+waits for all the controllers to run kube-apiserver with it: the joined controllers fetch the keys from
+the controller they joined. The key is then promoted to encrypt the resources, and once all the controllers
+run with the promoted key, the resources are rewritten.
+
+The command must be run on the controller the cluster was initialized with, the other controllers get
+the keys from it. An interrupted rotation is resumed by running the command again.
+
+Example:
+   k0s secrets-encryption rotate
+   k0s secrets-encryption rotate --rewrite=false`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c := CmdOpts(config.GetCmdOpts())
+			spec, err := c.encryptionSpec()
+			if err != nil {
+				return err
+			}
+			if spec == nil {
+				return fmt.Errorf("the encryption at rest is not enabled, see spec.api.encryption")
+			}
+
+			keysPath := encryption.KeysPath(c.K0sVars.CertRootDir)
+			keys, err := encryption.LoadKeys(keysPath)
+			if err != nil {
+				return err
+			}
+			if keys.Empty() {
+				return fmt.Errorf("no encryption keys found in %s, the controller has not been started with the encryption enabled", c.K0sVars.CertRootDir)
+			}
+			client, err := kubernetes.NewAdminClientFactory(c.K0sVars).GetClient()
+			if err != nil {
+				return err
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			defer cancel()
+
+			var key encryption.Key
+			if pending := keys.Pending(); pending != nil {
+				key = *pending
+				logrus.Infof("Resuming the rotation to the encryption key %s", key.Name)
+			} else {
+				if key, err = keys.Rotate(spec.Provider); err != nil {
+					return err
+				}
+				if err := keys.Save(keysPath); err != nil {
+					return err
+				}
+				logrus.Infof("Added the decryption only encryption key %s to %s", key.Name, keysPath)
+			}
+
+			logrus.Info("Waiting for all the controllers to run kube-apiserver with the new key")
+			if err := waitForControllers(ctx, client, func(names []string) bool { return contains(names, key.Name) }); err != nil {
+				return fmt.Errorf("not all the controllers have the encryption key %s: %w", key.Name, err)
+			}
+
+			if _, err := keys.Promote(); err != nil {
+				return err
+			}
+			if err := keys.Save(keysPath); err != nil {
+				return err
+			}
+			logrus.Infof("Promoted the encryption key %s to encrypt the resources", key.Name)
+			logrus.Info("Waiting for all the controllers to run kube-apiserver with the promoted key")
+			if err := waitForControllers(ctx, client, func(names []string) bool { return len(names) > 0 && names[0] == key.Name }); err != nil {
+				return fmt.Errorf("not all the controllers encrypt with the encryption key %s: %w", key.Name, err)
+			}
+
+			if !rewrite {
+				logrus.Info(`Not rewriting the resources, run "k0s secrets-encryption rewrite" to encrypt them with the new key`)
+				return nil
+			}
+			return c.rewrite(ctx)
+		},
+	}
+	cmd.Flags().BoolVar(&rewrite, "rewrite", true, "rewrite the encrypted resources with the new key")
+	cmd.Flags().DurationVar(&timeout, "timeout", 15*time.Minute, "how long to wait for the controllers to get the new key and for the resources to be rewritten")
+	cmd.PersistentFlags().AddFlagSet(config.GetPersistentFlagSet())
+	return cmd
+}
+
+// waitForControllers waits until every running controller reports the names of the keys its kube-apiserver
+// runs with, and the names are what the check expects. The running controllers are the ones holding a
+// controller lease, and this one.
+func waitForControllers(ctx context.Context, client k8s.Interface, check func(names []string) bool) error {
+	self, err := os.Hostname()
+	if err != nil {
+		return err
+	}
+	var pending []string
+	err = wait.PollImmediateUntil(5*time.Second, func() (bool, error) {
+		controllers, err := kubernetes.ActiveControllers(ctx, client)
+		if err != nil {
+			logrus.Debugf("failed to list the controllers: %v", err)
+			return false, nil
+		}
+		if !contains(controllers, self) {
+			controllers = append(controllers, self)
+		}
+		reported, err := encryption.ReportedKeyNames(ctx, client)
+		if err != nil {
+			logrus.Debugf("failed to get the reported encryption keys: %v", err)
+			return false, nil
+		}
+		pending = pending[:0]
+		for _, controller := range controllers {
+			if !check(reported[controller]) {
+				pending = append(pending, controller)
+			}
+		}
+		sort.Strings(pending)
+		return len(pending) == 0, nil
+	}, ctx.Done())
+	if err != nil && len(pending) > 0 {
+		return fmt.Errorf("%w, waiting for %s", err, strings.Join(pending, ", "))
+	}
+	return err
+}
+
+func contains(items []string, item string) bool {
+	for _, i := range items {
+		if i == item {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,79 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package secretsencryption
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/k0sproject/k0s/pkg/apis/v1beta1"
+	"github.com/k0sproject/k0s/pkg/config"
+	"github.com/k0sproject/k0s/pkg/encryption"
+	"github.com/k0sproject/k0s/pkg/kubernetes"
+)
+
+type CmdOpts config.CLIOptions
+
+func NewSecretsEncryptionCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "secrets-encryption",
+		Short: "Manage the encryption at rest of the Secrets",
+	}
+	cmd.SilenceUsage = true
+	cmd.AddCommand(rotateCmd())
+	cmd.AddCommand(rewriteCmd())
+	cmd.PersistentFlags().AddFlagSet(config.GetPersistentFlagSet())
+	return cmd
+}
+
+// encryptionSpec loads the encryption config of the controller
+func (c *CmdOpts) encryptionSpec() (*v1beta1.EncryptionSpec, error) {
+	_, cfg, err := config.LoadComposed(c.CfgFile, c.CfgDir, c.K0sVars)
+	if err != nil {
+		return nil, err
+	}
+	return cfg.Spec.API.Encryption, nil
+}
+
+// rewrite rewrites all the objects of the resources ever encrypted with the keys
+func (c *CmdOpts) rewrite(ctx context.Context) error {
+	keys, err := encryption.LoadKeys(encryption.KeysPath(c.K0sVars.CertRootDir))
+	if err != nil {
+		return err
+	}
+	if keys.Empty() {
+		return fmt.Errorf("no encryption keys found in %s, the encryption at rest has never been enabled on this controller", c.K0sVars.CertRootDir)
+	}
+
+	clientFactory := kubernetes.NewAdminClientFactory(c.K0sVars)
+	client, err := clientFactory.GetClient()
+	if err != nil {
+		return err
+	}
+	dynamicClient, err := clientFactory.GetDynamicClient()
+	if err != nil {
+		return err
+	}
+	count, err := encryption.Rewrite(ctx, client.Discovery(), dynamicClient, keys.Resources)
+	if err != nil {
+		return err
+	}
+	logrus.Infof("Rewrote %d objects of %v with the current encryption key", count, keys.Resources)
+	return nil
+}
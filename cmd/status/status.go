@@ -16,16 +16,21 @@ limitations under the License.
 package status
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"runtime"
 	"strings"
+	"time"
 
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 
+	"github.com/k0sproject/k0s/pkg/component/controller"
 	"github.com/k0sproject/k0s/pkg/config"
 	"github.com/k0sproject/k0s/pkg/install"
+	"github.com/k0sproject/k0s/pkg/kubernetes"
+	"github.com/k0sproject/k0s/pkg/leaderelection"
 )
 
 type CmdOpts config.CLIOptions
@@ -68,6 +73,13 @@ func NewStatusCmd() *cobra.Command {
 				if s.SysInit, s.StubFile, err = install.GetSysInit(strings.TrimSuffix(s.Role, "+worker")); err != nil {
 					return err
 				}
+
+				if strings.HasPrefix(s.Role, "controller") {
+					c := CmdOpts(config.GetCmdOpts())
+					if s.Leader, err = c.getLeaderStatus(); err != nil {
+						logrus.Debugf("can't get the leader status: %s", err.Error())
+					}
+				}
 			} else {
 				fmt.Fprintln(os.Stderr, "K0s not running")
 				os.Exit(1)
@@ -81,3 +93,27 @@ func NewStatusCmd() *cobra.Command {
 	cmd.PersistentFlags().StringVarP(&output, "out", "o", "", "sets type of output to json or yaml")
 	return cmd
 }
+
+// getLeaderStatus reads the leader lease to tell which controller is the leader
+func (c *CmdOpts) getLeaderStatus() (*install.LeaderStatus, error) {
+	client, err := kubernetes.NewClient(c.K0sVars.AdminKubeConfigPath)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	lease, err := leaderelection.GetLeaseStatus(ctx, client, controller.LeaderLeaseNamespace, controller.LeaderLeaseName)
+	if err != nil {
+		return nil, err
+	}
+	identity, err := controller.LeaderIdentity()
+	if err != nil {
+		return nil, err
+	}
+	return &install.LeaderStatus{
+		Identity:    lease.HolderIdentity,
+		IsSelf:      lease.HolderIdentity == identity,
+		AcquireTime: lease.AcquireTime,
+		Transitions: lease.Transitions,
+	}, nil
+}
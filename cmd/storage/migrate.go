@@ -0,0 +1,360 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"go.etcd.io/etcd/clientv3"
+
+	"github.com/k0sproject/k0s/pkg/apis/v1beta1"
+	"github.com/k0sproject/k0s/pkg/certificate"
+	"github.com/k0sproject/k0s/pkg/component"
+	"github.com/k0sproject/k0s/pkg/component/controller"
+	"github.com/k0sproject/k0s/pkg/config"
+	"github.com/k0sproject/k0s/pkg/constant"
+	"github.com/k0sproject/k0s/pkg/etcd"
+	"github.com/k0sproject/k0s/pkg/install"
+	"github.com/k0sproject/k0s/pkg/storage"
+)
+
+func storageMigrateCmd() *cobra.Command {
+	var (
+		to           string
+		dataSource   string
+		passwordFrom string
+		peerAddress  string
+		configOut    string
+		single       bool
+		timeout      time.Duration
+	)
+	cmd := &cobra.Command{
+		Use:   "migrate",
+		Short: "Copy the cluster state to another storage and switch the config to it",
+		Long: `Copy the cluster state to another storage and switch the config to it. k0s must be stopped, the
+current storage and the target one are started by the command for the time of the copy. The latest
+version of every key is copied in the order the keys were modified, the target storage assigns new
+revisions. The keys attached to a lease, such as the events, are left out.
+
+The config file given with --config is rewritten with the new storage, the previous one is kept next
+to it with the .pre-migrate suffix. Without a config file, the config is written to --config-out. The
+settings of the target storage already in the config, such as the TLS and pool settings of kine, are
+kept. The command refuses to migrate when spec.storage is set by a fragment of --config-dir or by an
+environment override, move it to the config file first.
+
+The postgres password is written to the config as a reference, pass it with --password-from instead
+of --data-source. kine only takes the mysql password as part of the data source, which is written to
+the config as is.
+
+Migrating from etcd is only possible once the other controllers have left the etcd cluster.
+
+Example:
+   k0s storage migrate --to etcd --config /etc/k0s/k0s.yaml
+   k0s storage migrate --to etcd --single --config-out /etc/k0s/k0s.yaml
+   k0s storage migrate --to kine --data-source "postgres://k0s@db:5432/k0s" --password-from file:/etc/k0s/db-password --config /etc/k0s/k0s.yaml`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c := CmdOpts(config.GetCmdOpts())
+			if single {
+				c.K0sVars.DefaultStorageType = v1beta1.KineStorageType
+			}
+			if c.CfgFile == "-" {
+				return fmt.Errorf("can't migrate a config read from stdin, use --config with a file")
+			}
+			if c.CfgFile == "" && configOut == "" {
+				return fmt.Errorf("no config file given, use --config or --config-out")
+			}
+			composed, cfg, err := config.LoadComposed(c.CfgFile, c.CfgDir, c.K0sVars)
+			if err != nil {
+				return err
+			}
+			// the rewritten config file would be overridden by the other sources
+			if composed != nil {
+				for _, origin := range composed.Origins("spec.storage") {
+					if origin != c.CfgFile {
+						return fmt.Errorf("spec.storage is set by %s, move it to the config file before migrating", origin)
+					}
+				}
+			}
+			target, err := targetStorage(to, dataSource, passwordFrom, peerAddress, cfg.Spec.Storage, c.K0sVars)
+			if err != nil {
+				return err
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			defer cancel()
+			if err := c.migrate(ctx, cfg.Spec.Storage, target); err != nil {
+				return err
+			}
+			return c.writeConfig(target, configOut)
+		},
+	}
+	cmd.Flags().StringVar(&to, "to", "", "storage to migrate to, etcd or kine")
+	cmd.Flags().StringVar(&dataSource, "data-source", "", "kine datasource to migrate to (default: the sqlite database in the data dir)")
+	cmd.Flags().StringVar(&passwordFrom, "password-from", "", "postgres password of the kine datasource, file:<path> or env:<variable>")
+	cmd.Flags().StringVar(&peerAddress, "peer-address", "", "etcd peer address of the controller (default: the first address of the node)")
+	cmd.Flags().StringVar(&configOut, "config-out", "", "write the migrated config to this path instead of rewriting the --config file")
+	cmd.Flags().BoolVar(&single, "single", false, "the controller is run with --single, its storage defaults to kine")
+	cmd.Flags().DurationVar(&timeout, "timeout", 30*time.Minute, "how long the storages may take to start and the keys to be copied")
+	_ = cmd.MarkFlagRequired("to")
+	cmd.PersistentFlags().AddFlagSet(config.GetPersistentFlagSet())
+	return cmd
+}
+
+// targetStorage returns the storage to migrate to, starting from the settings of the storage in the current config
+func targetStorage(to, dataSource, passwordFrom, peerAddress string, current *v1beta1.StorageSpec, k0sVars constant.CfgVars) (*v1beta1.StorageSpec, error) {
+	if passwordFrom != "" && (to != v1beta1.KineStorageType || dataSource == "") {
+		return nil, fmt.Errorf("--password-from is only used with --to kine and --data-source")
+	}
+	var target *v1beta1.StorageSpec
+	switch to {
+	case v1beta1.EtcdStorageType:
+		etcdConf := v1beta1.DefaultEtcdConfig()
+		if current.Etcd != nil {
+			c := *current.Etcd
+			etcdConf = &c
+		}
+		if peerAddress != "" {
+			etcdConf.PeerAddress = peerAddress
+		}
+		target = &v1beta1.StorageSpec{Type: v1beta1.EtcdStorageType, Etcd: etcdConf}
+	case v1beta1.KineStorageType:
+		kineConf := v1beta1.DefaultKineConfig(k0sVars.DataDir)
+		if current.Kine != nil {
+			c := *current.Kine
+			kineConf = &c
+		}
+		if dataSource != "" {
+			if controller.DataSourceHasPassword(dataSource) {
+				if strings.HasPrefix(dataSource, "postgres") {
+					return nil, fmt.Errorf("the password of --data-source would be written to the config in plain text, pass it with --password-from")
+				}
+				logrus.Warn("the password of --data-source is written to the config in plain text, restrict the access to the config file")
+			}
+			// the password reference belongs to the previous data source
+			kineConf.DataSource, kineConf.PasswordFrom = dataSource, nil
+		}
+		if passwordFrom != "" {
+			ref, err := parsePasswordFrom(passwordFrom)
+			if err != nil {
+				return nil, err
+			}
+			kineConf.PasswordFrom = ref
+		}
+		target = &v1beta1.StorageSpec{Type: v1beta1.KineStorageType, Kine: kineConf}
+	default:
+		return nil, fmt.Errorf("unsupported storage %q, use etcd or kine", to)
+	}
+	if errs := target.Validate(); len(errs) > 0 {
+		return nil, errs[0]
+	}
+	return target, nil
+}
+
+// parsePasswordFrom parses the file:<path> and env:<variable> password references
+func parsePasswordFrom(s string) (*v1beta1.SecretRef, error) {
+	parts := strings.SplitN(s, ":", 2)
+	if len(parts) == 2 && parts[1] != "" {
+		switch parts[0] {
+		case "file":
+			return &v1beta1.SecretRef{File: parts[1]}, nil
+		case "env":
+			return &v1beta1.SecretRef{Env: parts[1]}, nil
+		}
+	}
+	return nil, fmt.Errorf("invalid --password-from %q, use file:<path> or env:<variable>", s)
+}
+
+func (c *CmdOpts) migrate(ctx context.Context, from, to *v1beta1.StorageSpec) error {
+	if os.Geteuid() != 0 {
+		return fmt.Errorf("this command must be run as root")
+	}
+	if status, _ := install.GetPid(); status.Pid != 0 {
+		return fmt.Errorf("k0s is running, stop it before migrating the storage")
+	}
+	if from.Type == to.Type && (from.Type == v1beta1.EtcdStorageType || from.Kine.DataSource == to.Kine.DataSource) {
+		return fmt.Errorf("the controller already uses the %s storage", to.Type)
+	}
+
+	logrus.Infof("Starting the current %s storage", from.Type)
+	src, stopSrc, err := c.start(ctx, from, c.K0sVars)
+	if err != nil {
+		return err
+	}
+	defer stopSrc()
+
+	dstVars := c.K0sVars
+	if from.Type == v1beta1.KineStorageType && to.Type == v1beta1.KineStorageType {
+		// the second kine gets its own socket and pid file
+		dstVars.RunDir = filepath.Join(c.K0sVars.RunDir, "storage-migrate")
+		dstVars.KineSocketPath = filepath.Join(dstVars.RunDir, constant.KineSocket)
+	}
+	logrus.Infof("Starting the target %s storage", to.Type)
+	dst, stopDst, err := c.start(ctx, to, dstVars)
+	if err != nil {
+		return err
+	}
+	defer stopDst()
+
+	srcPrefix, dstPrefix := keyPrefix(from), keyPrefix(to)
+	count, err := storage.Count(ctx, dst, dstPrefix)
+	if err != nil {
+		return fmt.Errorf("failed to read the target storage: %w", err)
+	}
+	if count > 0 {
+		return fmt.Errorf("the target storage already has %d keys under %s", count, dstPrefix)
+	}
+
+	logrus.Infof("Copying the keys under %s", srcPrefix)
+	result, err := storage.Copy(ctx, src, dst, srcPrefix, dstPrefix)
+	if err != nil {
+		return err
+	}
+	logrus.Infof("Copied %d keys, left out %d keys attached to leases", result.Keys, result.LeasedKeys)
+	return nil
+}
+
+// start runs the storage for the time of the migration and connects to it
+func (c *CmdOpts) start(ctx context.Context, s *v1beta1.StorageSpec, k0sVars constant.CfgVars) (clientv3.KV, func(), error) {
+	switch s.Type {
+	case v1beta1.EtcdStorageType:
+		stop := func() {}
+		if s.Etcd.IsExternalClusterUsed() {
+			logrus.Infof("Using the external etcd cluster %s", strings.Join(s.Etcd.ExternalCluster.Endpoints, ","))
+		} else {
+			var err error
+			stop, err = runComponent(ctx, &controller.Etcd{
+				CertManager: certificate.Manager{K0sVars: k0sVars},
+				Config:      s.Etcd,
+				K0sVars:     k0sVars,
+				LogLevel:    "warn",
+			})
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+		client, err := etcd.NewClient(k0sVars.CertRootDir, k0sVars.EtcdCertDir, s.Etcd)
+		if err != nil {
+			stop()
+			return nil, nil, err
+		}
+		if !s.Etcd.IsExternalClusterUsed() {
+			members, err := client.Members(ctx)
+			if err != nil {
+				client.Close()
+				stop()
+				return nil, nil, fmt.Errorf("failed to list the etcd members: %w", err)
+			}
+			if len(members) > 1 {
+				client.Close()
+				stop()
+				return nil, nil, fmt.Errorf("etcd has %d members, reset the other controllers first", len(members))
+			}
+		}
+		return client.KV(), func() { client.Close(); stop() }, nil
+
+	case v1beta1.KineStorageType:
+		stop, err := runComponent(ctx, &controller.Kine{Config: s.Kine, K0sVars: k0sVars})
+		if err != nil {
+			return nil, nil, err
+		}
+		client, err := clientv3.New(clientv3.Config{
+			Endpoints:   []string{"unix://" + k0sVars.KineSocketPath},
+			DialTimeout: 5 * time.Second,
+		})
+		if err != nil {
+			stop()
+			return nil, nil, fmt.Errorf("can't connect to kine: %w", err)
+		}
+		return client, func() { client.Close(); stop() }, nil
+	}
+	return nil, nil, fmt.Errorf("unsupported storage type %s", s.Type)
+}
+
+// runComponent starts the storage component and waits for it to be healthy
+func runComponent(ctx context.Context, comp component.Component) (func(), error) {
+	if err := comp.Init(); err != nil {
+		return nil, err
+	}
+	if err := comp.Run(); err != nil {
+		return nil, err
+	}
+	stop := func() {
+		if err := comp.Stop(); err != nil {
+			logrus.Warnf("failed to stop the storage: %v", err)
+		}
+	}
+	for {
+		err := comp.Healthy()
+		if err == nil {
+			return stop, nil
+		}
+		select {
+		case <-ctx.Done():
+			stop()
+			return nil, fmt.Errorf("the storage didn't get healthy: %w", err)
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+// keyPrefix returns the prefix kube-apiserver stores its keys under
+func keyPrefix(s *v1beta1.StorageSpec) string {
+	if s.Type == v1beta1.EtcdStorageType && s.Etcd.IsExternalClusterUsed() && s.Etcd.ExternalCluster.EtcdPrefix != "" {
+		return strings.TrimSuffix(s.Etcd.ExternalCluster.EtcdPrefix, "/") + "/"
+	}
+	return "/registry/"
+}
+
+// writeConfig writes the config with the storage migrated to, keeping the previous config file
+func (c *CmdOpts) writeConfig(target *v1beta1.StorageSpec, configOut string) error {
+	var data []byte
+	mode := os.FileMode(0600)
+	if c.CfgFile != "" {
+		info, err := os.Stat(c.CfgFile)
+		if err != nil {
+			return err
+		}
+		mode = info.Mode()
+		if data, err = ioutil.ReadFile(c.CfgFile); err != nil {
+			return err
+		}
+	}
+	migrated, err := storage.SetStorage(data, target)
+	if err != nil {
+		return fmt.Errorf("failed to update the config: %w", err)
+	}
+
+	out := configOut
+	if out == "" {
+		out = c.CfgFile
+		if err := ioutil.WriteFile(c.CfgFile+".pre-migrate", data, mode); err != nil {
+			return err
+		}
+	}
+	if err := ioutil.WriteFile(out, migrated, mode); err != nil {
+		return err
+	}
+	logrus.Infof("Wrote the config with the %s storage to %s, start k0s with it", target.Type, out)
+	return nil
+}
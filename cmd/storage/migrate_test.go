@@ -0,0 +1,78 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package storage
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/k0sproject/k0s/pkg/apis/v1beta1"
+	"github.com/k0sproject/k0s/pkg/constant"
+)
+
+func TestTargetStorage(t *testing.T) {
+	k0sVars := constant.CfgVars{DataDir: "/var/lib/k0s"}
+	current := &v1beta1.StorageSpec{
+		Type: v1beta1.EtcdStorageType,
+		Etcd: &v1beta1.EtcdConfig{PeerAddress: "10.0.0.1", PeerPort: 2390},
+		Kine: &v1beta1.KineConfig{
+			DataSource:   "postgres://k0s@old-db/k0s",
+			PasswordFrom: &v1beta1.SecretRef{Env: "OLD_DB_PASSWORD"},
+			TLS:          &v1beta1.KineTLS{CAFile: "/etc/k0s/db-ca.crt"},
+			Pool:         &v1beta1.KinePool{MaxOpenConnections: 20},
+		},
+	}
+
+	t.Run("kine keeps the settings of the config", func(t *testing.T) {
+		target, err := targetStorage("kine", "postgres://k0s@db/k0s", "file:/etc/k0s/db-password", "", current, k0sVars)
+		require.NoError(t, err)
+		assert.Equal(t, &v1beta1.KineConfig{
+			DataSource:   "postgres://k0s@db/k0s",
+			PasswordFrom: &v1beta1.SecretRef{File: "/etc/k0s/db-password"},
+			TLS:          &v1beta1.KineTLS{CAFile: "/etc/k0s/db-ca.crt"},
+			Pool:         &v1beta1.KinePool{MaxOpenConnections: 20},
+		}, target.Kine)
+		assert.Equal(t, "postgres://k0s@old-db/k0s", current.Kine.DataSource, "the current config was modified")
+
+		target, err = targetStorage("kine", "postgres://k0s@db/k0s", "", "", current, k0sVars)
+		require.NoError(t, err)
+		assert.Nil(t, target.Kine.PasswordFrom, "kept the password reference of the previous data source")
+	})
+
+	t.Run("etcd keeps the settings of the config", func(t *testing.T) {
+		target, err := targetStorage("etcd", "", "", "10.0.0.2", current, k0sVars)
+		require.NoError(t, err)
+		assert.Equal(t, &v1beta1.EtcdConfig{PeerAddress: "10.0.0.2", PeerPort: 2390}, target.Etcd)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		for _, test := range []struct {
+			name, to, dataSource, passwordFrom string
+		}{
+			{"postgres password in the data source", "kine", "postgres://k0s:secret@db/k0s", ""},
+			{"password reference without data source", "kine", "", "env:DB_PASSWORD"},
+			{"password reference for etcd", "etcd", "", "env:DB_PASSWORD"},
+			{"invalid password reference", "kine", "postgres://k0s@db/k0s", "secret:db"},
+			{"password reference for mysql", "kine", "mysql://k0s@tcp(db:3306)/k0s", "env:DB_PASSWORD"},
+			{"unknown storage", "consul", "", ""},
+		} {
+			_, err := targetStorage(test.to, test.dataSource, test.passwordFrom, "", current, k0sVars)
+			assert.Error(t, err, test.name)
+		}
+	})
+}
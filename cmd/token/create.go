@@ -42,7 +42,7 @@ k0s token create --role worker --expiry 10m  //sets expiration time to 10 minute
 			// Disable logrus for token commands
 			logrus.SetLevel(logrus.FatalLevel)
 			c := CmdOpts(config.GetCmdOpts())
-			clusterConfig, err := config.GetYamlFromFile(c.CfgFile, c.K0sVars)
+			clusterConfig, err := config.GetYamlFromFile(c.CfgFile, c.CfgDir, c.K0sVars)
 			if err != nil {
 				return err
 			}
@@ -83,9 +83,21 @@ func (c *CmdOpts) StartWorker() error {
 		return fmt.Errorf("normal kubelet kubeconfig does not exist and no join-token given. dunno how to make kubelet auth to api")
 	}
 
-	// Dump join token into kubelet-bootstrap kubeconfig if it does not already exist
+	if c.WorkerProfile == "default" && runtime.GOOS == "windows" {
+		c.WorkerProfile = "default-windows"
+	}
+
+	// Join over the k0s api, or dump the join token into kubelet-bootstrap kubeconfig, if not already done
+	var joinInfo *worker.JoinInfo
+	var err error
 	if c.TokenArg != "" && !util.FileExists(c.K0sVars.KubeletBootstrapConfigPath) {
-		if err := worker.HandleKubeletBootstrapToken(c.TokenArg, c.K0sVars); err != nil {
+		joinInfo, err = worker.JoinWorker(c.TokenArg, c.K0sVars, c.WorkerProfile, c.Labels)
+		if err != nil {
+			return err
+		}
+	} else {
+		joinInfo, err = worker.LoadJoinInfo(c.K0sVars)
+		if err != nil {
 			return err
 		}
 	}
@@ -101,17 +113,15 @@ func (c *CmdOpts) StartWorker() error {
 	}
 	if c.CriSocket == "" {
 		componentManager.Add(&worker.ContainerD{
-			LogLevel: c.Logging["containerd"],
-			K0sVars:  c.K0sVars,
+			LogLevel:  c.Logging["containerd"],
+			K0sVars:   c.K0sVars,
+			ExtraArgs: c.ContainerdExtraArgs,
 		})
 	}
 
 	componentManager.Add(worker.NewOCIBundleReconciler(c.K0sVars))
-	if c.WorkerProfile == "default" && runtime.GOOS == "windows" {
-		c.WorkerProfile = "default-windows"
-	}
 
-	componentManager.Add(&worker.Kubelet{
+	kubelet := &worker.Kubelet{
 		CRISocket:           c.CriSocket,
 		EnableCloudProvider: c.CloudProvider,
 		K0sVars:             c.K0sVars,
@@ -120,22 +130,36 @@ func (c *CmdOpts) StartWorker() error {
 		Profile:             c.WorkerProfile,
 		Labels:              c.Labels,
 		ExtraArgs:           c.KubeletExtraArgs,
-	})
+	}
+	if joinInfo != nil {
+		kubelet.ClusterDomain = joinInfo.ClusterDomain
+	}
+	if joinInfo != nil && joinInfo.Profile == c.WorkerProfile {
+		kubelet.InitialConfig = joinInfo.KubeletConfig
+	}
+	componentManager.Add(kubelet)
+
+	if runtime.GOOS != "windows" {
+		componentManager.Add(&worker.NetworkCleanup{K0sVars: c.K0sVars})
+	}
 
 	if runtime.GOOS == "windows" {
 		if c.TokenArg == "" {
 			return fmt.Errorf("no join-token given, which is required for windows bootstrap")
 		}
+		if joinInfo == nil {
+			return fmt.Errorf("windows workers need to join over the k0s api, make sure the controllers are up to date and create a new join token")
+		}
 		componentManager.Add(&worker.KubeProxy{
 			K0sVars:   c.K0sVars,
 			LogLevel:  c.Logging["kube-proxy"],
-			CIDRRange: c.CIDRRange,
+			CIDRRange: joinInfo.ServiceCIDR,
 		})
 		componentManager.Add(&worker.CalicoInstaller{
 			Token:      c.TokenArg,
-			APIAddress: c.APIServer,
-			CIDRRange:  c.CIDRRange,
-			ClusterDNS: c.ClusterDNS,
+			APIAddress: joinInfo.K0sAPIAddress,
+			CIDRRange:  joinInfo.ServiceCIDR,
+			ClusterDNS: joinInfo.ClusterDNS,
 		})
 	}
 
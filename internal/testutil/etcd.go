@@ -0,0 +1,53 @@
+package testutil
+
+import (
+	"net"
+	"net/url"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go.etcd.io/etcd/embed"
+)
+
+// StartEtcd runs a single member etcd cluster listening on plain http for the duration of the test. The member
+// advertises the peer URL with the https scheme, the way the controllers run etcd, although it doesn't listen
+// on it. It returns the client and the advertised peer URL.
+func StartEtcd(t *testing.T) (clientURL, peerURL string) {
+	t.Helper()
+	dir := t.TempDir()
+	clientPort, peerPort := freePort(t), freePort(t)
+
+	cfg := embed.NewConfig()
+	cfg.Name = "test"
+	cfg.Dir = filepath.Join(dir, "data")
+	cfg.Logger = "zap"
+	cfg.LogOutputs = []string{filepath.Join(dir, "etcd.log")}
+	cfg.LCUrls = []url.URL{{Scheme: "http", Host: net.JoinHostPort("127.0.0.1", clientPort)}}
+	cfg.ACUrls = cfg.LCUrls
+	cfg.LPUrls = []url.URL{{Scheme: "http", Host: net.JoinHostPort("127.0.0.1", peerPort)}}
+	cfg.APUrls = []url.URL{{Scheme: "https", Host: net.JoinHostPort("127.0.0.1", peerPort)}}
+	cfg.InitialCluster = cfg.InitialClusterFromName(cfg.Name)
+
+	e, err := embed.StartEtcd(cfg)
+	if err != nil {
+		t.Fatalf("failed to start etcd: %v", err)
+	}
+	t.Cleanup(e.Close)
+	select {
+	case <-e.Server.ReadyNotify():
+	case <-time.After(time.Minute):
+		t.Fatal("etcd didn't get ready in time")
+	}
+	return cfg.ACUrls[0].String(), cfg.APUrls[0].String()
+}
+
+func freePort(t *testing.T) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to find a free port: %v", err)
+	}
+	defer l.Close()
+	_, port, _ := net.SplitHostPort(l.Addr().String())
+	return port
+}
@@ -23,12 +23,15 @@ func GetImageURIs(spec *v1beta1.ClusterImages) []string {
 		spec.KubeRouter.CNI.URI(),
 		spec.KubeRouter.CNIInstaller.URI(),
 	}
-	// Calico images do not currently support armv7, thus we need to exclude them from the list if we're running this on arm
+	// Calico and Cilium images do not currently support armv7, thus we need to exclude them from the list if we're running this on arm
 	if runtime.GOARCH != "arm" {
 		images = append(images,
 			spec.Calico.CNI.URI(),
 			spec.Calico.KubeControllers.URI(),
-			spec.Calico.Node.URI())
+			spec.Calico.Node.URI(),
+			spec.Cilium.Agent.URI(),
+			spec.Cilium.Operator.URI(),
+			spec.Cilium.HubbleRelay.URI())
 	}
 	return images
 }
@@ -0,0 +1,94 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package v1beta1
+
+import "fmt"
+
+// DefaultAdmissionPlugins are the admission plugins k0s enables on top of the ones kube-apiserver enables by default
+var DefaultAdmissionPlugins = []string{"NodeRestriction", "PodSecurityPolicy"}
+
+// AdmissionSpec configures the admission plugins of kube-apiserver
+type AdmissionSpec struct {
+	// EnablePlugins are enabled in addition to the k0s defaults
+	EnablePlugins []string `yaml:"enablePlugins,omitempty"`
+	// DisablePlugins are disabled, including the ones enabled by default
+	DisablePlugins []string `yaml:"disablePlugins,omitempty"`
+	// Plugins configure the admission plugins, k0s renders them into an AdmissionConfiguration file
+	Plugins []AdmissionPlugin `yaml:"plugins,omitempty"`
+}
+
+// AdmissionPlugin is the configuration of an admission plugin, e.g. a PodSecurityConfiguration for PodSecurity
+type AdmissionPlugin struct {
+	Name          string                 `yaml:"name"`
+	Configuration map[string]interface{} `yaml:"configuration"`
+}
+
+// EnabledPlugins returns the admission plugins to enable, the defaults and the enabled ones without the disabled ones
+func (a *AdmissionSpec) EnabledPlugins() []string {
+	plugins := append([]string{}, DefaultAdmissionPlugins...)
+	if a == nil {
+		return plugins
+	}
+	disabled := make(map[string]bool, len(a.DisablePlugins))
+	for _, p := range a.DisablePlugins {
+		disabled[p] = true
+	}
+	seen := map[string]bool{}
+	var enabled []string
+	for _, p := range append(plugins, a.EnablePlugins...) {
+		if disabled[p] || seen[p] {
+			continue
+		}
+		seen[p] = true
+		enabled = append(enabled, p)
+	}
+	return enabled
+}
+
+// Validate validates the admission config
+func (a *AdmissionSpec) Validate() []error {
+	var errors []error
+	disabled := map[string]bool{}
+	for _, p := range a.DisablePlugins {
+		if p == "" {
+			errors = append(errors, fieldError("spec.api.admission.disablePlugins", "plugin names must not be empty"))
+		}
+		disabled[p] = true
+	}
+	for _, p := range a.EnablePlugins {
+		if p == "" {
+			errors = append(errors, fieldError("spec.api.admission.enablePlugins", "plugin names must not be empty"))
+		}
+		if disabled[p] {
+			errors = append(errors, fieldError("spec.api.admission.enablePlugins", "%s is both enabled and disabled", p))
+		}
+	}
+	configured := map[string]bool{}
+	for i, p := range a.Plugins {
+		field := fmt.Sprintf("spec.api.admission.plugins[%d]", i)
+		switch {
+		case p.Name == "":
+			errors = append(errors, fieldError(field+".name", "must be set"))
+		case configured[p.Name]:
+			errors = append(errors, fieldError(field+".name", "%s is configured more than once", p.Name))
+		}
+		configured[p.Name] = true
+		if len(p.Configuration) == 0 {
+			errors = append(errors, fieldError(field+".configuration", "must be set"))
+		}
+	}
+	return errors
+}
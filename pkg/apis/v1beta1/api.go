@@ -27,12 +27,16 @@ var _ Validateable = (*APISpec)(nil)
 
 // APISpec ...
 type APISpec struct {
-	Address         string            `yaml:"address"`
-	Port            int               `yaml:"port"`
-	K0sAPIPort      int               `yaml:"k0sApiPort,omitempty"`
-	ExternalAddress string            `yaml:"externalAddress,omitempty"`
-	SANs            []string          `yaml:"sans"`
-	ExtraArgs       map[string]string `yaml:"extraArgs,omitempty"`
+	Address         string              `yaml:"address"`
+	Port            int                 `yaml:"port"`
+	K0sAPIPort      int                 `yaml:"k0sApiPort,omitempty"`
+	ExternalAddress string              `yaml:"externalAddress,omitempty"`
+	SANs            []string            `yaml:"sans"`
+	ExtraArgs       ExtraArgs           `yaml:"extraArgs,omitempty"`
+	Admission       *AdmissionSpec      `yaml:"admission,omitempty"`
+	Audit           *AuditSpec          `yaml:"audit,omitempty"`
+	Encryption      *EncryptionSpec     `yaml:"encryption,omitempty"`
+	Authentication  *AuthenticationSpec `yaml:"authentication,omitempty"`
 }
 
 // DefaultAPISpec default settings for api
@@ -45,7 +49,7 @@ func DefaultAPISpec() *APISpec {
 		K0sAPIPort: 9443,
 		SANs:       addresses,
 		Address:    publicAddress,
-		ExtraArgs:  make(map[string]string),
+		ExtraArgs:  make(ExtraArgs),
 	}
 }
 
@@ -107,12 +111,71 @@ func (a *APISpec) Validate() []error {
 		if govalidator.IsDNSName(a) {
 			continue
 		}
-		errors = append(errors, fmt.Errorf("%s is not a valid address for sans", a))
+		errors = append(errors, fieldError("spec.api.sans", "%s is not a valid address for sans", a))
 	}
 
 	if !govalidator.IsIP(a.Address) {
-		errors = append(errors, fmt.Errorf("spec.api.address: %q is not IP address", a.Address))
+		errors = append(errors, fieldError("spec.api.address", "%q is not IP address", a.Address))
 	}
+	if a.ExternalAddress != "" && !govalidator.IsIP(a.ExternalAddress) && !govalidator.IsDNSName(a.ExternalAddress) {
+		errors = append(errors, fieldError("spec.api.externalAddress", "%q is not an IP address or a DNS name", a.ExternalAddress))
+	}
+	errors = appendErr(errors, validatePort("spec.api.port", a.Port))
+	errors = appendErr(errors, validatePort("spec.api.k0sApiPort", a.K0sAPIPort))
+	if a.Port == a.K0sAPIPort {
+		errors = append(errors, fieldError("spec.api.k0sApiPort", "must differ from spec.api.port"))
+	}
+	errors = append(errors, a.ExtraArgs.Validate("spec.api.extraArgs")...)
+	errors = append(errors, a.ExtraArgs.validateConfiguredWith("spec.api.extraArgs", a.configuredWith())...)
+	errors = append(errors, validateSpecs(a.Admission)...)
+	errors = append(errors, validateSpecs(a.Audit)...)
+	errors = append(errors, validateSpecs(a.Encryption)...)
+	errors = append(errors, validateSpecs(a.Authentication)...)
 
 	return errors
 }
+
+// configuredWith returns the flags k0s sets from the configured fields, keyed by the flag name. The flags of the
+// unset fields are still accepted in the extra args, k0s warns about them as deprecated when it runs kube-apiserver.
+func (a *APISpec) configuredWith() map[string]string {
+	configuredWith := map[string]string{}
+	if ad := a.Admission; ad != nil {
+		if len(ad.EnablePlugins) > 0 {
+			configuredWith["enable-admission-plugins"] = "spec.api.admission.enablePlugins"
+		}
+		if len(ad.DisablePlugins) > 0 {
+			configuredWith["disable-admission-plugins"] = "spec.api.admission.disablePlugins"
+		}
+		if len(ad.Plugins) > 0 {
+			configuredWith["admission-control-config-file"] = "spec.api.admission.plugins"
+		}
+	}
+	if au := a.Audit; au != nil {
+		configuredWith["audit-policy-file"] = "spec.api.audit"
+		if au.Log != nil {
+			for _, name := range []string{"audit-log-format", "audit-log-maxage", "audit-log-maxbackup", "audit-log-maxsize", "audit-log-path"} {
+				configuredWith[name] = "spec.api.audit.log"
+			}
+		}
+		if au.Webhook != nil {
+			configuredWith["audit-webhook-config-file"] = "spec.api.audit.webhook"
+			configuredWith["audit-webhook-mode"] = "spec.api.audit.webhook"
+		}
+	}
+	if a.Encryption != nil {
+		configuredWith["encryption-provider-config"] = "spec.api.encryption"
+	}
+	if authn := a.Authentication; authn != nil {
+		if authn.OIDC != nil {
+			for _, name := range []string{"oidc-ca-file", "oidc-client-id", "oidc-groups-claim", "oidc-groups-prefix", "oidc-issuer-url", "oidc-signing-algs", "oidc-username-claim", "oidc-username-prefix"} {
+				configuredWith[name] = "spec.api.authentication.oidc"
+			}
+		}
+		if authn.Webhook != nil {
+			for _, name := range []string{"authentication-token-webhook-cache-ttl", "authentication-token-webhook-config-file", "authentication-token-webhook-version"} {
+				configuredWith[name] = "spec.api.authentication.webhook"
+			}
+		}
+	}
+	return configuredWith
+}
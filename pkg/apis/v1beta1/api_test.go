@@ -34,7 +34,9 @@ func (s *APISuite) TestValidation() {
 
 	s.T().Run("accepts_ipv6_as_address", func(t *testing.T) {
 		a := APISpec{
-			Address: "2001:0db8:85a3:0000:0000:8a2e:0370:7334",
+			Address:    "2001:0db8:85a3:0000:0000:8a2e:0370:7334",
+			Port:       6443,
+			K0sAPIPort: 9443,
 		}
 
 		s.Nil(a.Validate())
@@ -43,7 +45,9 @@ func (s *APISuite) TestValidation() {
 
 	s.T().Run("invalid_api_address", func(t *testing.T) {
 		a := APISpec{
-			Address: "somehting.that.is.not.valid//(())",
+			Address:    "somehting.that.is.not.valid//(())",
+			Port:       6443,
+			K0sAPIPort: 9443,
 		}
 
 		errors := a.Validate()
@@ -54,7 +58,9 @@ func (s *APISuite) TestValidation() {
 
 	s.T().Run("invalid_sans_address", func(t *testing.T) {
 		a := APISpec{
-			Address: "1.2.3.4",
+			Address:    "1.2.3.4",
+			Port:       6443,
+			K0sAPIPort: 9443,
 			SANs: []string{
 				"somehting.that.is.not.valid//(())",
 			},
@@ -65,6 +71,18 @@ func (s *APISuite) TestValidation() {
 		s.Len(errors, 1)
 		s.Contains(errors[0].Error(), "is not a valid address for sans")
 	})
+
+	s.T().Run("invalid_ports", func(t *testing.T) {
+		a := DefaultAPISpec()
+		a.Port = 70000
+		a.K0sAPIPort = 70000
+
+		errors := a.Validate()
+		s.Len(errors, 3)
+		s.Equal("spec.api.port: must be 1-65535, got 70000", errors[0].Error())
+		s.Equal("spec.api.k0sApiPort: must be 1-65535, got 70000", errors[1].Error())
+		s.Equal("spec.api.k0sApiPort: must differ from spec.api.port", errors[2].Error())
+	})
 }
 
 func TestApiSuite(t *testing.T) {
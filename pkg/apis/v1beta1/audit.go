@@ -0,0 +1,158 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package v1beta1
+
+import (
+	"net/url"
+	"path/filepath"
+)
+
+var _ Validateable = (*AuditSpec)(nil)
+
+// The built-in audit policy presets, named after the audit level of the requests
+const (
+	AuditPresetMetadata        = "metadata"
+	AuditPresetRequest         = "request"
+	AuditPresetRequestResponse = "requestResponse"
+)
+
+var (
+	// AuditPresets are the built-in audit policies
+	AuditPresets = []string{AuditPresetMetadata, AuditPresetRequest, AuditPresetRequestResponse}
+	// AuditLogFormats are the supported audit log formats
+	AuditLogFormats = []string{"json", "legacy"}
+	// AuditWebhookModes are the supported modes of sending the audit events to the webhook
+	AuditWebhookModes = []string{"batch", "blocking", "blocking-strict"}
+)
+
+// AuditSpec enables the audit logging of kube-apiserver. The policy is either one of the built-in presets
+// or an inline audit.k8s.io/v1 Policy, the events are written to the log file, sent to the webhook, or both.
+type AuditSpec struct {
+	Preset  string                 `yaml:"preset,omitempty"`
+	Policy  map[string]interface{} `yaml:"policy,omitempty"`
+	Log     *AuditLogSpec          `yaml:"log"`
+	Webhook *AuditWebhookSpec      `yaml:"webhook,omitempty"`
+}
+
+// AuditLogSpec configures the audit log file and its rotation
+type AuditLogSpec struct {
+	Path string `yaml:"path"`
+	// MaxAge is the number of days to keep the rotated files
+	MaxAge int `yaml:"maxAge"`
+	// MaxBackups is the number of rotated files to keep
+	MaxBackups int `yaml:"maxBackups"`
+	// MaxSize is the size in megabytes the file is rotated at
+	MaxSize int    `yaml:"maxSize"`
+	Format  string `yaml:"format"`
+}
+
+// AuditWebhookSpec configures the webhook the audit events are sent to
+type AuditWebhookSpec struct {
+	Server         string `yaml:"server"`
+	CAFile         string `yaml:"caFile,omitempty"`
+	ClientCertFile string `yaml:"clientCertFile,omitempty"`
+	ClientKeyFile  string `yaml:"clientKeyFile,omitempty"`
+	Mode           string `yaml:"mode,omitempty"`
+}
+
+// DefaultAuditLogSpec builds the default audit log config
+func DefaultAuditLogSpec() *AuditLogSpec {
+	return &AuditLogSpec{
+		Path:       "/var/log/k0s/audit/audit.log",
+		MaxAge:     30,
+		MaxBackups: 10,
+		MaxSize:    100,
+		Format:     "json",
+	}
+}
+
+// UnmarshalYAML sets in some sane defaults when unmarshaling the data from yaml. The log file is
+// enabled by default, setting the log to null leaves only the webhook.
+func (a *AuditSpec) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	a.Log = DefaultAuditLogSpec()
+
+	type yauditspec AuditSpec
+	return unmarshal((*yauditspec)(a))
+}
+
+// UnmarshalYAML sets in some sane defaults when unmarshaling the data from yaml
+func (l *AuditLogSpec) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	*l = *DefaultAuditLogSpec()
+
+	type yauditlogspec AuditLogSpec
+	return unmarshal((*yauditlogspec)(l))
+}
+
+// PolicyPreset returns the preset to use, the metadata preset unless an inline policy is given
+func (a *AuditSpec) PolicyPreset() string {
+	if a.Preset == "" && a.Policy == nil {
+		return AuditPresetMetadata
+	}
+	return a.Preset
+}
+
+// Validate validates the audit config
+func (a *AuditSpec) Validate() []error {
+	var errors []error
+
+	switch {
+	case a.Preset != "" && a.Policy != nil:
+		errors = append(errors, fieldError("spec.api.audit.policy", "can't be used with spec.api.audit.preset"))
+	case a.Preset != "":
+		errors = appendErr(errors, validateOneOf("spec.api.audit.preset", a.Preset, AuditPresets))
+	case a.Policy != nil:
+		if a.Policy["apiVersion"] != "audit.k8s.io/v1" || a.Policy["kind"] != "Policy" {
+			errors = append(errors, fieldError("spec.api.audit.policy", "must be an audit.k8s.io/v1 Policy"))
+		}
+	}
+
+	if a.Log == nil && a.Webhook == nil {
+		errors = append(errors, fieldError("spec.api.audit", "log or webhook must be set"))
+	}
+
+	if l := a.Log; l != nil {
+		if !filepath.IsAbs(l.Path) {
+			errors = append(errors, fieldError("spec.api.audit.log.path", "%q is not an absolute path", l.Path))
+		}
+		for _, v := range []struct {
+			field string
+			value int
+		}{
+			{"maxAge", l.MaxAge},
+			{"maxBackups", l.MaxBackups},
+			{"maxSize", l.MaxSize},
+		} {
+			if v.value < 0 {
+				errors = append(errors, fieldError("spec.api.audit.log."+v.field, "must not be negative, got %d", v.value))
+			}
+		}
+		errors = appendErr(errors, validateOneOf("spec.api.audit.log.format", l.Format, AuditLogFormats))
+	}
+
+	if w := a.Webhook; w != nil {
+		if u, err := url.Parse(w.Server); err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			errors = append(errors, fieldError("spec.api.audit.webhook.server", "%q is not a http or https URL", w.Server))
+		}
+		if (w.ClientCertFile == "") != (w.ClientKeyFile == "") {
+			errors = append(errors, fieldError("spec.api.audit.webhook", "clientCertFile and clientKeyFile must be set together"))
+		}
+		if w.Mode != "" {
+			errors = appendErr(errors, validateOneOf("spec.api.audit.webhook.mode", w.Mode, AuditWebhookModes))
+		}
+	}
+
+	return errors
+}
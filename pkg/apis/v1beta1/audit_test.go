@@ -0,0 +1,42 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package v1beta1
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+func TestAuditSpecDefaults(t *testing.T) {
+	var audit AuditSpec
+	require.NoError(t, yaml.Unmarshal([]byte("log:\n  maxAge: 7\n"), &audit))
+	assert.Equal(t, AuditPresetMetadata, audit.PolicyPreset())
+	assert.Equal(t, &AuditLogSpec{
+		Path:       "/var/log/k0s/audit/audit.log",
+		MaxAge:     7,
+		MaxBackups: 10,
+		MaxSize:    100,
+		Format:     "json",
+	}, audit.Log)
+
+	audit = AuditSpec{}
+	require.NoError(t, yaml.Unmarshal([]byte("log: null\nwebhook:\n  server: https://audit.example.com\n"), &audit))
+	assert.Nil(t, audit.Log)
+	assert.Empty(t, audit.Validate())
+}
@@ -0,0 +1,99 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package v1beta1
+
+import (
+	"net/url"
+	"path/filepath"
+	"time"
+)
+
+var _ Validateable = (*AuthenticationSpec)(nil)
+
+// AuthenticationWebhookVersions are the TokenReview API versions the authentication webhook can be called with
+var AuthenticationWebhookVersions = []string{"v1", "v1beta1"}
+
+// AuthenticationSpec configures the external authentication of kube-apiserver, the client certificates
+// and the service account tokens are always accepted
+type AuthenticationSpec struct {
+	OIDC    *OIDCSpec                  `yaml:"oidc,omitempty"`
+	Webhook *AuthenticationWebhookSpec `yaml:"webhook,omitempty"`
+}
+
+// OIDCSpec configures the OpenID Connect ID token authentication
+type OIDCSpec struct {
+	IssuerURL string `yaml:"issuerURL"`
+	ClientID  string `yaml:"clientID"`
+	// UsernameClaim is the claim of the ID token used as the user name, kube-apiserver defaults to sub
+	UsernameClaim  string   `yaml:"usernameClaim,omitempty"`
+	UsernamePrefix string   `yaml:"usernamePrefix,omitempty"`
+	GroupsClaim    string   `yaml:"groupsClaim,omitempty"`
+	GroupsPrefix   string   `yaml:"groupsPrefix,omitempty"`
+	SigningAlgs    []string `yaml:"signingAlgs,omitempty"`
+	// CA is the PEM encoded CA bundle of the issuer, CAFile the path of it on the controllers
+	CA     string `yaml:"ca,omitempty"`
+	CAFile string `yaml:"caFile,omitempty"`
+}
+
+// AuthenticationWebhookSpec configures the webhook the bearer tokens are verified with
+type AuthenticationWebhookSpec struct {
+	Server         string `yaml:"server"`
+	CAFile         string `yaml:"caFile,omitempty"`
+	ClientCertFile string `yaml:"clientCertFile,omitempty"`
+	ClientKeyFile  string `yaml:"clientKeyFile,omitempty"`
+	// CacheTTL is how long the webhook responses are cached, e.g. 2m
+	CacheTTL string `yaml:"cacheTTL,omitempty"`
+	Version  string `yaml:"version,omitempty"`
+}
+
+// Validate validates the authentication config
+func (a *AuthenticationSpec) Validate() []error {
+	var errors []error
+
+	if o := a.OIDC; o != nil {
+		if u, err := url.Parse(o.IssuerURL); err != nil || u.Scheme != "https" || u.Host == "" {
+			errors = append(errors, fieldError("spec.api.authentication.oidc.issuerURL", "%q is not a https URL", o.IssuerURL))
+		}
+		if o.ClientID == "" {
+			errors = append(errors, fieldError("spec.api.authentication.oidc.clientID", "must be set"))
+		}
+		if o.CA != "" && o.CAFile != "" {
+			errors = append(errors, fieldError("spec.api.authentication.oidc.caFile", "can't be used with spec.api.authentication.oidc.ca"))
+		}
+		if o.CAFile != "" && !filepath.IsAbs(o.CAFile) {
+			errors = append(errors, fieldError("spec.api.authentication.oidc.caFile", "%q is not an absolute path", o.CAFile))
+		}
+	}
+
+	if w := a.Webhook; w != nil {
+		if u, err := url.Parse(w.Server); err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			errors = append(errors, fieldError("spec.api.authentication.webhook.server", "%q is not a http or https URL", w.Server))
+		}
+		if (w.ClientCertFile == "") != (w.ClientKeyFile == "") {
+			errors = append(errors, fieldError("spec.api.authentication.webhook", "clientCertFile and clientKeyFile must be set together"))
+		}
+		if w.CacheTTL != "" {
+			if d, err := time.ParseDuration(w.CacheTTL); err != nil || d < 0 {
+				errors = append(errors, fieldError("spec.api.authentication.webhook.cacheTTL", "%q is not a valid duration", w.CacheTTL))
+			}
+		}
+		if w.Version != "" {
+			errors = appendErr(errors, validateOneOf("spec.api.authentication.webhook.version", w.Version, AuthenticationWebhookVersions))
+		}
+	}
+
+	return errors
+}
@@ -15,6 +15,8 @@ limitations under the License.
 */
 package v1beta1
 
+var _ Validateable = (*Calico)(nil)
+
 // Calico defines the calico related config options
 type Calico struct {
 	Mode                    string `yaml:"mode"`
@@ -66,3 +68,18 @@ func (c *Calico) UnmarshalYAML(unmarshal func(interface{}) error) error {
 
 	return nil
 }
+
+// Validate validates the calico settings
+func (c *Calico) Validate() []error {
+	var errors []error
+	errors = appendErr(errors, validateOneOf("spec.network.calico.mode", c.Mode, CalicoModes))
+	errors = appendErr(errors, validatePort("spec.network.calico.vxlanPort", c.VxlanPort))
+	if c.VxlanVNI < 1 || c.VxlanVNI > maxVxlanVNI {
+		errors = append(errors, fieldError("spec.network.calico.vxlanVNI", "must be 1-%d, got %d", maxVxlanVNI, c.VxlanVNI))
+	}
+	if c.MTU < 0 {
+		errors = append(errors, fieldError("spec.network.calico.mtu", "must not be negative"))
+	}
+	errors = appendErr(errors, validateOneOf("spec.network.calico.overlay", c.Overlay, CalicoOverlayModes))
+	return errors
+}
@@ -0,0 +1,69 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package v1beta1
+
+var _ Validateable = (*Cilium)(nil)
+
+// The routing modes of cilium
+const (
+	CiliumRoutingTunnel = "tunnel"
+	CiliumRoutingNative = "native"
+)
+
+// Cilium defines the cilium related config options
+type Cilium struct {
+	// RoutingMode is either tunnel, the pod traffic is encapsulated between the nodes, or native, the
+	// pod traffic is routed by the network of the nodes
+	RoutingMode string `yaml:"routingMode"`
+	// TunnelProtocol is the encapsulation used in the tunnel routing mode
+	TunnelProtocol string `yaml:"tunnelProtocol"`
+	MTU            int    `yaml:"mtu"`
+	// KubeProxyReplacement has cilium implement the services in place of kube-proxy, kube-proxy
+	// isn't deployed then
+	KubeProxyReplacement bool `yaml:"kubeProxyReplacement"`
+	// Hubble enables the hubble flow observability and deploys hubble-relay
+	Hubble bool `yaml:"hubble"`
+}
+
+// DefaultCilium returns the default config for cilium
+func DefaultCilium() *Cilium {
+	return &Cilium{
+		RoutingMode:    CiliumRoutingTunnel,
+		TunnelProtocol: "vxlan",
+	}
+}
+
+// UnmarshalYAML sets in some sane defaults when unmarshaling the data from yaml
+func (c *Cilium) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	*c = *DefaultCilium()
+
+	type ycilium Cilium
+	yc := (*ycilium)(c)
+	return unmarshal(yc)
+}
+
+// Validate validates the cilium settings
+func (c *Cilium) Validate() []error {
+	var errors []error
+	errors = appendErr(errors, validateOneOf("spec.network.cilium.routingMode", c.RoutingMode, CiliumRoutingModes))
+	if c.RoutingMode == CiliumRoutingTunnel {
+		errors = appendErr(errors, validateOneOf("spec.network.cilium.tunnelProtocol", c.TunnelProtocol, CiliumTunnelProtocols))
+	}
+	if c.MTU < 0 {
+		errors = append(errors, fieldError("spec.network.cilium.mtu", "must not be negative"))
+	}
+	return errors
+}
@@ -17,13 +17,18 @@ package v1beta1
 
 import (
 	"fmt"
-	"github.com/k0sproject/k0s/internal/util"
 	"io/ioutil"
 	"os"
+	"reflect"
+
+	"github.com/k0sproject/k0s/internal/util"
 
 	"github.com/k0sproject/k0s/pkg/constant"
 )
 
+// ClusterConfigAPIVersion is the apiVersion of the k0s config file
+const ClusterConfigAPIVersion = "k0s.k0sproject.io/v1beta1"
+
 // ClusterConfig cluster manifest
 type ClusterConfig struct {
 	APIVersion string       `yaml:"apiVersion" validate:"eq=k0s.k0sproject.io/v1beta1"`
@@ -52,13 +57,14 @@ type ClusterSpec struct {
 	Images            *ClusterImages         `yaml:"images"`
 	Extensions        *ClusterExtensions     `yaml:"extensions,omitempty"`
 	Konnectivity      *KonnectivitySpec      `yaml:"konnectivity,omitempty"`
+	FeatureGates      FeatureGates           `yaml:"featureGates,omitempty"`
 }
 
 var _ Validateable = (*ControllerManagerSpec)(nil)
 
 // ControllerManagerSpec ...
 type ControllerManagerSpec struct {
-	ExtraArgs map[string]string `yaml:"extraArgs,omitempty"`
+	ExtraArgs ExtraArgs `yaml:"extraArgs,omitempty"`
 }
 
 // IsZero needed to omit empty object from yaml output
@@ -66,15 +72,19 @@ func (c *ControllerManagerSpec) IsZero() bool {
 	return len(c.ExtraArgs) == 0
 }
 
+// Validate validates the extra args
 func (c *ControllerManagerSpec) Validate() []error {
-	return nil
+	if c == nil {
+		return nil
+	}
+	return c.ExtraArgs.Validate("spec.controllerManager.extraArgs")
 }
 
 var _ Validateable = (*SchedulerSpec)(nil)
 
 // SchedulerSpec ...
 type SchedulerSpec struct {
-	ExtraArgs map[string]string `yaml:"extraArgs,omitempty"`
+	ExtraArgs ExtraArgs `yaml:"extraArgs,omitempty"`
 }
 
 // IsZero needed to omit empty object from yaml output
@@ -82,8 +92,12 @@ func (s *SchedulerSpec) IsZero() bool {
 	return len(s.ExtraArgs) == 0
 }
 
+// Validate validates the extra args
 func (s *SchedulerSpec) Validate() []error {
-	return nil
+	if s == nil {
+		return nil
+	}
+	return s.ExtraArgs.Validate("spec.scheduler.extraArgs")
 }
 
 var _ Validateable = (*InstallSpec)(nil)
@@ -93,9 +107,28 @@ type InstallSpec struct {
 	SystemUsers *SystemUser `yaml:"users,omitempty"`
 }
 
-// Validate stub for Validateable interface
+// Validate validates that all the system users are set
 func (i *InstallSpec) Validate() []error {
-	return nil
+	if i == nil || i.SystemUsers == nil {
+		return nil
+	}
+	users := []struct {
+		field string
+		user  string
+	}{
+		{"etcdUser", i.SystemUsers.Etcd},
+		{"kineUser", i.SystemUsers.Kine},
+		{"konnectivityUser", i.SystemUsers.Konnectivity},
+		{"kubeAPIserverUser", i.SystemUsers.KubeAPIServer},
+		{"kubeSchedulerUser", i.SystemUsers.KubeScheduler},
+	}
+	var errors []error
+	for _, u := range users {
+		if u.user == "" {
+			errors = append(errors, fieldError("spec.installConfig.users."+u.field, "must be set"))
+		}
+	}
+	return errors
 }
 
 // Validateable interface to ensure that all config components implement Validate function
@@ -103,10 +136,20 @@ type Validateable interface {
 	Validate() []error
 }
 
-// Validate validates cluster config
+// Validate validates cluster config. The errors are FieldErrors, telling the path of the invalid field.
 func (c *ClusterConfig) Validate() []error {
 	var errors []error
 
+	if c.APIVersion != "" && c.APIVersion != ClusterConfigAPIVersion {
+		errors = append(errors, fieldError("apiVersion", "unsupported value %q, must be %s", c.APIVersion, ClusterConfigAPIVersion))
+	}
+	if c.Kind != "" && c.Kind != "Cluster" {
+		errors = append(errors, fieldError("kind", "unsupported value %q, must be Cluster", c.Kind))
+	}
+	if c.Spec == nil {
+		return append(errors, fieldError("spec", "must be set"))
+	}
+
 	errors = append(errors, validateSpecs(c.Spec.API)...)
 	errors = append(errors, validateSpecs(c.Spec.ControllerManager)...)
 	errors = append(errors, validateSpecs(c.Spec.Scheduler)...)
@@ -116,14 +159,19 @@ func (c *ClusterConfig) Validate() []error {
 	errors = append(errors, validateSpecs(c.Spec.WorkerProfiles)...)
 	errors = append(errors, validateSpecs(c.Spec.Telemetry)...)
 	errors = append(errors, validateSpecs(c.Spec.Install)...)
+	errors = append(errors, validateSpecs(c.Spec.Images)...)
 	errors = append(errors, validateSpecs(c.Spec.Extensions)...)
 	errors = append(errors, validateSpecs(c.Spec.Konnectivity)...)
+	errors = append(errors, c.Spec.validateFeatureGates()...)
 
 	return errors
 }
 
-// validateSpecs invokes validator Validate function
+// validateSpecs invokes validator Validate function, skipping the specs not set
 func validateSpecs(v Validateable) []error {
+	if v == nil || reflect.ValueOf(v).IsNil() {
+		return nil
+	}
 	return v.Validate()
 }
 
@@ -134,7 +182,7 @@ func ConfigFromFile(filename string, k0sVars constant.CfgVars) (*ClusterConfig,
 		return nil, fmt.Errorf("failed to read config file at %s: %w", filename, err)
 	}
 
-	return configFromString(string(buf), k0sVars)
+	return ConfigFromString(string(buf), k0sVars)
 }
 
 // ConfigFromStdin tries to read k0s.yaml config from stdin
@@ -143,11 +191,12 @@ func ConfigFromStdin(k0sVars constant.CfgVars) (*ClusterConfig, error) {
 	if err != nil {
 		return nil, fmt.Errorf("can't read configration from stdin: %v", err)
 	}
-	return configFromString(string(input), k0sVars)
+	return ConfigFromString(string(input), k0sVars)
 
 }
 
-func configFromString(yml string, k0sVars constant.CfgVars) (*ClusterConfig, error) {
+// ConfigFromString parses the k0s.yaml config
+func ConfigFromString(yml string, k0sVars constant.CfgVars) (*ClusterConfig, error) {
 	config := &ClusterConfig{k0sVars: k0sVars}
 	err := util.YamlUnmarshalStrictIgnoringFields([]byte(yml), &config, []string{"interval"})
 	if err != nil {
@@ -164,7 +213,7 @@ func configFromString(yml string, k0sVars constant.CfgVars) (*ClusterConfig, err
 // DefaultClusterConfig ...
 func DefaultClusterConfig(k0sVars constant.CfgVars) *ClusterConfig {
 	return &ClusterConfig{
-		APIVersion: "k0s.k0sproject.io/v1beta1",
+		APIVersion: ClusterConfigAPIVersion,
 		Kind:       "Cluster",
 		Metadata: &ClusterMeta{
 			Name: "k0s",
@@ -198,10 +247,10 @@ func DefaultClusterSpec(k0sVars constant.CfgVars) *ClusterSpec {
 		Network: DefaultNetwork(),
 		API:     DefaultAPISpec(),
 		ControllerManager: &ControllerManagerSpec{
-			ExtraArgs: make(map[string]string),
+			ExtraArgs: make(ExtraArgs),
 		},
 		Scheduler: &SchedulerSpec{
-			ExtraArgs: make(map[string]string),
+			ExtraArgs: make(ExtraArgs),
 		},
 		PodSecurityPolicy: DefaultPodSecurityPolicy(),
 		Install:           DefaultInstallSpec(),
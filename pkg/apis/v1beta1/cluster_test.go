@@ -16,6 +16,7 @@ limitations under the License.
 package v1beta1
 
 import (
+	goerrors "errors"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -29,7 +30,7 @@ var (
 )
 
 func TestClusterDefaults(t *testing.T) {
-	c, err := configFromString("apiVersion: k0s.k0sproject.io/v1beta1", k0sVars)
+	c, err := ConfigFromString("apiVersion: k0s.k0sproject.io/v1beta1", k0sVars)
 	assert.NoError(t, err)
 	assert.NotNil(t, c.Metadata)
 	assert.Equal(t, "k0s", c.Metadata.Name)
@@ -44,7 +45,7 @@ metadata:
   name: foobar
 `
 
-	c, err := configFromString(yamlData, k0sVars)
+	c, err := ConfigFromString(yamlData, k0sVars)
 	assert.NoError(t, err)
 	assert.Equal(t, "etcd", c.Spec.Storage.Type)
 	addr, err := util.FirstPublicAddress()
@@ -63,7 +64,7 @@ spec:
     type: etcd
 `
 
-	c, err := configFromString(yamlData, k0sVars)
+	c, err := ConfigFromString(yamlData, k0sVars)
 	assert.NoError(t, err)
 	assert.Equal(t, "etcd", c.Spec.Storage.Type)
 	addr, err := util.FirstPublicAddress()
@@ -84,7 +85,7 @@ spec:
     type: etcd
 `
 
-	c, err := configFromString(yamlData, k0sVars)
+	c, err := ConfigFromString(yamlData, k0sVars)
 	assert.NoError(t, err)
 	errors := c.Validate()
 	assert.Equal(t, 0, len(errors))
@@ -103,7 +104,7 @@ spec:
     type: etcd
 `
 
-	c, err := configFromString(yamlData, k0sVars)
+	c, err := ConfigFromString(yamlData, k0sVars)
 	assert.NoError(t, err)
 	errors := c.Validate()
 	assert.Equal(t, 0, len(errors))
@@ -122,11 +123,15 @@ spec:
     type: etcd
 `
 
-	c, err := configFromString(yamlData, k0sVars)
+	c, err := ConfigFromString(yamlData, k0sVars)
 	assert.NoError(t, err)
 	errors := c.Validate()
 	assert.Equal(t, 1, len(errors))
-	assert.Equal(t, "unsupported network provider: invalidProvider", errors[0].Error())
+	assert.Equal(t, `spec.network.provider: unsupported value "invalidProvider", must be one of calico, cilium, custom, kuberouter`, errors[0].Error())
+	var fieldErr *FieldError
+	if assert.True(t, goerrors.As(errors[0], &fieldErr)) {
+		assert.Equal(t, "spec.network.provider", fieldErr.Field)
+	}
 }
 
 func TestApiExternalAddress(t *testing.T) {
@@ -141,7 +146,7 @@ spec:
     address: 1.2.3.4
 `
 
-	c, err := configFromString(yamlData, k0sVars)
+	c, err := ConfigFromString(yamlData, k0sVars)
 	assert.NoError(t, err)
 	assert.Equal(t, "https://foo.bar.com:6443", c.Spec.API.APIAddressURL())
 	assert.Equal(t, "https://foo.bar.com:9443", c.Spec.API.K0sControlPlaneAPIAddress())
@@ -158,7 +163,7 @@ spec:
     address: 1.2.3.4
 `
 
-	c, err := configFromString(yamlData, k0sVars)
+	c, err := ConfigFromString(yamlData, k0sVars)
 	assert.NoError(t, err)
 	assert.Equal(t, "https://1.2.3.4:6443", c.Spec.API.APIAddressURL())
 	assert.Equal(t, "https://1.2.3.4:9443", c.Spec.API.K0sControlPlaneAPIAddress())
@@ -23,12 +23,16 @@ type CaResponse struct {
 	Cert  []byte `json:"cert"`
 	SAKey []byte `json:"saKey"`
 	SAPub []byte `json:"saPub"`
+	// EncryptionKeys are the keys the resources are encrypted at rest with, if the encryption is enabled
+	EncryptionKeys []byte `json:"encryptionKeys,omitempty"`
 }
 
 // EtcdRequest defines the etcd control api request structure
 type EtcdRequest struct {
 	Node        string `json:"node"`
 	PeerAddress string `json:"peerAddress"`
+	// Learner adds the node as a learner, which doesn't vote until promoted
+	Learner bool `json:"learner,omitempty"`
 }
 
 // Validate validates the request
@@ -49,3 +53,64 @@ type EtcdResponse struct {
 	CA             CaResponse `json:"ca"`
 	InitialCluster []string   `json:"initialCluster"`
 }
+
+// WorkerJoinRequest defines the worker join control API request structure
+type WorkerJoinRequest struct {
+	Hostname string            `json:"hostname"`
+	Arch     string            `json:"arch"`
+	OS       string            `json:"os"`
+	Profile  string            `json:"profile,omitempty"`
+	Labels   map[string]string `json:"labels,omitempty"`
+}
+
+// Validate validates the request
+func (w *WorkerJoinRequest) Validate() error {
+	if w.Hostname == "" {
+		return fmt.Errorf("hostname cannot be empty")
+	}
+
+	if w.OS == "" {
+		return fmt.Errorf("os cannot be empty")
+	}
+
+	if w.Arch == "" {
+		return fmt.Errorf("arch cannot be empty")
+	}
+
+	return nil
+}
+
+// WorkerJoinResponse defines the worker join control API response structure
+type WorkerJoinResponse struct {
+	// Kubeconfig is the bootstrap kubeconfig scoped to the joining node
+	Kubeconfig    []byte `json:"kubeconfig"`
+	Profile       string `json:"profile"`
+	KubeletConfig string `json:"kubeletConfig"`
+	APIAddress    string `json:"apiAddress"`
+	ClusterDNS    string `json:"clusterDNS"`
+	ClusterDomain string `json:"clusterDomain"`
+	ServiceCIDR   string `json:"serviceCIDR"`
+	PodCIDR       string `json:"podCIDR"`
+	CACert        []byte `json:"caCert"`
+}
+
+// ControlAPIVersion is the version of the k0s control API served by this k0s version
+const ControlAPIVersion = "v1beta1"
+
+// VersionResponse defines the response type for the /version control API discovery endpoint
+type VersionResponse struct {
+	K0sVersion  string   `json:"k0sVersion"`
+	APIVersions []string `json:"apiVersions"`
+}
+
+// ErrorResponse defines the body of every error returned by the control API
+type ErrorResponse struct {
+	Code    int    `json:"code"`
+	Reason  string `json:"reason"`
+	Message string `json:"message"`
+}
+
+// Error implements the error interface
+func (e *ErrorResponse) Error() string {
+	return fmt.Sprintf("%s (%d): %s", e.Reason, e.Code, e.Message)
+}
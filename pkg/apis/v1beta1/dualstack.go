@@ -7,20 +7,6 @@ type DualStack struct {
 	IPv6ServiceCIDR string `yaml:"IPv6serviceCIDR,omitempty"`
 }
 
-// EnableDualStackFeatureGate adds ipv6 feature gate to the given args colllection
-func (ds DualStack) EnableDualStackFeatureGate(args map[string]string) {
-	if !ds.Enabled {
-		return
-	}
-	fg, found := args["feature-gates"]
-	if !found {
-		args["feature-gates"] = "IPv6DualStack=true"
-	} else {
-		fg = fg + ",IPv6DualStack=true"
-		args["feature-gates"] = fg
-	}
-}
-
 // DefaultDualStack builds default values
 func DefaultDualStack() DualStack {
 	return DualStack{}
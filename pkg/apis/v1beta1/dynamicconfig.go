@@ -0,0 +1,128 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package v1beta1
+
+import (
+	"fmt"
+)
+
+// The in-cluster object holding the dynamic cluster config
+const (
+	ClusterConfigGroup     = "k0s.k0sproject.io"
+	ClusterConfigVersion   = "v1beta1"
+	ClusterConfigResource  = "clusterconfigs"
+	ClusterConfigKind      = "ClusterConfig"
+	ClusterConfigNamespace = "kube-system"
+	ClusterConfigName      = "k0s"
+)
+
+var _ Validateable = (*DynamicClusterSpec)(nil)
+
+// DynamicClusterSpec is the cluster wide part of the ClusterSpec. When the dynamic config is enabled,
+// it's stored in the cluster and changes are reconciled at runtime. The node local settings, such
+// as the API address and the storage, are always read from the config file.
+type DynamicClusterSpec struct {
+	Network           *Network           `yaml:"network"`
+	PodSecurityPolicy *PodSecurityPolicy `yaml:"podSecurityPolicy"`
+	WorkerProfiles    WorkerProfiles     `yaml:"workerProfiles,omitempty"`
+	Telemetry         *ClusterTelemetry  `yaml:"telemetry"`
+	Images            *ClusterImages     `yaml:"images"`
+	Extensions        *ClusterExtensions `yaml:"extensions,omitempty"`
+}
+
+// UnmarshalYAML sets in some sane defaults when unmarshaling the data from yaml
+func (d *DynamicClusterSpec) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	d.Network = DefaultNetwork()
+	d.PodSecurityPolicy = DefaultPodSecurityPolicy()
+	d.Telemetry = DefaultClusterTelemetry()
+	d.Images = DefaultClusterImages()
+
+	type ydynamicspec DynamicClusterSpec
+	yd := (*ydynamicspec)(d)
+
+	return unmarshal(yd)
+}
+
+// Validate validates the dynamic spec on its own, see ClusterConfig.ValidateDynamicSpec for the
+// checks against the node local config
+func (d *DynamicClusterSpec) Validate() []error {
+	var errors []error
+
+	errors = append(errors, validateSpecs(d.Network)...)
+	errors = append(errors, validateSpecs(d.PodSecurityPolicy)...)
+	errors = append(errors, validateSpecs(d.WorkerProfiles)...)
+	errors = append(errors, validateSpecs(d.Telemetry)...)
+	errors = append(errors, validateSpecs(d.Images)...)
+	errors = append(errors, validateSpecs(d.Extensions)...)
+
+	return errors
+}
+
+// DynamicSpec returns the cluster wide part of the config
+func (c *ClusterConfig) DynamicSpec() *DynamicClusterSpec {
+	return &DynamicClusterSpec{
+		Network:           c.Spec.Network,
+		PodSecurityPolicy: c.Spec.PodSecurityPolicy,
+		WorkerProfiles:    c.Spec.WorkerProfiles,
+		Telemetry:         c.Spec.Telemetry,
+		Images:            c.Spec.Images,
+		Extensions:        c.Spec.Extensions,
+	}
+}
+
+// WithDynamicSpec returns a copy of the config with the cluster wide part replaced by the given dynamic spec
+func (c *ClusterConfig) WithDynamicSpec(d *DynamicClusterSpec) *ClusterConfig {
+	spec := *c.Spec
+	spec.Network = d.Network
+	spec.PodSecurityPolicy = d.PodSecurityPolicy
+	spec.WorkerProfiles = d.WorkerProfiles
+	spec.Telemetry = d.Telemetry
+	spec.Images = d.Images
+	spec.Extensions = d.Extensions
+
+	cfg := *c
+	cfg.Spec = &spec
+	return &cfg
+}
+
+// ValidateDynamicSpec checks that the dynamic spec can be applied on top of this config. Some network
+// settings are used to configure the control plane processes and can't be changed at runtime. The
+// network provider can only be switched between the providers k0s is able to migrate.
+func (c *ClusterConfig) ValidateDynamicSpec(d *DynamicClusterSpec) []error {
+	if d.Network == nil {
+		return []error{fmt.Errorf("network: must be set")}
+	}
+	errors := d.Validate()
+
+	current := c.Spec.Network
+	if d.Network.Provider != current.Provider && !(IsMigratableNetworkProvider(current.Provider) && IsMigratableNetworkProvider(d.Network.Provider)) {
+		errors = append(errors, fmt.Errorf("network.provider: can't be changed from %s to %s at runtime", current.Provider, d.Network.Provider))
+	}
+	if d.Network.PodCIDR != current.PodCIDR {
+		errors = append(errors, fmt.Errorf("network.podCIDR: can't be changed from %s to %s at runtime", current.PodCIDR, d.Network.PodCIDR))
+	}
+	if d.Network.ServiceCIDR != current.ServiceCIDR {
+		errors = append(errors, fmt.Errorf("network.serviceCIDR: can't be changed from %s to %s at runtime", current.ServiceCIDR, d.Network.ServiceCIDR))
+	}
+	if d.Network.ClusterDomain != current.ClusterDomain {
+		errors = append(errors, fmt.Errorf("network.clusterDomain: can't be changed from %s to %s at runtime", current.ClusterDomain, d.Network.ClusterDomain))
+	}
+	if d.Network.DualStack.Enabled != current.DualStack.Enabled {
+		errors = append(errors, fmt.Errorf("network.dualStack.enabled: can't be changed at runtime"))
+	}
+
+	return errors
+}
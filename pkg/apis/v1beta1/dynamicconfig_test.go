@@ -0,0 +1,82 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package v1beta1
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v2"
+)
+
+func TestDynamicSpecRoundTrip(t *testing.T) {
+	c := DefaultClusterConfig(k0sVars)
+	c.Spec.Images.CoreDNS.Version = "1.2.3"
+
+	data, err := yaml.Marshal(c.DynamicSpec())
+	assert.NoError(t, err)
+
+	d := &DynamicClusterSpec{}
+	assert.NoError(t, yaml.Unmarshal(data, d))
+	assert.Equal(t, "1.2.3", d.Images.CoreDNS.Version)
+	assert.Equal(t, c.Spec.Network, d.Network)
+	assert.Empty(t, c.ValidateDynamicSpec(d))
+}
+
+func TestDynamicSpecDefaults(t *testing.T) {
+	d := &DynamicClusterSpec{}
+	assert.NoError(t, yaml.Unmarshal([]byte("images:\n  coredns:\n    image: coredns\n    version: 1.2.3\n"), d))
+	assert.Equal(t, DefaultNetwork(), d.Network)
+	assert.Equal(t, DefaultPodSecurityPolicy(), d.PodSecurityPolicy)
+	assert.Equal(t, "1.2.3", d.Images.CoreDNS.Version)
+}
+
+func TestWithDynamicSpecKeepsNodeLocalSettings(t *testing.T) {
+	c := DefaultClusterConfig(k0sVars)
+	c.Spec.API.Address = "1.2.3.4"
+
+	d := c.DynamicSpec()
+	d.Images = DefaultClusterImages()
+	d.Images.CoreDNS.Version = "1.2.3"
+
+	merged := c.WithDynamicSpec(d)
+	assert.Equal(t, "1.2.3.4", merged.Spec.API.Address)
+	assert.Equal(t, "1.2.3", merged.Spec.Images.CoreDNS.Version)
+	// the original config is left untouched
+	assert.NotEqual(t, "1.2.3", c.Spec.Images.CoreDNS.Version)
+}
+
+func TestValidateDynamicSpecRejectsImmutableNetworkChanges(t *testing.T) {
+	c := DefaultClusterConfig(k0sVars)
+
+	d := &DynamicClusterSpec{}
+	assert.NoError(t, yaml.Unmarshal([]byte("network:\n  provider: custom\n  serviceCIDR: 10.100.0.0/16\n"), d))
+
+	errors := c.ValidateDynamicSpec(d)
+	assert.Len(t, errors, 2)
+}
+
+func TestValidateDynamicSpecAllowsNetworkProviderMigration(t *testing.T) {
+	c := DefaultClusterConfig(k0sVars)
+
+	d := &DynamicClusterSpec{}
+	assert.NoError(t, yaml.Unmarshal([]byte("network:\n  provider: calico\n  calico:\n    mode: vxlan\n"), d))
+	assert.Empty(t, c.ValidateDynamicSpec(d))
+
+	c.Spec.Network.Provider = "custom"
+	errors := c.ValidateDynamicSpec(d)
+	assert.Len(t, errors, 1)
+}
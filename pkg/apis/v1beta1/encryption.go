@@ -0,0 +1,68 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package v1beta1
+
+var _ Validateable = (*EncryptionSpec)(nil)
+
+// The providers the resources can be encrypted with
+const (
+	EncryptionProviderAESCBC    = "aescbc"
+	EncryptionProviderAESGCM    = "aesgcm"
+	EncryptionProviderSecretbox = "secretbox"
+)
+
+// EncryptionProviders are the supported encryption at rest providers
+var EncryptionProviders = []string{EncryptionProviderAESCBC, EncryptionProviderAESGCM, EncryptionProviderSecretbox}
+
+// EncryptionSpec enables the encryption at rest of the given resources. The keys are generated and
+// managed by k0s, the provider is used for the newly generated keys.
+type EncryptionSpec struct {
+	Provider  string   `yaml:"provider"`
+	Resources []string `yaml:"resources"`
+}
+
+// DefaultEncryptionSpec builds the default encryption config, encrypting the Secrets with aescbc
+func DefaultEncryptionSpec() *EncryptionSpec {
+	return &EncryptionSpec{
+		Provider:  EncryptionProviderAESCBC,
+		Resources: []string{"secrets"},
+	}
+}
+
+// UnmarshalYAML sets in some sane defaults when unmarshaling the data from yaml
+func (e *EncryptionSpec) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	*e = *DefaultEncryptionSpec()
+
+	type yencryptionspec EncryptionSpec
+	return unmarshal((*yencryptionspec)(e))
+}
+
+// Validate validates the encryption config
+func (e *EncryptionSpec) Validate() []error {
+	var errors []error
+
+	errors = appendErr(errors, validateOneOf("spec.api.encryption.provider", e.Provider, EncryptionProviders))
+	if len(e.Resources) == 0 {
+		errors = append(errors, fieldError("spec.api.encryption.resources", "must not be empty"))
+	}
+	for _, r := range e.Resources {
+		if r == "" {
+			errors = append(errors, fieldError("spec.api.encryption.resources", "must not contain empty names"))
+		}
+	}
+
+	return errors
+}
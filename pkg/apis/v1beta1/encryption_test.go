@@ -0,0 +1,31 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package v1beta1
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+func TestEncryptionSpecDefaults(t *testing.T) {
+	var encryption EncryptionSpec
+	require.NoError(t, yaml.Unmarshal([]byte("provider: secretbox\n"), &encryption))
+	assert.Equal(t, EncryptionSpec{Provider: EncryptionProviderSecretbox, Resources: []string{"secrets"}}, encryption)
+	assert.Empty(t, encryption.Validate())
+}
@@ -15,6 +15,14 @@ limitations under the License.
 */
 package v1beta1
 
+import (
+	"fmt"
+	"net/url"
+
+	"gopkg.in/yaml.v2"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
 var _ Validateable = (*ClusterExtensions)(nil)
 
 // ClusterExtensions specifies cluster extensions
@@ -47,9 +55,57 @@ type Repository struct {
 	KeyFile  string `yaml:"keyfile"`
 	Username string `yaml:"username"`
 	Password string `yaml:"password"`
+	// UsernameFrom and PasswordFrom reference the credentials instead of giving them inline
+	UsernameFrom *SecretRef `yaml:"usernameFrom,omitempty"`
+	PasswordFrom *SecretRef `yaml:"passwordFrom,omitempty"`
 }
 
-// Validate stub for Validateable interface
+// Validate validates the helm repositories and charts
 func (e *ClusterExtensions) Validate() []error {
-	return nil
+	if e == nil || e.Helm == nil {
+		return nil
+	}
+	var errors []error
+
+	repos := map[string]bool{}
+	for i, repo := range e.Helm.Repositories {
+		field := fmt.Sprintf("spec.extensions.helm.repositories[%d]", i)
+		if repo.Name == "" {
+			errors = append(errors, fieldError(field+".name", "must be set"))
+		} else if repos[repo.Name] {
+			errors = append(errors, fieldError(field+".name", "duplicate repository %s", repo.Name))
+		}
+		repos[repo.Name] = true
+		if u, err := url.Parse(repo.URL); err != nil || u.Scheme == "" || u.Host == "" {
+			errors = append(errors, fieldError(field+".url", "%q is not a valid URL", repo.URL))
+		}
+		errors = append(errors, validateInlineOrRef(field+".username", repo.Username, repo.UsernameFrom, true)...)
+		errors = append(errors, validateInlineOrRef(field+".password", repo.Password, repo.PasswordFrom, true)...)
+	}
+
+	charts := map[string]bool{}
+	for i, chart := range e.Helm.Charts {
+		field := fmt.Sprintf("spec.extensions.helm.charts[%d]", i)
+		// the name is used as the name of the Chart object
+		for _, msg := range validation.IsDNS1123Subdomain(chart.Name) {
+			errors = append(errors, fieldError(field+".name", "%q is not a valid name: %s", chart.Name, msg))
+		}
+		if charts[chart.Name] {
+			errors = append(errors, fieldError(field+".name", "duplicate chart %s", chart.Name))
+		}
+		charts[chart.Name] = true
+		if chart.ChartName == "" {
+			errors = append(errors, fieldError(field+".chartname", "must be set"))
+		}
+		if chart.TargetNS != "" {
+			for _, msg := range validation.IsDNS1123Label(chart.TargetNS) {
+				errors = append(errors, fieldError(field+".namespace", "%q is not a valid namespace: %s", chart.TargetNS, msg))
+			}
+		}
+		var values map[string]interface{}
+		if err := yaml.Unmarshal([]byte(chart.Values), &values); err != nil {
+			errors = append(errors, fieldError(field+".values", "not a valid YAML map: %s", err.Error()))
+		}
+	}
+	return errors
 }
@@ -0,0 +1,89 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package v1beta1
+
+import (
+	"sort"
+	"strings"
+)
+
+// ExtraArgs are additional flags passed to a component k0s runs, keyed by the flag name without the leading dashes
+type ExtraArgs map[string]ExtraArg
+
+// ExtraArg is the value of an extra flag. The plain string form adds a flag k0s doesn't set. Flags k0s sets
+// can only be replaced with the long form setting replace, and only if the component allows replacing the flag.
+//
+//	extraArgs:
+//	  event-ttl: 2h
+//	  profiling:
+//	    value: "true"
+//	    replace: true
+type ExtraArg struct {
+	Value   string `yaml:"value"`
+	Replace bool   `yaml:"replace,omitempty"`
+}
+
+// UnmarshalYAML accepts both the plain string and the long form
+func (e *ExtraArg) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var value string
+	if err := unmarshal(&value); err == nil {
+		*e = ExtraArg{Value: value}
+		return nil
+	}
+	type yextraarg ExtraArg
+	return unmarshal((*yextraarg)(e))
+}
+
+// MarshalYAML uses the plain string form unless the flag replaces a k0s default
+func (e ExtraArg) MarshalYAML() (interface{}, error) {
+	if !e.Replace {
+		return e.Value, nil
+	}
+	type yextraarg ExtraArg
+	return yextraarg(e), nil
+}
+
+// Names returns the flag names sorted
+func (a ExtraArgs) Names() []string {
+	names := make([]string, 0, len(a))
+	for name := range a {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Validate checks the flag names, they're passed to the process as --name=value
+func (a ExtraArgs) Validate(field string) []error {
+	var errors []error
+	for _, name := range a.Names() {
+		if name == "" || strings.HasPrefix(name, "-") {
+			errors = append(errors, fieldError(field, "%q is not a valid flag name, give it without the leading dashes", name))
+		}
+	}
+	return errors
+}
+
+// validateConfiguredWith reports the flags k0s sets from the given config fields, keyed by the flag name
+func (a ExtraArgs) validateConfiguredWith(field string, configuredWith map[string]string) []error {
+	var errors []error
+	for _, name := range a.Names() {
+		if with, ok := configuredWith[name]; ok {
+			errors = append(errors, fieldError(field, "%s is configured with %s", name, with))
+		}
+	}
+	return errors
+}
@@ -0,0 +1,51 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package v1beta1
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+func TestExtraArgsYAML(t *testing.T) {
+	data := []byte(`
+event-ttl: 2h
+profiling:
+  value: "true"
+  replace: true
+`)
+	var args ExtraArgs
+	require.NoError(t, yaml.Unmarshal(data, &args))
+	assert.Equal(t, ExtraArgs{
+		"event-ttl": {Value: "2h"},
+		"profiling": {Value: "true", Replace: true},
+	}, args)
+
+	out, err := yaml.Marshal(args)
+	require.NoError(t, err)
+	assert.Equal(t, "event-ttl: 2h\nprofiling:\n  value: \"true\"\n  replace: true\n", string(out))
+}
+
+func TestExtraArgsValidate(t *testing.T) {
+	args := ExtraArgs{"v": {Value: "2"}, "--profiling": {Value: "true"}}
+	errors := args.Validate("spec.api.extraArgs")
+	require.Len(t, errors, 1)
+	assert.Contains(t, errors[0].Error(), "spec.api.extraArgs")
+	assert.Contains(t, errors[0].Error(), `"--profiling"`)
+}
@@ -0,0 +1,141 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package v1beta1
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// FeatureGates are the Kubernetes feature gates, applied to all the components accepting them:
+// kube-apiserver, kube-controller-manager, kube-scheduler, kubelet and kube-proxy
+type FeatureGates map[string]bool
+
+// featureGatesFlag is the flag the feature gates were set with in the extra args before spec.featureGates
+const featureGatesFlag = "feature-gates"
+
+var featureGateName = regexp.MustCompile(`^[A-Z][A-Za-z0-9]*$`)
+
+// Names returns the feature gate names sorted
+func (f FeatureGates) Names() []string {
+	names := make([]string, 0, len(f))
+	for name := range f {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// String renders the feature gates as the value of the --feature-gates flag
+func (f FeatureGates) String() string {
+	gates := make([]string, 0, len(f))
+	for _, name := range f.Names() {
+		gates = append(gates, fmt.Sprintf("%s=%t", name, f[name]))
+	}
+	return strings.Join(gates, ",")
+}
+
+// Validate checks the feature gate names
+func (f FeatureGates) Validate() []error {
+	var errors []error
+	for _, name := range f.Names() {
+		if !featureGateName.MatchString(name) {
+			errors = append(errors, fieldError("spec.featureGates", "%q is not a valid feature gate name", name))
+		}
+	}
+	return errors
+}
+
+// EffectiveFeatureGates returns the configured feature gates and the ones the enabled k0s features need
+func (s *ClusterSpec) EffectiveFeatureGates() FeatureGates {
+	gates := FeatureGates{}
+	if s.Network != nil && s.Network.DualStack.Enabled {
+		gates["IPv6DualStack"] = true
+	}
+	// the feature gates of the extra args are deprecated, they apply to all the components like spec.featureGates
+	for _, legacy := range s.legacyFeatureGates() {
+		parsed, _ := parseFeatureGates(legacy.value)
+		for name, enabled := range parsed {
+			gates[name] = enabled
+		}
+	}
+	for name, enabled := range s.FeatureGates {
+		gates[name] = enabled
+	}
+	return gates
+}
+
+type legacyFeatureGates struct {
+	field string
+	value string
+}
+
+// legacyFeatureGates returns the feature-gates flags of the extra args
+func (s *ClusterSpec) legacyFeatureGates() []legacyFeatureGates {
+	var legacy []legacyFeatureGates
+	add := func(field string, args ExtraArgs) {
+		if arg, ok := args[featureGatesFlag]; ok {
+			legacy = append(legacy, legacyFeatureGates{field: field, value: arg.Value})
+		}
+	}
+	if s.API != nil {
+		add("spec.api.extraArgs", s.API.ExtraArgs)
+	}
+	if s.ControllerManager != nil {
+		add("spec.controllerManager.extraArgs", s.ControllerManager.ExtraArgs)
+	}
+	if s.Scheduler != nil {
+		add("spec.scheduler.extraArgs", s.Scheduler.ExtraArgs)
+	}
+	return legacy
+}
+
+// parseFeatureGates parses the value of the --feature-gates flag
+func parseFeatureGates(value string) (FeatureGates, error) {
+	gates := FeatureGates{}
+	for _, gate := range strings.Split(value, ",") {
+		gate = strings.TrimSpace(gate)
+		if gate == "" {
+			continue
+		}
+		parts := strings.SplitN(gate, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("%q is not a name=bool pair", gate)
+		}
+		enabled, err := strconv.ParseBool(strings.TrimSpace(parts[1]))
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a name=bool pair", gate)
+		}
+		gates[strings.TrimSpace(parts[0])] = enabled
+	}
+	return gates, nil
+}
+
+func (s *ClusterSpec) validateFeatureGates() []error {
+	errors := s.FeatureGates.Validate()
+	for _, legacy := range s.legacyFeatureGates() {
+		if _, err := parseFeatureGates(legacy.value); err != nil {
+			errors = append(errors, fieldError(legacy.field, "%s: %s", featureGatesFlag, err.Error()))
+		}
+	}
+	if enabled, set := s.FeatureGates["IPv6DualStack"]; set && !enabled && s.Network != nil && s.Network.DualStack.Enabled {
+		errors = append(errors, fieldError("spec.featureGates", "IPv6DualStack can't be disabled when spec.network.dualStack is enabled"))
+	}
+	return errors
+}
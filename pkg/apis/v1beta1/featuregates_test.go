@@ -0,0 +1,45 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package v1beta1
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEffectiveFeatureGates(t *testing.T) {
+	spec := DefaultClusterSpec(k0sVars)
+	assert.Empty(t, spec.EffectiveFeatureGates())
+
+	spec.FeatureGates = FeatureGates{"PodSecurity": true, "CSIMigration": false}
+	assert.Equal(t, "CSIMigration=false,PodSecurity=true", spec.EffectiveFeatureGates().String())
+
+	spec.Network.DualStack.Enabled = true
+	assert.Equal(t, "CSIMigration=false,IPv6DualStack=true,PodSecurity=true", spec.EffectiveFeatureGates().String())
+}
+
+func TestAdmissionEnabledPlugins(t *testing.T) {
+	var admission *AdmissionSpec
+	assert.Equal(t, []string{"NodeRestriction", "PodSecurityPolicy"}, admission.EnabledPlugins())
+
+	admission = &AdmissionSpec{
+		EnablePlugins:  []string{"PodSecurity", "NodeRestriction", "EventRateLimit"},
+		DisablePlugins: []string{"PodSecurityPolicy"},
+	}
+	assert.Equal(t, []string{"NodeRestriction", "PodSecurity", "EventRateLimit"}, admission.EnabledPlugins())
+	assert.Equal(t, []string{"NodeRestriction", "PodSecurityPolicy"}, DefaultAdmissionPlugins)
+}
@@ -42,6 +42,7 @@ type ClusterImages struct {
 
 	Calico     CalicoImageSpec     `yaml:"calico"`
 	KubeRouter KubeRouterImageSpec `yaml:"kuberouter"`
+	Cilium     CiliumImageSpec     `yaml:"cilium"`
 
 	Repository        string `yaml:"repository,omitempty"`
 	DefaultPullPolicy string `yaml:"default_pull_policy,omitempty"`
@@ -53,12 +54,13 @@ func (ci *ClusterImages) UnmarshalYAML(unmarshal func(interface{}) error) error
 	if err := unmarshal(imagesWrapper); err != nil {
 		return err
 	}
-	ci.overrideImageRepositories()
+	ci.OverrideImageRepositories()
 	ci.DefaultPullPolicy = "IfNotPresent"
 	return nil
 }
 
-func (ci *ClusterImages) overrideImageRepositories() {
+// OverrideImageRepositories replaces the registry of all the images with the configured repository, if any
+func (ci *ClusterImages) OverrideImageRepositories() {
 	if ci.Repository == "" {
 		return
 	}
@@ -74,6 +76,9 @@ func (ci *ClusterImages) overrideImageRepositories() {
 	override(&ci.Calico.KubeControllers)
 	override(&ci.KubeRouter.CNI)
 	override(&ci.KubeRouter.CNIInstaller)
+	override(&ci.Cilium.Agent)
+	override(&ci.Cilium.Operator)
+	override(&ci.Cilium.HubbleRelay)
 }
 
 // CalicoImageSpec config group for calico related image settings
@@ -89,6 +94,13 @@ type KubeRouterImageSpec struct {
 	CNIInstaller ImageSpec `yaml:"cniInstaller"`
 }
 
+// CiliumImageSpec config group for cilium related images
+type CiliumImageSpec struct {
+	Agent       ImageSpec `yaml:"agent"`
+	Operator    ImageSpec `yaml:"operator"`
+	HubbleRelay ImageSpec `yaml:"hubbleRelay"`
+}
+
 // DefaultClusterImages default image settings
 func DefaultClusterImages() *ClusterImages {
 	return &ClusterImages{
@@ -133,6 +145,20 @@ func DefaultClusterImages() *ClusterImages {
 				Version: constant.KubeRouterCNIInstallerImageVersion,
 			},
 		},
+		Cilium: CiliumImageSpec{
+			Agent: ImageSpec{
+				Image:   constant.CiliumImage,
+				Version: constant.CiliumImageVersion,
+			},
+			Operator: ImageSpec{
+				Image:   constant.CiliumOperatorImage,
+				Version: constant.CiliumImageVersion,
+			},
+			HubbleRelay: ImageSpec{
+				Image:   constant.CiliumHubbleRelayImage,
+				Version: constant.CiliumImageVersion,
+			},
+		},
 	}
 }
 
@@ -152,7 +178,37 @@ func overrideRepository(repository string, originalImage string) string {
 	return fmt.Sprintf("%s/%s", repository, originalImage)
 }
 
-// Validate stub for Validateable interface
+// Validate validates that all the images are set and the pull policy is supported
 func (ci *ClusterImages) Validate() []error {
-	return nil
+	images := []struct {
+		field string
+		spec  ImageSpec
+	}{
+		{"konnectivity", ci.Konnectivity},
+		{"metricsserver", ci.MetricsServer},
+		{"kubeproxy", ci.KubeProxy},
+		{"coredns", ci.CoreDNS},
+		{"calico.cni", ci.Calico.CNI},
+		{"calico.node", ci.Calico.Node},
+		{"calico.kubecontrollers", ci.Calico.KubeControllers},
+		{"kuberouter.cni", ci.KubeRouter.CNI},
+		{"kuberouter.cniInstaller", ci.KubeRouter.CNIInstaller},
+		{"cilium.agent", ci.Cilium.Agent},
+		{"cilium.operator", ci.Cilium.Operator},
+		{"cilium.hubbleRelay", ci.Cilium.HubbleRelay},
+	}
+
+	var errors []error
+	for _, i := range images {
+		if i.spec.Image == "" {
+			errors = append(errors, fieldError("spec.images."+i.field+".image", "must be set"))
+		}
+		if i.spec.Version == "" {
+			errors = append(errors, fieldError("spec.images."+i.field+".version", "must be set"))
+		}
+	}
+	if ci.DefaultPullPolicy != "" {
+		errors = appendErr(errors, validateOneOf("spec.images.default_pull_policy", ci.DefaultPullPolicy, ImagePullPolicies))
+	}
+	return errors
 }
@@ -20,8 +20,9 @@ var _ Validateable = (*KonnectivitySpec)(nil)
 
 // KonnectivitySpec ...
 type KonnectivitySpec struct {
-	AgentPort int64 `yaml:"agentPort,omitempty"`
-	AdminPort int64 `yaml:"adminPort,omitempty"`
+	AgentPort int64     `yaml:"agentPort,omitempty"`
+	AdminPort int64     `yaml:"adminPort,omitempty"`
+	ExtraArgs ExtraArgs `yaml:"extraArgs,omitempty"`
 }
 
 // DefaultKonnectivitySpec builds default KonnectivitySpec
@@ -32,7 +33,17 @@ func DefaultKonnectivitySpec() *KonnectivitySpec {
 	}
 }
 
-// Validate stub for Validateable interface
+// Validate validates the konnectivity ports
 func (k *KonnectivitySpec) Validate() []error {
-	return nil
+	if k == nil {
+		return nil
+	}
+	var errors []error
+	errors = appendErr(errors, validatePort("spec.konnectivity.agentPort", int(k.AgentPort)))
+	errors = appendErr(errors, validatePort("spec.konnectivity.adminPort", int(k.AdminPort)))
+	if k.AgentPort == k.AdminPort {
+		errors = append(errors, fieldError("spec.konnectivity.adminPort", "must differ from spec.konnectivity.agentPort"))
+	}
+	errors = append(errors, k.ExtraArgs.Validate("spec.konnectivity.extraArgs")...)
+	return errors
 }
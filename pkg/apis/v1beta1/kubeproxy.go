@@ -15,8 +15,6 @@ limitations under the License.
 */
 package v1beta1
 
-import "fmt"
-
 var _ Validateable = (*KubeProxy)(nil)
 
 const (
@@ -45,8 +43,6 @@ func (k *KubeProxy) Validate() []error {
 		return nil
 	}
 	var errors []error
-	if k.Mode != "iptables" && k.Mode != "ipvs" && k.Mode != "userspace" {
-		errors = append(errors, fmt.Errorf("unsupported mode %s for kubeProxy config", k.Mode))
-	}
+	errors = appendErr(errors, validateOneOf("spec.network.kubeProxy.mode", k.Mode, KubeProxyModes))
 	return errors
 }
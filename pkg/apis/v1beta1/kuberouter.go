@@ -15,18 +15,153 @@ limitations under the License.
 */
 package v1beta1
 
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+var _ Validateable = (*KubeRouter)(nil)
+
+// The overlay types of kube-router
+const (
+	KubeRouterOverlaySubnet = "subnet"
+	KubeRouterOverlayFull   = "full"
+)
+
+// DefaultKubeRouterMetricsPort is the default port of the kube-router metrics
+const DefaultKubeRouterMetricsPort = 8080
+
 // KubeRouter defines the kube-router related config options
 type KubeRouter struct {
-	MTU            int    `yaml:"mtu"`
-	PeerRouterIPs  string `yaml:"peerRouterIPs"`
+	MTU     int  `yaml:"mtu"`
+	AutoMTU bool `yaml:"autoMTU"`
+	// PeerRouterIPs is deprecated, use Peers instead
+	PeerRouterIPs string `yaml:"peerRouterIPs"`
+	// PeerRouterASNs is deprecated, use Peers instead
 	PeerRouterASNs string `yaml:"peerRouterASNs"`
-	AutoMTU        bool   `yaml:"autoMTU"`
+	// Peers are the external BGP peers of all the nodes
+	Peers []KubeRouterPeer `yaml:"peers,omitempty"`
+	// IPIP enables the ipip tunneling of the pod traffic between the nodes
+	IPIP bool `yaml:"ipip"`
+	// OverlayType is either subnet, only the traffic between nodes in different subnets is tunneled, or
+	// full, all the traffic between the nodes is tunneled
+	OverlayType string `yaml:"overlayType"`
+	// Hairpin enables the hairpin traffic, pods reaching themselves through a service
+	Hairpin bool `yaml:"hairpin"`
+	// ServiceProxy has kube-router implement the services in place of kube-proxy, kube-proxy isn't
+	// deployed then
+	ServiceProxy bool `yaml:"serviceProxy"`
+	// MetricsPort is the port of the kube-router metrics, 0 disables the metrics
+	MetricsPort int `yaml:"metricsPort"`
+}
+
+// KubeRouterPeer defines an external BGP peer
+type KubeRouterPeer struct {
+	IP  string `yaml:"ip"`
+	ASN uint32 `yaml:"asn"`
+	// Password authenticates the BGP session with the peer, deprecated in favor of PasswordFrom
+	Password string `yaml:"password,omitempty"`
+	// PasswordFrom references the password instead of giving it inline
+	PasswordFrom *SecretRef `yaml:"passwordFrom,omitempty"`
+	// MultihopTTL enables eBGP multihop with the given TTL. kube-router applies a single TTL to all
+	// the peers, so it must be the same for all the peers setting it.
+	MultihopTTL uint8 `yaml:"multihopTTL,omitempty"`
 }
 
 // DefaultKubeRouter returns the default config for kube-router
 func DefaultKubeRouter() *KubeRouter {
 	return &KubeRouter{
-		MTU:     0,
-		AutoMTU: true,
+		MTU:         0,
+		AutoMTU:     true,
+		IPIP:        true,
+		OverlayType: KubeRouterOverlaySubnet,
+		MetricsPort: DefaultKubeRouterMetricsPort,
+	}
+}
+
+// UnmarshalYAML sets in some sane defaults when unmarshaling the data from yaml
+func (k *KubeRouter) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	*k = *DefaultKubeRouter()
+
+	type ykuberouter KubeRouter
+	yk := (*ykuberouter)(k)
+	return unmarshal(yk)
+}
+
+// Validate validates the kube-router settings
+func (k *KubeRouter) Validate() []error {
+	var errors []error
+	if k.MTU < 0 {
+		errors = append(errors, fieldError("spec.network.kuberouter.mtu", "must not be negative"))
+	}
+	if k.IPIP {
+		errors = appendErr(errors, validateOneOf("spec.network.kuberouter.overlayType", k.OverlayType, KubeRouterOverlayTypes))
+	}
+	if k.MetricsPort != 0 {
+		errors = appendErr(errors, validatePort("spec.network.kuberouter.metricsPort", k.MetricsPort))
+	}
+	errors = append(errors, k.validateLegacyPeers()...)
+	if len(k.Peers) > 0 && (k.PeerRouterIPs != "" || k.PeerRouterASNs != "") {
+		errors = append(errors, fieldError("spec.network.kuberouter.peers", "can't be used together with peerRouterIPs and peerRouterASNs"))
+	}
+
+	var multihopTTL uint8
+	for i, peer := range k.Peers {
+		field := fmt.Sprintf("spec.network.kuberouter.peers[%d]", i)
+		if net.ParseIP(peer.IP) == nil {
+			errors = append(errors, fieldError(field+".ip", "%q is not an IP address", peer.IP))
+		}
+		if peer.ASN == 0 {
+			errors = append(errors, fieldError(field+".asn", "must be set"))
+		}
+		errors = append(errors, validateInlineOrRef(field+".password", peer.Password, peer.PasswordFrom, true)...)
+		if peer.MultihopTTL == 0 {
+			continue
+		}
+		if peer.MultihopTTL < 2 {
+			errors = append(errors, fieldError(field+".multihopTTL", "must be at least 2, got %d", peer.MultihopTTL))
+		} else if multihopTTL != 0 && peer.MultihopTTL != multihopTTL {
+			errors = append(errors, fieldError(field+".multihopTTL", "must be the same for all the peers, got %d and %d", multihopTTL, peer.MultihopTTL))
+		} else {
+			multihopTTL = peer.MultihopTTL
+		}
+	}
+	return errors
+}
+
+func (k *KubeRouter) validateLegacyPeers() []error {
+	var errors []error
+	var ips, asns []string
+	if k.PeerRouterIPs != "" {
+		ips = strings.Split(k.PeerRouterIPs, ",")
+	}
+	if k.PeerRouterASNs != "" {
+		asns = strings.Split(k.PeerRouterASNs, ",")
+	}
+	for _, ip := range ips {
+		if net.ParseIP(strings.TrimSpace(ip)) == nil {
+			errors = append(errors, fieldError("spec.network.kuberouter.peerRouterIPs", "%q is not an IP address", ip))
+		}
+	}
+	for _, asn := range asns {
+		if _, err := strconv.ParseUint(strings.TrimSpace(asn), 10, 32); err != nil {
+			errors = append(errors, fieldError("spec.network.kuberouter.peerRouterASNs", "%q is not a valid AS number", asn))
+		}
+	}
+	if len(ips) != len(asns) {
+		errors = append(errors, fieldError("spec.network.kuberouter.peerRouterASNs", "must have as many entries as peerRouterIPs"))
+	}
+	return errors
+}
+
+// MultihopTTL returns the eBGP multihop TTL of the peers, 0 if multihop isn't enabled
+func (k *KubeRouter) MultihopTTL() uint8 {
+	for _, peer := range k.Peers {
+		if peer.MultihopTTL != 0 {
+			return peer.MultihopTTL
+		}
 	}
+	return 0
 }
@@ -19,71 +19,112 @@ import (
 	"fmt"
 	"net"
 
+	"github.com/k0sproject/k0s/internal/util"
+	"k8s.io/apimachinery/pkg/util/validation"
 	utilnet "k8s.io/utils/net"
 )
 
+// DefaultClusterDomain is the DNS domain of the cluster unless configured otherwise
+const DefaultClusterDomain = "cluster.local"
+
 var _ Validateable = (*Network)(nil)
 
+// MigratableNetworkProviders are the network providers k0s manages and can switch between at runtime
+var MigratableNetworkProviders = []string{"calico", "cilium", "kuberouter"}
+
+// IsMigratableNetworkProvider tells if the network provider can be replaced with `k0s network offline-migrate`
+func IsMigratableNetworkProvider(provider string) bool {
+	return util.StringSliceContains(MigratableNetworkProviders, provider)
+}
+
 // Network defines the network related config options
 type Network struct {
-	PodCIDR     string      `yaml:"podCIDR"`
-	ServiceCIDR string      `yaml:"serviceCIDR"`
-	Provider    string      `yaml:"provider"`
-	Calico      *Calico     `yaml:"calico"`
-	KubeRouter  *KubeRouter `yaml:"kuberouter"`
-	DualStack   DualStack   `yaml:"dualStack,omitempty"`
-	KubeProxy   *KubeProxy  `yaml:"kubeProxy"`
+	PodCIDR     string `yaml:"podCIDR"`
+	ServiceCIDR string `yaml:"serviceCIDR"`
+	// ClusterDomain is the DNS domain of the cluster, used by kubelet and CoreDNS
+	ClusterDomain string      `yaml:"clusterDomain,omitempty"`
+	Provider      string      `yaml:"provider"`
+	Calico        *Calico     `yaml:"calico"`
+	KubeRouter    *KubeRouter `yaml:"kuberouter"`
+	Cilium        *Cilium     `yaml:"cilium,omitempty"`
+	DualStack     DualStack   `yaml:"dualStack,omitempty"`
+	KubeProxy     *KubeProxy  `yaml:"kubeProxy"`
 }
 
 // DefaultNetwork creates the Network config struct with sane default values
 func DefaultNetwork() *Network {
 	return &Network{
-		PodCIDR:     "10.244.0.0/16",
-		ServiceCIDR: "10.96.0.0/12",
-		Provider:    "kuberouter",
-		KubeRouter:  DefaultKubeRouter(),
-		DualStack:   DefaultDualStack(),
-		KubeProxy:   DefaultKubeProxy(),
+		PodCIDR:       "10.244.0.0/16",
+		ServiceCIDR:   "10.96.0.0/12",
+		ClusterDomain: DefaultClusterDomain,
+		Provider:      "kuberouter",
+		KubeRouter:    DefaultKubeRouter(),
+		DualStack:     DefaultDualStack(),
+		KubeProxy:     DefaultKubeProxy(),
 	}
 }
 
 // Validate validates all the settings make sense and should work
 func (n *Network) Validate() []error {
 	var errors []error
-	if n.Provider != "calico" && n.Provider != "custom" && n.Provider != "kuberouter" {
-		errors = append(errors, fmt.Errorf("unsupported network provider: %s", n.Provider))
-	}
+	errors = appendErr(errors, validateOneOf("spec.network.provider", n.Provider, NetworkProviders))
 
 	_, _, err := net.ParseCIDR(n.PodCIDR)
 	if err != nil {
-		errors = append(errors, fmt.Errorf("invalid pod CIDR %s", n.PodCIDR))
+		errors = append(errors, fieldError("spec.network.podCIDR", "invalid pod CIDR %s", n.PodCIDR))
 	}
 
 	_, _, err = net.ParseCIDR(n.ServiceCIDR)
 	if err != nil {
-		errors = append(errors, fmt.Errorf("invalid service CIDR %s", n.ServiceCIDR))
+		errors = append(errors, fieldError("spec.network.serviceCIDR", "invalid service CIDR %s", n.ServiceCIDR))
+	}
+
+	for _, msg := range validation.IsDNS1123Subdomain(n.ClusterDomain) {
+		errors = append(errors, fieldError("spec.network.clusterDomain", "invalid cluster domain %s: %s", n.ClusterDomain, msg))
 	}
 
 	if n.DualStack.Enabled {
 		if n.Provider == "calico" && n.Calico.Mode != "bird" {
-			errors = append(errors, fmt.Errorf("network dual stack is supported only for calico mode `bird`"))
+			errors = append(errors, fieldError("spec.network.calico.mode", "network dual stack is supported only for calico mode `bird`"))
 		}
 		_, _, err := net.ParseCIDR(n.DualStack.IPv6PodCIDR)
 		if err != nil {
-			errors = append(errors, fmt.Errorf("invalid pod IPv6 CIDR %s", n.DualStack.IPv6PodCIDR))
+			errors = append(errors, fieldError("spec.network.dualStack.IPv6podCIDR", "invalid pod IPv6 CIDR %s", n.DualStack.IPv6PodCIDR))
 		}
 		_, _, err = net.ParseCIDR(n.DualStack.IPv6ServiceCIDR)
 		if err != nil {
-			errors = append(errors, fmt.Errorf("invalid service IPv6 CIDR %s", n.DualStack.IPv6ServiceCIDR))
+			errors = append(errors, fieldError("spec.network.dualStack.IPv6serviceCIDR", "invalid service IPv6 CIDR %s", n.DualStack.IPv6ServiceCIDR))
 		}
-		if n.KubeProxy.Mode != ModeIPVS {
-			errors = append(errors, fmt.Errorf("dual-stack requires kube-proxy in ipvs mode"))
+		if !n.KubeProxyDisabled() && n.KubeProxy.Mode != ModeIPVS {
+			errors = append(errors, fieldError("spec.network.kubeProxy.mode", "dual-stack requires kube-proxy in ipvs mode"))
 		}
 	}
-	errors = append(errors, n.KubeProxy.Validate()...)
+	if n.Provider == "calico" && n.Calico != nil {
+		errors = append(errors, n.Calico.Validate()...)
+	}
+	if n.Provider == "kuberouter" && n.KubeRouter != nil {
+		errors = append(errors, n.KubeRouter.Validate()...)
+	}
+	if n.Provider == "cilium" && n.Cilium != nil {
+		errors = append(errors, n.Cilium.Validate()...)
+	}
+	if n.KubeProxy != nil {
+		errors = append(errors, n.KubeProxy.Validate()...)
+	}
 	return errors
 }
 
+// KubeProxyDisabled tells if kube-proxy is disabled, either explicitly or because the network provider replaces it
+func (n *Network) KubeProxyDisabled() bool {
+	if n.Provider == "cilium" && n.Cilium != nil && n.Cilium.KubeProxyReplacement {
+		return true
+	}
+	if n.Provider == "kuberouter" && n.KubeRouter != nil && n.KubeRouter.ServiceProxy {
+		return true
+	}
+	return n.KubeProxy != nil && n.KubeProxy.Disabled
+}
+
 // DNSAddress calculates the 10th address of configured service CIDR block.
 func (n *Network) DNSAddress() (string, error) {
 	_, ipnet, err := net.ParseCIDR(n.ServiceCIDR)
@@ -133,6 +174,7 @@ func (n *Network) InternalAPIAddresses() ([]string, error) {
 // UnmarshalYAML sets in some sane defaults when unmarshaling the data from yaml
 func (n *Network) UnmarshalYAML(unmarshal func(interface{}) error) error {
 	n.Provider = "calico"
+	n.ClusterDomain = DefaultClusterDomain
 
 	type ynetwork Network
 	yc := (*ynetwork)(n)
@@ -147,6 +189,10 @@ func (n *Network) UnmarshalYAML(unmarshal func(interface{}) error) error {
 	} else if n.Provider == "kuberouter" && n.KubeRouter == nil {
 		n.KubeRouter = DefaultKubeRouter()
 		n.Calico = nil
+	} else if n.Provider == "cilium" && n.Cilium == nil {
+		n.Cilium = DefaultCilium()
+		n.Calico = nil
+		n.KubeRouter = nil
 	}
 
 	if n.KubeProxy == nil {
@@ -103,7 +103,7 @@ spec:
     calico:
 `
 
-	c, err := configFromString(yamlData, k0sVars)
+	c, err := ConfigFromString(yamlData, k0sVars)
 	s.NoError(err)
 	n := c.Spec.Network
 
@@ -126,7 +126,7 @@ spec:
     kuberouter:
 `
 
-	c, err := configFromString(yamlData, k0sVars)
+	c, err := ConfigFromString(yamlData, k0sVars)
 	s.NoError(err)
 	n := c.Spec.Network
 
@@ -138,6 +138,68 @@ spec:
 	s.Equal(0, n.KubeRouter.MTU)
 	s.Empty(n.KubeRouter.PeerRouterASNs)
 	s.Empty(n.KubeRouter.PeerRouterIPs)
+	s.True(n.KubeRouter.IPIP)
+	s.Equal(KubeRouterOverlaySubnet, n.KubeRouter.OverlayType)
+	s.Equal(DefaultKubeRouterMetricsPort, n.KubeRouter.MetricsPort)
+	s.False(n.KubeProxyDisabled())
+}
+
+func (s *NetworkSuite) TestKubeRouterPartialConfig() {
+	yamlData := `
+apiVersion: k0s.k0sproject.io/v1beta1
+kind: Cluster
+metadata:
+  name: foobar
+spec:
+  network:
+    provider: kuberouter
+    kuberouter:
+      serviceProxy: true
+      peers:
+      - ip: 10.0.1.1
+        asn: 65001
+        password: secret
+        multihopTTL: 2
+`
+
+	c, err := ConfigFromString(yamlData, k0sVars)
+	s.NoError(err)
+	n := c.Spec.Network
+
+	s.True(n.KubeRouter.AutoMTU)
+	s.True(n.KubeRouter.IPIP)
+	s.Equal(KubeRouterOverlaySubnet, n.KubeRouter.OverlayType)
+	s.Equal([]KubeRouterPeer{{IP: "10.0.1.1", ASN: 65001, Password: "secret", MultihopTTL: 2}}, n.KubeRouter.Peers)
+	s.Equal(uint8(2), n.KubeRouter.MultihopTTL())
+	s.True(n.KubeProxyDisabled())
+}
+
+func (s *NetworkSuite) TestCiliumDefaultsAfterMashaling() {
+	yamlData := `
+apiVersion: k0s.k0sproject.io/v1beta1
+kind: Cluster
+metadata:
+  name: foobar
+spec:
+  network:
+    provider: cilium
+    cilium:
+      kubeProxyReplacement: true
+`
+
+	c, err := ConfigFromString(yamlData, k0sVars)
+	s.NoError(err)
+	n := c.Spec.Network
+
+	s.Equal("cilium", n.Provider)
+	s.NotNil(n.Cilium)
+	s.Nil(n.Calico)
+
+	s.Equal(CiliumRoutingTunnel, n.Cilium.RoutingMode)
+	s.Equal("vxlan", n.Cilium.TunnelProtocol)
+	s.False(n.Cilium.Hubble)
+	s.True(n.KubeProxyDisabled())
+	s.False(n.KubeProxy.Disabled)
 }
 
 func (s *NetworkSuite) TestKubeProxyDefaultsAfterMashaling() {
@@ -149,7 +211,7 @@ metadata:
 spec:
 `
 
-	c, err := configFromString(yamlData, k0sVars)
+	c, err := ConfigFromString(yamlData, k0sVars)
 	s.NoError(err)
 	p := c.Spec.Network.KubeProxy
 
@@ -169,7 +231,7 @@ spec:
       disabled: true
 `
 
-	c, err := configFromString(yamlData, k0sVars)
+	c, err := ConfigFromString(yamlData, k0sVars)
 	s.NoError(err)
 	p := c.Spec.Network.KubeProxy
 
@@ -251,7 +313,7 @@ func (s *NetworkSuite) TestValidation() {
 		errors := n.Validate()
 		s.NotNil(errors)
 		s.Len(errors, 1)
-		s.Contains(errors[0].Error(), "unsupported mode")
+		s.Contains(errors[0].Error(), "spec.network.kubeProxy.mode: unsupported value")
 	})
 
 	s.T().Run("invalid_proxy_mode_for_dualstack", func(t *testing.T) {
@@ -269,6 +331,39 @@ func (s *NetworkSuite) TestValidation() {
 		s.Len(errors, 1)
 		s.Contains(errors[0].Error(), "dual-stack requires kube-proxy in ipvs mode")
 	})
+
+	s.T().Run("dualstack_with_cilium_replacing_kube_proxy", func(t *testing.T) {
+		n := DefaultNetwork()
+		n.Provider = "cilium"
+		n.Cilium = DefaultCilium()
+		n.Cilium.KubeProxyReplacement = true
+		n.DualStack = DefaultDualStack()
+		n.DualStack.Enabled = true
+		n.KubeProxy.Mode = "iptables"
+		n.DualStack.IPv6PodCIDR = "fd00::/108"
+		n.DualStack.IPv6ServiceCIDR = "fd01::/108"
+
+		s.Nil(n.Validate())
+	})
+
+	s.T().Run("invalid_cilium_settings", func(t *testing.T) {
+		n := DefaultNetwork()
+		n.Provider = "cilium"
+		n.Cilium = DefaultCilium()
+		n.Cilium.TunnelProtocol = "ipip"
+		n.Cilium.MTU = -1
+
+		s.Equal([]error{
+			fieldError("spec.network.cilium.tunnelProtocol", `unsupported value "ipip", must be one of vxlan, geneve`),
+			fieldError("spec.network.cilium.mtu", "must not be negative"),
+		}, n.Validate())
+
+		n.Cilium = DefaultCilium()
+		n.Cilium.RoutingMode = "direct"
+		s.Equal([]error{
+			fieldError("spec.network.cilium.routingMode", `unsupported value "direct", must be one of tunnel, native`),
+		}, n.Validate())
+	})
 }
 
 func TestNetworkSuite(t *testing.T) {
@@ -16,8 +16,6 @@ limitations under the License.
 package v1beta1
 
 import (
-	"fmt"
-
 	"github.com/k0sproject/k0s/pkg/constant"
 )
 
@@ -37,8 +35,10 @@ func DefaultPodSecurityPolicy() *PodSecurityPolicy {
 
 // Validate check that the given psp is one of the built-in ones
 func (p *PodSecurityPolicy) Validate() []error {
-	if p.DefaultPolicy != "00-k0s-privileged" && p.DefaultPolicy != "99-k0s-restricted" {
-		return []error{fmt.Errorf("%s is not a built-in pod security policy", p.DefaultPolicy)}
+	for _, psp := range BuiltInPSPs {
+		if p.DefaultPolicy == psp {
+			return nil
+		}
 	}
-	return nil
+	return []error{fieldError("spec.podSecurityPolicy.defaultPolicy", "%s is not a built-in pod security policy", p.DefaultPolicy)}
 }
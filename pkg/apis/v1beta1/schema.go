@@ -0,0 +1,123 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package v1beta1
+
+import (
+	"github.com/k0sproject/k0s/pkg/jsonschema"
+)
+
+// ClusterConfigSchema returns the JSON Schema of the k0s config file. The structure is reflected from the
+// config types, the constraints are the same the config validation checks.
+func ClusterConfigSchema() *jsonschema.Schema {
+	s := jsonschema.Reflect(ClusterConfig{}, "yaml")
+	s.Schema = "http://json-schema.org/draft-07/schema#"
+	s.Title = "k0s configuration"
+
+	enum(s, []string{ClusterConfigAPIVersion}, "apiVersion")
+	enum(s, []string{"Cluster"}, "kind")
+
+	spec := property(s, "spec")
+	port(spec, "api", "port")
+	port(spec, "api", "k0sApiPort")
+	enum(spec, StorageTypes, "storage", "type")
+	enum(spec, NetworkProviders, "network", "provider")
+	enum(spec, CalicoModes, "network", "calico", "mode")
+	enum(spec, CalicoOverlayModes, "network", "calico", "overlay")
+	port(spec, "network", "calico", "vxlanPort")
+	between(property(spec, "network", "calico", "vxlanVNI"), 1, maxVxlanVNI)
+	between(property(spec, "network", "calico", "mtu"), 0, -1)
+	between(property(spec, "network", "kuberouter", "mtu"), 0, -1)
+	enum(spec, KubeRouterOverlayTypes, "network", "kuberouter", "overlayType")
+	between(property(spec, "network", "kuberouter", "metricsPort"), 0, maxPort)
+	peer := property(spec, "network", "kuberouter", "peers").Items
+	peer.Required = []string{"ip", "asn"}
+	between(property(peer, "asn"), 1, 1<<32-1)
+	between(property(peer, "multihopTTL"), 2, 255)
+	enum(spec, CiliumRoutingModes, "network", "cilium", "routingMode")
+	enum(spec, CiliumTunnelProtocols, "network", "cilium", "tunnelProtocol")
+	between(property(spec, "network", "cilium", "mtu"), 0, -1)
+	enum(spec, KubeProxyModes, "network", "kubeProxy", "mode")
+	enum(spec, BuiltInPSPs, "podSecurityPolicy", "defaultPolicy")
+	enum(spec, AuditPresets, "api", "audit", "preset")
+	enum(spec, AuditLogFormats, "api", "audit", "log", "format")
+	enum(spec, AuditWebhookModes, "api", "audit", "webhook", "mode")
+	enum(spec, EncryptionProviders, "api", "encryption", "provider")
+	enum(spec, AuthenticationWebhookVersions, "api", "authentication", "webhook", "version")
+	enum(spec, ImagePullPolicies, "images", "default_pull_policy")
+	port(spec, "konnectivity", "agentPort")
+	port(spec, "konnectivity", "adminPort")
+	for _, path := range [][]string{
+		{"api", "extraArgs"},
+		{"controllerManager", "extraArgs"},
+		{"scheduler", "extraArgs"},
+		{"konnectivity", "extraArgs"},
+		{"storage", "etcd", "extraArgs"},
+		{"storage", "kine", "extraArgs"},
+	} {
+		extraArgs(spec, path...)
+	}
+
+	property(spec, "workerProfiles").Items.Required = []string{"name"}
+	property(spec, "api", "admission", "plugins").Items.Required = []string{"name", "configuration"}
+	helm := property(spec, "extensions", "helm")
+	property(helm, "repositories").Items.Required = []string{"name", "url"}
+	property(helm, "charts").Items.Required = []string{"name", "chartname"}
+
+	return s
+}
+
+// property returns the schema of the property at the given path, it panics if there's no such property
+// as that means the schema is out of sync with the config types
+func property(s *jsonschema.Schema, path ...string) *jsonschema.Schema {
+	for _, p := range path {
+		next, ok := s.Properties[p]
+		if !ok {
+			panic("no property " + p + " in the config schema")
+		}
+		s = next
+	}
+	return s
+}
+
+func enum(s *jsonschema.Schema, values []string, path ...string) {
+	p := property(s, path...)
+	p.Enum = make([]interface{}, len(values))
+	for i, v := range values {
+		p.Enum[i] = v
+	}
+}
+
+func port(s *jsonschema.Schema, path ...string) {
+	between(property(s, path...), minPort, maxPort)
+}
+
+// extraArgs allows the extra args to be given either as a plain value or as a value with the replace flag
+func extraArgs(s *jsonschema.Schema, path ...string) {
+	property(s, path...).AdditionalProperties = &jsonschema.Schema{
+		AnyOf: []*jsonschema.Schema{
+			{Type: "string"},
+			jsonschema.Reflect(ExtraArg{}, "yaml"),
+		},
+	}
+}
+
+// between sets the bounds of the numeric schema, a negative max leaves it unbounded
+func between(s *jsonschema.Schema, min, max float64) {
+	s.Minimum = &min
+	if max >= 0 {
+		s.Maximum = &max
+	}
+}
@@ -0,0 +1,79 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package v1beta1
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v2"
+
+	"github.com/k0sproject/k0s/pkg/jsonschema"
+)
+
+func TestClusterConfigSchema(t *testing.T) {
+	s := ClusterConfigSchema()
+
+	assert.Equal(t, []interface{}{"calico", "cilium", "custom", "kuberouter"}, property(s, "spec", "network", "provider").Enum)
+	vxlanPort := property(s, "spec", "network", "calico", "vxlanPort")
+	assert.Equal(t, "integer", vxlanPort.Type)
+	assert.Equal(t, float64(1), *vxlanPort.Minimum)
+	assert.Equal(t, float64(65535), *vxlanPort.Maximum)
+	assert.Equal(t, []string{"name", "chartname"}, property(s, "spec", "extensions", "helm", "charts").Items.Required)
+}
+
+// all the keys of a full config must be known to the schema
+func TestClusterConfigSchemaCoversConfig(t *testing.T) {
+	c := DefaultClusterConfig(k0sVars)
+	c.Spec.Network.Calico = DefaultCalico()
+	c.Spec.Storage.Kine = DefaultKineConfig("/var/lib/k0s")
+	c.Spec.Extensions = &ClusterExtensions{Helm: &HelmExtensions{
+		Repositories: []Repository{{Name: "stable", URL: "https://charts.helm.sh/stable"}},
+		Charts:       []Chart{{Name: "prometheus", ChartName: "stable/prometheus"}},
+	}}
+	data, err := yaml.Marshal(c)
+	assert.NoError(t, err)
+	var doc map[interface{}]interface{}
+	assert.NoError(t, yaml.Unmarshal(data, &doc))
+
+	assertKnownKeys(t, ClusterConfigSchema(), doc, "")
+}
+
+func assertKnownKeys(t *testing.T, s *jsonschema.Schema, v interface{}, path string) {
+	if s.Type == "" {
+		// any value goes
+		return
+	}
+	switch v := v.(type) {
+	case map[interface{}]interface{}:
+		for k, val := range v {
+			field := fmt.Sprintf("%s.%v", path, k)
+			if s.AdditionalProperties != nil {
+				assertKnownKeys(t, s.AdditionalProperties, val, field)
+				continue
+			}
+			p, ok := s.Properties[fmt.Sprint(k)]
+			if assert.True(t, ok, "%s is missing from the schema", field) {
+				assertKnownKeys(t, p, val, field)
+			}
+		}
+	case []interface{}:
+		for i, val := range v {
+			assertKnownKeys(t, s.Items, val, fmt.Sprintf("%s[%d]", path, i))
+		}
+	}
+}
@@ -0,0 +1,76 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package v1beta1
+
+// SecretRef references a secret value kept out of the config file, exactly one of the sources must be set.
+// The value is resolved when it's used and it's never written back to the config, the logs or the backups.
+type SecretRef struct {
+	// File is the path of a file holding the value, the surrounding whitespace is trimmed
+	File string `yaml:"file,omitempty"`
+	// Env is the name of an environment variable of the k0s process holding the value
+	Env string `yaml:"env,omitempty"`
+	// Secret is a key of a Kubernetes Secret, only supported by the cluster wide settings
+	Secret *SecretKeyRef `yaml:"secret,omitempty"`
+}
+
+// SecretKeyRef references a key of a Kubernetes Secret
+type SecretKeyRef struct {
+	// Namespace defaults to kube-system
+	Namespace string `yaml:"namespace,omitempty"`
+	Name      string `yaml:"name"`
+	Key       string `yaml:"key"`
+}
+
+// SecretNamespace is the default namespace of the Secrets referenced in the config
+const SecretNamespace = "kube-system"
+
+// validateSecretRef checks that exactly one source is set, the cluster wide settings allow Secrets
+func validateSecretRef(field string, r *SecretRef, allowSecret bool) []error {
+	if r == nil {
+		return nil
+	}
+	sources := 0
+	for _, set := range []bool{r.File != "", r.Env != "", r.Secret != nil} {
+		if set {
+			sources++
+		}
+	}
+	if sources != 1 {
+		return []error{fieldError(field, "exactly one of file, env and secret must be set")}
+	}
+	if r.Secret == nil {
+		return nil
+	}
+	if !allowSecret {
+		return []error{fieldError(field+".secret", "not supported, the value is needed before the cluster is up")}
+	}
+	var errors []error
+	if r.Secret.Name == "" {
+		errors = append(errors, fieldError(field+".secret.name", "must be set"))
+	}
+	if r.Secret.Key == "" {
+		errors = append(errors, fieldError(field+".secret.key", "must be set"))
+	}
+	return errors
+}
+
+// validateInlineOrRef checks that the secret value is given either inline or as a reference
+func validateInlineOrRef(field string, inline string, r *SecretRef, allowSecret bool) []error {
+	if inline != "" && r != nil {
+		return []error{fieldError(field+"From", "can't be used together with %s", field)}
+	}
+	return validateSecretRef(field+"From", r, allowSecret)
+}
@@ -17,6 +17,8 @@ package v1beta1
 
 import (
 	"testing"
+
+	"github.com/stretchr/testify/assert"
 )
 
 func TestStorageSpec_IsJoinable(t *testing.T) {
@@ -81,3 +83,17 @@ func TestStorageSpec_IsJoinable(t *testing.T) {
 		})
 	}
 }
+
+func TestEtcdSupportsTLSMinVersion(t *testing.T) {
+	for version, supported := range map[string]bool{
+		"":        false,
+		"3.4.16":  false,
+		"v3.4.16": false,
+		"3.5.0":   true,
+		"v3.5.1":  true,
+		"4.0.0":   true,
+		"3":       false,
+	} {
+		assert.Equal(t, supported, etcdSupportsTLSMinVersion(version), version)
+	}
+}
@@ -14,7 +14,7 @@ func DefaultClusterTelemetry() *ClusterTelemetry {
 	}
 }
 
-// Validate stub for Validateable interface
+// Validate stub for Validateable interface, there's nothing to validate in the telemetry settings
 func (c *ClusterTelemetry) Validate() []error {
 	return nil
 }
@@ -0,0 +1,81 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package v1beta1
+
+import (
+	"fmt"
+	"strings"
+)
+
+// The allowed values of the enumerated config fields, shared by the validation and the JSON schema
+var (
+	NetworkProviders       = []string{"calico", "cilium", "custom", "kuberouter"}
+	CalicoModes            = []string{"vxlan", "ipip", "bird"}
+	CalicoOverlayModes     = []string{"Always", "Never", "CrossSubnet"}
+	CiliumRoutingModes     = []string{CiliumRoutingTunnel, CiliumRoutingNative}
+	CiliumTunnelProtocols  = []string{"vxlan", "geneve"}
+	KubeRouterOverlayTypes = []string{KubeRouterOverlaySubnet, KubeRouterOverlayFull}
+	KubeProxyModes         = []string{"iptables", "ipvs", "userspace"}
+	StorageTypes           = []string{EtcdStorageType, KineStorageType}
+	KineDataSources        = []string{"sqlite", "mysql", "postgres", "postgresql", "http", "https", "unix"}
+	BuiltInPSPs            = []string{"00-k0s-privileged", "99-k0s-restricted"}
+	ImagePullPolicies      = []string{"Always", "IfNotPresent", "Never"}
+)
+
+const (
+	minPort     = 1
+	maxPort     = 65535
+	maxVxlanVNI = 1<<24 - 1
+)
+
+// FieldError is a validation error of a single config field
+type FieldError struct {
+	// Field is the path of the field in the config file, e.g. spec.network.calico.vxlanPort
+	Field   string
+	Message string
+}
+
+func (e *FieldError) Error() string {
+	return e.Field + ": " + e.Message
+}
+
+func fieldError(field string, format string, args ...interface{}) error {
+	return &FieldError{Field: field, Message: fmt.Sprintf(format, args...)}
+}
+
+func validatePort(field string, port int) error {
+	if port < minPort || port > maxPort {
+		return fieldError(field, "must be %d-%d, got %d", minPort, maxPort, port)
+	}
+	return nil
+}
+
+func validateOneOf(field string, value string, allowed []string) error {
+	for _, a := range allowed {
+		if value == a {
+			return nil
+		}
+	}
+	return fieldError(field, "unsupported value %q, must be one of %s", value, strings.Join(allowed, ", "))
+}
+
+// appendErr appends the error unless it's nil
+func appendErr(errors []error, err error) []error {
+	if err != nil {
+		return append(errors, err)
+	}
+	return errors
+}
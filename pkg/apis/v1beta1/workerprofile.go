@@ -27,9 +27,17 @@ type WorkerProfiles []WorkerProfile
 // Validate validates all profiles
 func (wps WorkerProfiles) Validate() []error {
 	var errors []error
-	for _, p := range wps {
+	names := map[string]bool{}
+	for i, p := range wps {
+		field := fmt.Sprintf("spec.workerProfiles[%d]", i)
+		if p.Name == "" {
+			errors = append(errors, fieldError(field+".name", "must be set"))
+		} else if names[p.Name] {
+			errors = append(errors, fieldError(field+".name", "duplicate worker profile %s", p.Name))
+		}
+		names[p.Name] = true
 		if err := p.Validate(); err != nil {
-			errors = append(errors, err)
+			errors = append(errors, fieldError(field+".values", "%s", err.Error()))
 		}
 	}
 	return errors
@@ -0,0 +1,137 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package v1beta2
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	"github.com/k0sproject/k0s/internal/util"
+	"github.com/k0sproject/k0s/pkg/apis/v1beta1"
+	"github.com/k0sproject/k0s/pkg/constant"
+)
+
+// ClusterConfigAPIVersion is the apiVersion of the v1beta2 k0s config file
+const ClusterConfigAPIVersion = "k0s.k0sproject.io/v1beta2"
+
+// The sections not changed since v1beta1
+type (
+	ClusterMeta           = v1beta1.ClusterMeta
+	APISpec               = v1beta1.APISpec
+	ControllerManagerSpec = v1beta1.ControllerManagerSpec
+	SchedulerSpec         = v1beta1.SchedulerSpec
+	StorageSpec           = v1beta1.StorageSpec
+	KineConfig            = v1beta1.KineConfig
+	EtcdConfig            = v1beta1.EtcdConfig
+	PodSecurityPolicy     = v1beta1.PodSecurityPolicy
+	WorkerProfiles        = v1beta1.WorkerProfiles
+	WorkerProfile         = v1beta1.WorkerProfile
+	ClusterTelemetry      = v1beta1.ClusterTelemetry
+	KonnectivitySpec      = v1beta1.KonnectivitySpec
+	KubeRouter            = v1beta1.KubeRouter
+	Cilium                = v1beta1.Cilium
+	KubeProxy             = v1beta1.KubeProxy
+	ImageSpec             = v1beta1.ImageSpec
+	KubeRouterImageSpec   = v1beta1.KubeRouterImageSpec
+	CiliumImageSpec       = v1beta1.CiliumImageSpec
+	SecretRef             = v1beta1.SecretRef
+	SecretKeyRef          = v1beta1.SecretKeyRef
+	FeatureGates          = v1beta1.FeatureGates
+)
+
+// ClusterConfig cluster manifest
+type ClusterConfig struct {
+	APIVersion string       `yaml:"apiVersion"`
+	Kind       string       `yaml:"kind"`
+	Metadata   *ClusterMeta `yaml:"metadata"`
+	Spec       *ClusterSpec `yaml:"spec"`
+	k0sVars    constant.CfgVars
+}
+
+// ClusterSpec ...
+type ClusterSpec struct {
+	API               *APISpec               `yaml:"api"`
+	ControllerManager *ControllerManagerSpec `yaml:"controllerManager,omitempty"`
+	Scheduler         *SchedulerSpec         `yaml:"scheduler,omitempty"`
+	Storage           *StorageSpec           `yaml:"storage"`
+	Network           *Network               `yaml:"network"`
+	PodSecurityPolicy *PodSecurityPolicy     `yaml:"podSecurityPolicy"`
+	WorkerProfiles    WorkerProfiles         `yaml:"workerProfiles,omitempty"`
+	Telemetry         *ClusterTelemetry      `yaml:"telemetry"`
+	Install           *InstallSpec           `yaml:"install,omitempty"`
+	Images            *ClusterImages         `yaml:"images"`
+	Extensions        *ClusterExtensions     `yaml:"extensions,omitempty"`
+	Konnectivity      *KonnectivitySpec      `yaml:"konnectivity,omitempty"`
+	FeatureGates      FeatureGates           `yaml:"featureGates,omitempty"`
+}
+
+// InstallSpec defines the required fields for the `k0s install` command
+type InstallSpec struct {
+	SystemUsers *SystemUser `yaml:"users,omitempty"`
+}
+
+// SystemUser defines the user to use for each component
+type SystemUser struct {
+	Etcd          string `yaml:"etcdUser,omitempty"`
+	Kine          string `yaml:"kineUser,omitempty"`
+	Konnectivity  string `yaml:"konnectivityUser,omitempty"`
+	KubeAPIServer string `yaml:"kubeAPIServerUser,omitempty"`
+	KubeScheduler string `yaml:"kubeSchedulerUser,omitempty"`
+}
+
+// ConfigFromFile parses the v1beta2 config file
+func ConfigFromFile(filename string, k0sVars constant.CfgVars) (*ClusterConfig, error) {
+	buf, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file at %s: %w", filename, err)
+	}
+
+	return ConfigFromString(string(buf), k0sVars)
+}
+
+// ConfigFromString parses the v1beta2 config, the omitted settings get the same defaults as in v1beta1
+func ConfigFromString(yml string, k0sVars constant.CfgVars) (*ClusterConfig, error) {
+	config := &ClusterConfig{k0sVars: k0sVars}
+	err := util.YamlUnmarshalStrictIgnoringFields([]byte(yml), &config, []string{"interval"})
+	if err != nil {
+		return config, err
+	}
+
+	if config.Spec == nil {
+		config.Spec = specFromV1beta1(v1beta1.DefaultClusterSpec(k0sVars))
+	}
+
+	return config, nil
+}
+
+// DefaultClusterConfig ...
+func DefaultClusterConfig(k0sVars constant.CfgVars) *ClusterConfig {
+	return FromV1beta1(v1beta1.DefaultClusterConfig(k0sVars))
+}
+
+// UnmarshalYAML sets in some sane defaults when unmarshaling the data from yaml
+func (c *ClusterConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	c.Kind = "Cluster"
+	c.Metadata = &ClusterMeta{
+		Name: "k0s",
+	}
+	c.Spec = specFromV1beta1(v1beta1.DefaultClusterSpec(c.k0sVars))
+
+	type yclusterconfig ClusterConfig
+	yc := (*yclusterconfig)(c)
+
+	return unmarshal(yc)
+}
@@ -0,0 +1,145 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package v1beta2
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+
+	"github.com/k0sproject/k0s/pkg/apis/v1beta1"
+	"github.com/k0sproject/k0s/pkg/constant"
+)
+
+var k0sVars = constant.CfgVars{}
+
+const v1beta1Config = `
+apiVersion: k0s.k0sproject.io/v1beta1
+kind: Cluster
+metadata:
+  name: k0s
+spec:
+  installConfig:
+    users:
+      kubeAPIserverUser: apiserver
+  network:
+    provider: calico
+    calico:
+      mode: bird
+      ipV6AutodetectionMethod: first-found
+    dualStack:
+      enabled: true
+      IPv6podCIDR: fd00::/108
+      IPv6serviceCIDR: fd01::/108
+  images:
+    coredns:
+      image: docker.io/coredns/coredns
+      version: 1.7.0
+    calico:
+      kubecontrollers:
+        image: calico/kube-controllers
+        version: v3.16.2
+    default_pull_policy: Never
+  extensions:
+    helm:
+      repositories:
+      - name: stable
+        url: https://charts.helm.sh/stable
+        keyfile: /tmp/key
+      charts:
+      - name: prometheus-stack
+        chartname: prometheus-community/prometheus
+        version: "11.16.8"
+        namespace: default
+`
+
+func TestConversionRoundTrip(t *testing.T) {
+	for name, cfg := range map[string]*v1beta1.ClusterConfig{
+		"default": v1beta1.DefaultClusterConfig(k0sVars),
+		"full":    mustParseV1beta1(t, v1beta1Config),
+	} {
+		t.Run(name, func(t *testing.T) {
+			converted := FromV1beta1(cfg)
+			assert.Equal(t, ClusterConfigAPIVersion, converted.APIVersion)
+			assert.Equal(t, cfg, converted.ToV1beta1())
+		})
+	}
+}
+
+func TestDefaultsMatchV1beta1(t *testing.T) {
+	c, err := ConfigFromString("apiVersion: k0s.k0sproject.io/v1beta2\nkind: Cluster", k0sVars)
+	require.NoError(t, err)
+	assert.Equal(t, v1beta1.DefaultClusterConfig(k0sVars).Spec, c.ToV1beta1().Spec)
+}
+
+func TestMigrate(t *testing.T) {
+	migrated, err := Migrate([]byte(v1beta1Config))
+	require.NoError(t, err)
+
+	t.Run("keys are renamed", func(t *testing.T) {
+		doc := map[string]interface{}{}
+		require.NoError(t, yaml.Unmarshal(migrated, &doc))
+		assert.Equal(t, ClusterConfigAPIVersion, doc["apiVersion"])
+		spec := doc["spec"].(map[interface{}]interface{})
+		assert.Contains(t, spec, "install")
+		assert.NotContains(t, spec, "installConfig")
+		images := spec["images"].(map[interface{}]interface{})
+		assert.Contains(t, images, "coreDNS")
+		assert.Equal(t, "Never", images["defaultPullPolicy"])
+		charts := spec["extensions"].(map[interface{}]interface{})["helm"].(map[interface{}]interface{})["charts"].([]interface{})
+		assert.Equal(t, "prometheus-community/prometheus", charts[0].(map[interface{}]interface{})["chartName"])
+	})
+
+	t.Run("migrated config is equivalent", func(t *testing.T) {
+		c, err := ConfigFromString(string(migrated), k0sVars)
+		require.NoError(t, err)
+		assert.Equal(t, mustParseV1beta1(t, v1beta1Config), c.ToV1beta1())
+	})
+
+	t.Run("migrated config matches the typed conversion", func(t *testing.T) {
+		c, err := ConfigFromString(string(migrated), k0sVars)
+		require.NoError(t, err)
+		assert.Equal(t, FromV1beta1(mustParseV1beta1(t, v1beta1Config)), c)
+	})
+
+	t.Run("v1beta2 config is left as is", func(t *testing.T) {
+		again, err := Migrate(migrated)
+		assert.NoError(t, err)
+		assert.Equal(t, migrated, again)
+	})
+}
+
+func TestMigrateWithoutAPIVersion(t *testing.T) {
+	migrated, err := Migrate([]byte("spec:\n  images:\n    coredns:\n      version: 1.7.0\n"))
+	require.NoError(t, err)
+	assert.Equal(t, "apiVersion: k0s.k0sproject.io/v1beta2\nspec:\n  images:\n    coreDNS:\n      version: 1.7.0\n", string(migrated))
+}
+
+func TestMigrateErrors(t *testing.T) {
+	_, err := Migrate([]byte("apiVersion: k0s.k0sproject.io/v1\nkind: Cluster"))
+	assert.EqualError(t, err, "unsupported apiVersion k0s.k0sproject.io/v1")
+
+	_, err = Migrate([]byte("apiVersion: k0s.k0sproject.io/v1beta1\nspec:\n  foo: bar"))
+	assert.Error(t, err)
+}
+
+func mustParseV1beta1(t *testing.T, yml string) *v1beta1.ClusterConfig {
+	c, err := v1beta1.ConfigFromString(yml, k0sVars)
+	require.NoError(t, err)
+	return c
+}
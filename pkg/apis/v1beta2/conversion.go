@@ -0,0 +1,263 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package v1beta2
+
+import (
+	"github.com/k0sproject/k0s/pkg/apis/v1beta1"
+)
+
+// The conversions are lossless, v1beta2 only renames some of the v1beta1 keys. The sections not changed
+// since v1beta1 are shared between the converted and the original config.
+
+// FromV1beta1 converts the v1beta1 config to v1beta2
+func FromV1beta1(in *v1beta1.ClusterConfig) *ClusterConfig {
+	if in == nil {
+		return nil
+	}
+	out := &ClusterConfig{
+		APIVersion: in.APIVersion,
+		Kind:       in.Kind,
+		Metadata:   in.Metadata,
+		Spec:       specFromV1beta1(in.Spec),
+	}
+	if out.APIVersion == v1beta1.ClusterConfigAPIVersion {
+		out.APIVersion = ClusterConfigAPIVersion
+	}
+	return out
+}
+
+// ToV1beta1 converts the config to v1beta1, the version used by the k0s components
+func (c *ClusterConfig) ToV1beta1() *v1beta1.ClusterConfig {
+	if c == nil {
+		return nil
+	}
+	out := &v1beta1.ClusterConfig{
+		APIVersion: c.APIVersion,
+		Kind:       c.Kind,
+		Metadata:   c.Metadata,
+		Spec:       c.Spec.toV1beta1(),
+	}
+	if out.APIVersion == ClusterConfigAPIVersion {
+		out.APIVersion = v1beta1.ClusterConfigAPIVersion
+	}
+	return out
+}
+
+func specFromV1beta1(in *v1beta1.ClusterSpec) *ClusterSpec {
+	if in == nil {
+		return nil
+	}
+	return &ClusterSpec{
+		API:               in.API,
+		ControllerManager: in.ControllerManager,
+		Scheduler:         in.Scheduler,
+		Storage:           in.Storage,
+		Network:           networkFromV1beta1(in.Network),
+		PodSecurityPolicy: in.PodSecurityPolicy,
+		WorkerProfiles:    in.WorkerProfiles,
+		Telemetry:         in.Telemetry,
+		Install:           installFromV1beta1(in.Install),
+		Images:            imagesFromV1beta1(in.Images),
+		Extensions:        extensionsFromV1beta1(in.Extensions),
+		Konnectivity:      in.Konnectivity,
+		FeatureGates:      in.FeatureGates,
+	}
+}
+
+func (s *ClusterSpec) toV1beta1() *v1beta1.ClusterSpec {
+	if s == nil {
+		return nil
+	}
+	return &v1beta1.ClusterSpec{
+		API:               s.API,
+		ControllerManager: s.ControllerManager,
+		Scheduler:         s.Scheduler,
+		Storage:           s.Storage,
+		Network:           s.Network.toV1beta1(),
+		PodSecurityPolicy: s.PodSecurityPolicy,
+		WorkerProfiles:    s.WorkerProfiles,
+		Telemetry:         s.Telemetry,
+		Install:           s.Install.toV1beta1(),
+		Images:            s.Images.toV1beta1(),
+		Extensions:        s.Extensions.toV1beta1(),
+		Konnectivity:      s.Konnectivity,
+		FeatureGates:      s.FeatureGates,
+	}
+}
+
+func networkFromV1beta1(in *v1beta1.Network) *Network {
+	if in == nil {
+		return nil
+	}
+	return &Network{
+		PodCIDR:       in.PodCIDR,
+		ServiceCIDR:   in.ServiceCIDR,
+		ClusterDomain: in.ClusterDomain,
+		Provider:      in.Provider,
+		Calico:        calicoFromV1beta1(in.Calico),
+		KubeRouter:    in.KubeRouter,
+		Cilium:        in.Cilium,
+		DualStack: DualStack{
+			Enabled:         in.DualStack.Enabled,
+			IPv6PodCIDR:     in.DualStack.IPv6PodCIDR,
+			IPv6ServiceCIDR: in.DualStack.IPv6ServiceCIDR,
+		},
+		KubeProxy: in.KubeProxy,
+	}
+}
+
+func (n *Network) toV1beta1() *v1beta1.Network {
+	if n == nil {
+		return nil
+	}
+	return &v1beta1.Network{
+		PodCIDR:       n.PodCIDR,
+		ServiceCIDR:   n.ServiceCIDR,
+		ClusterDomain: n.ClusterDomain,
+		Provider:      n.Provider,
+		Calico:        n.Calico.toV1beta1(),
+		KubeRouter:    n.KubeRouter,
+		Cilium:        n.Cilium,
+		DualStack: v1beta1.DualStack{
+			Enabled:         n.DualStack.Enabled,
+			IPv6PodCIDR:     n.DualStack.IPv6PodCIDR,
+			IPv6ServiceCIDR: n.DualStack.IPv6ServiceCIDR,
+		},
+		KubeProxy: n.KubeProxy,
+	}
+}
+
+func calicoFromV1beta1(in *v1beta1.Calico) *Calico {
+	if in == nil {
+		return nil
+	}
+	c := Calico(*in)
+	return &c
+}
+
+func (c *Calico) toV1beta1() *v1beta1.Calico {
+	if c == nil {
+		return nil
+	}
+	out := v1beta1.Calico(*c)
+	return &out
+}
+
+func installFromV1beta1(in *v1beta1.InstallSpec) *InstallSpec {
+	if in == nil {
+		return nil
+	}
+	out := &InstallSpec{}
+	if in.SystemUsers != nil {
+		users := SystemUser(*in.SystemUsers)
+		out.SystemUsers = &users
+	}
+	return out
+}
+
+func (i *InstallSpec) toV1beta1() *v1beta1.InstallSpec {
+	if i == nil {
+		return nil
+	}
+	out := &v1beta1.InstallSpec{}
+	if i.SystemUsers != nil {
+		users := v1beta1.SystemUser(*i.SystemUsers)
+		out.SystemUsers = &users
+	}
+	return out
+}
+
+func imagesFromV1beta1(in *v1beta1.ClusterImages) *ClusterImages {
+	if in == nil {
+		return nil
+	}
+	return &ClusterImages{
+		Konnectivity:      in.Konnectivity,
+		MetricsServer:     in.MetricsServer,
+		KubeProxy:         in.KubeProxy,
+		CoreDNS:           in.CoreDNS,
+		Calico:            CalicoImageSpec(in.Calico),
+		KubeRouter:        in.KubeRouter,
+		Cilium:            in.Cilium,
+		Repository:        in.Repository,
+		DefaultPullPolicy: in.DefaultPullPolicy,
+	}
+}
+
+func (ci *ClusterImages) toV1beta1() *v1beta1.ClusterImages {
+	if ci == nil {
+		return nil
+	}
+	return &v1beta1.ClusterImages{
+		Konnectivity:      ci.Konnectivity,
+		MetricsServer:     ci.MetricsServer,
+		KubeProxy:         ci.KubeProxy,
+		CoreDNS:           ci.CoreDNS,
+		Calico:            v1beta1.CalicoImageSpec(ci.Calico),
+		KubeRouter:        ci.KubeRouter,
+		Cilium:            ci.Cilium,
+		Repository:        ci.Repository,
+		DefaultPullPolicy: ci.DefaultPullPolicy,
+	}
+}
+
+func extensionsFromV1beta1(in *v1beta1.ClusterExtensions) *ClusterExtensions {
+	if in == nil {
+		return nil
+	}
+	out := &ClusterExtensions{}
+	if in.Helm == nil {
+		return out
+	}
+	out.Helm = &HelmExtensions{}
+	if in.Helm.Repositories != nil {
+		out.Helm.Repositories = make([]Repository, len(in.Helm.Repositories))
+		for i, r := range in.Helm.Repositories {
+			out.Helm.Repositories[i] = Repository(r)
+		}
+	}
+	if in.Helm.Charts != nil {
+		out.Helm.Charts = make([]Chart, len(in.Helm.Charts))
+		for i, c := range in.Helm.Charts {
+			out.Helm.Charts[i] = Chart(c)
+		}
+	}
+	return out
+}
+
+func (e *ClusterExtensions) toV1beta1() *v1beta1.ClusterExtensions {
+	if e == nil {
+		return nil
+	}
+	out := &v1beta1.ClusterExtensions{}
+	if e.Helm == nil {
+		return out
+	}
+	out.Helm = &v1beta1.HelmExtensions{}
+	if e.Helm.Repositories != nil {
+		out.Helm.Repositories = make([]v1beta1.Repository, len(e.Helm.Repositories))
+		for i, r := range e.Helm.Repositories {
+			out.Helm.Repositories[i] = v1beta1.Repository(r)
+		}
+	}
+	if e.Helm.Charts != nil {
+		out.Helm.Charts = make([]v1beta1.Chart, len(e.Helm.Charts))
+		for i, c := range e.Helm.Charts {
+			out.Helm.Charts[i] = v1beta1.Chart(c)
+		}
+	}
+	return out
+}
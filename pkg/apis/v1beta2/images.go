@@ -0,0 +1,52 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package v1beta2
+
+// ClusterImages sets docker images for addon components
+type ClusterImages struct {
+	Konnectivity  ImageSpec `yaml:"konnectivity"`
+	MetricsServer ImageSpec `yaml:"metricsServer"`
+	KubeProxy     ImageSpec `yaml:"kubeProxy"`
+	CoreDNS       ImageSpec `yaml:"coreDNS"`
+
+	Calico     CalicoImageSpec     `yaml:"calico"`
+	KubeRouter KubeRouterImageSpec `yaml:"kubeRouter"`
+	Cilium     CiliumImageSpec     `yaml:"cilium"`
+
+	Repository        string `yaml:"repository,omitempty"`
+	DefaultPullPolicy string `yaml:"defaultPullPolicy,omitempty"`
+}
+
+// CalicoImageSpec config group for calico related image settings
+type CalicoImageSpec struct {
+	CNI             ImageSpec `yaml:"cni"`
+	Node            ImageSpec `yaml:"node"`
+	KubeControllers ImageSpec `yaml:"kubeControllers"`
+}
+
+// UnmarshalYAML overrides the image repositories the same way as v1beta1
+func (ci *ClusterImages) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type wrapper ClusterImages
+	imagesWrapper := (*wrapper)(ci)
+	if err := unmarshal(imagesWrapper); err != nil {
+		return err
+	}
+	v1 := ci.toV1beta1()
+	v1.OverrideImageRepositories()
+	*ci = *imagesFromV1beta1(v1)
+	ci.DefaultPullPolicy = "IfNotPresent"
+	return nil
+}
@@ -0,0 +1,130 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package v1beta2
+
+import (
+	"fmt"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/k0sproject/k0s/pkg/apis/v1beta1"
+	"github.com/k0sproject/k0s/pkg/constant"
+)
+
+// keyRenames lists the v1beta1 keys renamed in v1beta2 by their v1beta1 path, * matching any list item
+var keyRenames = []struct {
+	path []string
+	to   string
+}{
+	{[]string{"spec", "installConfig"}, "install"},
+	{[]string{"spec", "installConfig", "users", "kubeAPIserverUser"}, "kubeAPIServerUser"},
+	{[]string{"spec", "network", "kuberouter"}, "kubeRouter"},
+	{[]string{"spec", "network", "calico", "ipV6AutodetectionMethod"}, "ipv6AutodetectionMethod"},
+	{[]string{"spec", "network", "dualStack", "IPv6podCIDR"}, "ipv6PodCIDR"},
+	{[]string{"spec", "network", "dualStack", "IPv6serviceCIDR"}, "ipv6ServiceCIDR"},
+	{[]string{"spec", "images", "metricsserver"}, "metricsServer"},
+	{[]string{"spec", "images", "kubeproxy"}, "kubeProxy"},
+	{[]string{"spec", "images", "coredns"}, "coreDNS"},
+	{[]string{"spec", "images", "calico", "kubecontrollers"}, "kubeControllers"},
+	{[]string{"spec", "images", "kuberouter"}, "kubeRouter"},
+	{[]string{"spec", "images", "default_pull_policy"}, "defaultPullPolicy"},
+	{[]string{"spec", "extensions", "helm", "charts", "*", "chartname"}, "chartName"},
+	{[]string{"spec", "extensions", "helm", "repositories", "*", "keyfile"}, "keyFile"},
+}
+
+// Migrate rewrites the v1beta1 config file to v1beta2. Only the renamed keys are changed, the settings
+// omitted from the file are left out so that they keep getting the defaults of the node the config is
+// used on. v1beta2 configs are returned as is.
+func Migrate(data []byte) ([]byte, error) {
+	var doc yaml.MapSlice
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	if len(doc) == 0 {
+		return nil, fmt.Errorf("empty config")
+	}
+
+	apiVersion := ""
+	for _, item := range doc {
+		if item.Key == "apiVersion" {
+			apiVersion = fmt.Sprint(item.Value)
+		}
+	}
+	switch apiVersion {
+	case ClusterConfigAPIVersion:
+		return data, nil
+	case v1beta1.ClusterConfigAPIVersion, "":
+	default:
+		return nil, fmt.Errorf("unsupported apiVersion %s", apiVersion)
+	}
+	// make sure it's a valid v1beta1 config so that we don't produce an invalid v1beta2 one
+	if _, err := v1beta1.ConfigFromString(string(data), constant.CfgVars{}); err != nil {
+		return nil, fmt.Errorf("invalid v1beta1 config: %w", err)
+	}
+
+	doc = renameKeys(doc, nil).(yaml.MapSlice)
+	if apiVersion == "" {
+		doc = append(yaml.MapSlice{{Key: "apiVersion"}}, doc...)
+	}
+	for i := range doc {
+		if doc[i].Key == "apiVersion" {
+			doc[i].Value = ClusterConfigAPIVersion
+		}
+	}
+
+	return yaml.Marshal(doc)
+}
+
+func renameKeys(v interface{}, path []string) interface{} {
+	switch v := v.(type) {
+	case yaml.MapSlice:
+		for i, item := range v {
+			key := fmt.Sprint(item.Key)
+			itemPath := append(append([]string{}, path...), key)
+			if to, ok := renamedKey(itemPath); ok {
+				v[i].Key = to
+			}
+			v[i].Value = renameKeys(item.Value, itemPath)
+		}
+		return v
+	case []interface{}:
+		itemPath := append(append([]string{}, path...), "*")
+		for i, item := range v {
+			v[i] = renameKeys(item, itemPath)
+		}
+		return v
+	}
+	return v
+}
+
+func renamedKey(path []string) (string, bool) {
+	for _, r := range keyRenames {
+		if len(r.path) != len(path) {
+			continue
+		}
+		match := true
+		for i := range path {
+			if path[i] != r.path[i] {
+				match = false
+				break
+			}
+		}
+		if match {
+			return r.to, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,103 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package v1beta2
+
+import (
+	"gopkg.in/yaml.v2"
+
+	"github.com/k0sproject/k0s/pkg/apis/v1beta1"
+)
+
+// Network defines the network related config options
+type Network struct {
+	PodCIDR     string `yaml:"podCIDR"`
+	ServiceCIDR string `yaml:"serviceCIDR"`
+	// ClusterDomain is the DNS domain of the cluster, used by kubelet and CoreDNS
+	ClusterDomain string      `yaml:"clusterDomain,omitempty"`
+	Provider      string      `yaml:"provider"`
+	Calico        *Calico     `yaml:"calico"`
+	KubeRouter    *KubeRouter `yaml:"kubeRouter"`
+	Cilium        *Cilium     `yaml:"cilium,omitempty"`
+	DualStack     DualStack   `yaml:"dualStack,omitempty"`
+	KubeProxy     *KubeProxy  `yaml:"kubeProxy"`
+}
+
+// DualStack defines network configuration for ipv4\ipv6 mixed cluster setup
+type DualStack struct {
+	Enabled         bool   `yaml:"enabled,omitempty"`
+	IPv6PodCIDR     string `yaml:"ipv6PodCIDR,omitempty"`
+	IPv6ServiceCIDR string `yaml:"ipv6ServiceCIDR,omitempty"`
+}
+
+// Calico defines the calico related config options
+type Calico struct {
+	Mode                    string `yaml:"mode"`
+	VxlanPort               int    `yaml:"vxlanPort"`
+	VxlanVNI                int    `yaml:"vxlanVNI"`
+	MTU                     int    `yaml:"mtu"`
+	EnableWireguard         bool   `yaml:"wireguard"`
+	FlexVolumeDriverPath    string `yaml:"flexVolumeDriverPath"`
+	WithWindowsNodes        bool   `yaml:"withWindowsNodes"`
+	Overlay                 string `yaml:"overlay"`
+	IPAutodetectionMethod   string `yaml:"ipAutodetectionMethod,omitempty"`
+	IPv6AutodetectionMethod string `yaml:"ipv6AutodetectionMethod,omitempty"`
+}
+
+// UnmarshalYAML sets in the same defaults as v1beta1
+func (n *Network) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	n.Provider = "calico"
+	n.ClusterDomain = v1beta1.DefaultClusterDomain
+
+	type ynetwork Network
+	yn := (*ynetwork)(n)
+
+	if err := unmarshal(yn); err != nil {
+		return err
+	}
+
+	if n.Provider == "calico" && n.Calico == nil {
+		n.Calico = calicoFromV1beta1(v1beta1.DefaultCalico())
+		n.KubeRouter = nil
+	} else if n.Provider == "kuberouter" && n.KubeRouter == nil {
+		n.KubeRouter = v1beta1.DefaultKubeRouter()
+		n.Calico = nil
+	} else if n.Provider == "cilium" && n.Cilium == nil {
+		n.Cilium = v1beta1.DefaultCilium()
+		n.Calico = nil
+		n.KubeRouter = nil
+	}
+
+	if n.KubeProxy == nil {
+		n.KubeProxy = v1beta1.DefaultKubeProxy()
+	}
+
+	return nil
+}
+
+// UnmarshalYAML sets in the same defaults as v1beta1
+func (c *Calico) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	// v1beta1 has different defaults for parsed and built calico configs, use the parsed ones
+	defaults := &v1beta1.Calico{}
+	if err := yaml.Unmarshal([]byte("{}"), defaults); err != nil {
+		return err
+	}
+	*c = *calicoFromV1beta1(defaults)
+
+	type ycalico Calico
+	yc := (*ycalico)(c)
+
+	return unmarshal(yc)
+}
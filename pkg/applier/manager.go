@@ -24,7 +24,6 @@ import (
 	"gopkg.in/fsnotify.v1"
 
 	"github.com/k0sproject/k0s/internal/util"
-	"github.com/k0sproject/k0s/pkg/component/controller"
 	"github.com/k0sproject/k0s/pkg/constant"
 	kubeutil "github.com/k0sproject/k0s/pkg/kubernetes"
 )
@@ -35,13 +34,10 @@ type Manager struct {
 	KubeClientFactory kubeutil.ClientFactory
 
 	//client               kubernetes.Interface
-	applier       Applier
-	bundlePath    string
-	cancelWatcher context.CancelFunc
-	log           *logrus.Entry
-	stacks        map[string]*StackApplier
-
-	LeaderElector controller.LeaderElector
+	applier    Applier
+	bundlePath string
+	log        *logrus.Entry
+	stacks     map[string]*StackApplier
 }
 
 // Init initializes the Manager
@@ -56,19 +52,6 @@ func (m *Manager) Init() error {
 
 	m.applier = NewApplier(m.K0sVars.ManifestsDir, m.KubeClientFactory)
 
-	m.LeaderElector.AddAcquiredLeaseCallback(func() {
-		ctx, cancel := context.WithCancel(context.Background())
-		m.cancelWatcher = cancel
-		go func() {
-			_ = m.runWatchers(ctx)
-		}()
-	})
-	m.LeaderElector.AddLostLeaseCallback(func() {
-		if m.cancelWatcher != nil {
-			m.cancelWatcher()
-		}
-	})
-
 	return err
 }
 
@@ -77,16 +60,19 @@ func (m *Manager) Run() error {
 	return nil
 }
 
+// RunLeader applies the manifest stacks and watches for changes for as long as we're the leader
+func (m *Manager) RunLeader(ctx context.Context) error {
+	defer m.stopStacks()
+	return m.runWatchers(ctx)
+}
+
 // Stop stops the Manager
 func (m *Manager) Stop() error {
-	if m.cancelWatcher != nil {
-		m.cancelWatcher()
-	}
 	return nil
 }
 
 func (m *Manager) runWatchers(ctx context.Context) error {
-	log := logrus.WithField("component", "applier-manager")
+	log := m.log
 
 	dirs, err := util.GetAllDirs(m.bundlePath)
 	if err != nil {
@@ -184,5 +170,15 @@ func (m *Manager) removeStack(name string) error {
 	return nil
 }
 
+// stopStacks stops all the stack appliers, so that they get started again once we're the leader again
+func (m *Manager) stopStacks() {
+	for name, sa := range m.stacks {
+		if err := sa.Stop(); err != nil {
+			m.log.WithField("stack", name).WithError(err).Warn("failed to stop stack applier")
+		}
+		delete(m.stacks, name)
+	}
+}
+
 // Health-check interface
 func (m *Manager) Healthy() error { return nil }
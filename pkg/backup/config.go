@@ -3,23 +3,35 @@
 package backup
 
 import (
+	"bytes"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"path"
+	"regexp"
 
 	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v2"
 
 	"github.com/k0sproject/k0s/internal/util"
 )
 
+// redacted replaces the secret values of the config in the backups
+const redacted = "REDACTED"
+
+// dataSourcePassword matches the password of the kine data source URLs
+var dataSourcePassword = regexp.MustCompile(`(://[^:/@]*):[^@]*@`)
+
 type configurationStep struct {
 	path               string
+	tmpDir             string
 	restoredConfigPath string
 }
 
-func newConfigurationStep(path string, restoredConfigPath string) *configurationStep {
+func newConfigurationStep(path string, tmpDir string, restoredConfigPath string) *configurationStep {
 	return &configurationStep{
 		path:               path,
+		tmpDir:             tmpDir,
 		restoredConfigPath: restoredConfigPath,
 	}
 }
@@ -37,7 +49,52 @@ func (c configurationStep) Backup() (StepResult, error) {
 	if err != nil {
 		return StepResult{}, fmt.Errorf("can't backup `%s`: %v", c.path, err)
 	}
-	return StepResult{filesForBackup: []string{c.path}}, nil
+	data, err := ioutil.ReadFile(c.path)
+	if err != nil {
+		return StepResult{}, fmt.Errorf("can't backup `%s`: %v", c.path, err)
+	}
+	data, err = redactConfig(data)
+	if err != nil {
+		return StepResult{}, fmt.Errorf("can't redact the secrets of `%s`: %v", c.path, err)
+	}
+	// the archive holds the config as k0s.yaml, regardless of the name of the config file
+	backupPath := path.Join(c.tmpDir, "k0s.yaml")
+	if err := ioutil.WriteFile(backupPath, data, 0600); err != nil {
+		return StepResult{}, fmt.Errorf("can't backup `%s`: %v", c.path, err)
+	}
+	return StepResult{filesForBackup: []string{backupPath}}, nil
+}
+
+// redactConfig replaces the inline passwords of the config, the backups must not leak them. The values
+// referenced from files, the environment or Secrets are kept as they are, they aren't secret.
+func redactConfig(data []byte) ([]byte, error) {
+	var cfg yaml.MapSlice
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, err
+	}
+	return yaml.Marshal(redactValue(cfg))
+}
+
+func redactValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case yaml.MapSlice:
+		for i, item := range v {
+			s, isString := item.Value.(string)
+			switch {
+			case isString && s != "" && item.Key == "password":
+				v[i].Value = redacted
+			case isString && item.Key == "dataSource":
+				v[i].Value = dataSourcePassword.ReplaceAllString(s, "$1:"+redacted+"@")
+			default:
+				v[i].Value = redactValue(item.Value)
+			}
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = redactValue(item)
+		}
+	}
+	return v
 }
 
 func (c configurationStep) Restore(restoreFrom, restoreTo string) error {
@@ -50,5 +107,11 @@ func (c configurationStep) Restore(restoreFrom, restoreTo string) error {
 	logrus.Infof("Previously used k0s.yaml saved under the data directory `%s`", restoreTo)
 
 	logrus.Infof("restoring from `%s` to `%s`", objectPathInArchive, c.restoredConfigPath)
-	return util.FileCopy(objectPathInArchive, c.restoredConfigPath)
+	if err := util.FileCopy(objectPathInArchive, c.restoredConfigPath); err != nil {
+		return err
+	}
+	if data, err := ioutil.ReadFile(objectPathInArchive); err == nil && bytes.Contains(data, []byte(redacted)) {
+		logrus.Warnf("the inline passwords of the backed up k0s.yaml are redacted, set them in `%s` before using it", c.restoredConfigPath)
+	}
+	return nil
 }
@@ -25,9 +25,11 @@ import (
 
 	"github.com/k0sproject/k0s/internal/util"
 
+	"github.com/sirupsen/logrus"
 	"go.etcd.io/etcd/clientv3/snapshot"
 	"go.uber.org/zap"
 
+	"github.com/k0sproject/k0s/pkg/apis/v1beta1"
 	"github.com/k0sproject/k0s/pkg/etcd"
 )
 
@@ -37,13 +39,13 @@ type etcdStep struct {
 	certRootDir string
 	etcdCertDir string
 
-	peerAddress string
+	etcdConfig  *v1beta1.EtcdConfig
 	etcdDataDir string
 	tmpDir      string
 }
 
-func newEtcdStep(tmpDir string, certRootDir string, etcdCertDir string, peerAddress string, etcdDataDir string) *etcdStep {
-	return &etcdStep{tmpDir: tmpDir, certRootDir: certRootDir, etcdCertDir: etcdCertDir, peerAddress: peerAddress, etcdDataDir: etcdDataDir}
+func newEtcdStep(tmpDir string, certRootDir string, etcdCertDir string, etcdConfig *v1beta1.EtcdConfig, etcdDataDir string) *etcdStep {
+	return &etcdStep{tmpDir: tmpDir, certRootDir: certRootDir, etcdCertDir: etcdCertDir, etcdConfig: etcdConfig, etcdDataDir: etcdDataDir}
 }
 
 func (e etcdStep) Name() string {
@@ -52,18 +54,12 @@ func (e etcdStep) Name() string {
 
 func (e etcdStep) Backup() (StepResult, error) {
 	ctx := context.TODO()
-	etcdClient, err := etcd.NewClient(e.certRootDir, e.etcdCertDir)
+	etcdClient, err := etcd.NewClient(e.certRootDir, e.etcdCertDir, e.etcdConfig)
 	if err != nil {
 		return StepResult{}, err
 	}
 	path := filepath.Join(e.tmpDir, etcdBackup)
-
-	// disable etcd's logging
-	lg := zap.NewNop()
-	m := snapshot.NewV3(lg)
-
-	// save snapshot
-	if err = m.Save(ctx, *etcdClient.Config, path); err != nil {
+	if err := etcdClient.SaveSnapshot(ctx, path); err != nil {
 		return StepResult{}, err
 	}
 	// add snapshot's path to assets
@@ -71,6 +67,11 @@ func (e etcdStep) Backup() (StepResult, error) {
 }
 
 func (e etcdStep) Restore(restoreFrom, _ string) error {
+	if e.etcdConfig.IsExternalClusterUsed() {
+		logrus.Warnf("the external etcd cluster is not restored by k0s, restore the %s snapshot of the backup archive with etcdctl", etcdBackup)
+		return nil
+	}
+
 	snapshotPath := filepath.Join(restoreFrom, etcdBackup)
 	if !util.FileExists(snapshotPath) {
 		return fmt.Errorf("etcd snapshot not found at %s", snapshotPath)
@@ -83,7 +84,7 @@ func (e etcdStep) Restore(restoreFrom, _ string) error {
 	if err != nil {
 		return err
 	}
-	peerURL := fmt.Sprintf("https://%s:2380", e.peerAddress)
+	peerURL := e.etcdConfig.PeerURL(e.etcdConfig.PeerAddress)
 	restoreConfig := snapshot.RestoreConfig{
 		SnapshotPath:   snapshotPath,
 		OutputDataDir:  e.etcdDataDir,
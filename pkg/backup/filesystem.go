@@ -14,7 +14,8 @@ import (
 
 // FileSystemStep creates backup for file system object
 type FileSystemStep struct {
-	path string
+	path     string
+	excluded map[string]bool
 }
 
 func (d FileSystemStep) Name() string {
@@ -42,6 +43,9 @@ func (d FileSystemStep) dir() (StepResult, error) {
 		if err != nil {
 			return err
 		}
+		if d.excluded[path] {
+			return nil
+		}
 		files = append(files, path)
 		return nil
 	}); err != nil {
@@ -66,8 +70,12 @@ func (d FileSystemStep) Restore(restoreFrom, restoreTo string) error {
 	return util.FileCopy(objectPathInArchive, objectPathInRestored)
 }
 
-// NewFilesystemStep constructor
-func NewFilesystemStep(path string) FileSystemStep {
-	return FileSystemStep{path: path}
+// NewFilesystemStep constructor, the excluded paths are left out of the backup
+func NewFilesystemStep(path string, excluded ...string) FileSystemStep {
+	step := FileSystemStep{path: path, excluded: map[string]bool{}}
+	for _, e := range excluded {
+		step.excluded[e] = true
+	}
+	return step
 
 }
@@ -72,7 +72,7 @@ func (bm *Manager) RunBackup(cfgPath string, clusterSpec *v1beta1.ClusterSpec, v
 
 func (bm *Manager) discoverSteps(cfgPath string, clusterSpec *v1beta1.ClusterSpec, vars constant.CfgVars, action string, restoredConfigPath string) {
 	if clusterSpec.Storage.Type == v1beta1.EtcdStorageType {
-		bm.Add(newEtcdStep(bm.tmpDir, vars.CertRootDir, vars.EtcdCertDir, clusterSpec.Storage.Etcd.PeerAddress, vars.EtcdDataDir))
+		bm.Add(newEtcdStep(bm.tmpDir, vars.CertRootDir, vars.EtcdCertDir, clusterSpec.Storage.Etcd, vars.EtcdDataDir))
 	} else if clusterSpec.Storage.Type == v1beta1.KineStorageType && strings.HasPrefix(clusterSpec.Storage.Kine.DataSource, "sqlite://") {
 		bm.Add(newSqliteStep(bm.tmpDir, clusterSpec.Storage.Kine.DataSource, vars.DataDir))
 	} else {
@@ -84,14 +84,14 @@ func (bm *Manager) discoverSteps(cfgPath string, clusterSpec *v1beta1.ClusterSpe
 		vars.ManifestsDir,
 		vars.OCIBundleDir,
 		vars.HelmHome,
-		vars.HelmRepositoryConfig,
 	} {
 		if action == "backup" {
 			logrus.Infof("adding `%s` path to the backup archive", path)
 		}
-		bm.Add(NewFilesystemStep(path))
+		// the helm repositories file holds the resolved repository credentials, it's recreated from the config on startup
+		bm.Add(NewFilesystemStep(path, vars.HelmRepositoryConfig))
 	}
-	bm.Add(newConfigurationStep(cfgPath, restoredConfigPath))
+	bm.Add(newConfigurationStep(cfgPath, bm.tmpDir, restoredConfigPath))
 }
 
 // Add adds backup step
@@ -155,7 +155,7 @@ func (bm Manager) getConfigForRestore(k0sVars constant.CfgVars) (*v1beta1.Cluste
 	}
 	logrus.Infof("Using k0s.yaml from: %s", configFromBackup)
 
-	cfg, err := config.GetYamlFromFile(configFromBackup, k0sVars)
+	cfg, err := config.GetYamlFromFile(configFromBackup, "", k0sVars)
 	if err != nil {
 		return nil, err
 	}
@@ -29,6 +29,7 @@ import (
 
 type Config struct {
 	cfgFile          string
+	cfgDir           string
 	containerd       *containerdConfig
 	containerRuntime runtime.ContainerRuntime
 	dataDir          string
@@ -42,7 +43,7 @@ type containerdConfig struct {
 	socketPath string
 }
 
-func NewConfig(k0sVars constant.CfgVars, cfgFile string, criSocketPath string) (*Config, error) {
+func NewConfig(k0sVars constant.CfgVars, cfgFile string, cfgDir string, criSocketPath string) (*Config, error) {
 	runDir := "/run/k0s" // https://github.com/k0sproject/k0s/pull/591/commits/c3f932de85a0b209908ad39b817750efc4987395
 
 	var err error
@@ -65,6 +66,7 @@ func NewConfig(k0sVars constant.CfgVars, cfgFile string, criSocketPath string) (
 
 	return &Config{
 		cfgFile:          cfgFile,
+		cfgDir:           cfgDir,
 		containerd:       containerdCfg,
 		containerRuntime: runtime.NewContainerRuntime(runtimeType, criSocketPath),
 		dataDir:          k0sVars.DataDir,
@@ -76,6 +78,7 @@ func NewConfig(k0sVars constant.CfgVars, cfgFile string, criSocketPath string) (
 func (c *Config) Cleanup() error {
 	var msg []error
 	cleanupSteps := []Step{
+		&etcdLeave{Config: c},
 		&containers{Config: c},
 		&users{Config: c},
 		&services{Config: c},
@@ -5,6 +5,7 @@ import (
 	"os"
 
 	"github.com/k0sproject/k0s/internal/util"
+	"github.com/k0sproject/k0s/pkg/component/worker"
 	"github.com/sirupsen/logrus"
 )
 
@@ -20,10 +21,9 @@ func (c *cni) Name() string {
 
 // NeedsToRun checks if there are and CNI leftovers
 func (c *cni) NeedsToRun() bool {
-	files := []string{
-		"/etc/cni/net.d/10-calico.conflist",
-		"/etc/cni/net.d/calico-kubeconfig",
-		"/etc/cni/net.d/10-kuberouter.conflist",
+	var files []string
+	for _, provider := range []string{"calico", "cilium", "kuberouter"} {
+		files = append(files, worker.CNIConfigFiles(provider)...)
 	}
 
 	for _, file := range files {
@@ -0,0 +1,108 @@
+package cleanup
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/k0sproject/k0s/internal/util"
+	"github.com/k0sproject/k0s/pkg/apis/v1beta1"
+	"github.com/k0sproject/k0s/pkg/component/controller"
+	"github.com/k0sproject/k0s/pkg/config"
+	"github.com/k0sproject/k0s/pkg/etcd"
+)
+
+type etcdLeave struct {
+	Config *Config
+}
+
+// Name returns the name of the step
+func (e *etcdLeave) Name() string {
+	return "leave etcd cluster step"
+}
+
+// NeedsToRun detects a controller that is an etcd member
+func (e *etcdLeave) NeedsToRun() bool {
+	if !util.FileExists(filepath.Join(e.Config.k0sVars.EtcdDataDir, "member", "snap", "db")) ||
+		!util.FileExists(filepath.Join(e.Config.k0sVars.BinDir, "etcd")) {
+		return false
+	}
+	clusterConfig, err := config.GetYamlFromFile(e.Config.cfgFile, e.Config.cfgDir, e.Config.k0sVars)
+	if err != nil {
+		return false
+	}
+	storage := clusterConfig.Spec.Storage
+	return storage.Type == v1beta1.EtcdStorageType && !storage.Etcd.IsExternalClusterUsed()
+}
+
+// Run starts the etcd member of the controller once more to remove it from the cluster, so that the
+// remaining members don't wait for it to come back
+func (e *etcdLeave) Run() error {
+	clusterConfig, err := config.GetYamlFromFile(e.Config.cfgFile, e.Config.cfgDir, e.Config.k0sVars)
+	if err != nil {
+		return err
+	}
+	etcdConf := clusterConfig.Spec.Storage.Etcd
+	args, err := (&controller.Etcd{Config: etcdConf, K0sVars: e.Config.k0sVars, LogLevel: "warn"}).Args()
+	if err != nil {
+		return err
+	}
+	peerURL := args["initial-advertise-peer-urls"]
+
+	cmd := exec.Command(filepath.Join(e.Config.k0sVars.BinDir, "etcd"), args.ToArgs()...)
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start etcd: %w", err)
+	}
+	defer func() {
+		// etcd exits by itself once removed, it's still there if it failed to leave
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+	for {
+		checkCtx, checkCancel := context.WithTimeout(ctx, 2*time.Second)
+		err = etcd.CheckEtcdReady(checkCtx, e.Config.k0sVars.CertRootDir, e.Config.k0sVars.EtcdCertDir, etcdConf)
+		checkCancel()
+		if err == nil {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("etcd didn't get ready to leave the cluster, remove the member %s with k0s etcd leave on another controller: %w", peerURL, err)
+		case <-time.After(time.Second):
+		}
+	}
+
+	client, err := etcd.NewClient(e.Config.k0sVars.CertRootDir, e.Config.k0sVars.EtcdCertDir, etcdConf)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	members, err := client.Members(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list the etcd members: %w", err)
+	}
+	if len(members) == 1 {
+		logrus.Debug("the controller is the last etcd member, not leaving")
+		return nil
+	}
+	for _, m := range members {
+		if m.PeerURL != peerURL {
+			continue
+		}
+		if err := client.DeleteMember(ctx, m.ID); err != nil {
+			return fmt.Errorf("failed to leave the etcd cluster: %w", err)
+		}
+		logrus.Infof("left the etcd cluster as member %s", m.Name)
+		return nil
+	}
+	logrus.Debugf("no etcd member with peer URL %s, already left", peerURL)
+	return nil
+}
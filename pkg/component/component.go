@@ -15,6 +15,12 @@ limitations under the License.
 */
 package component
 
+import (
+	"time"
+
+	"github.com/k0sproject/k0s/pkg/apis/v1beta1"
+)
+
 // Component defines the interface each managed component implements
 type Component interface {
 	Init() error
@@ -22,3 +28,17 @@ type Component interface {
 	Stop() error
 	Healthy() error
 }
+
+// SlowStarter is implemented by the components that may take longer than the default two minutes to get healthy
+type SlowStarter interface {
+	Component
+	// HealthTimeout is how long the component manager waits for the component to get healthy
+	HealthTimeout() time.Duration
+}
+
+// ConfigReconciler is implemented by the components able to apply a changed cluster config at runtime
+type ConfigReconciler interface {
+	Component
+	// Reconcile applies the given cluster config, replacing the config the component was created with
+	Reconcile(cfg *v1beta1.ClusterConfig) error
+}
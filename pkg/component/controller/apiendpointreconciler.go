@@ -21,7 +21,6 @@ import (
 	"net"
 	"reflect"
 	"sort"
-	"sync/atomic"
 	"time"
 
 	config "github.com/k0sproject/k0s/pkg/apis/v1beta1"
@@ -39,19 +38,13 @@ type APIEndpointReconciler struct {
 
 	L *logrus.Entry
 
-	leaderElector     LeaderElector
-	stopCh            chan struct{}
 	kubeClientFactory k8sutil.ClientFactory
 }
 
 // NewEndpointReconciler creates new endpoint reconciler
-func NewEndpointReconciler(c *k0sv1beta1.ClusterConfig, leaderElector LeaderElector, kubeClientFactory k8sutil.ClientFactory) *APIEndpointReconciler {
-	d := atomic.Value{}
-	d.Store(true)
+func NewEndpointReconciler(c *k0sv1beta1.ClusterConfig, kubeClientFactory k8sutil.ClientFactory) *APIEndpointReconciler {
 	return &APIEndpointReconciler{
 		ClusterConfig:     c,
-		leaderElector:     leaderElector,
-		stopCh:            make(chan struct{}),
 		kubeClientFactory: kubeClientFactory,
 		L:                 logrus.WithFields(logrus.Fields{"component": "endpointreconciler"}),
 	}
@@ -67,32 +60,31 @@ func (a *APIEndpointReconciler) Init() error {
 	return nil
 }
 
-// Run runs the main loop for reconciling the externalAddress
+// Run does nothing, the endpoints are only reconciled by the leader
 func (a *APIEndpointReconciler) Run() error {
+	return nil
+}
 
-	go func() {
-		ticker := time.NewTicker(10 * time.Second)
-		defer ticker.Stop()
-		for {
-			select {
-			case <-ticker.C:
-				err := a.reconcileEndpoints()
-				if err != nil {
-					a.L.Warnf("external API address reconciliation failed: %s", err.Error())
-				}
-			case <-a.stopCh:
-				a.L.Info("endpoint reconciler done")
-				return
+// RunLeader runs the main loop for reconciling the externalAddress
+func (a *APIEndpointReconciler) RunLeader(ctx context.Context) error {
+	ticker := time.NewTicker(10 * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			err := a.reconcileEndpoints()
+			if err != nil {
+				a.L.Warnf("external API address reconciliation failed: %s", err.Error())
 			}
+		case <-ctx.Done():
+			a.L.Info("endpoint reconciler done")
+			return nil
 		}
-	}()
-
-	return nil
+	}
 }
 
 // Stop stops the reconciler
 func (a *APIEndpointReconciler) Stop() error {
-	close(a.stopCh)
 	return nil
 }
 
@@ -100,12 +92,6 @@ func (a *APIEndpointReconciler) Stop() error {
 func (a *APIEndpointReconciler) Healthy() error { return nil }
 
 func (a *APIEndpointReconciler) reconcileEndpoints() error {
-
-	if !a.leaderElector.IsLeader() {
-		a.L.Debug("we're not the leader, not reconciling api endpoints")
-		return nil
-	}
-
 	ips, err := net.LookupIP(a.ClusterConfig.Spec.API.ExternalAddress)
 	if err != nil {
 		a.L.Errorf("cannot resolve api.externalAddress: %s", err.Error())
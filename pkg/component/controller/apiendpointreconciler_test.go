@@ -23,7 +23,6 @@ import (
 	"github.com/k0sproject/k0s/pkg/apis/v1beta1"
 	"github.com/stretchr/testify/assert"
 	corev1 "k8s.io/api/core/v1"
-	"k8s.io/apimachinery/pkg/api/errors"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -34,32 +33,6 @@ var expectedAddresses = []string{
 	"185.199.111.153",
 }
 
-func TestBasicReconcilerWithNoLeader(t *testing.T) {
-	fakeFactory := testutil.NewFakeClientFactory()
-
-	config := &v1beta1.ClusterConfig{
-		Spec: &v1beta1.ClusterSpec{
-			API: &v1beta1.APISpec{
-				Address:         "1.2.3.4",
-				ExternalAddress: "get.k0s.sh",
-			},
-		},
-	}
-
-	r := NewEndpointReconciler(config, &DummyLeaderElector{Leader: false}, fakeFactory)
-
-	assert.NoError(t, r.Init())
-
-	assert.NoError(t, r.reconcileEndpoints())
-	client, err := fakeFactory.GetClient()
-	assert.NoError(t, err)
-	_, err = client.CoreV1().Endpoints("default").Get(context.TODO(), "kubernetes", v1.GetOptions{})
-	// The reconciler should not make any modification as we're not the leader so the endpoint should not get created
-	assert.Error(t, err)
-	assert.True(t, errors.IsNotFound(err))
-	//verifyEndpointAddresses(t, expectedAddresses, fakeFactory)
-}
-
 func TestBasicReconcilerWithNoExistingEndpoint(t *testing.T) {
 	fakeFactory := testutil.NewFakeClientFactory()
 	config := &v1beta1.ClusterConfig{
@@ -71,7 +44,7 @@ func TestBasicReconcilerWithNoExistingEndpoint(t *testing.T) {
 		},
 	}
 
-	r := NewEndpointReconciler(config, &DummyLeaderElector{Leader: true}, fakeFactory)
+	r := NewEndpointReconciler(config, fakeFactory)
 
 	assert.NoError(t, r.Init())
 
@@ -105,7 +78,7 @@ func TestBasicReconcilerWithEmptyEndpointSubset(t *testing.T) {
 		},
 	}
 
-	r := NewEndpointReconciler(config, &DummyLeaderElector{Leader: true}, fakeFactory)
+	r := NewEndpointReconciler(config, fakeFactory)
 
 	assert.NoError(t, r.Init())
 
@@ -146,7 +119,7 @@ func TestReconcilerWithNoNeedForUpdate(t *testing.T) {
 			},
 		},
 	}
-	r := NewEndpointReconciler(config, &DummyLeaderElector{Leader: true}, fakeFactory)
+	r := NewEndpointReconciler(config, fakeFactory)
 
 	assert.NoError(t, r.Init())
 
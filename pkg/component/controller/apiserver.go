@@ -23,14 +23,20 @@ import (
 	"net/http"
 	"path"
 	"strings"
+	"sync"
 
 	"github.com/sirupsen/logrus"
+	"gopkg.in/fsnotify.v1"
+	"gopkg.in/yaml.v2"
 
 	"github.com/k0sproject/k0s/internal/util"
 	config "github.com/k0sproject/k0s/pkg/apis/v1beta1"
 	"github.com/k0sproject/k0s/pkg/assets"
 	"github.com/k0sproject/k0s/pkg/component"
 	"github.com/k0sproject/k0s/pkg/constant"
+	"github.com/k0sproject/k0s/pkg/debounce"
+	"github.com/k0sproject/k0s/pkg/encryption"
+	"github.com/k0sproject/k0s/pkg/flags"
 	"github.com/k0sproject/k0s/pkg/supervisor"
 )
 
@@ -41,9 +47,17 @@ type APIServer struct {
 	LogLevel           string
 	Storage            component.Component
 	EnableKonnectivity bool
-	gid                int
-	supervisor         supervisor.Supervisor
-	uid                int
+	// GenerateEncryptionKeys is set on the controller the cluster was initialized with. The encryption keys
+	// must be the same on all the controllers, the joined controllers get them from it.
+	GenerateEncryptionKeys bool
+	gid                    int
+	supervisor             supervisor.Supervisor
+	uid                    int
+
+	// mutex guards the supervisor while kube-apiserver is restarted on encryption key changes
+	mutex               sync.Mutex
+	encryptionWatcher   *fsnotify.Watcher
+	encryptionDebouncer debounce.Debouncer
 }
 
 var apiDefaultArgs = map[string]string{
@@ -82,10 +96,55 @@ func (a *APIServer) Init() error {
 	return assets.Stage(a.K0sVars.BinDir, "kube-apiserver", constant.BinDirMode)
 }
 
-// Run runs kube api
-func (a *APIServer) Run() error {
-	logrus.Info("Starting kube-apiserver")
-	args := map[string]string{
+// apiServerPolicy lists the flags k0s sets that can be replaced with the extra args and the deprecated ones
+var apiServerPolicy = flags.Policy{
+	Component: "kube-apiserver",
+	Replaceable: []string{
+		"allow-privileged",
+		"anonymous-auth",
+		"api-audiences",
+		"authorization-mode",
+		"enable-admission-plugins",
+		"kubelet-preferred-address-types",
+		"profiling",
+		"requestheader-extra-headers-prefix",
+		"requestheader-group-headers",
+		"requestheader-username-headers",
+		"service-account-issuer",
+		"service-account-jwks-uri",
+		"tls-cipher-suites",
+		"v",
+	},
+	Deprecated: map[string]string{
+		"admission-control-config-file":            "spec.api.admission.plugins",
+		"audit-log-format":                         "spec.api.audit.log",
+		"audit-log-maxage":                         "spec.api.audit.log",
+		"audit-log-maxbackup":                      "spec.api.audit.log",
+		"audit-log-maxsize":                        "spec.api.audit.log",
+		"audit-log-path":                           "spec.api.audit.log",
+		"audit-policy-file":                        "spec.api.audit",
+		"audit-webhook-config-file":                "spec.api.audit.webhook",
+		"audit-webhook-mode":                       "spec.api.audit.webhook",
+		"authentication-token-webhook-cache-ttl":   "spec.api.authentication.webhook.cacheTTL",
+		"authentication-token-webhook-config-file": "spec.api.authentication.webhook",
+		"authentication-token-webhook-version":     "spec.api.authentication.webhook.version",
+		"disable-admission-plugins":                "spec.api.admission.disablePlugins",
+		"encryption-provider-config":               "spec.api.encryption",
+		"feature-gates":                            "spec.featureGates",
+		"oidc-ca-file":                             "spec.api.authentication.oidc",
+		"oidc-client-id":                           "spec.api.authentication.oidc.clientID",
+		"oidc-groups-claim":                        "spec.api.authentication.oidc.groupsClaim",
+		"oidc-groups-prefix":                       "spec.api.authentication.oidc.groupsPrefix",
+		"oidc-issuer-url":                          "spec.api.authentication.oidc.issuerURL",
+		"oidc-signing-algs":                        "spec.api.authentication.oidc.signingAlgs",
+		"oidc-username-claim":                      "spec.api.authentication.oidc.usernameClaim",
+		"oidc-username-prefix":                     "spec.api.authentication.oidc.usernamePrefix",
+	},
+}
+
+// Args returns the flags kube-apiserver is run with
+func (a *APIServer) Args() (flags.Args, error) {
+	args := flags.Args{
 		"advertise-address":                a.ClusterConfig.Spec.API.Address,
 		"secure-port":                      fmt.Sprintf("%d", a.ClusterConfig.Spec.API.Port),
 		"authorization-mode":               "Node,RBAC",
@@ -109,41 +168,103 @@ func (a *APIServer) Run() error {
 		"profiling":                        "false",
 		"v":                                a.LogLevel,
 		"kubelet-certificate-authority":    path.Join(a.K0sVars.CertRootDir, "ca.crt"),
-		"enable-admission-plugins":         "NodeRestriction,PodSecurityPolicy",
+	}
+	for name, value := range apiDefaultArgs {
+		if args[name] == "" {
+			args[name] = value
+		}
 	}
 
 	apiAudiences := []string{"https://kubernetes.default.svc"}
-
 	if a.EnableKonnectivity {
-		err := a.writeKonnectivityConfig()
-		if err != nil {
-			return err
-		}
 		args["egress-selector-config-file"] = path.Join(a.K0sVars.DataDir, "konnectivity.conf")
 		apiAudiences = append(apiAudiences, "system:konnectivity-server")
 	}
-
 	args["api-audiences"] = strings.Join(apiAudiences, ",")
 
-	for name, value := range a.ClusterConfig.Spec.API.ExtraArgs {
-		if args[name] != "" && name != "profiling" {
-			return fmt.Errorf("cannot override apiserver flag: %s", name)
+	if a.ClusterConfig.Spec.API.ExternalAddress != "" {
+		args["endpoint-reconciler-type"] = "none"
+	}
+
+	admission := a.ClusterConfig.Spec.API.Admission
+	args["enable-admission-plugins"] = strings.Join(admission.EnabledPlugins(), ",")
+	if admission != nil && len(admission.DisablePlugins) > 0 {
+		args["disable-admission-plugins"] = strings.Join(admission.DisablePlugins, ",")
+	}
+	if admission != nil && len(admission.Plugins) > 0 {
+		args["admission-control-config-file"] = a.admissionConfigPath()
+	}
+	if gates := a.ClusterConfig.Spec.EffectiveFeatureGates(); len(gates) > 0 {
+		args["feature-gates"] = gates.String()
+	}
+	a.addAuditArgs(args)
+	a.addAuthenticationArgs(args)
+	if a.encryptionEnabled() {
+		args["encryption-provider-config"] = encryption.ConfigPath(a.K0sVars.DataDir)
+	}
+
+	switch a.ClusterConfig.Spec.Storage.Type {
+	case config.KineStorageType:
+		args["etcd-servers"] = fmt.Sprintf("unix://%s", a.K0sVars.KineSocketPath) // kine endpoint
+	case config.EtcdStorageType:
+		if etcdConf := a.ClusterConfig.Spec.Storage.Etcd; etcdConf.IsExternalClusterUsed() {
+			ext := etcdConf.ExternalCluster
+			args["etcd-servers"] = strings.Join(ext.Endpoints, ",")
+			if ext.EtcdPrefix != "" {
+				args["etcd-prefix"] = ext.EtcdPrefix
+			}
+			if ext.CaFile != "" {
+				args["etcd-cafile"] = ext.CaFile
+			}
+			if ext.IsTLSEnabled() {
+				args["etcd-certfile"] = ext.ClientCertFile
+				args["etcd-keyfile"] = ext.ClientKeyFile
+			}
+			break
 		}
-		args[name] = value
+		args["etcd-servers"] = a.ClusterConfig.Spec.Storage.Etcd.ClientURL()
+		args["etcd-cafile"] = path.Join(a.K0sVars.CertRootDir, "etcd/ca.crt")
+		args["etcd-certfile"] = path.Join(a.K0sVars.CertRootDir, "apiserver-etcd-client.crt")
+		args["etcd-keyfile"] = path.Join(a.K0sVars.CertRootDir, "apiserver-etcd-client.key")
+	default:
+		return nil, fmt.Errorf("invalid storage type: %s", a.ClusterConfig.Spec.Storage.Type)
 	}
-	a.ClusterConfig.Spec.Network.DualStack.EnableDualStackFeatureGate(args)
 
-	for name, value := range apiDefaultArgs {
-		if args[name] == "" {
-			args[name] = value
+	return apiServerPolicy.Merge(args, a.ClusterConfig.Spec.API.ExtraArgs)
+}
+
+// Run runs kube api
+func (a *APIServer) Run() error {
+	logrus.Info("Starting kube-apiserver")
+	if a.EnableKonnectivity {
+		if err := a.writeKonnectivityConfig(); err != nil {
+			return err
 		}
 	}
-	if a.ClusterConfig.Spec.API.ExternalAddress != "" {
-		args["endpoint-reconciler-type"] = "none"
+	if err := a.writeAdmissionConfig(); err != nil {
+		return err
+	}
+	if err := a.writeAuditConfig(); err != nil {
+		return err
 	}
-	var apiServerArgs []string
-	for name, value := range args {
-		apiServerArgs = append(apiServerArgs, fmt.Sprintf("--%s=%s", name, value))
+	if err := a.writeAuthenticationConfig(); err != nil {
+		return err
+	}
+	encryptionConfig, err := a.encryptionConfig()
+	if err != nil {
+		return err
+	}
+	if encryptionConfig != nil {
+		if err := a.writeEncryptionConfig(encryptionConfig); err != nil {
+			return err
+		}
+	}
+	args, err := a.Args()
+	if err != nil {
+		return err
+	}
+	if err := apiServerPolicy.Validate(assets.BinPath("kube-apiserver", a.K0sVars.BinDir), a.ClusterConfig.Spec.API.ExtraArgs); err != nil {
+		return err
 	}
 
 	a.supervisor = supervisor.Supervisor{
@@ -151,24 +272,17 @@ func (a *APIServer) Run() error {
 		BinPath: assets.BinPath("kube-apiserver", a.K0sVars.BinDir),
 		RunDir:  a.K0sVars.RunDir,
 		DataDir: a.K0sVars.DataDir,
-		Args:    apiServerArgs,
+		Args:    args.ToArgs(),
 		UID:     a.uid,
 		GID:     a.gid,
 	}
-	switch a.ClusterConfig.Spec.Storage.Type {
-	case config.KineStorageType:
-		a.supervisor.Args = append(a.supervisor.Args,
-			fmt.Sprintf("--etcd-servers=unix://%s", a.K0sVars.KineSocketPath)) // kine endpoint
-	case config.EtcdStorageType:
-		a.supervisor.Args = append(a.supervisor.Args,
-			"--etcd-servers=https://127.0.0.1:2379",
-			fmt.Sprintf("--etcd-cafile=%s", path.Join(a.K0sVars.CertRootDir, "etcd/ca.crt")),
-			fmt.Sprintf("--etcd-certfile=%s", path.Join(a.K0sVars.CertRootDir, "apiserver-etcd-client.crt")),
-			fmt.Sprintf("--etcd-keyfile=%s", path.Join(a.K0sVars.CertRootDir, "apiserver-etcd-client.key")))
-	default:
-		return fmt.Errorf("invalid storage type: %s", a.ClusterConfig.Spec.Storage.Type)
+	if err := a.supervisor.Supervise(); err != nil {
+		return err
 	}
-	return a.supervisor.Supervise()
+	if encryptionConfig != nil {
+		return a.watchEncryptionKeys()
+	}
+	return nil
 }
 
 func (a *APIServer) writeKonnectivityConfig() error {
@@ -188,8 +302,49 @@ func (a *APIServer) writeKonnectivityConfig() error {
 	return nil
 }
 
+func (a *APIServer) admissionConfigPath() string {
+	return path.Join(a.K0sVars.DataDir, "admission-config.yaml")
+}
+
+// writeAdmissionConfig renders the configured admission plugins into an AdmissionConfiguration file
+func (a *APIServer) writeAdmissionConfig() error {
+	admission := a.ClusterConfig.Spec.API.Admission
+	if admission == nil || len(admission.Plugins) == 0 {
+		return nil
+	}
+	type plugin struct {
+		Name          string      `yaml:"name"`
+		Configuration interface{} `yaml:"configuration"`
+	}
+	cfg := struct {
+		APIVersion string   `yaml:"apiVersion"`
+		Kind       string   `yaml:"kind"`
+		Plugins    []plugin `yaml:"plugins"`
+	}{
+		APIVersion: "apiserver.config.k8s.io/v1",
+		Kind:       "AdmissionConfiguration",
+	}
+	for _, p := range admission.Plugins {
+		cfg.Plugins = append(cfg.Plugins, plugin{Name: p.Name, Configuration: p.Configuration})
+	}
+	data, err := yaml.Marshal(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to render the admission config: %w", err)
+	}
+	if err := ioutil.WriteFile(a.admissionConfigPath(), data, constant.CertMode); err != nil {
+		return fmt.Errorf("failed to write the admission config: %w", err)
+	}
+	return nil
+}
+
 // Stop stops APIServer
 func (a *APIServer) Stop() error {
+	if a.encryptionDebouncer != nil {
+		a.encryptionDebouncer.Stop()
+		a.encryptionWatcher.Close()
+	}
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
 	return a.supervisor.Stop()
 }
 
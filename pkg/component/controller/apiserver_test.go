@@ -0,0 +1,132 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package controller
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	config "github.com/k0sproject/k0s/pkg/apis/v1beta1"
+	"github.com/k0sproject/k0s/pkg/constant"
+)
+
+func TestAPIServerArgs(t *testing.T) {
+	k0sVars := constant.GetConfig(t.TempDir())
+	newAPIServer := func(extraArgs config.ExtraArgs) *APIServer {
+		cfg := config.DefaultClusterConfig(k0sVars)
+		cfg.Spec.API.ExtraArgs = extraArgs
+		return &APIServer{ClusterConfig: cfg, K0sVars: k0sVars, LogLevel: "1"}
+	}
+
+	t.Run("defaults", func(t *testing.T) {
+		args, err := newAPIServer(nil).Args()
+		require.NoError(t, err)
+		assert.Equal(t, "false", args["profiling"])
+		assert.Equal(t, "6443", args["secure-port"])
+		assert.Equal(t, "https://127.0.0.1:2379", args["etcd-servers"])
+		assert.Equal(t, "NodeRestriction,PodSecurityPolicy", args["enable-admission-plugins"])
+		assert.NotContains(t, args, "feature-gates")
+		assert.NotContains(t, args, "admission-control-config-file")
+	})
+
+	t.Run("admission and feature gates", func(t *testing.T) {
+		api := newAPIServer(nil)
+		api.ClusterConfig.Spec.FeatureGates = config.FeatureGates{"PodSecurity": true}
+		api.ClusterConfig.Spec.API.Admission = &config.AdmissionSpec{
+			EnablePlugins:  []string{"PodSecurity"},
+			DisablePlugins: []string{"PodSecurityPolicy"},
+			Plugins: []config.AdmissionPlugin{{
+				Name: "PodSecurity",
+				Configuration: map[string]interface{}{
+					"apiVersion": "pod-security.admission.config.k8s.io/v1alpha1",
+					"kind":       "PodSecurityConfiguration",
+					"defaults":   map[string]interface{}{"enforce": "baseline"},
+				},
+			}},
+		}
+		args, err := api.Args()
+		require.NoError(t, err)
+		assert.Equal(t, "PodSecurity=true", args["feature-gates"])
+		assert.Equal(t, "NodeRestriction,PodSecurity", args["enable-admission-plugins"])
+		assert.Equal(t, "PodSecurityPolicy", args["disable-admission-plugins"])
+		assert.Equal(t, filepath.Join(k0sVars.DataDir, "admission-config.yaml"), args["admission-control-config-file"])
+
+		require.NoError(t, api.writeAdmissionConfig())
+		data, err := ioutil.ReadFile(args["admission-control-config-file"])
+		require.NoError(t, err)
+		assert.Equal(t, `apiVersion: apiserver.config.k8s.io/v1
+kind: AdmissionConfiguration
+plugins:
+- name: PodSecurity
+  configuration:
+    apiVersion: pod-security.admission.config.k8s.io/v1alpha1
+    defaults:
+      enforce: baseline
+    kind: PodSecurityConfiguration
+`, string(data))
+	})
+
+	t.Run("external etcd cluster", func(t *testing.T) {
+		api := newAPIServer(nil)
+		api.ClusterConfig.Spec.Storage.Etcd.ExternalCluster = &config.ExternalCluster{
+			Endpoints:      []string{"https://etcd-1:2379", "https://etcd-2:2379"},
+			EtcdPrefix:     "k0s-tenant-1",
+			CaFile:         "/etc/pki/etcd/ca.crt",
+			ClientCertFile: "/etc/pki/etcd/client.crt",
+			ClientKeyFile:  "/etc/pki/etcd/client.key",
+		}
+		args, err := api.Args()
+		require.NoError(t, err)
+		assert.Equal(t, "https://etcd-1:2379,https://etcd-2:2379", args["etcd-servers"])
+		assert.Equal(t, "k0s-tenant-1", args["etcd-prefix"])
+		assert.Equal(t, "/etc/pki/etcd/ca.crt", args["etcd-cafile"])
+		assert.Equal(t, "/etc/pki/etcd/client.crt", args["etcd-certfile"])
+		assert.Equal(t, "/etc/pki/etcd/client.key", args["etcd-keyfile"])
+	})
+
+	t.Run("extra args", func(t *testing.T) {
+		args, err := newAPIServer(config.ExtraArgs{
+			"event-ttl": {Value: "2h"},
+			"profiling": {Value: "true", Replace: true},
+		}).Args()
+		require.NoError(t, err)
+		assert.Equal(t, "2h", args["event-ttl"])
+		assert.Equal(t, "true", args["profiling"])
+	})
+
+	t.Run("deprecated flags", func(t *testing.T) {
+		api := newAPIServer(config.ExtraArgs{
+			"enable-admission-plugins": {Value: "NodeRestriction"},
+			"feature-gates":            {Value: "EphemeralContainers=true"},
+			"oidc-issuer-url":          {Value: "https://dex.example.com"},
+		})
+		api.ClusterConfig.Spec.FeatureGates = config.FeatureGates{"PodSecurity": true}
+		args, err := api.Args()
+		require.NoError(t, err)
+		assert.Equal(t, "NodeRestriction", args["enable-admission-plugins"])
+		assert.Equal(t, "EphemeralContainers=true,PodSecurity=true", args["feature-gates"])
+		assert.Equal(t, "https://dex.example.com", args["oidc-issuer-url"])
+	})
+
+	t.Run("managed flags", func(t *testing.T) {
+		_, err := newAPIServer(config.ExtraArgs{"etcd-servers": {Value: "https://10.0.0.1:2379", Replace: true}}).Args()
+		assert.EqualError(t, err, "can't override kube-apiserver flags: etcd-servers is managed by k0s")
+	})
+}
@@ -0,0 +1,184 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package controller
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/k0sproject/k0s/internal/util"
+	config "github.com/k0sproject/k0s/pkg/apis/v1beta1"
+	"github.com/k0sproject/k0s/pkg/constant"
+	"github.com/k0sproject/k0s/pkg/flags"
+)
+
+var auditPresetLevels = map[string]string{
+	config.AuditPresetMetadata:        "Metadata",
+	config.AuditPresetRequest:         "Request",
+	config.AuditPresetRequestResponse: "RequestResponse",
+}
+
+// presetAuditPolicy builds the audit policy of the preset. The health checks are not audited and the
+// secrets, config maps and token reviews are audited at the metadata level not to leak their content.
+func presetAuditPolicy(preset string) map[string]interface{} {
+	rules := []interface{}{
+		map[string]interface{}{
+			"level":           "None",
+			"nonResourceURLs": []string{"/healthz*", "/livez*", "/readyz*", "/version"},
+		},
+	}
+	level := auditPresetLevels[preset]
+	if level != "Metadata" {
+		rules = append(rules, map[string]interface{}{
+			"level": "Metadata",
+			"resources": []interface{}{
+				map[string]interface{}{"group": "", "resources": []string{"secrets", "configmaps"}},
+				map[string]interface{}{"group": "authentication.k8s.io", "resources": []string{"tokenreviews"}},
+			},
+		})
+	}
+	rules = append(rules, map[string]interface{}{"level": level})
+
+	return map[string]interface{}{
+		"apiVersion": "audit.k8s.io/v1",
+		"kind":       "Policy",
+		"omitStages": []string{"RequestReceived"},
+		"rules":      rules,
+	}
+}
+
+// webhookKubeconfigTemplate is the kubeconfig kube-apiserver calls the audit and authentication webhooks with
+const webhookKubeconfigTemplate = `apiVersion: v1
+kind: Config
+clusters:
+- name: {{ .Name }}
+  cluster:
+    server: {{ .Server }}
+{{- if .CAFile }}
+    certificate-authority: {{ .CAFile }}
+{{- end }}
+users:
+- name: kube-apiserver
+  user:
+{{- if .ClientCertFile }}
+    client-certificate: {{ .ClientCertFile }}
+    client-key: {{ .ClientKeyFile }}
+{{- end }}
+contexts:
+- name: default
+  context:
+    cluster: {{ .Name }}
+    user: kube-apiserver
+current-context: default
+`
+
+type webhookKubeconfig struct {
+	Name           string
+	Server         string
+	CAFile         string
+	ClientCertFile string
+	ClientKeyFile  string
+}
+
+func writeWebhookKubeconfig(path string, data webhookKubeconfig) error {
+	tw := util.TemplateWriter{
+		Name:     data.Name,
+		Template: webhookKubeconfigTemplate,
+		Data:     data,
+		Path:     path,
+	}
+	return tw.Write()
+}
+
+func (a *APIServer) auditPolicyPath() string {
+	return path.Join(a.K0sVars.DataDir, "audit-policy.yaml")
+}
+
+func (a *APIServer) auditWebhookConfigPath() string {
+	return path.Join(a.K0sVars.DataDir, "audit-webhook.conf")
+}
+
+// addAuditArgs sets the audit flags of the configured audit backends
+func (a *APIServer) addAuditArgs(args flags.Args) {
+	audit := a.ClusterConfig.Spec.API.Audit
+	if audit == nil {
+		return
+	}
+	args["audit-policy-file"] = a.auditPolicyPath()
+	if l := audit.Log; l != nil {
+		args["audit-log-path"] = l.Path
+		args["audit-log-maxage"] = strconv.Itoa(l.MaxAge)
+		args["audit-log-maxbackup"] = strconv.Itoa(l.MaxBackups)
+		args["audit-log-maxsize"] = strconv.Itoa(l.MaxSize)
+		args["audit-log-format"] = l.Format
+	}
+	if w := audit.Webhook; w != nil {
+		args["audit-webhook-config-file"] = a.auditWebhookConfigPath()
+		if w.Mode != "" {
+			args["audit-webhook-mode"] = w.Mode
+		}
+	}
+}
+
+// writeAuditConfig renders the audit policy and the webhook kubeconfig and creates the log directory
+func (a *APIServer) writeAuditConfig() error {
+	audit := a.ClusterConfig.Spec.API.Audit
+	if audit == nil {
+		return nil
+	}
+
+	policy := audit.Policy
+	if policy == nil {
+		policy = presetAuditPolicy(audit.PolicyPreset())
+	}
+	data, err := yaml.Marshal(policy)
+	if err != nil {
+		return fmt.Errorf("failed to render the audit policy: %w", err)
+	}
+	if err := ioutil.WriteFile(a.auditPolicyPath(), data, constant.CertMode); err != nil {
+		return fmt.Errorf("failed to write the audit policy: %w", err)
+	}
+
+	if audit.Log != nil {
+		logDir := filepath.Dir(audit.Log.Path)
+		if err := util.InitDirectory(logDir, constant.AuditLogDirMode); err != nil {
+			return fmt.Errorf("failed to create the audit log dir: %w", err)
+		}
+		if err := os.Chown(logDir, a.uid, a.gid); err != nil && os.Geteuid() == 0 {
+			return fmt.Errorf("failed to chown the audit log dir: %w", err)
+		}
+	}
+
+	if audit.Webhook != nil {
+		w := audit.Webhook
+		if err := writeWebhookKubeconfig(a.auditWebhookConfigPath(), webhookKubeconfig{
+			Name:           "audit-webhook",
+			Server:         w.Server,
+			CAFile:         w.CAFile,
+			ClientCertFile: w.ClientCertFile,
+			ClientKeyFile:  w.ClientKeyFile,
+		}); err != nil {
+			return fmt.Errorf("failed to write the audit webhook config: %w", err)
+		}
+	}
+	return nil
+}
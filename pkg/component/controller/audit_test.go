@@ -0,0 +1,95 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package controller
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	config "github.com/k0sproject/k0s/pkg/apis/v1beta1"
+	"github.com/k0sproject/k0s/pkg/constant"
+)
+
+func TestAPIServerAudit(t *testing.T) {
+	k0sVars := constant.GetConfig(t.TempDir())
+	cfg := config.DefaultClusterConfig(k0sVars)
+	logPath := filepath.Join(t.TempDir(), "audit", "audit.log")
+	cfg.Spec.API.Audit = &config.AuditSpec{
+		Preset: config.AuditPresetRequest,
+		Log:    &config.AuditLogSpec{Path: logPath, MaxAge: 7, MaxBackups: 3, MaxSize: 50, Format: "json"},
+		Webhook: &config.AuditWebhookSpec{
+			Server: "https://audit.example.com/events",
+			CAFile: "/etc/audit/ca.crt",
+			Mode:   "blocking",
+		},
+	}
+	api := &APIServer{ClusterConfig: cfg, K0sVars: k0sVars, LogLevel: "1"}
+
+	args, err := api.Args()
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(k0sVars.DataDir, "audit-policy.yaml"), args["audit-policy-file"])
+	assert.Equal(t, logPath, args["audit-log-path"])
+	assert.Equal(t, "7", args["audit-log-maxage"])
+	assert.Equal(t, "3", args["audit-log-maxbackup"])
+	assert.Equal(t, "50", args["audit-log-maxsize"])
+	assert.Equal(t, "json", args["audit-log-format"])
+	assert.Equal(t, filepath.Join(k0sVars.DataDir, "audit-webhook.conf"), args["audit-webhook-config-file"])
+	assert.Equal(t, "blocking", args["audit-webhook-mode"])
+
+	require.NoError(t, api.writeAuditConfig())
+	assert.DirExists(t, filepath.Dir(logPath))
+
+	policy, err := ioutil.ReadFile(args["audit-policy-file"])
+	require.NoError(t, err)
+	assert.Equal(t, `apiVersion: audit.k8s.io/v1
+kind: Policy
+omitStages:
+- RequestReceived
+rules:
+- level: None
+  nonResourceURLs:
+  - /healthz*
+  - /livez*
+  - /readyz*
+  - /version
+- level: Metadata
+  resources:
+  - group: ""
+    resources:
+    - secrets
+    - configmaps
+  - group: authentication.k8s.io
+    resources:
+    - tokenreviews
+- level: Request
+`, string(policy))
+
+	webhook, err := ioutil.ReadFile(args["audit-webhook-config-file"])
+	require.NoError(t, err)
+	assert.Contains(t, string(webhook), "    server: https://audit.example.com/events\n    certificate-authority: /etc/audit/ca.crt\n")
+	assert.NotContains(t, string(webhook), "client-certificate")
+}
+
+func TestPresetAuditPolicyMetadata(t *testing.T) {
+	policy := presetAuditPolicy(config.AuditPresetMetadata)
+	rules := policy["rules"].([]interface{})
+	require.Len(t, rules, 2)
+	assert.Equal(t, map[string]interface{}{"level": "Metadata"}, rules[1])
+}
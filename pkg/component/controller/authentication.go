@@ -0,0 +1,99 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package controller
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path"
+	"strings"
+
+	"github.com/k0sproject/k0s/pkg/constant"
+	"github.com/k0sproject/k0s/pkg/flags"
+)
+
+func (a *APIServer) oidcCAPath() string {
+	return path.Join(a.K0sVars.DataDir, "oidc-ca.crt")
+}
+
+func (a *APIServer) authenticationWebhookConfigPath() string {
+	return path.Join(a.K0sVars.DataDir, "authentication-webhook.conf")
+}
+
+// addAuthenticationArgs sets the flags of the configured OIDC and webhook token authenticators
+func (a *APIServer) addAuthenticationArgs(args flags.Args) {
+	authn := a.ClusterConfig.Spec.API.Authentication
+	if authn == nil {
+		return
+	}
+	if o := authn.OIDC; o != nil {
+		args["oidc-issuer-url"] = o.IssuerURL
+		args["oidc-client-id"] = o.ClientID
+		for name, value := range map[string]string{
+			"oidc-username-claim":  o.UsernameClaim,
+			"oidc-username-prefix": o.UsernamePrefix,
+			"oidc-groups-claim":    o.GroupsClaim,
+			"oidc-groups-prefix":   o.GroupsPrefix,
+			"oidc-signing-algs":    strings.Join(o.SigningAlgs, ","),
+		} {
+			if value != "" {
+				args[name] = value
+			}
+		}
+		switch {
+		case o.CA != "":
+			args["oidc-ca-file"] = a.oidcCAPath()
+		case o.CAFile != "":
+			args["oidc-ca-file"] = o.CAFile
+		}
+	}
+	if w := authn.Webhook; w != nil {
+		args["authentication-token-webhook-config-file"] = a.authenticationWebhookConfigPath()
+		if w.CacheTTL != "" {
+			args["authentication-token-webhook-cache-ttl"] = w.CacheTTL
+		}
+		if w.Version != "" {
+			args["authentication-token-webhook-version"] = w.Version
+		}
+	}
+}
+
+// writeAuthenticationConfig writes the inline OIDC issuer CA and the authentication webhook kubeconfig
+func (a *APIServer) writeAuthenticationConfig() error {
+	authn := a.ClusterConfig.Spec.API.Authentication
+	if authn == nil {
+		return nil
+	}
+
+	if o := authn.OIDC; o != nil && o.CA != "" {
+		if err := ioutil.WriteFile(a.oidcCAPath(), []byte(o.CA), constant.CertMode); err != nil {
+			return fmt.Errorf("failed to write the OIDC issuer CA: %w", err)
+		}
+	}
+
+	if w := authn.Webhook; w != nil {
+		if err := writeWebhookKubeconfig(a.authenticationWebhookConfigPath(), webhookKubeconfig{
+			Name:           "authentication-webhook",
+			Server:         w.Server,
+			CAFile:         w.CAFile,
+			ClientCertFile: w.ClientCertFile,
+			ClientKeyFile:  w.ClientKeyFile,
+		}); err != nil {
+			return fmt.Errorf("failed to write the authentication webhook config: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package controller
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	config "github.com/k0sproject/k0s/pkg/apis/v1beta1"
+	"github.com/k0sproject/k0s/pkg/constant"
+)
+
+func TestAPIServerAuthentication(t *testing.T) {
+	k0sVars := constant.GetConfig(t.TempDir())
+	cfg := config.DefaultClusterConfig(k0sVars)
+	cfg.Spec.API.Authentication = &config.AuthenticationSpec{
+		OIDC: &config.OIDCSpec{
+			IssuerURL:     "https://dex.example.com",
+			ClientID:      "k0s",
+			UsernameClaim: "email",
+			GroupsClaim:   "groups",
+			GroupsPrefix:  "oidc:",
+			SigningAlgs:   []string{"RS256", "ES256"},
+			CA:            "-----BEGIN CERTIFICATE-----\n",
+		},
+		Webhook: &config.AuthenticationWebhookSpec{
+			Server:         "https://authn.example.com/tokenreview",
+			ClientCertFile: "/etc/authn/client.crt",
+			ClientKeyFile:  "/etc/authn/client.key",
+			CacheTTL:       "30s",
+		},
+	}
+	api := &APIServer{ClusterConfig: cfg, K0sVars: k0sVars, LogLevel: "1"}
+
+	args, err := api.Args()
+	require.NoError(t, err)
+	assert.Equal(t, "https://dex.example.com", args["oidc-issuer-url"])
+	assert.Equal(t, "k0s", args["oidc-client-id"])
+	assert.Equal(t, "email", args["oidc-username-claim"])
+	assert.NotContains(t, args, "oidc-username-prefix")
+	assert.Equal(t, "groups", args["oidc-groups-claim"])
+	assert.Equal(t, "oidc:", args["oidc-groups-prefix"])
+	assert.Equal(t, "RS256,ES256", args["oidc-signing-algs"])
+	assert.Equal(t, filepath.Join(k0sVars.DataDir, "oidc-ca.crt"), args["oidc-ca-file"])
+	assert.Equal(t, filepath.Join(k0sVars.DataDir, "authentication-webhook.conf"), args["authentication-token-webhook-config-file"])
+	assert.Equal(t, "30s", args["authentication-token-webhook-cache-ttl"])
+	assert.NotContains(t, args, "authentication-token-webhook-version")
+
+	require.NoError(t, api.writeAuthenticationConfig())
+	ca, err := ioutil.ReadFile(args["oidc-ca-file"])
+	require.NoError(t, err)
+	assert.Equal(t, "-----BEGIN CERTIFICATE-----\n", string(ca))
+	webhook, err := ioutil.ReadFile(args["authentication-token-webhook-config-file"])
+	require.NoError(t, err)
+	assert.Contains(t, string(webhook), "- name: authentication-webhook\n  cluster:\n    server: https://authn.example.com/tokenreview\n")
+	assert.Contains(t, string(webhook), "    client-certificate: /etc/authn/client.crt\n    client-key: /etc/authn/client.key\n")
+
+	cfg.Spec.API.Authentication.OIDC.CA = ""
+	cfg.Spec.API.Authentication.OIDC.CAFile = "/etc/dex/ca.crt"
+	args, err = api.Args()
+	require.NoError(t, err)
+	assert.Equal(t, "/etc/dex/ca.crt", args["oidc-ca-file"])
+}
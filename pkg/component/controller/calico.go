@@ -20,6 +20,7 @@ import (
 	"fmt"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/k0sproject/k0s/static"
@@ -33,6 +34,7 @@ import (
 // Calico is the Component interface implementation to manage Calico
 type Calico struct {
 	clusterConf *config.ClusterConfig
+	configMu    sync.Mutex
 	tickerDone  chan struct{}
 	log         *logrus.Entry
 
@@ -197,6 +199,9 @@ func (c *Calico) processConfigChanges(previousConfig calicoConfig) *calicoConfig
 }
 
 func (c *Calico) getConfig() (calicoConfig, error) {
+	c.configMu.Lock()
+	defer c.configMu.Unlock()
+
 	ipv6AutoDetectionMethod := c.clusterConf.Spec.Network.Calico.IPAutodetectionMethod
 	if c.clusterConf.Spec.Network.Calico.IPv6AutodetectionMethod != "" {
 		ipv6AutoDetectionMethod = c.clusterConf.Spec.Network.Calico.IPv6AutodetectionMethod
@@ -224,6 +229,14 @@ func (c *Calico) getConfig() (calicoConfig, error) {
 	return config, nil
 }
 
+// Reconcile applies the changed cluster config on the next reconciliation round
+func (c *Calico) Reconcile(cfg *config.ClusterConfig) error {
+	c.configMu.Lock()
+	defer c.configMu.Unlock()
+	c.clusterConf = cfg
+	return nil
+}
+
 // Stop stops the calico reconciler
 func (c *Calico) Stop() error {
 	if c.tickerDone != nil {
@@ -198,7 +198,7 @@ func (c *Certificates) Init() error {
 		"kubernetes.default",
 		"kubernetes.default.svc",
 		"kubernetes.default.svc.cluster",
-		"kubernetes.svc.cluster.local",
+		"kubernetes.svc." + c.ClusterSpec.Network.ClusterDomain,
 		"127.0.0.1",
 		"localhost",
 	}
@@ -0,0 +1,794 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package controller
+
+import (
+	"bytes"
+	"strconv"
+	"sync"
+
+	"github.com/k0sproject/k0s/internal/util"
+	config "github.com/k0sproject/k0s/pkg/apis/v1beta1"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// Cilium implements the cilium reconciler component
+type Cilium struct {
+	clusterConf *config.ClusterConfig
+	configMu    sync.Mutex
+	log         *logrus.Entry
+
+	saver manifestsSaver
+}
+
+type ciliumConfig struct {
+	Image            string
+	OperatorImage    string
+	HubbleRelayImage string
+	PullPolicy       string
+
+	Tunnel                string
+	NativeRoutingCIDR     string
+	IPv6NativeRoutingCIDR string
+	EnableIPv6            bool
+	MTU                   int
+	KubeProxyReplacement  bool
+	APIServerHost         string
+	APIServerPort         string
+	Hubble                bool
+}
+
+// NewCilium creates new Cilium reconciler component
+func NewCilium(clusterConf *config.ClusterConfig, manifestsSaver manifestsSaver) (*Cilium, error) {
+	return &Cilium{
+		clusterConf: clusterConf,
+		saver:       manifestsSaver,
+		log:         logrus.WithFields(logrus.Fields{"component": "cilium"}),
+	}, nil
+}
+
+// Init does nothing
+func (c *Cilium) Init() error { return nil }
+
+// Healthy is a no-op check
+func (c *Cilium) Healthy() error { return nil }
+
+// Stop no-op as nothing running
+func (c *Cilium) Stop() error { return nil }
+
+// Run runs the cilium reconciler
+func (c *Cilium) Run() error {
+	c.configMu.Lock()
+	defer c.configMu.Unlock()
+	return c.writeManifests()
+}
+
+// Reconcile re-renders the manifests with the changed cluster config
+func (c *Cilium) Reconcile(cfg *config.ClusterConfig) error {
+	c.configMu.Lock()
+	defer c.configMu.Unlock()
+	c.clusterConf = cfg
+	return c.writeManifests()
+}
+
+func (c *Cilium) getConfig() ciliumConfig {
+	network := c.clusterConf.Spec.Network
+	cilium := network.Cilium
+	if cilium == nil {
+		cilium = config.DefaultCilium()
+	}
+
+	cfg := ciliumConfig{
+		Image:                c.clusterConf.Spec.Images.Cilium.Agent.URI(),
+		OperatorImage:        c.clusterConf.Spec.Images.Cilium.Operator.URI(),
+		HubbleRelayImage:     c.clusterConf.Spec.Images.Cilium.HubbleRelay.URI(),
+		PullPolicy:           c.clusterConf.Spec.Images.DefaultPullPolicy,
+		Tunnel:               cilium.TunnelProtocol,
+		EnableIPv6:           network.DualStack.Enabled,
+		MTU:                  cilium.MTU,
+		KubeProxyReplacement: cilium.KubeProxyReplacement,
+		APIServerHost:        c.clusterConf.Spec.API.APIAddress(),
+		APIServerPort:        strconv.Itoa(c.clusterConf.Spec.API.Port),
+		Hubble:               cilium.Hubble,
+	}
+	if cilium.RoutingMode == config.CiliumRoutingNative {
+		cfg.Tunnel = "disabled"
+		cfg.NativeRoutingCIDR = network.PodCIDR
+		if network.DualStack.Enabled {
+			cfg.IPv6NativeRoutingCIDR = network.DualStack.IPv6PodCIDR
+		}
+	}
+	return cfg
+}
+
+// writeManifests renders and saves the manifests, the caller must hold the config lock
+func (c *Cilium) writeManifests() error {
+	c.log.Info("starting to dump manifests")
+
+	output := bytes.NewBuffer([]byte{})
+	tw := util.TemplateWriter{
+		Name:     "cilium",
+		Template: ciliumTemplate,
+		Data:     c.getConfig(),
+	}
+	if err := tw.WriteToBuffer(output); err != nil {
+		return errors.Wrap(err, "error writing cilium manifests, will NOT retry")
+	}
+	if err := c.saver.Save("cilium.yaml", output.Bytes()); err != nil {
+		return errors.Wrap(err, "error writing cilium manifests, will NOT retry")
+	}
+	return nil
+}
+
+const ciliumTemplate = `---
+apiVersion: v1
+kind: ServiceAccount
+metadata:
+  name: cilium
+  namespace: kube-system
+---
+apiVersion: v1
+kind: ServiceAccount
+metadata:
+  name: cilium-operator
+  namespace: kube-system
+{{- if .Hubble }}
+---
+apiVersion: v1
+kind: ServiceAccount
+metadata:
+  name: hubble-relay
+  namespace: kube-system
+{{- end }}
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: cilium-config
+  namespace: kube-system
+data:
+  identity-allocation-mode: crd
+  cilium-endpoint-gc-interval: "5m0s"
+  debug: "false"
+  enable-ipv4: "true"
+  enable-ipv6: "{{ .EnableIPv6 }}"
+  enable-bpf-clock-probe: "true"
+  monitor-aggregation: medium
+  monitor-aggregation-interval: 5s
+  monitor-aggregation-flags: all
+  bpf-map-dynamic-size-ratio: "0.0025"
+  bpf-policy-map-max: "16384"
+  bpf-lb-map-max: "65536"
+  preallocate-bpf-maps: "false"
+  cluster-name: default
+  tunnel: {{ .Tunnel }}
+  {{- if .NativeRoutingCIDR }}
+  ipv4-native-routing-cidr: {{ .NativeRoutingCIDR }}
+  auto-direct-node-routes: "true"
+  {{- end }}
+  {{- if .IPv6NativeRoutingCIDR }}
+  ipv6-native-routing-cidr: {{ .IPv6NativeRoutingCIDR }}
+  {{- end }}
+  {{- if .MTU }}
+  mtu: "{{ .MTU }}"
+  {{- end }}
+  enable-ipv4-masquerade: "true"
+  enable-ipv6-masquerade: "{{ .EnableIPv6 }}"
+  enable-bpf-masquerade: "false"
+  enable-xt-socket-fallback: "true"
+  install-iptables-rules: "true"
+  install-no-conntrack-iptables-rules: "false"
+  {{- if .KubeProxyReplacement }}
+  kube-proxy-replacement: strict
+  enable-health-check-nodeport: "true"
+  node-port-bind-protection: "true"
+  enable-auto-protect-node-port-range: "true"
+  enable-session-affinity: "true"
+  {{- else }}
+  kube-proxy-replacement: disabled
+  {{- end }}
+  enable-endpoint-health-checking: "true"
+  enable-health-checking: "true"
+  enable-well-known-identities: "false"
+  enable-remote-node-identity: "true"
+  operator-api-serve-addr: "127.0.0.1:9234"
+  ipam: kubernetes
+  disable-cnp-status-updates: "true"
+  {{- if .Hubble }}
+  enable-hubble: "true"
+  hubble-socket-path: /var/run/cilium/hubble.sock
+  hubble-listen-address: ":4244"
+  hubble-disable-tls: "true"
+  {{- end }}
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRole
+metadata:
+  name: cilium
+rules:
+- apiGroups:
+  - networking.k8s.io
+  resources:
+  - networkpolicies
+  verbs:
+  - get
+  - list
+  - watch
+- apiGroups:
+  - discovery.k8s.io
+  resources:
+  - endpointslices
+  verbs:
+  - get
+  - list
+  - watch
+- apiGroups:
+  - ""
+  resources:
+  - namespaces
+  - services
+  - nodes
+  - endpoints
+  verbs:
+  - get
+  - list
+  - watch
+- apiGroups:
+  - ""
+  resources:
+  - pods
+  - pods/finalizers
+  verbs:
+  - get
+  - list
+  - watch
+  - update
+  - delete
+- apiGroups:
+  - ""
+  resources:
+  - nodes
+  verbs:
+  - get
+  - list
+  - watch
+  - update
+- apiGroups:
+  - ""
+  resources:
+  - nodes
+  - nodes/status
+  verbs:
+  - patch
+- apiGroups:
+  - apiextensions.k8s.io
+  resources:
+  - customresourcedefinitions
+  verbs:
+  - create
+  - list
+  - watch
+  - update
+  - get
+- apiGroups:
+  - cilium.io
+  resources:
+  - ciliumnetworkpolicies
+  - ciliumnetworkpolicies/status
+  - ciliumnetworkpolicies/finalizers
+  - ciliumclusterwidenetworkpolicies
+  - ciliumclusterwidenetworkpolicies/status
+  - ciliumclusterwidenetworkpolicies/finalizers
+  - ciliumendpoints
+  - ciliumendpoints/status
+  - ciliumendpoints/finalizers
+  - ciliumnodes
+  - ciliumnodes/status
+  - ciliumnodes/finalizers
+  - ciliumidentities
+  - ciliumidentities/finalizers
+  - ciliumlocalredirectpolicies
+  - ciliumlocalredirectpolicies/status
+  - ciliumlocalredirectpolicies/finalizers
+  - ciliumegressnatpolicies
+  verbs:
+  - '*'
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRole
+metadata:
+  name: cilium-operator
+rules:
+- apiGroups:
+  - ""
+  resources:
+  - pods
+  verbs:
+  - get
+  - list
+  - watch
+  - delete
+- apiGroups:
+  - discovery.k8s.io
+  resources:
+  - endpointslices
+  verbs:
+  - get
+  - list
+  - watch
+- apiGroups:
+  - ""
+  resources:
+  - services
+  - endpoints
+  - namespaces
+  - nodes
+  verbs:
+  - get
+  - list
+  - watch
+- apiGroups:
+  - cilium.io
+  resources:
+  - ciliumnetworkpolicies
+  - ciliumnetworkpolicies/status
+  - ciliumnetworkpolicies/finalizers
+  - ciliumclusterwidenetworkpolicies
+  - ciliumclusterwidenetworkpolicies/status
+  - ciliumclusterwidenetworkpolicies/finalizers
+  - ciliumendpoints
+  - ciliumendpoints/status
+  - ciliumendpoints/finalizers
+  - ciliumnodes
+  - ciliumnodes/status
+  - ciliumnodes/finalizers
+  - ciliumidentities
+  - ciliumidentities/status
+  - ciliumidentities/finalizers
+  - ciliumlocalredirectpolicies
+  - ciliumlocalredirectpolicies/status
+  - ciliumlocalredirectpolicies/finalizers
+  verbs:
+  - '*'
+- apiGroups:
+  - apiextensions.k8s.io
+  resources:
+  - customresourcedefinitions
+  verbs:
+  - create
+  - get
+  - list
+  - update
+  - watch
+- apiGroups:
+  - coordination.k8s.io
+  resources:
+  - leases
+  verbs:
+  - create
+  - get
+  - update
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRoleBinding
+metadata:
+  name: cilium
+roleRef:
+  apiGroup: rbac.authorization.k8s.io
+  kind: ClusterRole
+  name: cilium
+subjects:
+- kind: ServiceAccount
+  name: cilium
+  namespace: kube-system
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRoleBinding
+metadata:
+  name: cilium-operator
+roleRef:
+  apiGroup: rbac.authorization.k8s.io
+  kind: ClusterRole
+  name: cilium-operator
+subjects:
+- kind: ServiceAccount
+  name: cilium-operator
+  namespace: kube-system
+---
+apiVersion: apps/v1
+kind: DaemonSet
+metadata:
+  labels:
+    k8s-app: cilium
+  name: cilium
+  namespace: kube-system
+spec:
+  selector:
+    matchLabels:
+      k8s-app: cilium
+  updateStrategy:
+    rollingUpdate:
+      maxUnavailable: 2
+    type: RollingUpdate
+  template:
+    metadata:
+      labels:
+        k8s-app: cilium
+    spec:
+      affinity:
+        nodeAffinity:
+          requiredDuringSchedulingIgnoredDuringExecution:
+            nodeSelectorTerms:
+            - matchExpressions:
+              - key: kubernetes.io/os
+                operator: In
+                values:
+                - linux
+      containers:
+      - name: cilium-agent
+        image: {{ .Image }}
+        imagePullPolicy: {{ .PullPolicy }}
+        command:
+        - cilium-agent
+        args:
+        - --config-dir=/tmp/cilium/config-map
+        env:
+        - name: K8S_NODE_NAME
+          valueFrom:
+            fieldRef:
+              apiVersion: v1
+              fieldPath: spec.nodeName
+        - name: CILIUM_K8S_NAMESPACE
+          valueFrom:
+            fieldRef:
+              apiVersion: v1
+              fieldPath: metadata.namespace
+        - name: CILIUM_CLUSTERMESH_CONFIG
+          value: /var/lib/cilium/clustermesh/
+        - name: CILIUM_CNI_CHAINING_MODE
+          valueFrom:
+            configMapKeyRef:
+              key: cni-chaining-mode
+              name: cilium-config
+              optional: true
+        - name: CILIUM_CUSTOM_CNI_CONF
+          valueFrom:
+            configMapKeyRef:
+              key: custom-cni-conf
+              name: cilium-config
+              optional: true
+        {{- if .KubeProxyReplacement }}
+        - name: KUBERNETES_SERVICE_HOST
+          value: "{{ .APIServerHost }}"
+        - name: KUBERNETES_SERVICE_PORT
+          value: "{{ .APIServerPort }}"
+        {{- end }}
+        lifecycle:
+          postStart:
+            exec:
+              command:
+              - /cni-install.sh
+              - --enable-debug=false
+              - --cni-exclusive=true
+          preStop:
+            exec:
+              command:
+              - /cni-uninstall.sh
+        livenessProbe:
+          httpGet:
+            host: 127.0.0.1
+            path: /healthz
+            port: 9876
+            scheme: HTTP
+            httpHeaders:
+            - name: brief
+              value: "true"
+          failureThreshold: 10
+          periodSeconds: 30
+          successThreshold: 1
+          timeoutSeconds: 5
+        readinessProbe:
+          httpGet:
+            host: 127.0.0.1
+            path: /healthz
+            port: 9876
+            scheme: HTTP
+            httpHeaders:
+            - name: brief
+              value: "true"
+          failureThreshold: 3
+          initialDelaySeconds: 5
+          periodSeconds: 30
+          successThreshold: 1
+          timeoutSeconds: 5
+        startupProbe:
+          httpGet:
+            host: 127.0.0.1
+            path: /healthz
+            port: 9876
+            scheme: HTTP
+            httpHeaders:
+            - name: brief
+              value: "true"
+          failureThreshold: 105
+          periodSeconds: 2
+          successThreshold: 1
+        securityContext:
+          capabilities:
+            add:
+            - NET_ADMIN
+            - SYS_MODULE
+          privileged: true
+        volumeMounts:
+        - mountPath: /sys/fs/bpf
+          name: bpf-maps
+          mountPropagation: Bidirectional
+        - mountPath: /var/run/cilium
+          name: cilium-run
+        - mountPath: /host/opt/cni/bin
+          name: cni-path
+        - mountPath: /host/etc/cni/net.d
+          name: etc-cni-netd
+        - mountPath: /var/lib/cilium/clustermesh
+          name: clustermesh-secrets
+          readOnly: true
+        - mountPath: /tmp/cilium/config-map
+          name: cilium-config-path
+          readOnly: true
+        - mountPath: /lib/modules
+          name: lib-modules
+          readOnly: true
+        - mountPath: /run/xtables.lock
+          name: xtables-lock
+      hostNetwork: true
+      initContainers:
+      - name: clean-cilium-state
+        image: {{ .Image }}
+        imagePullPolicy: {{ .PullPolicy }}
+        command:
+        - /init-container.sh
+        env:
+        - name: CILIUM_ALL_STATE
+          valueFrom:
+            configMapKeyRef:
+              key: clean-cilium-state
+              name: cilium-config
+              optional: true
+        - name: CILIUM_BPF_STATE
+          valueFrom:
+            configMapKeyRef:
+              key: clean-cilium-bpf-state
+              name: cilium-config
+              optional: true
+        {{- if .KubeProxyReplacement }}
+        - name: KUBERNETES_SERVICE_HOST
+          value: "{{ .APIServerHost }}"
+        - name: KUBERNETES_SERVICE_PORT
+          value: "{{ .APIServerPort }}"
+        {{- end }}
+        securityContext:
+          capabilities:
+            add:
+            - NET_ADMIN
+          privileged: true
+        volumeMounts:
+        - mountPath: /sys/fs/bpf
+          name: bpf-maps
+        - mountPath: /var/run/cilium
+          name: cilium-run
+        resources:
+          requests:
+            cpu: 100m
+            memory: 100Mi
+      priorityClassName: system-node-critical
+      restartPolicy: Always
+      serviceAccountName: cilium
+      terminationGracePeriodSeconds: 1
+      tolerations:
+      - operator: Exists
+      volumes:
+      - name: cilium-run
+        hostPath:
+          path: /var/run/cilium
+          type: DirectoryOrCreate
+      - name: bpf-maps
+        hostPath:
+          path: /sys/fs/bpf
+          type: DirectoryOrCreate
+      - name: cni-path
+        hostPath:
+          path: /opt/cni/bin
+          type: DirectoryOrCreate
+      - name: etc-cni-netd
+        hostPath:
+          path: /etc/cni/net.d
+          type: DirectoryOrCreate
+      - name: lib-modules
+        hostPath:
+          path: /lib/modules
+      - name: xtables-lock
+        hostPath:
+          path: /run/xtables.lock
+          type: FileOrCreate
+      - name: clustermesh-secrets
+        secret:
+          defaultMode: 420
+          optional: true
+          secretName: cilium-clustermesh
+      - name: cilium-config-path
+        configMap:
+          name: cilium-config
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  labels:
+    io.cilium/app: operator
+    name: cilium-operator
+  name: cilium-operator
+  namespace: kube-system
+spec:
+  replicas: 1
+  selector:
+    matchLabels:
+      io.cilium/app: operator
+      name: cilium-operator
+  strategy:
+    rollingUpdate:
+      maxSurge: 1
+      maxUnavailable: 1
+    type: RollingUpdate
+  template:
+    metadata:
+      labels:
+        io.cilium/app: operator
+        name: cilium-operator
+    spec:
+      containers:
+      - name: cilium-operator
+        image: {{ .OperatorImage }}
+        imagePullPolicy: {{ .PullPolicy }}
+        command:
+        - cilium-operator-generic
+        args:
+        - --config-dir=/tmp/cilium/config-map
+        - --debug=$(CILIUM_DEBUG)
+        env:
+        - name: K8S_NODE_NAME
+          valueFrom:
+            fieldRef:
+              apiVersion: v1
+              fieldPath: spec.nodeName
+        - name: CILIUM_K8S_NAMESPACE
+          valueFrom:
+            fieldRef:
+              apiVersion: v1
+              fieldPath: metadata.namespace
+        - name: CILIUM_DEBUG
+          valueFrom:
+            configMapKeyRef:
+              key: debug
+              name: cilium-config
+              optional: true
+        {{- if .KubeProxyReplacement }}
+        - name: KUBERNETES_SERVICE_HOST
+          value: "{{ .APIServerHost }}"
+        - name: KUBERNETES_SERVICE_PORT
+          value: "{{ .APIServerPort }}"
+        {{- end }}
+        livenessProbe:
+          httpGet:
+            host: 127.0.0.1
+            path: /healthz
+            port: 9234
+            scheme: HTTP
+          initialDelaySeconds: 60
+          periodSeconds: 10
+          timeoutSeconds: 3
+        volumeMounts:
+        - mountPath: /tmp/cilium/config-map
+          name: cilium-config-path
+          readOnly: true
+      hostNetwork: true
+      restartPolicy: Always
+      priorityClassName: system-cluster-critical
+      serviceAccountName: cilium-operator
+      tolerations:
+      - operator: Exists
+      volumes:
+      - name: cilium-config-path
+        configMap:
+          name: cilium-config
+{{- if .Hubble }}
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: hubble-relay
+  namespace: kube-system
+  labels:
+    k8s-app: hubble-relay
+spec:
+  type: ClusterIP
+  selector:
+    k8s-app: hubble-relay
+  ports:
+  - protocol: TCP
+    port: 80
+    targetPort: 4245
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: hubble-relay
+  namespace: kube-system
+  labels:
+    k8s-app: hubble-relay
+spec:
+  replicas: 1
+  selector:
+    matchLabels:
+      k8s-app: hubble-relay
+  template:
+    metadata:
+      labels:
+        k8s-app: hubble-relay
+    spec:
+      affinity:
+        podAffinity:
+          requiredDuringSchedulingIgnoredDuringExecution:
+          - labelSelector:
+              matchExpressions:
+              - key: k8s-app
+                operator: In
+                values:
+                - cilium
+            topologyKey: kubernetes.io/hostname
+      containers:
+      - name: hubble-relay
+        image: {{ .HubbleRelayImage }}
+        imagePullPolicy: {{ .PullPolicy }}
+        command:
+        - hubble-relay
+        args:
+        - serve
+        - --peer-service=unix:///var/run/cilium/hubble.sock
+        - --listen-address=:4245
+        - --disable-client-tls
+        - --disable-server-tls
+        ports:
+        - name: grpc
+          containerPort: 4245
+        readinessProbe:
+          tcpSocket:
+            port: grpc
+        livenessProbe:
+          tcpSocket:
+            port: grpc
+        volumeMounts:
+        - mountPath: /var/run/cilium
+          name: hubble-sock-dir
+          readOnly: true
+      restartPolicy: Always
+      serviceAccountName: hubble-relay
+      volumes:
+      - name: hubble-sock-dir
+        hostPath:
+          path: /var/run/cilium
+          type: Directory
+{{- end }}
+`
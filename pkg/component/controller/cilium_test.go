@@ -0,0 +1,131 @@
+package controller
+
+import (
+	"testing"
+
+	"github.com/k0sproject/k0s/internal/testutil"
+	"github.com/k0sproject/k0s/pkg/apis/v1beta1"
+	"github.com/k0sproject/k0s/pkg/constant"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func ciliumClusterConfig() *v1beta1.ClusterConfig {
+	cfg := v1beta1.DefaultClusterConfig(constant.CfgVars{})
+	cfg.Spec.Network.Calico = nil
+	cfg.Spec.Network.Provider = "cilium"
+	cfg.Spec.Network.Cilium = v1beta1.DefaultCilium()
+	return cfg
+}
+
+func renderCilium(t *testing.T, cfg *v1beta1.ClusterConfig) []*unstructured.Unstructured {
+	saver := inMemorySaver{}
+	c, err := NewCilium(cfg, saver)
+	require.NoError(t, err)
+	require.NoError(t, c.Run())
+	require.NoError(t, c.Stop())
+
+	manifestData, foundRaw := saver["cilium.yaml"]
+	require.True(t, foundRaw, "must have manifests for cilium")
+
+	resources, err := testutil.ParseManifests(manifestData)
+	require.NoError(t, err)
+	return resources
+}
+
+func ciliumAgentEnv(t *testing.T, resources []*unstructured.Unstructured) map[string]string {
+	ds, err := findDaemonset(resources)
+	require.NoError(t, err)
+	require.Equal(t, "cilium", ds.Name)
+	env := map[string]string{}
+	for _, e := range ds.Spec.Template.Spec.Containers[0].Env {
+		env[e.Name] = e.Value
+	}
+	return env
+}
+
+func TestCiliumDefaultManifests(t *testing.T) {
+	resources := renderCilium(t, ciliumClusterConfig())
+
+	cm, err := findConfig(resources)
+	require.NoError(t, err)
+	require.Equal(t, "vxlan", cm.Data["tunnel"])
+	require.Equal(t, "disabled", cm.Data["kube-proxy-replacement"])
+	require.Equal(t, "false", cm.Data["enable-ipv6"])
+	require.NotContains(t, cm.Data, "ipv4-native-routing-cidr")
+	require.NotContains(t, cm.Data, "enable-hubble")
+
+	env := ciliumAgentEnv(t, resources)
+	require.NotContains(t, env, "KUBERNETES_SERVICE_HOST")
+
+	for _, r := range resources {
+		require.NotEqual(t, "hubble-relay", r.GetName())
+	}
+}
+
+func TestCiliumNativeRoutingWithKubeProxyReplacement(t *testing.T) {
+	cfg := ciliumClusterConfig()
+	cfg.Spec.API.Address = "10.0.0.10"
+	cfg.Spec.Network.Cilium.RoutingMode = v1beta1.CiliumRoutingNative
+	cfg.Spec.Network.Cilium.KubeProxyReplacement = true
+	cfg.Spec.Network.Cilium.MTU = 1400
+	cfg.Spec.Network.DualStack.Enabled = true
+	cfg.Spec.Network.DualStack.IPv6PodCIDR = "fd00::/108"
+	resources := renderCilium(t, cfg)
+
+	cm, err := findConfig(resources)
+	require.NoError(t, err)
+	require.Equal(t, "disabled", cm.Data["tunnel"])
+	require.Equal(t, cfg.Spec.Network.PodCIDR, cm.Data["ipv4-native-routing-cidr"])
+	require.Equal(t, "fd00::/108", cm.Data["ipv6-native-routing-cidr"])
+	require.Equal(t, "true", cm.Data["auto-direct-node-routes"])
+	require.Equal(t, "strict", cm.Data["kube-proxy-replacement"])
+	require.Equal(t, "true", cm.Data["enable-ipv6"])
+	require.Equal(t, "1400", cm.Data["mtu"])
+
+	env := ciliumAgentEnv(t, resources)
+	require.Equal(t, "10.0.0.10", env["KUBERNETES_SERVICE_HOST"])
+	require.Equal(t, "6443", env["KUBERNETES_SERVICE_PORT"])
+}
+
+func TestCiliumHubble(t *testing.T) {
+	cfg := ciliumClusterConfig()
+	cfg.Spec.Network.Cilium.Hubble = true
+	resources := renderCilium(t, cfg)
+
+	cm, err := findConfig(resources)
+	require.NoError(t, err)
+	require.Equal(t, "true", cm.Data["enable-hubble"])
+
+	var relay, svc bool
+	for _, r := range resources {
+		if r.GetName() != "hubble-relay" {
+			continue
+		}
+		switch r.GetKind() {
+		case "Deployment":
+			relay = true
+		case "Service":
+			svc = true
+		}
+	}
+	require.True(t, relay, "must have the hubble-relay deployment")
+	require.True(t, svc, "must have the hubble-relay service")
+}
+
+func TestCiliumReconcile(t *testing.T) {
+	saver := inMemorySaver{}
+	c, err := NewCilium(ciliumClusterConfig(), saver)
+	require.NoError(t, err)
+	require.NoError(t, c.Run())
+
+	cfg := ciliumClusterConfig()
+	cfg.Spec.Network.Cilium.TunnelProtocol = "geneve"
+	require.NoError(t, c.Reconcile(cfg))
+
+	resources, err := testutil.ParseManifests(saver["cilium.yaml"])
+	require.NoError(t, err)
+	cm, err := findConfig(resources)
+	require.NoError(t, err)
+	require.Equal(t, "geneve", cm.Data["tunnel"])
+}
@@ -0,0 +1,342 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v2"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/wait"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/tools/cache"
+
+	config "github.com/k0sproject/k0s/pkg/apis/v1beta1"
+	"github.com/k0sproject/k0s/pkg/component"
+	kubeutil "github.com/k0sproject/k0s/pkg/kubernetes"
+)
+
+var clusterConfigGVR = schema.GroupVersionResource{
+	Group:    config.ClusterConfigGroup,
+	Version:  config.ClusterConfigVersion,
+	Resource: config.ClusterConfigResource,
+}
+
+// ClusterConfigReconciler watches the cluster wide config stored in the cluster and passes the changes
+// to the config reconcilers. The leader creates the object from the config file if it doesn't exist yet
+// and reports the outcome of the reconciliation in the object status.
+type ClusterConfigReconciler struct {
+	fileConfig        *config.ClusterConfig
+	kubeClientFactory kubeutil.ClientFactory
+	reconcilers       map[string]component.ConfigReconciler
+	log               *logrus.Entry
+
+	mu       sync.Mutex
+	current  *config.ClusterConfig
+	leading  bool
+	informer cache.SharedIndexInformer
+	stopCh   chan struct{}
+}
+
+// NewClusterConfigReconciler creates new ClusterConfigReconciler
+func NewClusterConfigReconciler(fileConfig *config.ClusterConfig, kubeClientFactory kubeutil.ClientFactory) *ClusterConfigReconciler {
+	return &ClusterConfigReconciler{
+		fileConfig:        fileConfig,
+		kubeClientFactory: kubeClientFactory,
+		reconcilers:       make(map[string]component.ConfigReconciler),
+		log:               logrus.WithFields(logrus.Fields{"component": "clusterconfig"}),
+		current:           fileConfig,
+	}
+}
+
+// Load reads the config stored in the cluster and returns it merged into the config file. The config file
+// is used as is if there's no config stored in the cluster yet.
+func (r *ClusterConfigReconciler) Load(ctx context.Context) (*config.ClusterConfig, error) {
+	client, err := r.kubeClientFactory.GetDynamicClient()
+	if err != nil {
+		return nil, err
+	}
+	obj, err := client.Resource(clusterConfigGVR).Namespace(config.ClusterConfigNamespace).Get(ctx, config.ClusterConfigName, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		r.log.Info("no cluster config stored in the cluster yet, using the config file")
+		return r.fileConfig, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("can't get cluster config %s/%s: %w", config.ClusterConfigNamespace, config.ClusterConfigName, err)
+	}
+
+	cfg, err := r.merge(obj)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.current = cfg
+	return cfg, nil
+}
+
+// SetReconcilers sets the components the config changes are passed to, components not able to
+// reconcile config changes are skipped
+func (r *ClusterConfigReconciler) SetReconcilers(reconcilers map[string]component.Component) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for name, comp := range reconcilers {
+		if cr, ok := component.Unwrap(comp).(component.ConfigReconciler); ok {
+			r.reconcilers[name] = cr
+		}
+	}
+}
+
+// Init does nothing
+func (r *ClusterConfigReconciler) Init() error { return nil }
+
+// Run starts watching the cluster config
+func (r *ClusterConfigReconciler) Run() error {
+	client, err := r.kubeClientFactory.GetDynamicClient()
+	if err != nil {
+		return err
+	}
+
+	factory := dynamicinformer.NewFilteredDynamicSharedInformerFactory(client, 0, config.ClusterConfigNamespace, func(opts *metav1.ListOptions) {
+		opts.FieldSelector = fields.OneTermEqualSelector("metadata.name", config.ClusterConfigName).String()
+	})
+	informer := factory.ForResource(clusterConfigGVR).Informer()
+	informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			r.reconcile(obj.(*unstructured.Unstructured))
+		},
+		UpdateFunc: func(_, newObj interface{}) {
+			r.reconcile(newObj.(*unstructured.Unstructured))
+		},
+		DeleteFunc: func(_ interface{}) {
+			r.log.Warn("cluster config deleted, keeping the current config until it's recreated")
+		},
+	})
+
+	r.mu.Lock()
+	r.informer = informer
+	r.stopCh = make(chan struct{})
+	r.mu.Unlock()
+
+	go informer.Run(r.stopCh)
+	return nil
+}
+
+// RunLeader makes sure the cluster config object exists and reports the reconciliation status for as long as we're the leader
+func (r *ClusterConfigReconciler) RunLeader(ctx context.Context) error {
+	r.setLeading(true)
+	defer r.setLeading(false)
+
+	client, err := r.kubeClientFactory.GetDynamicClient()
+	if err != nil {
+		return err
+	}
+
+	// the CRD is applied by the leader too, retry until it's in place
+	err = wait.PollImmediateUntil(5*time.Second, func() (bool, error) {
+		if err := r.createIfMissing(ctx, client); err != nil {
+			r.log.WithError(err).Warn("can't create the cluster config, will retry")
+			return false, nil
+		}
+		return true, nil
+	}, ctx.Done())
+	if err != nil {
+		// only fails when the context is done
+		return nil
+	}
+
+	// report the status of what's been reconciled before we got the lease
+	r.mu.Lock()
+	informer := r.informer
+	r.mu.Unlock()
+	if informer != nil {
+		for _, obj := range informer.GetStore().List() {
+			r.reconcile(obj.(*unstructured.Unstructured))
+		}
+	}
+
+	<-ctx.Done()
+	return nil
+}
+
+// Stop stops watching the cluster config
+func (r *ClusterConfigReconciler) Stop() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.stopCh != nil {
+		close(r.stopCh)
+		r.stopCh = nil
+	}
+	return nil
+}
+
+// Healthy is a no-op healthcheck
+func (r *ClusterConfigReconciler) Healthy() error { return nil }
+
+func (r *ClusterConfigReconciler) setLeading(leading bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.leading = leading
+}
+
+func (r *ClusterConfigReconciler) createIfMissing(ctx context.Context, client dynamic.Interface) error {
+	resource := client.Resource(clusterConfigGVR).Namespace(config.ClusterConfigNamespace)
+	_, err := resource.Get(ctx, config.ClusterConfigName, metav1.GetOptions{})
+	if err == nil {
+		return nil
+	}
+	if !apierrors.IsNotFound(err) {
+		return err
+	}
+
+	spec, err := specToUnstructured(r.fileConfig.DynamicSpec())
+	if err != nil {
+		return err
+	}
+	obj := &unstructured.Unstructured{}
+	obj.SetAPIVersion(clusterConfigGVR.GroupVersion().String())
+	obj.SetKind(config.ClusterConfigKind)
+	obj.SetNamespace(config.ClusterConfigNamespace)
+	obj.SetName(config.ClusterConfigName)
+	obj.Object["spec"] = spec
+
+	_, err = resource.Create(ctx, obj, metav1.CreateOptions{})
+	if apierrors.IsAlreadyExists(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	r.log.Info("created the cluster config from the config file")
+	return nil
+}
+
+// reconcile passes the changed config to the reconcilers and updates the status when leading
+func (r *ClusterConfigReconciler) reconcile(obj *unstructured.Unstructured) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var errMsg string
+	cfg, err := r.merge(obj)
+	if err != nil {
+		r.log.WithError(err).Error("invalid cluster config, not applying it")
+		errMsg = err.Error()
+	} else if !reflect.DeepEqual(cfg.DynamicSpec(), r.current.DynamicSpec()) {
+		r.log.Info("cluster config changed, reconciling")
+		var errs []string
+		for name, reconciler := range r.reconcilers {
+			if err := reconciler.Reconcile(cfg); err != nil {
+				r.log.WithError(err).Errorf("failed to reconcile %s", name)
+				errs = append(errs, fmt.Sprintf("%s: %s", name, err.Error()))
+			}
+		}
+		r.current = cfg
+		errMsg = strings.Join(errs, "; ")
+	}
+
+	if r.leading {
+		if err := r.updateStatus(obj, errMsg); err != nil {
+			r.log.WithError(err).Warn("failed to update the cluster config status")
+		}
+	}
+}
+
+func (r *ClusterConfigReconciler) updateStatus(obj *unstructured.Unstructured, errMsg string) error {
+	status := map[string]interface{}{
+		"observedGeneration": obj.GetGeneration(),
+	}
+	if errMsg != "" {
+		status["error"] = errMsg
+	}
+	if reflect.DeepEqual(obj.Object["status"], status) {
+		return nil
+	}
+
+	client, err := r.kubeClientFactory.GetDynamicClient()
+	if err != nil {
+		return err
+	}
+	updated := obj.DeepCopy()
+	updated.Object["status"] = status
+	_, err = client.Resource(clusterConfigGVR).Namespace(config.ClusterConfigNamespace).UpdateStatus(context.Background(), updated, metav1.UpdateOptions{})
+	return err
+}
+
+// merge parses the spec of the cluster config object and merges it into the config file
+func (r *ClusterConfigReconciler) merge(obj *unstructured.Unstructured) (*config.ClusterConfig, error) {
+	spec, err := parseDynamicSpec(obj)
+	if err != nil {
+		return nil, err
+	}
+	if errs := r.fileConfig.ValidateDynamicSpec(spec); len(errs) > 0 {
+		messages := make([]string, len(errs))
+		for i, err := range errs {
+			messages[i] = err.Error()
+		}
+		return nil, fmt.Errorf("invalid cluster config: %s", strings.Join(messages, "; "))
+	}
+	return r.fileConfig.WithDynamicSpec(spec), nil
+}
+
+func parseDynamicSpec(obj *unstructured.Unstructured) (*config.DynamicClusterSpec, error) {
+	spec, found, err := unstructured.NestedMap(obj.Object, "spec")
+	if err != nil {
+		return nil, fmt.Errorf("invalid cluster config spec: %w", err)
+	}
+	if !found {
+		spec = map[string]interface{}{}
+	}
+	// JSON is valid YAML, this way the yaml tags and the defaulting of the config types are used
+	data, err := json.Marshal(spec)
+	if err != nil {
+		return nil, err
+	}
+	d := &config.DynamicClusterSpec{}
+	if err := yaml.Unmarshal(data, d); err != nil {
+		return nil, fmt.Errorf("can't parse cluster config spec: %w", err)
+	}
+	return d, nil
+}
+
+func specToUnstructured(d *config.DynamicClusterSpec) (map[string]interface{}, error) {
+	data, err := yaml.Marshal(d)
+	if err != nil {
+		return nil, err
+	}
+	data, err = utilyaml.ToJSON(data)
+	if err != nil {
+		return nil, err
+	}
+	spec := map[string]interface{}{}
+	if err := json.Unmarshal(data, &spec); err != nil {
+		return nil, err
+	}
+	return spec, nil
+}
@@ -0,0 +1,102 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package controller
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/k0sproject/k0s/internal/testutil"
+	config "github.com/k0sproject/k0s/pkg/apis/v1beta1"
+	"github.com/k0sproject/k0s/pkg/component"
+)
+
+type fakeConfigReconciler struct {
+	cfg *config.ClusterConfig
+}
+
+func (f *fakeConfigReconciler) Init() error    { return nil }
+func (f *fakeConfigReconciler) Run() error     { return nil }
+func (f *fakeConfigReconciler) Stop() error    { return nil }
+func (f *fakeConfigReconciler) Healthy() error { return nil }
+
+func (f *fakeConfigReconciler) Reconcile(cfg *config.ClusterConfig) error {
+	f.cfg = cfg
+	return nil
+}
+
+func TestClusterConfigLoadFallsBackToConfigFile(t *testing.T) {
+	fileConfig := config.DefaultClusterConfig(k0sVars)
+	r := NewClusterConfigReconciler(fileConfig, testutil.NewFakeClientFactory())
+
+	cfg, err := r.Load(context.Background())
+	assert.NoError(t, err)
+	assert.Same(t, fileConfig, cfg)
+}
+
+func TestClusterConfigCreatedFromConfigFile(t *testing.T) {
+	fileConfig := config.DefaultClusterConfig(k0sVars)
+	fileConfig.Spec.Images.CoreDNS.Version = "1.2.3"
+	fakeFactory := testutil.NewFakeClientFactory()
+	r := NewClusterConfigReconciler(fileConfig, fakeFactory)
+
+	assert.NoError(t, r.createIfMissing(context.Background(), fakeFactory.DynamicClient))
+	// creating it again is a no-op
+	assert.NoError(t, r.createIfMissing(context.Background(), fakeFactory.DynamicClient))
+
+	cfg, err := r.Load(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "1.2.3", cfg.Spec.Images.CoreDNS.Version)
+	assert.Equal(t, fileConfig.Spec.Network, cfg.Spec.Network)
+}
+
+func TestClusterConfigReconcile(t *testing.T) {
+	fileConfig := config.DefaultClusterConfig(k0sVars)
+	fakeFactory := testutil.NewFakeClientFactory()
+	r := NewClusterConfigReconciler(fileConfig, fakeFactory)
+	fake := &fakeConfigReconciler{}
+	r.SetReconcilers(map[string]component.Component{"fake": fake})
+
+	assert.NoError(t, r.createIfMissing(context.Background(), fakeFactory.DynamicClient))
+	resource := fakeFactory.DynamicClient.Resource(clusterConfigGVR).Namespace(config.ClusterConfigNamespace)
+	obj, err := resource.Get(context.Background(), config.ClusterConfigName, metav1.GetOptions{})
+	assert.NoError(t, err)
+
+	t.Run("unchanged config is not reconciled", func(t *testing.T) {
+		r.reconcile(obj)
+		assert.Nil(t, fake.cfg)
+	})
+
+	t.Run("changed config is reconciled", func(t *testing.T) {
+		changed := obj.DeepCopy()
+		changed.Object["spec"].(map[string]interface{})["images"].(map[string]interface{})["coredns"].(map[string]interface{})["version"] = "1.2.3"
+		r.reconcile(changed)
+		if assert.NotNil(t, fake.cfg) {
+			assert.Equal(t, "1.2.3", fake.cfg.Spec.Images.CoreDNS.Version)
+		}
+	})
+
+	t.Run("immutable settings are rejected", func(t *testing.T) {
+		fake.cfg = nil
+		changed := obj.DeepCopy()
+		changed.Object["spec"].(map[string]interface{})["network"].(map[string]interface{})["podCIDR"] = "10.0.0.0/8"
+		r.reconcile(changed)
+		assert.Nil(t, fake.cfg)
+	})
+}
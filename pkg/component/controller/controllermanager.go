@@ -27,6 +27,7 @@ import (
 	config "github.com/k0sproject/k0s/pkg/apis/v1beta1"
 	"github.com/k0sproject/k0s/pkg/assets"
 	"github.com/k0sproject/k0s/pkg/constant"
+	"github.com/k0sproject/k0s/pkg/flags"
 	"github.com/k0sproject/k0s/pkg/supervisor"
 )
 
@@ -67,11 +68,30 @@ func (a *Manager) Init() error {
 	return assets.Stage(a.K0sVars.BinDir, "kube-controller-manager", constant.BinDirMode)
 }
 
-// Run runs kube Manager
-func (a *Manager) Run() error {
-	logrus.Info("Starting kube-controller-manager")
+// controllerManagerPolicy lists the flags k0s sets that can be replaced with the extra args and the deprecated ones
+var controllerManagerPolicy = flags.Policy{
+	Component: "kube-controller-manager",
+	Replaceable: []string{
+		"allocate-node-cidrs",
+		"bind-address",
+		"cluster-name",
+		"controllers",
+		"enable-hostpath-provisioner",
+		"node-cidr-mask-size",
+		"node-cidr-mask-size-ipv4",
+		"node-cidr-mask-size-ipv6",
+		"profiling",
+		"terminated-pod-gc-threshold",
+		"use-service-account-credentials",
+		"v",
+	},
+	Deprecated: map[string]string{"feature-gates": "spec.featureGates"},
+}
+
+// Args returns the flags kube-controller-manager is run with
+func (a *Manager) Args() (flags.Args, error) {
 	ccmAuthConf := filepath.Join(a.K0sVars.CertRootDir, "ccm.conf")
-	args := map[string]string{
+	args := flags.Args{
 		"authentication-kubeconfig":        ccmAuthConf,
 		"authorization-kubeconfig":         ccmAuthConf,
 		"kubeconfig":                       ccmAuthConf,
@@ -87,11 +107,7 @@ func (a *Manager) Run() error {
 		"terminated-pod-gc-threshold":      "12500",
 		"v":                                a.LogLevel,
 	}
-
-	for name, value := range a.ClusterConfig.Spec.ControllerManager.ExtraArgs {
-		if args[name] != "" && name != "profiling" {
-			return fmt.Errorf("cannot override kube-controller-manager flag: %s", name)
-		}
+	for name, value := range cmDefaultArgs {
 		args[name] = value
 	}
 	if a.ClusterConfig.Spec.Network.DualStack.Enabled {
@@ -100,19 +116,26 @@ func (a *Manager) Run() error {
 	} else {
 		args["node-cidr-mask-size"] = "24"
 	}
-	a.ClusterConfig.Spec.Network.DualStack.EnableDualStackFeatureGate(args)
-	for name, value := range cmDefaultArgs {
-		if args[name] == "" {
-			args[name] = value
-		}
+	if a.ClusterConfig.Spec.API.ExternalAddress == "" {
+		args["leader-elect"] = "false"
 	}
-	var cmArgs []string
-	for name, value := range args {
-		cmArgs = append(cmArgs, fmt.Sprintf("--%s=%s", name, value))
+
+	if gates := a.ClusterConfig.Spec.EffectiveFeatureGates(); len(gates) > 0 {
+		args["feature-gates"] = gates.String()
 	}
 
-	if a.ClusterConfig.Spec.API.ExternalAddress == "" {
-		cmArgs = append(cmArgs, "--leader-elect=false")
+	return controllerManagerPolicy.Merge(args, a.ClusterConfig.Spec.ControllerManager.ExtraArgs)
+}
+
+// Run runs kube Manager
+func (a *Manager) Run() error {
+	logrus.Info("Starting kube-controller-manager")
+	args, err := a.Args()
+	if err != nil {
+		return err
+	}
+	if err := controllerManagerPolicy.Validate(assets.BinPath("kube-controller-manager", a.K0sVars.BinDir), a.ClusterConfig.Spec.ControllerManager.ExtraArgs); err != nil {
+		return err
 	}
 
 	a.supervisor = supervisor.Supervisor{
@@ -120,7 +143,7 @@ func (a *Manager) Run() error {
 		BinPath: assets.BinPath("kube-controller-manager", a.K0sVars.BinDir),
 		RunDir:  a.K0sVars.RunDir,
 		DataDir: a.K0sVars.DataDir,
-		Args:    cmArgs,
+		Args:    args.ToArgs(),
 		UID:     a.uid,
 		GID:     a.gid,
 	}
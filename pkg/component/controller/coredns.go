@@ -20,6 +20,7 @@ import (
 	"math"
 	"path"
 	"path/filepath"
+	"sync"
 	"time"
 
 	"github.com/sirupsen/logrus"
@@ -242,6 +243,7 @@ type CoreDNS struct {
 	tickerDone    chan struct{}
 	log           *logrus.Entry
 	clusterConfig *config.ClusterConfig
+	configMu      sync.Mutex
 	K0sVars       constant.CfgVars
 }
 
@@ -322,6 +324,9 @@ func (c *CoreDNS) Run() error {
 }
 
 func (c *CoreDNS) getConfig() (coreDNSConfig, error) {
+	c.configMu.Lock()
+	defer c.configMu.Unlock()
+
 	dns, err := c.clusterConfig.Spec.Network.DNSAddress()
 	if err != nil {
 		return coreDNSConfig{}, err
@@ -337,7 +342,7 @@ func (c *CoreDNS) getConfig() (coreDNSConfig, error) {
 
 	config := coreDNSConfig{
 		Replicas:      replicas,
-		ClusterDomain: "cluster.local",
+		ClusterDomain: c.clusterConfig.Spec.Network.ClusterDomain,
 		ClusterDNSIP:  dns,
 		Image:         c.clusterConfig.Spec.Images.CoreDNS.URI(),
 		PullPolicy:    c.clusterConfig.Spec.Images.DefaultPullPolicy,
@@ -356,6 +361,14 @@ func replicaCount(nodeCount int) int {
 	return 1 + extraReplicas
 }
 
+// Reconcile applies the changed cluster config on the next reconciliation round
+func (c *CoreDNS) Reconcile(cfg *config.ClusterConfig) error {
+	c.configMu.Lock()
+	defer c.configMu.Unlock()
+	c.clusterConfig = cfg
+	return nil
+}
+
 // Stop stops the CoreDNS reconciler
 func (c *CoreDNS) Stop() error {
 	if c.tickerDone != nil {
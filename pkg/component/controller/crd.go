@@ -36,6 +36,7 @@ func NewCRD(s manifestsSaver) *CRD {
 
 var bundles = []string{
 	"helm",
+	"k0s",
 }
 
 // Init  (c CRD) Init() error {
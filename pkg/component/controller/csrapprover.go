@@ -22,17 +22,18 @@ import (
 	"fmt"
 	"reflect"
 	"strings"
-	"sync/atomic"
 	"time"
 
 	config "github.com/k0sproject/k0s/pkg/apis/v1beta1"
 	k0sv1beta1 "github.com/k0sproject/k0s/pkg/apis/v1beta1"
 	k8sutil "github.com/k0sproject/k0s/pkg/kubernetes"
 	kubeutil "github.com/k0sproject/k0s/pkg/kubernetes"
+	"github.com/k0sproject/k0s/pkg/token"
 	"github.com/sirupsen/logrus"
 	authorization "k8s.io/api/authorization/v1"
 	v1 "k8s.io/api/certificates/v1"
 	core "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	clientset "k8s.io/client-go/kubernetes"
 )
@@ -43,6 +44,18 @@ var kubeletServerUsages = []v1.KeyUsage{
 	v1.UsageServerAuth,
 }
 
+var kubeletClientUsages = []v1.KeyUsage{
+	v1.UsageKeyEncipherment,
+	v1.UsageDigitalSignature,
+	v1.UsageClientAuth,
+}
+
+const (
+	bootstrapUserPrefix = "system:bootstrap:"
+	bootstrappersGroup  = "system:bootstrappers"
+	nodeUserPrefix      = "system:node:"
+)
+
 type csrRecognizer struct {
 	recognize      func(csr *v1.CertificateSigningRequest, x509cr *x509.CertificateRequest) bool
 	permission     authorization.ResourceAttributes
@@ -50,23 +63,17 @@ type csrRecognizer struct {
 }
 
 type CSRApprover struct {
-	L      *logrus.Entry
-	stopCh chan struct{}
+	L *logrus.Entry
 
 	ClusterConfig     *config.ClusterConfig
 	KubeClientFactory kubeutil.ClientFactory
-	leaderElector     LeaderElector
 	clientset         clientset.Interface
 }
 
 // NewCSRApprover creates the CSRApprover component
-func NewCSRApprover(c *k0sv1beta1.ClusterConfig, leaderElector LeaderElector, kubeClientFactory k8sutil.ClientFactory) *CSRApprover {
-	d := atomic.Value{}
-	d.Store(true)
+func NewCSRApprover(c *k0sv1beta1.ClusterConfig, kubeClientFactory k8sutil.ClientFactory) *CSRApprover {
 	return &CSRApprover{
 		ClusterConfig:     c,
-		leaderElector:     leaderElector,
-		stopCh:            make(chan struct{}),
 		KubeClientFactory: kubeClientFactory,
 		L:                 logrus.WithFields(logrus.Fields{"component": "csrapprover"}),
 	}
@@ -76,7 +83,6 @@ func (a *CSRApprover) Healthy() error { return nil }
 
 // Stop stops the CSRApprover
 func (a *CSRApprover) Stop() error {
-	close(a.stopCh)
 	return nil
 }
 
@@ -91,37 +97,35 @@ func (a *CSRApprover) Init() error {
 	return nil
 }
 
-// Run every 10 seconds checks for newly issued CSRs and approves them
+// Run does nothing, CSRs are only approved by the leader
 func (a *CSRApprover) Run() error {
+	return nil
+}
 
-	go func() {
-		ticker := time.NewTicker(10 * time.Second) // TODO: sometimes this should be refactored so it watches instead of polls for CSRs
-		defer ticker.Stop()
-		for {
-			select {
-			case <-ticker.C:
-				err := a.approveCSR()
-				if err != nil {
-					a.L.Warnf("CSR approval failed: %s", err.Error())
-				}
-			case <-a.stopCh:
-				a.L.Info("CSR Approver done")
-				return
+// RunLeader every 10 seconds checks for newly issued CSRs and approves them
+func (a *CSRApprover) RunLeader(ctx context.Context) error {
+	ticker := time.NewTicker(10 * time.Second) // TODO: sometimes this should be refactored so it watches instead of polls for CSRs
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			err := a.approveCSR()
+			if err != nil {
+				a.L.Warnf("CSR approval failed: %s", err.Error())
 			}
+			err = a.approveNodeClientCSRs()
+			if err != nil {
+				a.L.Warnf("kubelet client CSR approval failed: %s", err.Error())
+			}
+		case <-ctx.Done():
+			a.L.Info("CSR Approver done")
+			return nil
 		}
-	}()
-
-	return nil
+	}
 }
 
 // Majority of this code has been adapted from https://github.com/kontena/kubelet-rubber-stamp
 func (a *CSRApprover) approveCSR() error {
-
-	if !a.leaderElector.IsLeader() {
-		a.L.Debug("not the leader, can't approve certificates")
-		return nil
-	}
-
 	opts := metav1.ListOptions{
 		FieldSelector: "spec.signerName=kubernetes.io/kubelet-serving",
 	}
@@ -173,6 +177,105 @@ func (a *CSRApprover) approveCSR() error {
 	return nil
 }
 
+// approveNodeClientCSRs approves the kubelet client certificates requested with bootstrap tokens. The
+// certificates of node scoped tokens are only approved for the node the token was created for, the ones
+// requesting another node name are denied.
+func (a *CSRApprover) approveNodeClientCSRs() error {
+	opts := metav1.ListOptions{
+		FieldSelector: "spec.signerName=" + v1.KubeAPIServerClientKubeletSignerName,
+	}
+	csrs, err := a.clientset.CertificatesV1().CertificateSigningRequests().List(context.TODO(), opts)
+	if err != nil {
+		return fmt.Errorf("can't fetch CSRs: %v", err)
+	}
+
+	for i := range csrs.Items {
+		csr := &csrs.Items[i]
+		if csr.Spec.SignerName != v1.KubeAPIServerClientKubeletSignerName || !strings.HasPrefix(csr.Spec.Username, bootstrapUserPrefix) {
+			continue
+		}
+		if approved, denied := getCertApprovalCondition(&csr.Status); approved || denied {
+			continue
+		}
+		x509cr, err := parseCSR(csr)
+		if err != nil {
+			return fmt.Errorf("unable to parse csr %q: %v", csr.Name, err)
+		}
+
+		reason, ok, err := a.checkNodeClientCSR(csr, x509cr)
+		if err != nil {
+			return err
+		}
+		if ok {
+			a.L.Infof("approving kubelet client csr %s for %s", csr.Name, x509cr.Subject.CommonName)
+			appendApprovalCondition(csr, "Auto approving kubelet client certificate of bootstrapping node.")
+		} else {
+			a.L.Warnf("denying kubelet client csr %s requested by %s: %s", csr.Name, csr.Spec.Username, reason)
+			appendDenialCondition(csr, reason)
+		}
+		_, err = a.clientset.CertificatesV1().CertificateSigningRequests().UpdateApproval(context.TODO(), csr.Name, csr, metav1.UpdateOptions{})
+		if err != nil {
+			return fmt.Errorf("error updating approval for csr %s: %v", csr.Name, err)
+		}
+	}
+	return nil
+}
+
+// checkNodeClientCSR checks a kubelet client CSR made with a bootstrap token, it returns the denial reason
+// if the CSR must not be approved
+func (a *CSRApprover) checkNodeClientCSR(csr *v1.CertificateSigningRequest, x509cr *x509.CertificateRequest) (string, bool, error) {
+	if !hasGroup(csr.Spec.Groups, bootstrappersGroup) {
+		return fmt.Sprintf("requester is not in the %s group", bootstrappersGroup), false, nil
+	}
+	if !reflect.DeepEqual([]string{"system:nodes"}, x509cr.Subject.Organization) {
+		return fmt.Sprintf("organization %v is not system:nodes", x509cr.Subject.Organization), false, nil
+	}
+	if !strings.HasPrefix(x509cr.Subject.CommonName, nodeUserPrefix) {
+		return fmt.Sprintf("CN %q does not start with %s", x509cr.Subject.CommonName, nodeUserPrefix), false, nil
+	}
+	if len(x509cr.DNSNames) > 0 || len(x509cr.EmailAddresses) > 0 || len(x509cr.IPAddresses) > 0 || len(x509cr.URIs) > 0 {
+		return "kubelet client certificates must not have subject alternative names", false, nil
+	}
+	if !hasExactUsages(csr, kubeletClientUsages) && !hasExactUsages(csr, kubeletClientUsages[1:]) {
+		return fmt.Sprintf("usages %v are not the kubelet client usages", csr.Spec.Usages), false, nil
+	}
+
+	var nodeScoped bool
+	for _, g := range csr.Spec.Groups {
+		nodeScoped = nodeScoped || strings.HasPrefix(g, token.NodeBootstrapGroupPrefix)
+	}
+	if !nodeScoped {
+		// cluster wide worker tokens may bootstrap any node
+		return "", true, nil
+	}
+
+	tokenID := strings.TrimPrefix(csr.Spec.Username, bootstrapUserPrefix)
+	secret, err := a.clientset.CoreV1().Secrets("kube-system").Get(context.TODO(), "bootstrap-token-"+tokenID, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return fmt.Sprintf("node scoped bootstrap token %s no longer exists", tokenID), false, nil
+	}
+	if err != nil {
+		return "", false, fmt.Errorf("can't fetch bootstrap token %s: %v", tokenID, err)
+	}
+	nodeName := secret.Labels[token.NodeNameLabel]
+	if nodeName == "" || !hasGroup(csr.Spec.Groups, token.NodeBootstrapGroup(nodeName)) {
+		return fmt.Sprintf("bootstrap token %s is not scoped to a node", tokenID), false, nil
+	}
+	if x509cr.Subject.CommonName != nodeUserPrefix+nodeName {
+		return fmt.Sprintf("bootstrap token %s is scoped to node %s, but the certificate is requested for %s", tokenID, nodeName, x509cr.Subject.CommonName), false, nil
+	}
+	return "", true, nil
+}
+
+func hasGroup(groups []string, group string) bool {
+	for _, g := range groups {
+		if g == group {
+			return true
+		}
+	}
+	return false
+}
+
 func (a *CSRApprover) authorize(csr *v1.CertificateSigningRequest, rattrs authorization.ResourceAttributes) (bool, error) {
 	extra := make(map[string]authorization.ExtraValue)
 	for k, v := range csr.Spec.Extra {
@@ -277,6 +380,15 @@ func parseCSR(obj *v1.CertificateSigningRequest) (*x509.CertificateRequest, erro
 	return csr, nil
 }
 
+func appendDenialCondition(csr *v1.CertificateSigningRequest, message string) {
+	csr.Status.Conditions = append(csr.Status.Conditions, v1.CertificateSigningRequestCondition{
+		Type:    v1.CertificateDenied,
+		Reason:  "Denied by K0s CSRApprover",
+		Message: message,
+		Status:  core.ConditionTrue,
+	})
+}
+
 func appendApprovalCondition(csr *v1.CertificateSigningRequest, message string) {
 	csr.Status.Conditions = append(csr.Status.Conditions, v1.CertificateSigningRequestCondition{
 		Type:    v1.CertificateApproved,
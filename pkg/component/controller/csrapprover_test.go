@@ -23,11 +23,14 @@ import (
 	"crypto/x509"
 	"crypto/x509/pkix"
 	"encoding/pem"
+	"fmt"
 	"testing"
 
 	"github.com/k0sproject/k0s/internal/testutil"
 	"github.com/k0sproject/k0s/pkg/apis/v1beta1"
+	"github.com/k0sproject/k0s/pkg/token"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	certv1 "k8s.io/api/certificates/v1"
 	core "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -70,7 +73,7 @@ func TestBasicCRSApprover(t *testing.T) {
 			},
 		},
 	}
-	c := NewCSRApprover(config, &DummyLeaderElector{Leader: true}, fakeFactory)
+	c := NewCSRApprover(config, fakeFactory)
 
 	assert.NoError(t, c.Init())
 	assert.NoError(t, c.approveCSR())
@@ -112,3 +115,70 @@ func pemWithTemplate(template *x509.CertificateRequest, key crypto.PrivateKey) [
 
 	return p
 }
+
+func TestNodeClientCSRApproval(t *testing.T) {
+	fakeFactory := testutil.NewFakeClientFactory()
+	client, err := fakeFactory.GetClient()
+	require.NoError(t, err)
+	ctx := context.TODO()
+
+	// node scoped token of node worker-1.example.com
+	_, err = client.CoreV1().Secrets("kube-system").Create(ctx, &core.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "bootstrap-token-abcdef",
+			Namespace: "kube-system",
+			Labels:    map[string]string{token.NodeNameLabel: "worker-1.example.com"},
+		},
+		Type: core.SecretTypeBootstrapToken,
+	}, v1.CreateOptions{})
+	require.NoError(t, err)
+
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	nodeScopedGroups := []string{"system:bootstrappers", token.NodeBootstrapGroup("worker-1.example.com"), "system:authenticated"}
+	clusterWideGroups := []string{"system:bootstrappers", "system:authenticated"}
+
+	tests := []struct {
+		name     string
+		username string
+		groups   []string
+		cn       string
+		approved bool
+	}{
+		{"node scoped token for its node", "system:bootstrap:abcdef", nodeScopedGroups, "system:node:worker-1.example.com", true},
+		{"node scoped token for another node", "system:bootstrap:abcdef", nodeScopedGroups, "system:node:worker-2", false},
+		{"node scoped token for a colliding node name", "system:bootstrap:abcdef", nodeScopedGroups, "system:node:worker-1-example-com", false},
+		{"deleted node scoped token", "system:bootstrap:ghijkl", nodeScopedGroups, "system:node:worker-1.example.com", false},
+		{"cluster wide token", "system:bootstrap:mnopqr", clusterWideGroups, "system:node:worker-3", true},
+		{"cluster wide token for a non node user", "system:bootstrap:mnopqr", clusterWideGroups, "admin", false},
+	}
+	for i, tc := range tests {
+		_, err := client.CertificatesV1().CertificateSigningRequests().Create(ctx, &certv1.CertificateSigningRequest{
+			ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("node-csr-%d", i)},
+			Spec: certv1.CertificateSigningRequestSpec{
+				Request: pemWithTemplate(&x509.CertificateRequest{
+					Subject: pkix.Name{CommonName: tc.cn, Organization: []string{"system:nodes"}},
+				}, privateKey),
+				SignerName: certv1.KubeAPIServerClientKubeletSignerName,
+				Username:   tc.username,
+				Groups:     tc.groups,
+				Usages:     []certv1.KeyUsage{certv1.UsageDigitalSignature, certv1.UsageKeyEncipherment, certv1.UsageClientAuth},
+			},
+		}, v1.CreateOptions{})
+		require.NoError(t, err)
+	}
+
+	c := NewCSRApprover(&v1beta1.ClusterConfig{}, fakeFactory)
+	require.NoError(t, c.Init())
+	require.NoError(t, c.approveNodeClientCSRs())
+
+	for i, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			csr, err := client.CertificatesV1().CertificateSigningRequests().Get(ctx, fmt.Sprintf("node-csr-%d", i), v1.GetOptions{})
+			require.NoError(t, err)
+			approved, denied := getCertApprovalCondition(&csr.Status)
+			assert.Equal(t, tc.approved, approved, "approved")
+			assert.Equal(t, !tc.approved, denied, "denied")
+		})
+	}
+}
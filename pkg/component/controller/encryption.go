@@ -0,0 +1,121 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package controller
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gopkg.in/fsnotify.v1"
+
+	"github.com/k0sproject/k0s/internal/util"
+	"github.com/k0sproject/k0s/pkg/constant"
+	"github.com/k0sproject/k0s/pkg/debounce"
+	"github.com/k0sproject/k0s/pkg/encryption"
+)
+
+// encryptionEnabled tells if kube-apiserver is run with an encryption config. Once enabled, the config
+// is kept even if the encryption gets disabled, so that the encrypted resources stay readable.
+func (a *APIServer) encryptionEnabled() bool {
+	return a.ClusterConfig.Spec.API.Encryption != nil || util.FileExists(encryption.KeysPath(a.K0sVars.CertRootDir))
+}
+
+// encryptionConfig reconciles the encryption keys with the cluster config and renders the
+// EncryptionConfiguration, nil is returned if the encryption isn't enabled
+func (a *APIServer) encryptionConfig() ([]byte, error) {
+	if !a.encryptionEnabled() {
+		return nil, nil
+	}
+	spec := a.ClusterConfig.Spec.API.Encryption
+	keysPath := encryption.KeysPath(a.K0sVars.CertRootDir)
+	keys, err := encryption.LoadKeys(keysPath)
+	if err != nil {
+		return nil, err
+	}
+	changed, err := keys.Reconcile(spec, a.GenerateEncryptionKeys)
+	if errors.Is(err, encryption.ErrKeyMissing) {
+		return nil, fmt.Errorf("%s has no %s encryption key, the keys are generated by the controller the cluster was initialized with: copy the file from it or rejoin this controller", keysPath, spec.Provider)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if changed {
+		if err := keys.Save(keysPath); err != nil {
+			return nil, err
+		}
+	}
+	data, err := keys.Config(spec)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render the encryption config: %w", err)
+	}
+	return data, nil
+}
+
+func (a *APIServer) writeEncryptionConfig(data []byte) error {
+	configPath := encryption.ConfigPath(a.K0sVars.DataDir)
+	if err := ioutil.WriteFile(configPath, data, constant.CertSecureMode); err != nil {
+		return fmt.Errorf("failed to write the encryption config: %w", err)
+	}
+	return util.ChownFile(configPath, constant.ApiserverUser, constant.CertSecureMode)
+}
+
+// watchEncryptionKeys restarts kube-apiserver with the new config whenever the encryption keys change
+func (a *APIServer) watchEncryptionKeys() error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	if err := watcher.Add(filepath.Dir(encryption.KeysPath(a.K0sVars.CertRootDir))); err != nil {
+		watcher.Close()
+		return fmt.Errorf("failed to watch the encryption keys: %w", err)
+	}
+	a.encryptionWatcher = watcher
+	a.encryptionDebouncer = debounce.New(5*time.Second, watcher.Events, func(fsnotify.Event) {
+		if err := a.reloadEncryptionConfig(); err != nil {
+			logrus.Warnf("failed to reload the encryption config: %v", err)
+		}
+	})
+	go a.encryptionDebouncer.Start()
+	return nil
+}
+
+func (a *APIServer) reloadEncryptionConfig() error {
+	data, err := a.encryptionConfig()
+	if err != nil {
+		return err
+	}
+	current, err := ioutil.ReadFile(encryption.ConfigPath(a.K0sVars.DataDir))
+	if err == nil && bytes.Equal(current, data) {
+		return nil
+	}
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	logrus.Info("Encryption keys changed, restarting kube-apiserver")
+	if err := a.supervisor.Stop(); err != nil {
+		return err
+	}
+	// the config is written only once the old process is gone, so that it's applied when the file changes
+	if err := a.writeEncryptionConfig(data); err != nil {
+		return err
+	}
+	return a.supervisor.Supervise()
+}
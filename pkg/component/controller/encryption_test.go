@@ -0,0 +1,127 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package controller
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+
+	config "github.com/k0sproject/k0s/pkg/apis/v1beta1"
+	"github.com/k0sproject/k0s/pkg/constant"
+	"github.com/k0sproject/k0s/pkg/encryption"
+)
+
+func TestAPIServerEncryption(t *testing.T) {
+	k0sVars := constant.GetConfig(t.TempDir())
+	cfg := config.DefaultClusterConfig(k0sVars)
+	api := &APIServer{ClusterConfig: cfg, K0sVars: k0sVars, LogLevel: "1", GenerateEncryptionKeys: true}
+
+	args, err := api.Args()
+	require.NoError(t, err)
+	assert.NotContains(t, args, "encryption-provider-config")
+	data, err := api.encryptionConfig()
+	require.NoError(t, err)
+	assert.Nil(t, data)
+
+	cfg.Spec.API.Encryption = config.DefaultEncryptionSpec()
+	args, err = api.Args()
+	require.NoError(t, err)
+	assert.Equal(t, encryption.ConfigPath(k0sVars.DataDir), args["encryption-provider-config"])
+
+	data, err = api.encryptionConfig()
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "aescbc:")
+	keys, err := encryption.LoadKeys(encryption.KeysPath(k0sVars.CertRootDir))
+	require.NoError(t, err)
+	require.Len(t, keys.Keys, 1)
+	assert.Contains(t, string(data), keys.Keys[0].Secret)
+
+	again, err := api.encryptionConfig()
+	require.NoError(t, err)
+	assert.Equal(t, data, again, "the keys must not be regenerated")
+
+	// the keys are kept to read the resources encrypted before the encryption got disabled
+	cfg.Spec.API.Encryption = nil
+	args, err = api.Args()
+	require.NoError(t, err)
+	assert.Equal(t, encryption.ConfigPath(k0sVars.DataDir), args["encryption-provider-config"])
+	data, err = api.encryptionConfig()
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "- identity: {}\n  - aescbc:")
+}
+
+func TestAPIServerEncryptionOnJoinedController(t *testing.T) {
+	k0sVars := constant.GetConfig(t.TempDir())
+	cfg := config.DefaultClusterConfig(k0sVars)
+	cfg.Spec.API.Encryption = config.DefaultEncryptionSpec()
+	api := &APIServer{ClusterConfig: cfg, K0sVars: k0sVars, LogLevel: "1"}
+
+	_, err := api.encryptionConfig()
+	assert.Error(t, err, "a joined controller must not generate its own key")
+	keysPath := encryption.KeysPath(k0sVars.CertRootDir)
+	assert.NoFileExists(t, keysPath)
+
+	// the keys copied from the initial controller are used as they are
+	keys := &encryption.Keys{}
+	_, err = keys.Reconcile(cfg.Spec.API.Encryption, true)
+	require.NoError(t, err)
+	require.NoError(t, keys.Save(keysPath))
+	data, err := api.encryptionConfig()
+	require.NoError(t, err)
+	assert.Contains(t, string(data), keys.Keys[0].Secret)
+
+	cfg.Spec.API.Encryption.Provider = config.EncryptionProviderSecretbox
+	_, err = api.encryptionConfig()
+	assert.Error(t, err, "a joined controller must not rotate the keys on a provider change")
+}
+
+func TestSyncEncryptionKeys(t *testing.T) {
+	k0sVars := constant.GetConfig(t.TempDir())
+	keysPath := encryption.KeysPath(k0sVars.CertRootDir)
+
+	shared := &encryption.Keys{}
+	_, err := shared.Reconcile(config.DefaultEncryptionSpec(), true)
+	require.NoError(t, err)
+	fetch := func() ([]byte, error) { return yaml.Marshal(shared) }
+
+	synced, err := SyncEncryptionKeys(k0sVars.CertRootDir, func() ([]byte, error) { return nil, nil })
+	require.NoError(t, err)
+	assert.False(t, synced, "nothing to take when the controller has no keys")
+
+	synced, err = SyncEncryptionKeys(k0sVars.CertRootDir, fetch)
+	require.NoError(t, err)
+	assert.True(t, synced)
+
+	synced, err = SyncEncryptionKeys(k0sVars.CertRootDir, fetch)
+	require.NoError(t, err)
+	assert.False(t, synced, "the keys of the same generation are kept")
+
+	_, err = shared.Rotate(config.EncryptionProviderAESCBC)
+	require.NoError(t, err)
+	synced, err = SyncEncryptionKeys(k0sVars.CertRootDir, fetch)
+	require.NoError(t, err)
+	assert.True(t, synced)
+	keys, err := encryption.LoadKeys(keysPath)
+	require.NoError(t, err)
+	assert.Equal(t, shared, keys)
+
+	_, err = SyncEncryptionKeys(k0sVars.CertRootDir, func() ([]byte, error) { return nil, errors.New("token expired") })
+	assert.Error(t, err)
+}
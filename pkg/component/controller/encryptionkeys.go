@@ -0,0 +1,145 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package controller
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/k0sproject/k0s/pkg/constant"
+	"github.com/k0sproject/k0s/pkg/encryption"
+	kubeutil "github.com/k0sproject/k0s/pkg/kubernetes"
+)
+
+// EncryptionKeys keeps the encryption keys of the controller in sync with the other controllers. The joined
+// controllers take the keys of a newer generation from the controller they joined, and every controller reports
+// the names of the keys its kube-apiserver runs with, so that a rotation can tell when all of them have a key.
+type EncryptionKeys struct {
+	K0sVars           constant.CfgVars
+	KubeClientFactory kubeutil.ClientFactory
+	// FetchKeys returns the keys file of the controller this controller joined, it's nil on the controller the
+	// cluster was initialized with
+	FetchKeys func() ([]byte, error)
+
+	log        *logrus.Entry
+	name       string
+	tickerDone chan struct{}
+}
+
+// Init initializes the logger and the controller name
+func (e *EncryptionKeys) Init() error {
+	e.log = logrus.WithFields(logrus.Fields{"component": "encryption-keys"})
+	// the same name as the controller lease
+	name, err := os.Hostname()
+	if err != nil {
+		return err
+	}
+	e.name = name
+	return nil
+}
+
+// Run starts syncing and reporting the keys
+func (e *EncryptionKeys) Run() error {
+	e.tickerDone = make(chan struct{})
+	go func(done <-chan struct{}) {
+		ticker := time.NewTicker(30 * time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+			case <-done:
+				return
+			}
+			if e.FetchKeys != nil {
+				if synced, err := SyncEncryptionKeys(e.K0sVars.CertRootDir, e.FetchKeys); err != nil {
+					e.log.Warnf("failed to sync the encryption keys: %v", err)
+				} else if synced {
+					e.log.Info("took the newer encryption keys of the controller this controller joined")
+				}
+			}
+			if err := e.report(context.Background()); err != nil {
+				e.log.Warnf("failed to report the encryption keys: %v", err)
+			}
+		}
+	}(e.tickerDone)
+	return nil
+}
+
+// Stop stops syncing and reporting the keys
+func (e *EncryptionKeys) Stop() error {
+	if e.tickerDone != nil {
+		close(e.tickerDone)
+		e.tickerDone = nil
+	}
+	return nil
+}
+
+// Healthy is a no-op check
+func (e *EncryptionKeys) Healthy() error { return nil }
+
+// report records the names of the keys of the encryption config kube-apiserver has been started with. The
+// config is written while kube-apiserver is stopped, so reaching the API means it runs with the config.
+func (e *EncryptionKeys) report(ctx context.Context) error {
+	data, err := ioutil.ReadFile(encryption.ConfigPath(e.K0sVars.DataDir))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	names, err := encryption.ConfigKeyNames(data)
+	if err != nil {
+		return err
+	}
+	client, err := e.KubeClientFactory.GetClient()
+	if err != nil {
+		return err
+	}
+	return encryption.ReportKeyNames(ctx, client, e.name, names)
+}
+
+// SyncEncryptionKeys replaces the keys of the controller with the fetched ones when it has no keys yet or the
+// fetched ones are of a newer generation, e.g. after a rotation. It tells if the keys have been replaced.
+func SyncEncryptionKeys(certRootDir string, fetch func() ([]byte, error)) (bool, error) {
+	data, err := fetch()
+	if err != nil {
+		return false, err
+	}
+	if len(data) == 0 {
+		return false, nil
+	}
+	fetched, err := encryption.ParseKeys(data)
+	if err != nil {
+		return false, err
+	}
+	keysPath := encryption.KeysPath(certRootDir)
+	keys, err := encryption.LoadKeys(keysPath)
+	if err != nil {
+		return false, err
+	}
+	if fetched.Empty() || (!keys.Empty() && fetched.Generation <= keys.Generation) {
+		return false, nil
+	}
+	if err := fetched.Save(keysPath); err != nil {
+		return false, fmt.Errorf("failed to save the encryption keys: %w", err)
+	}
+	return true, nil
+}
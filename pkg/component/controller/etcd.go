@@ -19,9 +19,11 @@ import (
 	"context"
 	"fmt"
 	"io/ioutil"
+	"net"
 	"os"
 	"path/filepath"
 	"runtime"
+	"strconv"
 	"strings"
 	"time"
 
@@ -34,6 +36,7 @@ import (
 	"github.com/k0sproject/k0s/pkg/certificate"
 	"github.com/k0sproject/k0s/pkg/constant"
 	"github.com/k0sproject/k0s/pkg/etcd"
+	"github.com/k0sproject/k0s/pkg/flags"
 	"github.com/k0sproject/k0s/pkg/supervisor"
 	"github.com/k0sproject/k0s/pkg/token"
 )
@@ -49,6 +52,8 @@ type Etcd struct {
 	supervisor supervisor.Supervisor
 	uid        int
 	gid        int
+	// lastLearnerLog is when the controller last logged that it waits for the promotion of its learner
+	lastLearnerLog time.Time
 }
 
 // Init extracts the needed binaries
@@ -89,7 +94,7 @@ func (e *Etcd) syncEtcdConfig(peerURL, etcdCaCert, etcdCaCertKey string) ([]stri
 	var err error
 	for i := 0; i < 20; i++ {
 		logrus.Infof("trying to sync etcd config")
-		etcdResponse, err = e.JoinClient.JoinEtcd(peerURL)
+		etcdResponse, err = e.JoinClient.JoinEtcd(peerURL, e.Config.IsLearnerEnabled())
 		if err == nil {
 			break
 		}
@@ -122,44 +127,115 @@ func (e *Etcd) syncEtcdConfig(peerURL, etcdCaCert, etcdCaCertKey string) ([]stri
 	return etcdResponse.InitialCluster, nil
 }
 
+// etcdPolicy lists the flags k0s sets that can be replaced with the extra args
+var etcdPolicy = flags.Policy{
+	Component:   "etcd",
+	Replaceable: []string{"enable-pprof", "log-level"},
+}
+
+func (e *Etcd) defaultArgs() (flags.Args, error) {
+	name, err := os.Hostname()
+	if err != nil {
+		return nil, err
+	}
+
+	peerURL := e.Config.PeerURL(e.Config.PeerAddress)
+	listenPeerURL := peerURL
+	if e.Config.PeerListenAddress != "" {
+		listenPeerURL = e.Config.PeerURL(e.Config.PeerListenAddress)
+	}
+	listenClientURLs, advertiseClientURLs := e.clientURLs()
+	etcdCaCert := filepath.Join(e.K0sVars.EtcdCertDir, "ca.crt")
+
+	args := flags.Args{
+		"data-dir":                    e.K0sVars.EtcdDataDir,
+		"listen-client-urls":          strings.Join(listenClientURLs, ","),
+		"advertise-client-urls":       strings.Join(advertiseClientURLs, ","),
+		"client-cert-auth":            "true",
+		"listen-peer-urls":            listenPeerURL,
+		"initial-advertise-peer-urls": peerURL,
+		"name":                        name,
+		"trusted-ca-file":             etcdCaCert,
+		"cert-file":                   filepath.Join(e.K0sVars.EtcdCertDir, "server.crt"),
+		"key-file":                    filepath.Join(e.K0sVars.EtcdCertDir, "server.key"),
+		"peer-trusted-ca-file":        etcdCaCert,
+		"peer-key-file":               filepath.Join(e.K0sVars.EtcdCertDir, "peer.key"),
+		"peer-cert-file":              filepath.Join(e.K0sVars.EtcdCertDir, "peer.crt"),
+		"log-level":                   e.LogLevel,
+		"peer-client-cert-auth":       "true",
+		"enable-pprof":                "false",
+	}
+	if e.Config.QuotaBackendBytes > 0 {
+		args["quota-backend-bytes"] = strconv.FormatInt(e.Config.QuotaBackendBytes, 10)
+	}
+	if e.Config.MetricsListenAddress != "" {
+		args["listen-metrics-urls"] = "http://" + e.Config.MetricsListenAddress
+	}
+	if t := e.Config.TLS; t != nil {
+		if len(t.CipherSuites) > 0 {
+			args["cipher-suites"] = strings.Join(t.CipherSuites, ",")
+		}
+		// etcd accepts TLS 1.2 and newer by default, the validation rejects TLS 1.3 with an etcd older than 3.5
+		if t.MinVersion == config.TLSVersion13 {
+			args["tls-min-version"] = "TLS1.3"
+		}
+	}
+	return args, nil
+}
+
+// clientURLs returns the URLs etcd listens on and advertises for the clients. The controller always
+// connects over the loopback address, the client listen address is for the clients on other hosts.
+func (e *Etcd) clientURLs() (listen []string, advertise []string) {
+	clientURL := e.Config.ClientURL()
+	addr := net.ParseIP(e.Config.ClientListenAddress)
+	switch {
+	case addr == nil || addr.IsLoopback():
+		return []string{clientURL}, []string{clientURL}
+	case addr.IsUnspecified():
+		// listening on all the addresses includes the loopback one
+		return []string{"https://" + net.JoinHostPort(addr.String(), strconv.Itoa(e.Config.GetClientPort()))}, []string{clientURL}
+	}
+	listenURL := "https://" + net.JoinHostPort(addr.String(), strconv.Itoa(e.Config.GetClientPort()))
+	return []string{clientURL, listenURL}, []string{clientURL, listenURL}
+}
+
+// serverHostnames returns the names the server certificate of etcd is valid for
+func (e *Etcd) serverHostnames() []string {
+	hostnames := []string{"127.0.0.1", "localhost"}
+	addr := net.ParseIP(e.Config.ClientListenAddress)
+	switch {
+	case addr == nil || addr.IsLoopback():
+	case addr.IsUnspecified():
+		hostnames = append(hostnames, e.Config.PeerAddress)
+	default:
+		hostnames = append(hostnames, addr.String())
+	}
+	return hostnames
+}
+
+// Args returns the flags etcd is run with, leaving out the ones only known when joining the cluster
+func (e *Etcd) Args() (flags.Args, error) {
+	args, err := e.defaultArgs()
+	if err != nil {
+		return nil, err
+	}
+	return etcdPolicy.Merge(args, e.Config.ExtraArgs)
+}
+
 // Run runs etcd
 func (e *Etcd) Run() error {
 	etcdCaCert := filepath.Join(e.K0sVars.EtcdCertDir, "ca.crt")
 	etcdCaCertKey := filepath.Join(e.K0sVars.EtcdCertDir, "ca.key")
-	etcdServerCert := filepath.Join(e.K0sVars.EtcdCertDir, "server.crt")
-	etcdServerKey := filepath.Join(e.K0sVars.EtcdCertDir, "server.key")
-	etcdPeerCert := filepath.Join(e.K0sVars.EtcdCertDir, "peer.crt")
-	etcdPeerKey := filepath.Join(e.K0sVars.EtcdCertDir, "peer.key")
 	etcdSignKey := filepath.Join(e.K0sVars.EtcdCertDir, "jwt.key")
 	etcdSignPub := filepath.Join(e.K0sVars.EtcdCertDir, "jwt.pub")
 
 	logrus.Info("Starting etcd")
 
-	name, err := os.Hostname()
+	args, err := e.defaultArgs()
 	if err != nil {
 		return err
 	}
-
-	peerURL := fmt.Sprintf("https://%s:2380", e.Config.PeerAddress)
-
-	args := util.MappedArgs{
-		"--data-dir":                    e.K0sVars.EtcdDataDir,
-		"--listen-client-urls":          "https://127.0.0.1:2379",
-		"--advertise-client-urls":       "https://127.0.0.1:2379",
-		"--client-cert-auth":            "true",
-		"--listen-peer-urls":            peerURL,
-		"--initial-advertise-peer-urls": peerURL,
-		"--name":                        name,
-		"--trusted-ca-file":             etcdCaCert,
-		"--cert-file":                   etcdServerCert,
-		"--key-file":                    etcdServerKey,
-		"--peer-trusted-ca-file":        etcdCaCert,
-		"--peer-key-file":               etcdPeerKey,
-		"--peer-cert-file":              etcdPeerCert,
-		"--log-level":                   e.LogLevel,
-		"--peer-client-cert-auth":       "true",
-		"--enable-pprof":                "false",
-	}
+	peerURL := args["initial-advertise-peer-urls"]
 
 	if util.FileExists(filepath.Join(e.K0sVars.EtcdDataDir, "member", "snap", "db")) {
 		logrus.Warnf("etcd db file(s) already exist, not gonna run join process")
@@ -168,8 +244,8 @@ func (e *Etcd) Run() error {
 		if err != nil {
 			return fmt.Errorf("failed to sync etcd config: %w", err)
 		}
-		args["--initial-cluster"] = strings.Join(initialCluster, ",")
-		args["--initial-cluster-state"] = "existing"
+		args["initial-cluster"] = strings.Join(initialCluster, ",")
+		args["initial-cluster-state"] = "existing"
 	}
 
 	if err := e.setupCerts(); err != nil {
@@ -179,7 +255,15 @@ func (e *Etcd) Run() error {
 	// In case this is upgrade/restart, the sign key is not created
 	if util.FileExists(etcdSignKey) && util.FileExists(etcdSignPub) {
 		auth := fmt.Sprintf("jwt,pub-key=%s,priv-key=%s,sign-method=RS512,ttl=10m", etcdSignPub, etcdSignKey)
-		args["--auth-token"] = auth
+		args["auth-token"] = auth
+	}
+
+	args, err = etcdPolicy.Merge(args, e.Config.ExtraArgs)
+	if err != nil {
+		return err
+	}
+	if err := etcdPolicy.Validate(assets.BinPath("etcd", e.K0sVars.BinDir), e.Config.ExtraArgs); err != nil {
+		return err
 	}
 
 	logrus.Infof("starting etcd with args: %v", args)
@@ -232,15 +316,12 @@ func (e *Etcd) setupCerts() error {
 	eg.Go(func() error {
 		// etcd server cert
 		etcdCertReq := certificate.Request{
-			Name:   filepath.Join("etcd", "server"),
-			CN:     "etcd-server",
-			O:      "etcd-server",
-			CACert: etcdCaCert,
-			CAKey:  etcdCaCertKey,
-			Hostnames: []string{
-				"127.0.0.1",
-				"localhost",
-			},
+			Name:      filepath.Join("etcd", "server"),
+			CN:        "etcd-server",
+			O:         "etcd-server",
+			CACert:    etcdCaCert,
+			CAKey:     etcdCaCertKey,
+			Hostnames: e.serverHostnames(),
 		}
 		_, err := e.CertManager.EnsureCertificate(etcdCertReq, constant.EtcdUser)
 		return err
@@ -273,10 +354,42 @@ func (e *Etcd) Healthy() error {
 	logrus.WithField("component", "etcd").Debug("checking etcd endpoint for health")
 	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
 	defer cancel()
-	err := etcd.CheckEtcdReady(ctx, e.K0sVars.CertRootDir, e.K0sVars.EtcdCertDir)
+	if e.Config.IsLearnerEnabled() {
+		// learners don't serve the health check, tell why the controller is waiting
+		c, err := etcd.NewClient(e.K0sVars.CertRootDir, e.K0sVars.EtcdCertDir, e.Config)
+		if err != nil {
+			return err
+		}
+		defer c.Close()
+		status, err := c.Status(ctx)
+		if err != nil {
+			return err
+		}
+		if status.IsLearner {
+			err := fmt.Errorf("etcd member is a learner, waiting for the leader controller to promote it")
+			if e.Config.Learner.ManualPromotion {
+				err = fmt.Errorf("etcd member is a learner, promote it with k0s etcd promote --peer-address %s on a voting member", e.Config.PeerAddress)
+			}
+			// the health check runs many times a second, the error alone is only logged once
+			if time.Since(e.lastLearnerLog) >= time.Minute {
+				logrus.WithField("component", "etcd").Infof("%v, giving up after spec.storage.etcd.learner.promotionTimeout (%s)", err, e.Config.Learner.GetPromotionTimeout())
+				e.lastLearnerLog = time.Now()
+			}
+			return err
+		}
+	}
+	err := etcd.CheckEtcdReady(ctx, e.K0sVars.CertRootDir, e.K0sVars.EtcdCertDir, e.Config)
 	return err
 }
 
+// HealthTimeout gives the learners time to catch up with the leader and get promoted
+func (e *Etcd) HealthTimeout() time.Duration {
+	if e.Config.IsLearnerEnabled() {
+		return e.Config.Learner.GetPromotionTimeout()
+	}
+	return 0
+}
+
 func detectUnsupportedEtcdArch() error {
 	if strings.Contains(runtime.GOARCH, "arm") {
 		if os.Getenv("ETCD_UNSUPPORTED_ARCH") != runtime.GOARCH {
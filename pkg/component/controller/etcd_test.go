@@ -0,0 +1,75 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package controller
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	config "github.com/k0sproject/k0s/pkg/apis/v1beta1"
+)
+
+func TestEtcdArgs(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		e := &Etcd{Config: &config.EtcdConfig{PeerAddress: "10.0.0.1"}}
+		args, err := e.Args()
+		require.NoError(t, err)
+		assert.Equal(t, "https://10.0.0.1:2380", args["listen-peer-urls"])
+		assert.Equal(t, "https://10.0.0.1:2380", args["initial-advertise-peer-urls"])
+		assert.Equal(t, "https://127.0.0.1:2379", args["listen-client-urls"])
+		assert.Equal(t, "https://127.0.0.1:2379", args["advertise-client-urls"])
+		assert.NotContains(t, args, "listen-metrics-urls")
+		assert.NotContains(t, args, "cipher-suites")
+		assert.Equal(t, []string{"127.0.0.1", "localhost"}, e.serverHostnames())
+	})
+
+	t.Run("ports and listeners", func(t *testing.T) {
+		e := &Etcd{Config: &config.EtcdConfig{
+			PeerAddress:          "10.0.0.1",
+			PeerPort:             12380,
+			ClientPort:           12379,
+			PeerListenAddress:    "0.0.0.0",
+			ClientListenAddress:  "10.0.0.1",
+			MetricsListenAddress: "0.0.0.0:12381",
+			TLS: &config.EtcdTLS{
+				CipherSuites: []string{"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256", "TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384"},
+				MinVersion:   config.TLSVersion13,
+			},
+		}}
+		args, err := e.Args()
+		require.NoError(t, err)
+		assert.Equal(t, "https://0.0.0.0:12380", args["listen-peer-urls"])
+		assert.Equal(t, "https://10.0.0.1:12380", args["initial-advertise-peer-urls"])
+		assert.Equal(t, "https://127.0.0.1:12379,https://10.0.0.1:12379", args["listen-client-urls"])
+		assert.Equal(t, "https://127.0.0.1:12379,https://10.0.0.1:12379", args["advertise-client-urls"])
+		assert.Equal(t, "http://0.0.0.0:12381", args["listen-metrics-urls"])
+		assert.Equal(t, "TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384", args["cipher-suites"])
+		assert.Equal(t, "TLS1.3", args["tls-min-version"])
+		assert.Equal(t, []string{"127.0.0.1", "localhost", "10.0.0.1"}, e.serverHostnames())
+	})
+
+	t.Run("all client addresses", func(t *testing.T) {
+		e := &Etcd{Config: &config.EtcdConfig{PeerAddress: "fd00::1", ClientListenAddress: "::"}}
+		args, err := e.Args()
+		require.NoError(t, err)
+		assert.Equal(t, "https://[fd00::1]:2380", args["initial-advertise-peer-urls"])
+		assert.Equal(t, "https://[::]:2379", args["listen-client-urls"])
+		assert.Equal(t, "https://127.0.0.1:2379", args["advertise-client-urls"])
+		assert.Equal(t, []string{"127.0.0.1", "localhost", "fd00::1"}, e.serverHostnames())
+	})
+}
@@ -0,0 +1,113 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"go.etcd.io/etcd/etcdserver/api/v3rpc/rpctypes"
+
+	config "github.com/k0sproject/k0s/pkg/apis/v1beta1"
+	"github.com/k0sproject/k0s/pkg/constant"
+	"github.com/k0sproject/k0s/pkg/etcd"
+)
+
+// etcdLearnerClient is the part of the etcd client the learner promoter uses
+type etcdLearnerClient interface {
+	Members(ctx context.Context) ([]etcd.Member, error)
+	PromoteMember(ctx context.Context, peerID uint64) error
+	Close()
+}
+
+// EtcdLearnerPromoter promotes the etcd learners of the joining controllers once they have caught up with the leader
+type EtcdLearnerPromoter struct {
+	Config  *config.EtcdConfig
+	K0sVars constant.CfgVars
+
+	L *logrus.Entry
+
+	newClient func() (etcdLearnerClient, error)
+}
+
+// Init initializes the component needs
+func (p *EtcdLearnerPromoter) Init() error {
+	p.L = logrus.WithFields(logrus.Fields{"component": "etcdlearnerpromoter"})
+	p.newClient = func() (etcdLearnerClient, error) {
+		return etcd.NewClient(p.K0sVars.CertRootDir, p.K0sVars.EtcdCertDir, p.Config)
+	}
+	return nil
+}
+
+// Run does nothing, the learners are only promoted by the leader
+func (p *EtcdLearnerPromoter) Run() error {
+	return nil
+}
+
+// RunLeader checks for learners every 10 seconds
+func (p *EtcdLearnerPromoter) RunLeader(ctx context.Context) error {
+	ticker := time.NewTicker(10 * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := p.promoteLearners(ctx); err != nil {
+				p.L.Warnf("etcd learner promotion failed: %v", err)
+			}
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// Stop does nothing
+func (p *EtcdLearnerPromoter) Stop() error {
+	return nil
+}
+
+// Healthy dummy implementation
+func (p *EtcdLearnerPromoter) Healthy() error { return nil }
+
+func (p *EtcdLearnerPromoter) promoteLearners(ctx context.Context) error {
+	client, err := p.newClient()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	members, err := client.Members(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list the etcd members: %w", err)
+	}
+	for _, m := range members {
+		if !m.IsLearner {
+			continue
+		}
+		err := client.PromoteMember(ctx, m.ID)
+		switch {
+		case err == nil:
+			p.L.Infof("promoted etcd learner %s (%s)", m.Name, m.PeerURL)
+		case errors.Is(err, rpctypes.ErrMemberLearnerNotReady):
+			p.L.Debugf("etcd learner %s hasn't caught up with the leader yet", m.PeerURL)
+		default:
+			return fmt.Errorf("failed to promote etcd learner %s: %w", m.PeerURL, err)
+		}
+	}
+	return nil
+}
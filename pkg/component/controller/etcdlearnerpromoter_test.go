@@ -0,0 +1,66 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package controller
+
+import (
+	"context"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.etcd.io/etcd/etcdserver/api/v3rpc/rpctypes"
+
+	"github.com/k0sproject/k0s/pkg/etcd"
+)
+
+type fakeEtcdLearnerClient struct {
+	members  []etcd.Member
+	notReady map[uint64]bool
+	promoted []uint64
+}
+
+func (f *fakeEtcdLearnerClient) Members(context.Context) ([]etcd.Member, error) {
+	return f.members, nil
+}
+
+func (f *fakeEtcdLearnerClient) PromoteMember(_ context.Context, peerID uint64) error {
+	if f.notReady[peerID] {
+		return rpctypes.ErrMemberLearnerNotReady
+	}
+	f.promoted = append(f.promoted, peerID)
+	return nil
+}
+
+func (f *fakeEtcdLearnerClient) Close() {}
+
+func TestEtcdLearnerPromoter(t *testing.T) {
+	client := &fakeEtcdLearnerClient{
+		members: []etcd.Member{
+			{ID: 1, Name: "controller-1", PeerURL: "https://10.0.0.1:2380"},
+			{ID: 2, Name: "controller-2", PeerURL: "https://10.0.0.2:2380", IsLearner: true},
+			{ID: 3, PeerURL: "https://10.0.0.3:2380", IsLearner: true},
+		},
+		notReady: map[uint64]bool{3: true},
+	}
+	p := &EtcdLearnerPromoter{
+		L:         logrus.WithField("test", t.Name()),
+		newClient: func() (etcdLearnerClient, error) { return client, nil },
+	}
+
+	require.NoError(t, p.promoteLearners(context.Background()))
+	assert.Equal(t, []uint64{2}, client.promoted, "only the learner in sync should have been promoted")
+}
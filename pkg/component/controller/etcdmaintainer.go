@@ -0,0 +1,208 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	config "github.com/k0sproject/k0s/pkg/apis/v1beta1"
+	"github.com/k0sproject/k0s/pkg/constant"
+	"github.com/k0sproject/k0s/pkg/etcd"
+)
+
+// etcdMaintenanceClient is the part of the etcd client the maintainer uses
+type etcdMaintenanceClient interface {
+	Status(ctx context.Context) (*etcd.Status, error)
+	NoSpaceAlarms(ctx context.Context) ([]uint64, error)
+	DisarmNoSpaceAlarm(ctx context.Context, memberID uint64) error
+	Defragment(ctx context.Context) error
+	Lock(ctx context.Context, name string) (func(), error)
+	Close()
+}
+
+// etcdDefragLock is the etcd lock making the controllers defragment their members one at a time
+const etcdDefragLock = "/k0s/etcd-defrag"
+
+// etcdNoSpaceDefragTurn is how long a member with the NOSPACE alarm waits for each member with a lower ID
+// that still has the alarm, before defragmenting anyway. It's the defragmentation timeout, so that a member
+// that can't clear its alarm, e.g. because it's down, doesn't block the rest.
+const etcdNoSpaceDefragTurn = 5 * time.Minute
+
+// EtcdMaintainer watches the database size and the alarms of the etcd member of the controller and
+// defragments it when scheduled, when the database grows past the threshold or when the quota is exceeded
+type EtcdMaintainer struct {
+	Config  *config.EtcdConfig
+	K0sVars constant.CfgVars
+
+	L *logrus.Entry
+
+	newClient  func() (etcdMaintenanceClient, error)
+	now        func() time.Time
+	lastDefrag time.Time
+	nearQuota  bool
+	// noSpaceSince is when the NOSPACE alarm of the member was first seen, zero if it's not raised
+	noSpaceSince time.Time
+	cancel       context.CancelFunc
+	done         chan struct{}
+}
+
+// Init initializes the component needs
+func (m *EtcdMaintainer) Init() error {
+	m.L = logrus.WithFields(logrus.Fields{"component": "etcdmaintainer"})
+	m.now = time.Now
+	m.newClient = func() (etcdMaintenanceClient, error) {
+		return etcd.NewClient(m.K0sVars.CertRootDir, m.K0sVars.EtcdCertDir, m.Config)
+	}
+	return nil
+}
+
+// Run checks the member every minute
+func (m *EtcdMaintainer) Run() error {
+	ctx, cancel := context.WithCancel(context.Background())
+	m.cancel = cancel
+	m.done = make(chan struct{})
+	m.lastDefrag = m.now()
+
+	go func() {
+		defer close(m.done)
+		ticker := time.NewTicker(time.Minute)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if err := m.reconcile(ctx); err != nil {
+					m.L.Warnf("etcd maintenance failed: %v", err)
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return nil
+}
+
+// Stop stops the maintenance, waiting for a running defragmentation to return
+func (m *EtcdMaintainer) Stop() error {
+	if m.cancel != nil {
+		m.cancel()
+		<-m.done
+	}
+	return nil
+}
+
+// Healthy dummy implementation
+func (m *EtcdMaintainer) Healthy() error { return nil }
+
+func (m *EtcdMaintainer) reconcile(ctx context.Context) error {
+	client, err := m.newClient()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	status, err := client.Status(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get the etcd member status: %w", err)
+	}
+	alarms, err := client.NoSpaceAlarms(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list the etcd alarms: %w", err)
+	}
+	noSpace := false
+	for _, id := range alarms {
+		noSpace = noSpace || id == status.MemberID
+	}
+	if !noSpace {
+		m.noSpaceSince = time.Time{}
+	} else if m.noSpaceSince.IsZero() {
+		m.noSpaceSince = m.now()
+	}
+
+	quota := m.Config.GetQuotaBackendBytes()
+	usage := int(status.DBSize * 100 / quota)
+	m.L.Debugf("etcd database size %d bytes, %d bytes in use, %d%% of the quota", status.DBSize, status.DBSizeInUse, usage)
+	if usage >= 80 && !m.nearQuota {
+		m.L.Warnf("etcd database size is %d%% of the quota of %d bytes, etcd stops accepting writes once it's exceeded", usage, quota)
+	}
+	m.nearQuota = usage >= 80
+
+	var reason string
+	maintenance := m.Config.Maintenance
+	switch {
+	case noSpace:
+		reason = "the etcd database exceeds the quota"
+	case maintenance != nil && maintenance.DefragThresholdPercent > 0 && usage >= maintenance.DefragThresholdPercent &&
+		// defragmenting only helps if at least a tenth of the database is free
+		(status.DBSize-status.DBSizeInUse)*10 >= status.DBSize:
+		reason = fmt.Sprintf("the etcd database size is %d%% of the quota", usage)
+	case maintenance.GetDefragInterval() > 0 && m.now().Sub(m.lastDefrag) >= maintenance.GetDefragInterval():
+		reason = "scheduled defragmentation"
+	default:
+		return nil
+	}
+
+	// The lock can't be written while the NOSPACE alarm is raised. The members with the alarm take turns in the
+	// order of their IDs instead, the alarm list is readable and a member leaves it once it's defragmented.
+	if noSpace {
+		ahead := 0
+		for _, id := range alarms {
+			if id < status.MemberID {
+				ahead++
+			}
+		}
+		if ahead > 0 && m.now().Sub(m.noSpaceSince) < time.Duration(ahead)*etcdNoSpaceDefragTurn {
+			m.L.Infof("waiting for %d etcd members with a lower ID to clear their NOSPACE alarm before defragmenting", ahead)
+			return nil
+		}
+	} else {
+		lockCtx, cancel := context.WithTimeout(ctx, 10*time.Minute)
+		defer cancel()
+		unlock, err := client.Lock(lockCtx, etcdDefragLock)
+		if err != nil {
+			return fmt.Errorf("failed to acquire the etcd defragmentation lock: %w", err)
+		}
+		defer unlock()
+	}
+
+	m.L.Infof("defragmenting the etcd member: %s", reason)
+	defragCtx, cancel := context.WithTimeout(ctx, 5*time.Minute)
+	defer cancel()
+	if err := client.Defragment(defragCtx); err != nil {
+		return fmt.Errorf("failed to defragment the etcd member: %w", err)
+	}
+	m.lastDefrag = m.now()
+
+	status, err = client.Status(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get the etcd member status: %w", err)
+	}
+	m.L.Infof("defragmented the etcd member, database size is %d bytes", status.DBSize)
+	if !noSpace {
+		return nil
+	}
+	if status.DBSize >= quota {
+		return fmt.Errorf("etcd database still exceeds the quota of %d bytes after defragmentation, increase spec.storage.etcd.quotaBackendBytes", quota)
+	}
+	if err := client.DisarmNoSpaceAlarm(ctx, status.MemberID); err != nil {
+		return fmt.Errorf("failed to clear the etcd NOSPACE alarm: %w", err)
+	}
+	m.L.Info("cleared the etcd NOSPACE alarm")
+	return nil
+}
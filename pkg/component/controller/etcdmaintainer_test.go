@@ -0,0 +1,175 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package controller
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	config "github.com/k0sproject/k0s/pkg/apis/v1beta1"
+	"github.com/k0sproject/k0s/pkg/etcd"
+)
+
+type fakeEtcdMaintenanceClient struct {
+	status        etcd.Status
+	sizeAfter     int64
+	noSpace       []uint64
+	locked        bool
+	defragmented  bool
+	defragLocked  bool
+	alarmDisarmed bool
+}
+
+func (f *fakeEtcdMaintenanceClient) Status(context.Context) (*etcd.Status, error) {
+	status := f.status
+	return &status, nil
+}
+
+func (f *fakeEtcdMaintenanceClient) NoSpaceAlarms(context.Context) ([]uint64, error) {
+	return f.noSpace, nil
+}
+
+func (f *fakeEtcdMaintenanceClient) DisarmNoSpaceAlarm(context.Context, uint64) error {
+	f.alarmDisarmed = true
+	return nil
+}
+
+func (f *fakeEtcdMaintenanceClient) Defragment(context.Context) error {
+	f.defragmented = true
+	f.defragLocked = f.locked
+	f.status.DBSize = f.sizeAfter
+	return nil
+}
+
+func (f *fakeEtcdMaintenanceClient) Lock(context.Context, string) (func(), error) {
+	f.locked = true
+	return func() { f.locked = false }, nil
+}
+
+func (f *fakeEtcdMaintenanceClient) Close() {}
+
+func TestEtcdMaintainer(t *testing.T) {
+	const mb = 1024 * 1024
+	newMaintainer := func(etcdConf *config.EtcdConfig, client *fakeEtcdMaintenanceClient) (*EtcdMaintainer, *time.Time) {
+		now := time.Now()
+		return &EtcdMaintainer{
+			Config:     etcdConf,
+			L:          logrus.WithField("test", t.Name()),
+			newClient:  func() (etcdMaintenanceClient, error) { return client, nil },
+			now:        func() time.Time { return now },
+			lastDefrag: now,
+		}, &now
+	}
+
+	t.Run("nothing to do", func(t *testing.T) {
+		client := &fakeEtcdMaintenanceClient{status: etcd.Status{MemberID: 1, DBSize: 100 * mb, DBSizeInUse: 50 * mb}}
+		m, _ := newMaintainer(&config.EtcdConfig{}, client)
+		require.NoError(t, m.reconcile(context.Background()))
+		assert.False(t, client.defragmented)
+	})
+
+	t.Run("scheduled", func(t *testing.T) {
+		client := &fakeEtcdMaintenanceClient{status: etcd.Status{MemberID: 1, DBSize: 100 * mb, DBSizeInUse: 90 * mb}}
+		m, now := newMaintainer(&config.EtcdConfig{Maintenance: &config.EtcdMaintenance{DefragInterval: "24h"}}, client)
+		require.NoError(t, m.reconcile(context.Background()))
+		assert.False(t, client.defragmented)
+
+		*now = now.Add(25 * time.Hour)
+		require.NoError(t, m.reconcile(context.Background()))
+		assert.True(t, client.defragmented)
+		assert.True(t, client.defragLocked, "defragmented without holding the lock")
+		assert.False(t, client.locked, "lock not released")
+		assert.Equal(t, *now, m.lastDefrag)
+	})
+
+	t.Run("size threshold", func(t *testing.T) {
+		etcdConf := &config.EtcdConfig{QuotaBackendBytes: 1000 * mb, Maintenance: &config.EtcdMaintenance{DefragThresholdPercent: 70}}
+
+		client := &fakeEtcdMaintenanceClient{status: etcd.Status{MemberID: 1, DBSize: 800 * mb, DBSizeInUse: 780 * mb}}
+		m, _ := newMaintainer(etcdConf, client)
+		require.NoError(t, m.reconcile(context.Background()))
+		assert.False(t, client.defragmented, "defragmented without enough free space")
+
+		client = &fakeEtcdMaintenanceClient{status: etcd.Status{MemberID: 1, DBSize: 800 * mb, DBSizeInUse: 300 * mb}, sizeAfter: 300 * mb}
+		m, _ = newMaintainer(etcdConf, client)
+		require.NoError(t, m.reconcile(context.Background()))
+		assert.True(t, client.defragmented)
+		assert.False(t, client.alarmDisarmed)
+	})
+
+	t.Run("NOSPACE alarm", func(t *testing.T) {
+		client := &fakeEtcdMaintenanceClient{
+			status:    etcd.Status{MemberID: 1, DBSize: 2 * config.DefaultEtcdQuotaBackendBytes, DBSizeInUse: 100 * mb},
+			sizeAfter: 100 * mb,
+			noSpace:   []uint64{1},
+		}
+		m, _ := newMaintainer(&config.EtcdConfig{}, client)
+		require.NoError(t, m.reconcile(context.Background()))
+		assert.True(t, client.defragmented)
+		assert.False(t, client.defragLocked, "the lock can't be taken while the alarm is raised")
+		assert.True(t, client.alarmDisarmed)
+	})
+
+	t.Run("NOSPACE alarm kept while over quota", func(t *testing.T) {
+		client := &fakeEtcdMaintenanceClient{
+			status:    etcd.Status{MemberID: 1, DBSize: 2 * config.DefaultEtcdQuotaBackendBytes, DBSizeInUse: 2 * config.DefaultEtcdQuotaBackendBytes},
+			sizeAfter: 2 * config.DefaultEtcdQuotaBackendBytes,
+			noSpace:   []uint64{1},
+		}
+		m, _ := newMaintainer(&config.EtcdConfig{}, client)
+		assert.Error(t, m.reconcile(context.Background()))
+		assert.False(t, client.alarmDisarmed)
+	})
+
+	t.Run("NOSPACE alarm waits for members with a lower ID", func(t *testing.T) {
+		client := &fakeEtcdMaintenanceClient{
+			status:    etcd.Status{MemberID: 3, DBSize: 2 * config.DefaultEtcdQuotaBackendBytes, DBSizeInUse: 100 * mb},
+			sizeAfter: 100 * mb,
+			noSpace:   []uint64{1, 2, 3},
+		}
+		m, now := newMaintainer(&config.EtcdConfig{}, client)
+		require.NoError(t, m.reconcile(context.Background()))
+		assert.False(t, client.defragmented, "defragmented while members with a lower ID have the alarm")
+
+		client.noSpace = []uint64{3}
+		require.NoError(t, m.reconcile(context.Background()))
+		assert.True(t, client.defragmented)
+		assert.True(t, client.alarmDisarmed)
+		assert.Equal(t, *now, m.lastDefrag)
+	})
+
+	t.Run("NOSPACE alarm stops waiting after the turns of the lower members", func(t *testing.T) {
+		client := &fakeEtcdMaintenanceClient{
+			status:    etcd.Status{MemberID: 3, DBSize: 2 * config.DefaultEtcdQuotaBackendBytes, DBSizeInUse: 100 * mb},
+			sizeAfter: 100 * mb,
+			noSpace:   []uint64{1, 2, 3},
+		}
+		m, now := newMaintainer(&config.EtcdConfig{}, client)
+		require.NoError(t, m.reconcile(context.Background()))
+		*now = now.Add(etcdNoSpaceDefragTurn)
+		require.NoError(t, m.reconcile(context.Background()))
+		assert.False(t, client.defragmented, "defragmented before the turn of the second member passed")
+
+		*now = now.Add(etcdNoSpaceDefragTurn)
+		require.NoError(t, m.reconcile(context.Background()))
+		assert.True(t, client.defragmented)
+	})
+}
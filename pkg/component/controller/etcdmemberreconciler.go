@@ -0,0 +1,209 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	config "github.com/k0sproject/k0s/pkg/apis/v1beta1"
+	"github.com/k0sproject/k0s/pkg/constant"
+	"github.com/k0sproject/k0s/pkg/etcd"
+	kubeutil "github.com/k0sproject/k0s/pkg/kubernetes"
+)
+
+// etcdMemberClient is the part of the etcd client the member reconciler uses
+type etcdMemberClient interface {
+	Members(ctx context.Context) ([]etcd.Member, error)
+	DeleteMember(ctx context.Context, peerID uint64) error
+	Close()
+}
+
+// EtcdMemberReconciler removes the etcd members of the controllers that have been unreachable for longer than
+// the grace period, one at a time and only while the cluster has quorum
+type EtcdMemberReconciler struct {
+	ClusterConfig     *config.ClusterConfig
+	K0sVars           constant.CfgVars
+	KubeClientFactory kubeutil.ClientFactory
+
+	L *logrus.Entry
+
+	newClient        func() (etcdMemberClient, error)
+	checkPeer        func(ctx context.Context, peerURL string) error
+	now              func() time.Time
+	unreachableSince map[uint64]time.Time
+}
+
+// NewEtcdMemberReconciler creates the etcd member reconciler
+func NewEtcdMemberReconciler(c *config.ClusterConfig, k0sVars constant.CfgVars, kubeClientFactory kubeutil.ClientFactory) *EtcdMemberReconciler {
+	r := &EtcdMemberReconciler{
+		ClusterConfig:     c,
+		K0sVars:           k0sVars,
+		KubeClientFactory: kubeClientFactory,
+		L:                 logrus.WithFields(logrus.Fields{"component": "etcdmemberreconciler"}),
+		now:               time.Now,
+		unreachableSince:  map[uint64]time.Time{},
+	}
+	r.newClient = func() (etcdMemberClient, error) {
+		return etcd.NewClient(k0sVars.CertRootDir, k0sVars.EtcdCertDir, c.Spec.Storage.Etcd)
+	}
+	r.checkPeer = func(ctx context.Context, peerURL string) error {
+		return etcd.CheckPeerReachable(ctx, k0sVars.EtcdCertDir, peerURL)
+	}
+	return r
+}
+
+// Init does nothing
+func (r *EtcdMemberReconciler) Init() error {
+	return nil
+}
+
+// Run does nothing, the members are only reconciled by the leader
+func (r *EtcdMemberReconciler) Run() error {
+	return nil
+}
+
+// RunLeader checks the members every 30 seconds
+func (r *EtcdMemberReconciler) RunLeader(ctx context.Context) error {
+	// the members unreachable while another controller was leading have to be waited for again
+	r.unreachableSince = map[uint64]time.Time{}
+
+	ticker := time.NewTicker(30 * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := r.reconcile(ctx); err != nil {
+				r.L.Warnf("etcd member reconciliation failed: %v", err)
+			}
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// Stop does nothing
+func (r *EtcdMemberReconciler) Stop() error {
+	return nil
+}
+
+// Healthy dummy implementation
+func (r *EtcdMemberReconciler) Healthy() error { return nil }
+
+func (r *EtcdMemberReconciler) reconcile(ctx context.Context) error {
+	client, err := r.newClient()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	members, err := client.Members(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list the etcd members: %w", err)
+	}
+
+	now := r.now()
+	listed := make(map[uint64]bool, len(members))
+	voters, reachable := 0, 0
+	for _, m := range members {
+		listed[m.ID] = true
+		if !m.IsLearner {
+			voters++
+		}
+		checkCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		err := r.checkPeer(checkCtx, m.PeerURL)
+		cancel()
+		if err == nil {
+			if !m.IsLearner {
+				reachable++
+			}
+			delete(r.unreachableSince, m.ID)
+			continue
+		}
+		if _, ok := r.unreachableSince[m.ID]; !ok {
+			r.L.Infof("etcd member %s (%s) is unreachable: %v", m.Name, m.PeerURL, err)
+			r.unreachableSince[m.ID] = now
+		}
+	}
+	for id := range r.unreachableSince {
+		if !listed[id] {
+			delete(r.unreachableSince, id)
+		}
+	}
+
+	// the learners don't vote, they don't count for the quorum
+	quorum := voters/2 + 1
+	if reachable < quorum {
+		r.L.Warnf("only %d of %d voting etcd members are reachable, not removing any", reachable, voters)
+		return nil
+	}
+
+	heldLeases, err := r.heldControllerLeases(ctx)
+	if err != nil {
+		return err
+	}
+
+	gracePeriod := r.ClusterConfig.Spec.Storage.Etcd.MemberCleanup.GetGracePeriod()
+	for _, m := range members {
+		since, ok := r.unreachableSince[m.ID]
+		if !ok || now.Sub(since) < gracePeriod {
+			continue
+		}
+		if heldLeases["k0s-ctrl-"+m.Name] {
+			r.L.Debugf("etcd member %s is unreachable but its controller still holds its lease", m.Name)
+			continue
+		}
+
+		// the member is unreachable, the cluster keeps its quorum without it
+		r.L.Infof("removing etcd member %s (%s), unreachable since %s", m.Name, m.PeerURL, since.Format(time.RFC3339))
+		if err := client.DeleteMember(ctx, m.ID); err != nil {
+			return fmt.Errorf("failed to remove etcd member %s: %w", m.Name, err)
+		}
+		delete(r.unreachableSince, m.ID)
+		// one at a time, the next round sees the new member list
+		return nil
+	}
+
+	return nil
+}
+
+// heldControllerLeases returns the names of the controller leases still held, the controllers only hold
+// them when running behind an external address
+func (r *EtcdMemberReconciler) heldControllerLeases(ctx context.Context) (map[string]bool, error) {
+	held := map[string]bool{}
+	if r.ClusterConfig.Spec.API.ExternalAddress == "" {
+		return held, nil
+	}
+
+	client, err := r.KubeClientFactory.GetClient()
+	if err != nil {
+		return nil, err
+	}
+	leases, err := client.CoordinationV1().Leases("kube-node-lease").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the controller leases: %w", err)
+	}
+	for _, l := range leases.Items {
+		if kubeutil.IsValidLease(l) {
+			held[l.Name] = true
+		}
+	}
+	return held, nil
+}
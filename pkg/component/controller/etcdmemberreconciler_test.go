@@ -0,0 +1,146 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package controller
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/k0sproject/k0s/internal/testutil"
+	config "github.com/k0sproject/k0s/pkg/apis/v1beta1"
+	"github.com/k0sproject/k0s/pkg/constant"
+	"github.com/k0sproject/k0s/pkg/etcd"
+)
+
+type fakeEtcdMemberClient struct {
+	members []etcd.Member
+	deleted []uint64
+}
+
+func (f *fakeEtcdMemberClient) Members(context.Context) ([]etcd.Member, error) {
+	return f.members, nil
+}
+
+func (f *fakeEtcdMemberClient) DeleteMember(_ context.Context, peerID uint64) error {
+	f.deleted = append(f.deleted, peerID)
+	return nil
+}
+
+func (f *fakeEtcdMemberClient) Close() {}
+
+func TestEtcdMemberReconciler(t *testing.T) {
+	newReconciler := func(t *testing.T, members []etcd.Member, unreachable []string) (*EtcdMemberReconciler, *fakeEtcdMemberClient, *time.Time) {
+		k0sVars := constant.GetConfig(t.TempDir())
+		cfg := config.DefaultClusterConfig(k0sVars)
+		cfg.Spec.Storage.Etcd.MemberCleanup = &config.EtcdMemberCleanup{Enabled: true, GracePeriod: "10m"}
+		client := &fakeEtcdMemberClient{members: members}
+		now := time.Now()
+		r := &EtcdMemberReconciler{
+			ClusterConfig:     cfg,
+			K0sVars:           k0sVars,
+			KubeClientFactory: testutil.NewFakeClientFactory(),
+			L:                 logrus.WithField("test", t.Name()),
+			newClient:         func() (etcdMemberClient, error) { return client, nil },
+			checkPeer: func(_ context.Context, peerURL string) error {
+				for _, u := range unreachable {
+					if u == peerURL {
+						return errors.New("connection refused")
+					}
+				}
+				return nil
+			},
+			now:              func() time.Time { return now },
+			unreachableSince: map[uint64]time.Time{},
+		}
+		return r, client, &now
+	}
+	members := []etcd.Member{
+		{ID: 1, Name: "controller-1", PeerURL: "https://10.0.0.1:2380"},
+		{ID: 2, Name: "controller-2", PeerURL: "https://10.0.0.2:2380"},
+		{ID: 3, Name: "controller-3", PeerURL: "https://10.0.0.3:2380"},
+	}
+
+	t.Run("removes unreachable member after grace period", func(t *testing.T) {
+		r, client, now := newReconciler(t, members, []string{"https://10.0.0.3:2380"})
+		ctx := context.Background()
+
+		require.NoError(t, r.reconcile(ctx))
+		assert.Empty(t, client.deleted)
+
+		*now = now.Add(5 * time.Minute)
+		require.NoError(t, r.reconcile(ctx))
+		assert.Empty(t, client.deleted)
+
+		*now = now.Add(6 * time.Minute)
+		require.NoError(t, r.reconcile(ctx))
+		assert.Equal(t, []uint64{3}, client.deleted)
+	})
+
+	t.Run("member reachable again is not removed", func(t *testing.T) {
+		r, client, now := newReconciler(t, members, []string{"https://10.0.0.3:2380"})
+		ctx := context.Background()
+
+		require.NoError(t, r.reconcile(ctx))
+		r.checkPeer = func(context.Context, string) error { return nil }
+		*now = now.Add(5 * time.Minute)
+		require.NoError(t, r.reconcile(ctx))
+
+		r.checkPeer = func(_ context.Context, peerURL string) error {
+			if peerURL == "https://10.0.0.3:2380" {
+				return errors.New("connection refused")
+			}
+			return nil
+		}
+		*now = now.Add(6 * time.Minute)
+		require.NoError(t, r.reconcile(ctx))
+		assert.Empty(t, client.deleted)
+	})
+
+	t.Run("never removes members without quorum", func(t *testing.T) {
+		r, client, now := newReconciler(t, members, []string{"https://10.0.0.2:2380", "https://10.0.0.3:2380"})
+		ctx := context.Background()
+
+		require.NoError(t, r.reconcile(ctx))
+		*now = now.Add(time.Hour)
+		require.NoError(t, r.reconcile(ctx))
+		assert.Empty(t, client.deleted)
+	})
+
+	t.Run("keeps members of controllers holding their lease", func(t *testing.T) {
+		r, client, now := newReconciler(t, members, []string{"https://10.0.0.3:2380"})
+		r.ClusterConfig.Spec.API.ExternalAddress = "k0s.example.com"
+		duration := int32(60)
+		renewTime := metav1.NewMicroTime(time.Now())
+		r.KubeClientFactory = testutil.NewFakeClientFactory(&coordinationv1.Lease{
+			ObjectMeta: metav1.ObjectMeta{Name: "k0s-ctrl-controller-3", Namespace: "kube-node-lease"},
+			Spec:       coordinationv1.LeaseSpec{LeaseDurationSeconds: &duration, RenewTime: &renewTime},
+		})
+		ctx := context.Background()
+
+		require.NoError(t, r.reconcile(ctx))
+		*now = now.Add(time.Hour)
+		require.NoError(t, r.reconcile(ctx))
+		assert.Empty(t, client.deleted)
+	})
+}
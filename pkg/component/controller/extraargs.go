@@ -0,0 +1,66 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package controller
+
+import (
+	"path/filepath"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/k0sproject/k0s/pkg/assets"
+	"github.com/k0sproject/k0s/pkg/flags"
+
+	config "github.com/k0sproject/k0s/pkg/apis/v1beta1"
+)
+
+type extraArgsCheck struct {
+	binary string
+	policy flags.Policy
+	extra  config.ExtraArgs
+}
+
+// ValidateExtraArgs checks the extra args of the control plane components against the flags the binaries in
+// binDir know. The components whose binary isn't installed are skipped with a warning.
+func ValidateExtraArgs(spec *config.ClusterSpec, binDir string) []error {
+	components := []extraArgsCheck{
+		{"kube-apiserver", apiServerPolicy, spec.API.ExtraArgs},
+		{"kube-controller-manager", controllerManagerPolicy, spec.ControllerManager.ExtraArgs},
+		{"kube-scheduler", schedulerPolicy, spec.Scheduler.ExtraArgs},
+		{"konnectivity-server", konnectivityPolicy, spec.Konnectivity.ExtraArgs},
+	}
+	switch {
+	case spec.Storage.Type == config.EtcdStorageType && !spec.Storage.Etcd.IsExternalClusterUsed():
+		components = append(components, extraArgsCheck{"etcd", etcdPolicy, spec.Storage.Etcd.ExtraArgs})
+	case spec.Storage.Type == config.KineStorageType && spec.Storage.Kine != nil:
+		components = append(components, extraArgsCheck{"kine", kinePolicy, spec.Storage.Kine.ExtraArgs})
+	}
+
+	var errors []error
+	for _, c := range components {
+		if len(c.extra) == 0 {
+			continue
+		}
+		binPath := assets.BinPath(c.binary, binDir)
+		if !filepath.IsAbs(binPath) {
+			logrus.Warnf("can't check the %s extra args, the %s binary isn't installed", c.policy.Component, c.binary)
+			continue
+		}
+		if err := c.policy.Validate(binPath, c.extra); err != nil {
+			errors = append(errors, err)
+		}
+	}
+	return errors
+}
@@ -5,6 +5,7 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/sirupsen/logrus"
@@ -23,6 +24,7 @@ import (
 	"github.com/k0sproject/k0s/pkg/constant"
 	"github.com/k0sproject/k0s/pkg/helm"
 	kubeutil "github.com/k0sproject/k0s/pkg/kubernetes"
+	"github.com/k0sproject/k0s/pkg/secretref"
 )
 
 // Helm watch for Chart crd
@@ -31,25 +33,23 @@ type HelmAddons struct {
 	ClusterConfig     *k0sv1beta1.ClusterConfig
 	saver             manifestsSaver
 	L                 *logrus.Entry
-	stopCh            chan struct{}
 	informer          cache.SharedIndexInformer
 	helm              *helm.Commands
 	kubeConfig        string
 	kubeClientFactory kubeutil.ClientFactory
-	leaderElector     LeaderElector
+
+	configMu sync.Mutex
 }
 
 // NewHelmAddons builds new HelmAddons
-func NewHelmAddons(c *k0sv1beta1.ClusterConfig, s manifestsSaver, k0sVars constant.CfgVars, kubeClientFactory kubeutil.ClientFactory, leaderElector LeaderElector) *HelmAddons {
+func NewHelmAddons(c *k0sv1beta1.ClusterConfig, s manifestsSaver, k0sVars constant.CfgVars, kubeClientFactory kubeutil.ClientFactory) *HelmAddons {
 	return &HelmAddons{
 		ClusterConfig:     c,
 		saver:             s,
 		L:                 logrus.WithFields(logrus.Fields{"component": "helmaddons"}),
-		stopCh:            make(chan struct{}),
 		helm:              helm.NewCommands(k0sVars),
 		kubeConfig:        k0sVars.AdminKubeConfigPath,
 		kubeClientFactory: kubeClientFactory,
-		leaderElector:     leaderElector,
 	}
 }
 
@@ -64,10 +64,6 @@ const (
 // Run runs the helm controller
 func (h *HelmAddons) Run() error {
 	h.L.Info("run begin")
-	if h.ClusterConfig.Spec.Extensions == nil || h.ClusterConfig.Spec.Extensions.Helm == nil {
-		h.L.Info("No helm addons specified, do not run HelmAddons reconciler")
-		return nil
-	}
 	// TODO Can we use the shared kube client factory to create the clientset for helm CRDs?
 	client, err := clientset.NewForConfig(h.kubeConfig)
 	if err != nil {
@@ -76,21 +72,42 @@ func (h *HelmAddons) Run() error {
 
 	h.Client = client
 
+	h.configMu.Lock()
+	defer h.configMu.Unlock()
 	if err := h.initHelm(); err != nil {
 		return fmt.Errorf("can't init helm: %v", err)
 	}
+	return nil
+}
 
-	h.L.Info("Successfully inited helm")
-	if !cache.WaitForCacheSync(h.stopCh) {
-		panic("Can't sync cache")
+// Reconcile adds the repositories and chart manifests of the changed cluster config. Charts removed from
+// the config are not uninstalled, the Chart object needs to be deleted for that.
+func (h *HelmAddons) Reconcile(cfg *k0sv1beta1.ClusterConfig) error {
+	h.configMu.Lock()
+	defer h.configMu.Unlock()
+	h.ClusterConfig = cfg
+	if h.Client == nil {
+		// not running yet, Run takes care of it
+		return nil
 	}
-	h.L.Info("Successfully synced controller cache")
+	return h.initHelm()
+}
 
-	go h.CrdControlLoop()
+// RunLeader runs the chart control loop for as long as we're the leader
+func (h *HelmAddons) RunLeader(ctx context.Context) error {
+	if h.Client == nil {
+		return nil
+	}
+	h.CrdControlLoop(ctx)
 	return nil
 }
 
+// initHelm adds the repositories and saves the chart manifests, the caller must hold the config lock
 func (h *HelmAddons) initHelm() error {
+	if h.ClusterConfig.Spec.Extensions == nil || h.ClusterConfig.Spec.Extensions.Helm == nil {
+		h.L.Info("No helm addons specified")
+		return nil
+	}
 	for _, repo := range h.ClusterConfig.Spec.Extensions.Helm.Repositories {
 		if err := h.addRepo(repo); err != nil {
 			return fmt.Errorf("can't init repository `%s`: %v", repo.URL, err)
@@ -112,6 +129,7 @@ func (h *HelmAddons) initHelm() error {
 			return fmt.Errorf("can't save addon CRD manifest: %v", err)
 		}
 	}
+	h.L.Info("Successfully inited helm")
 	return nil
 }
 
@@ -120,7 +138,7 @@ type queueJob struct {
 	operation string
 }
 
-func (h *HelmAddons) CrdControlLoop() {
+func (h *HelmAddons) CrdControlLoop(ctx context.Context) {
 	queue := workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter())
 	defer queue.ShutDown()
 	h.informer = cache.NewSharedIndexInformer(
@@ -171,23 +189,27 @@ func (h *HelmAddons) CrdControlLoop() {
 			queue.Add(queueJob{key: chart.Status.Namespace + "/" + chart.Status.ReleaseName, operation: operationDelete})
 		},
 	})
-	go h.informer.Run(h.stopCh)
+	go h.informer.Run(ctx.Done())
+	go func() {
+		// unblock the workers
+		<-ctx.Done()
+		queue.ShutDown()
+	}()
 	wait.Until(func() {
-		for {
-			h.processMessage(queue)
+		for h.processMessage(queue) {
 		}
-	}, time.Second, h.stopCh)
+	}, time.Second, ctx.Done())
 }
 
 const maxRetries = 5
 
-func (h *HelmAddons) processMessage(q workqueue.RateLimitingInterface) {
+// processMessage processes a single job from the queue, it returns false once the queue is shut down
+func (h *HelmAddons) processMessage(q workqueue.RateLimitingInterface) bool {
 	jobI, quit := q.Get()
-	job := jobI.(queueJob)
-
 	if quit {
-		return
+		return false
 	}
+	job := jobI.(queueJob)
 
 	defer q.Done(job)
 
@@ -203,7 +225,7 @@ func (h *HelmAddons) processMessage(q workqueue.RateLimitingInterface) {
 		if q.NumRequeues(job) < maxRetries {
 			h.L.WithError(err).Errorf("Error processing %s (will retry)", job.key)
 			q.AddRateLimited(job)
-			return
+			return true
 		}
 		h.saveError(err, job.key)
 		h.L.WithError(err).Errorf("Error processing %s (giving up)", job.key)
@@ -211,7 +233,7 @@ func (h *HelmAddons) processMessage(q workqueue.RateLimitingInterface) {
 	}
 
 	q.Forget(job)
-
+	return true
 }
 
 func (h *HelmAddons) saveError(origErr error, objectID string) {
@@ -234,10 +256,6 @@ func (h *HelmAddons) saveError(origErr error, objectID string) {
 func (h *HelmAddons) uninstall(id string) error {
 	parts := strings.Split(id, "/")
 	namespace, releaseName := parts[0], parts[1]
-	if !h.leaderElector.IsLeader() {
-		h.L.Info("dry run, doesn't uninstall")
-		return nil
-	}
 	if err := h.helm.UninstallRelease(releaseName, namespace); err != nil {
 		return fmt.Errorf("can't uninstall release `%s`: %v", releaseName, err)
 	}
@@ -245,11 +263,6 @@ func (h *HelmAddons) uninstall(id string) error {
 }
 
 func (h *HelmAddons) reconcile(objectID string) error {
-
-	if !h.leaderElector.IsLeader() {
-		h.L.Info("dry run, doesn't reconcile")
-		return nil
-	}
 	name := strings.Split(objectID, "/")[1]
 	chart, err := h.Client.Charts(namespaceToWatch).Get(context.Background(), name, metav1.GetOptions{})
 
@@ -294,6 +307,18 @@ func (h *HelmAddons) reconcile(objectID string) error {
 }
 
 func (h *HelmAddons) addRepo(repo k0sv1beta1.Repository) error {
+	if repo.UsernameFrom != nil || repo.PasswordFrom != nil {
+		client, err := h.kubeClientFactory.GetClient()
+		if err != nil {
+			return err
+		}
+		if repo.Username, err = secretref.ValueOrRef(context.Background(), repo.Username, repo.UsernameFrom, client); err != nil {
+			return fmt.Errorf("can't get the username: %w", err)
+		}
+		if repo.Password, err = secretref.ValueOrRef(context.Background(), repo.Password, repo.PasswordFrom, client); err != nil {
+			return fmt.Errorf("can't get the password: %w", err)
+		}
+	}
 	return h.helm.AddRepository(repo)
 }
 
@@ -316,9 +341,8 @@ func (h *HelmAddons) Init() error {
 	return nil
 }
 
-// Stop
+// Stop does nothing, the control loop is stopped along with the leader only work
 func (h *HelmAddons) Stop() error {
-	close(h.stopCh)
 	return nil
 }
 
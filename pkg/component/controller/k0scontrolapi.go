@@ -27,6 +27,7 @@ import (
 // K0SControlAPI implements the k0s control API component
 type K0SControlAPI struct {
 	ConfigPath    string
+	ConfigDir     string
 	ClusterConfig *config.ClusterConfig
 	K0sVars       constant.CfgVars
 	supervisor    supervisor.Supervisor
@@ -57,6 +58,9 @@ func (m *K0SControlAPI) Run() error {
 			fmt.Sprintf("--data-dir=%s", m.K0sVars.DataDir),
 		},
 	}
+	if m.ConfigDir != "" {
+		m.supervisor.Args = append(m.supervisor.Args, fmt.Sprintf("--config-dir=%s", m.ConfigDir))
+	}
 
 	return m.supervisor.Supervise()
 }
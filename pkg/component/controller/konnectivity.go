@@ -21,16 +21,15 @@ import (
 	"os"
 	"path/filepath"
 	"strconv"
-	"strings"
 	"time"
 
 	"github.com/sirupsen/logrus"
-	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	"github.com/k0sproject/k0s/internal/util"
 	config "github.com/k0sproject/k0s/pkg/apis/v1beta1"
 	"github.com/k0sproject/k0s/pkg/assets"
 	"github.com/k0sproject/k0s/pkg/constant"
+	"github.com/k0sproject/k0s/pkg/flags"
 	"github.com/k0sproject/k0s/pkg/kubernetes"
 	k8sutil "github.com/k0sproject/k0s/pkg/kubernetes"
 	"github.com/k0sproject/k0s/pkg/supervisor"
@@ -85,6 +84,13 @@ func (k *Konnectivity) Run() error {
 	// Buffered chan to send updates for the count of servers
 	k.serverCountChan = make(chan int, 1)
 
+	if err := konnectivityPolicy.Validate(assets.BinPath("konnectivity-server", k.K0sVars.BinDir), k.ClusterConfig.Spec.Konnectivity.ExtraArgs); err != nil {
+		return err
+	}
+	if _, err := k.Args(1); err != nil {
+		return err
+	}
+
 	k.stopCtx, k.stopFunc = context.WithCancel(context.Background())
 
 	go k.runServer()
@@ -99,29 +105,37 @@ func (k *Konnectivity) Run() error {
 	return k.writeKonnectivityAgent()
 }
 
-func (k *Konnectivity) defaultArgs() util.MappedArgs {
+// konnectivityPolicy lists the flags k0s sets that can be replaced with the extra args
+var konnectivityPolicy = flags.Policy{
+	Component:   "konnectivity-server",
+	Replaceable: []string{"enable-profiling", "logtostderr", "stderrthreshold", "v"},
+}
+
+// Args returns the flags konnectivity-server is run with for the given count of servers
+func (k *Konnectivity) Args(serverCount int) (flags.Args, error) {
 	serverID, err := util.MachineID()
 	if err != nil {
 		logrus.Errorf("failed to fetch server ID for konnectivity-server")
 	}
-	return util.MappedArgs{
-		"--uds-name":                filepath.Join(k.K0sVars.KonnectivitySocketDir, "konnectivity-server.sock"),
-		"--cluster-cert":            filepath.Join(k.K0sVars.CertRootDir, "server.crt"),
-		"--cluster-key":             filepath.Join(k.K0sVars.CertRootDir, "server.key"),
-		"--kubeconfig":              k.K0sVars.KonnectivityKubeConfigPath,
-		"--mode":                    "grpc",
-		"--server-port":             "0",
-		"--agent-port":              fmt.Sprintf("%d", k.ClusterConfig.Spec.Konnectivity.AgentPort),
-		"--admin-port":              fmt.Sprintf("%d", k.ClusterConfig.Spec.Konnectivity.AdminPort),
-		"--agent-namespace":         "kube-system",
-		"--agent-service-account":   "konnectivity-agent",
-		"--authentication-audience": "system:konnectivity-server",
-		"--logtostderr":             "true",
-		"--stderrthreshold":         "1",
-		"--v":                       k.LogLevel,
-		"--enable-profiling":        "false",
-		"--server-id":               serverID,
-	}
+	return konnectivityPolicy.Merge(flags.Args{
+		"uds-name":                filepath.Join(k.K0sVars.KonnectivitySocketDir, "konnectivity-server.sock"),
+		"cluster-cert":            filepath.Join(k.K0sVars.CertRootDir, "server.crt"),
+		"cluster-key":             filepath.Join(k.K0sVars.CertRootDir, "server.key"),
+		"kubeconfig":              k.K0sVars.KonnectivityKubeConfigPath,
+		"mode":                    "grpc",
+		"server-port":             "0",
+		"agent-port":              fmt.Sprintf("%d", k.ClusterConfig.Spec.Konnectivity.AgentPort),
+		"admin-port":              fmt.Sprintf("%d", k.ClusterConfig.Spec.Konnectivity.AdminPort),
+		"agent-namespace":         "kube-system",
+		"agent-service-account":   "konnectivity-agent",
+		"authentication-audience": "system:konnectivity-server",
+		"logtostderr":             "true",
+		"stderrthreshold":         "1",
+		"v":                       k.LogLevel,
+		"enable-profiling":        "false",
+		"server-id":               serverID,
+		"server-count":            strconv.Itoa(serverCount),
+	}, k.ClusterConfig.Spec.Konnectivity.ExtraArgs)
 }
 
 // runs the supervisor and restarts if the calculated server count changes
@@ -141,8 +155,11 @@ func (k *Konnectivity) runServer() {
 						// TODO Should we just return? That means other part will continue to run but the server is never properly restarted
 					}
 				}
-				args := k.defaultArgs()
-				args["--server-count"] = strconv.Itoa(count)
+				args, err := k.Args(count)
+				if err != nil {
+					logrus.Errorf("failed to build konnectivity-server args: %s", err)
+					continue
+				}
 				k.supervisor = &supervisor.Supervisor{
 					Name:    "konnectivity",
 					BinPath: assets.BinPath("konnectivity-server", k.K0sVars.BinDir),
@@ -151,7 +168,7 @@ func (k *Konnectivity) runServer() {
 					Args:    args.ToArgs(),
 					UID:     k.uid,
 				}
-				err := k.supervisor.Supervise()
+				err = k.supervisor.Supervise()
 				if err != nil {
 					logrus.Errorf("failed to start konnectivity supervisor: %s", err)
 					k.supervisor = nil // not to make the next loop to try to stop it first
@@ -233,20 +250,7 @@ func (k *Konnectivity) countLeaseHolders() (int, error) {
 	}
 	ctx, cancel := context.WithTimeout(k.stopCtx, 5*time.Second)
 	defer cancel()
-	count := 0
-	leases, err := client.CoordinationV1().Leases("kube-node-lease").List(ctx, v1.ListOptions{})
-	if err != nil {
-		return 0, err
-	}
-	for _, l := range leases.Items {
-		if strings.HasPrefix(l.ObjectMeta.Name, "k0s-ctrl") {
-			if kubernetes.IsValidLease(l) {
-				count++
-			}
-		}
-	}
-
-	return count, nil
+	return kubernetes.CountActiveControllerLeases(ctx, client)
 }
 
 const konnectivityAgentTemplate = `
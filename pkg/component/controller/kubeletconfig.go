@@ -20,6 +20,7 @@ import (
 	"fmt"
 	"path"
 	"path/filepath"
+	"sync"
 
 	"io"
 	"io/ioutil"
@@ -36,6 +37,7 @@ import (
 // KubeletConfig is the reconciler for generic kubelet configs
 type KubeletConfig struct {
 	clusterSpec *config.ClusterSpec
+	specMu      sync.Mutex
 	log         *logrus.Entry
 	k0sVars     constant.CfgVars
 }
@@ -62,6 +64,21 @@ func (k *KubeletConfig) Stop() error {
 
 // Run dumps the needed manifest objects
 func (k *KubeletConfig) Run() error {
+	k.specMu.Lock()
+	defer k.specMu.Unlock()
+	return k.writeManifests()
+}
+
+// Reconcile re-renders the worker profiles with the changed cluster config
+func (k *KubeletConfig) Reconcile(cfg *config.ClusterConfig) error {
+	k.specMu.Lock()
+	defer k.specMu.Unlock()
+	k.clusterSpec = cfg.Spec
+	return k.writeManifests()
+}
+
+// writeManifests renders and saves the config maps, the caller must hold the spec lock
+func (k *KubeletConfig) writeManifests() error {
 	dnsAddress, err := k.clusterSpec.Network.DNSAddress()
 	if err != nil {
 		return fmt.Errorf("failed to get DNS address for kubelet config: %v", err)
@@ -81,8 +98,9 @@ func (k *KubeletConfig) Run() error {
 
 func (k *KubeletConfig) run(dnsAddress string) (*bytes.Buffer, error) {
 	manifest := bytes.NewBuffer([]byte{})
-	defaultProfile := getDefaultProfile(dnsAddress, k.clusterSpec.Network.DualStack.Enabled)
-	winDefaultProfile := getDefaultProfile(dnsAddress, k.clusterSpec.Network.DualStack.Enabled)
+	featureGates := k.clusterSpec.EffectiveFeatureGates()
+	defaultProfile := getDefaultProfile(dnsAddress, k.clusterSpec.Network.ClusterDomain, featureGates)
+	winDefaultProfile := getDefaultProfile(dnsAddress, k.clusterSpec.Network.ClusterDomain, featureGates)
 	if err := k.writeConfigMapWithProfile(manifest, "default", defaultProfile); err != nil {
 		return nil, fmt.Errorf("can't write manifest for default profile config map: %v", err)
 	}
@@ -94,11 +112,12 @@ func (k *KubeletConfig) run(dnsAddress string) (*bytes.Buffer, error) {
 		formatProfileName("default-windows"),
 	}
 	for _, profile := range k.clusterSpec.WorkerProfiles {
-		profileConfig := getDefaultProfile(dnsAddress, false) // Do not add dualstack feature gate to the custom profiles
+		profileConfig := getDefaultProfile(dnsAddress, k.clusterSpec.Network.ClusterDomain, nil)
 		merged, err := mergeProfiles(&profileConfig, profile.Values)
 		if err != nil {
 			return nil, fmt.Errorf("can't merge profile `%s` with default profile: %v", profile.Name, err)
 		}
+		mergeFeatureGates(merged, featureGates)
 
 		if err := k.writeConfigMapWithProfile(manifest,
 			profile.Name,
@@ -166,7 +185,7 @@ func (k *KubeletConfig) writeRbacRoleBindings(w io.Writer, configMapNames []stri
 	return tw.WriteToBuffer(w)
 }
 
-func getDefaultProfile(dnsAddress string, dualStack bool) unstructuredYamlObject {
+func getDefaultProfile(dnsAddress string, clusterDomain string, featureGates config.FeatureGates) unstructuredYamlObject {
 	// the motivation to keep it like this instead of the yaml template:
 	// - it's easier to merge programatically defined structure
 	// - apart from map[string]interface there is no good way to define free-form mapping
@@ -195,7 +214,7 @@ func getDefaultProfile(dnsAddress string, dualStack bool) unstructuredYamlObject
 			},
 		},
 		"clusterDNS":    []string{dnsAddress},
-		"clusterDomain": "cluster.local",
+		"clusterDomain": clusterDomain,
 		"tlsCipherSuites": []string{
 			"TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256",
 			"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256",
@@ -213,10 +232,8 @@ func getDefaultProfile(dnsAddress string, dualStack bool) unstructuredYamlObject
 		"serverTLSBootstrap":   true,
 		"eventRecordQPS":       0,
 	}
-	if dualStack {
-		profile["featureGates"] = map[string]bool{
-			"IPv6DualStack": true,
-		}
+	if len(featureGates) > 0 {
+		profile["featureGates"] = map[string]bool(featureGates)
 	}
 	return profile
 }
@@ -273,5 +290,27 @@ func mergeProfiles(a *unstructuredYamlObject, b unstructuredYamlObject) (unstruc
 	return *a, nil
 }
 
+// mergeFeatureGates adds the cluster feature gates to the profile, the feature gates of the profile take precedence
+func mergeFeatureGates(profile unstructuredYamlObject, featureGates config.FeatureGates) {
+	if len(featureGates) == 0 {
+		return
+	}
+	gates := make(map[string]interface{}, len(featureGates))
+	for name, enabled := range featureGates {
+		gates[name] = enabled
+	}
+	switch profileGates := profile["featureGates"].(type) {
+	case map[interface{}]interface{}:
+		for name, enabled := range profileGates {
+			gates[fmt.Sprint(name)] = enabled
+		}
+	case map[string]interface{}:
+		for name, enabled := range profileGates {
+			gates[name] = enabled
+		}
+	}
+	profile["featureGates"] = gates
+}
+
 // Health-check interface
 func (k *KubeletConfig) Healthy() error { return nil }
@@ -50,11 +50,23 @@ func Test_KubeletConfig(t *testing.T) {
 		})
 	})
 	t.Run("default_profile_must_have_feature_gates_if_dualstack_setup", func(t *testing.T) {
-		profile := getDefaultProfile(dnsAddr, true)
+		spec := config.DefaultClusterConfig(k0sVars).Spec
+		spec.Network.DualStack.Enabled = true
+		profile := getDefaultProfile(dnsAddr, spec.Network.ClusterDomain, spec.EffectiveFeatureGates())
 		require.Equal(t, map[string]bool{
 			"IPv6DualStack": true,
 		}, profile["featureGates"])
 	})
+	t.Run("profile_feature_gates_take_precedence", func(t *testing.T) {
+		profile := unstructuredYamlObject{
+			"featureGates": map[interface{}]interface{}{"CSIMigration": false},
+		}
+		mergeFeatureGates(profile, config.FeatureGates{"CSIMigration": true, "PodSecurity": true})
+		require.Equal(t, map[string]interface{}{
+			"CSIMigration": false,
+			"PodSecurity":  true,
+		}, profile["featureGates"])
+	})
 	t.Run("with_user_provided_profiles", func(t *testing.T) {
 		k := defaultConfigWithUserProvidedProfiles(t)
 		buf, err := k.run(dnsAddr)
@@ -87,12 +99,12 @@ func Test_KubeletConfig(t *testing.T) {
 			require.NoError(t, yaml.Unmarshal([]byte(manifestYamls[3]), &profileYYY))
 
 			// manually apple the same changes to default config and check that there is no diff
-			defaultProfileKubeletConfig := getDefaultProfile(dnsAddr, false)
+			defaultProfileKubeletConfig := getDefaultProfile(dnsAddr, config.DefaultClusterDomain, nil)
 			defaultProfileKubeletConfig["authentication"].(map[string]interface{})["anonymous"].(map[string]interface{})["enabled"] = false
 			defaultWithChangesXXX, err := yaml.Marshal(defaultProfileKubeletConfig)
 			require.NoError(t, err)
 
-			defaultProfileKubeletConfig = getDefaultProfile(dnsAddr, false)
+			defaultProfileKubeletConfig = getDefaultProfile(dnsAddr, config.DefaultClusterDomain, nil)
 			defaultProfileKubeletConfig["authentication"].(map[string]interface{})["webhook"].(map[string]interface{})["cacheTTL"] = "15s"
 			defaultWithChangesYYY, err := yaml.Marshal(defaultProfileKubeletConfig)
 
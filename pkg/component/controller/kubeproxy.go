@@ -19,6 +19,8 @@ import (
 	"os"
 	"path"
 	"path/filepath"
+	"reflect"
+	"sync"
 	"time"
 
 	"github.com/sirupsen/logrus"
@@ -34,6 +36,7 @@ type KubeProxy struct {
 	tickerDone  chan struct{}
 	log         *logrus.Entry
 	clusterConf *config.ClusterConfig
+	configMu    sync.Mutex
 	K0sVars     constant.CfgVars
 }
 
@@ -56,17 +59,14 @@ func (k *KubeProxy) Init() error {
 // Run runs the kube-proxy reconciler
 func (k *KubeProxy) Run() error {
 	proxyDir := path.Join(k.K0sVars.ManifestsDir, "kubeproxy")
-	if k.clusterConf.Spec.Network.KubeProxy.Disabled {
-		return k.removeKubeProxy(proxyDir)
+	if k.isDisabled() {
+		if err := k.removeKubeProxy(proxyDir); err != nil {
+			return err
+		}
 	}
 
 	k.tickerDone = make(chan struct{})
 
-	err := util.InitDirectory(proxyDir, constant.ManifestsDirMode)
-	if err != nil {
-		return err
-	}
-
 	go func() {
 		ticker := time.NewTicker(10 * time.Second)
 		defer ticker.Stop()
@@ -74,15 +74,30 @@ func (k *KubeProxy) Run() error {
 		for {
 			select {
 			case <-ticker.C:
+				// kube-proxy can be disabled and enabled at runtime with the dynamic config
+				if k.isDisabled() {
+					if !reflect.DeepEqual(previousConfig, proxyConfig{}) {
+						if err := k.removeKubeProxy(proxyDir); err != nil {
+							k.log.Errorf("error removing kube-proxy manifests: %s. will retry", err.Error())
+							continue
+						}
+						previousConfig = proxyConfig{}
+					}
+					continue
+				}
 				cfg, err := k.getConfig()
 				if err != nil {
 					k.log.Errorf("error calculating proxy configs: %s. will retry", err.Error())
 					continue
 				}
-				if cfg == previousConfig {
+				if reflect.DeepEqual(cfg, previousConfig) {
 					k.log.Infof("current cfg matches existing, not gonna do anything")
 					continue
 				}
+				if err := util.InitDirectory(proxyDir, constant.ManifestsDirMode); err != nil {
+					k.log.Errorf("error creating kube-proxy manifests dir: %s. will retry", err.Error())
+					continue
+				}
 				tw := util.TemplateWriter{
 					Name:     "kube-proxy",
 					Template: proxyTemplate,
@@ -105,6 +120,20 @@ func (k *KubeProxy) Run() error {
 	return nil
 }
 
+func (k *KubeProxy) isDisabled() bool {
+	k.configMu.Lock()
+	defer k.configMu.Unlock()
+	return k.clusterConf.Spec.Network.KubeProxyDisabled()
+}
+
+// Reconcile applies the changed cluster config on the next reconciliation round
+func (k *KubeProxy) Reconcile(cfg *config.ClusterConfig) error {
+	k.configMu.Lock()
+	defer k.configMu.Unlock()
+	k.clusterConf = cfg
+	return nil
+}
+
 // Stop stop the reconcilier
 func (k *KubeProxy) Stop() error {
 	if k.tickerDone != nil {
@@ -121,12 +150,15 @@ func (k *KubeProxy) removeKubeProxy(manifestDir string) error {
 }
 
 func (k *KubeProxy) getConfig() (proxyConfig, error) {
+	k.configMu.Lock()
+	defer k.configMu.Unlock()
+
 	cfg := proxyConfig{
 		ClusterCIDR:          k.clusterConf.Spec.Network.BuildPodCIDR(),
 		ControlPlaneEndpoint: k.clusterConf.Spec.API.APIAddressURL(),
 		Image:                k.clusterConf.Spec.Images.KubeProxy.URI(),
 		PullPolicy:           k.clusterConf.Spec.Images.DefaultPullPolicy,
-		DualStack:            k.clusterConf.Spec.Network.DualStack.Enabled,
+		FeatureGates:         k.clusterConf.Spec.EffectiveFeatureGates(),
 		Mode:                 k.clusterConf.Spec.Network.KubeProxy.Mode,
 	}
 
@@ -134,7 +166,7 @@ func (k *KubeProxy) getConfig() (proxyConfig, error) {
 }
 
 type proxyConfig struct {
-	DualStack            bool
+	FeatureGates         config.FeatureGates
 	ControlPlaneEndpoint string
 	ClusterCIDR          string
 	Image                string
@@ -234,10 +266,12 @@ data:
       qps: 0
     clusterCIDR: {{ .ClusterCIDR }}
     configSyncPeriod: 0s
-    {{ if .DualStack }}
+    {{- if .FeatureGates }}
     featureGates:
-      IPv6DualStack: true
-    {{ end }}
+    {{- range $name, $enabled := .FeatureGates }}
+      {{ $name }}: {{ $enabled }}
+    {{- end }}
+    {{- end }}
     mode: "{{ .Mode }}"
     conntrack:
       maxPerCore: 0
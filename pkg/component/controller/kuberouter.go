@@ -17,19 +17,40 @@ package controller
 
 import (
 	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/k0sproject/k0s/internal/util"
 	config "github.com/k0sproject/k0s/pkg/apis/v1beta1"
+	"github.com/k0sproject/k0s/pkg/applier"
+	k8sutil "github.com/k0sproject/k0s/pkg/kubernetes"
+	"github.com/k0sproject/k0s/pkg/secretref"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	kubeRouterPeerPasswordsSecret = "kube-router-peer-passwords"
+	kubeRouterPeerPasswordsKey    = "peer-passwords"
 )
 
 // KubeRouter implements the kube-router reconciler component
 type KubeRouter struct {
 	clusterConf *config.ClusterConfig
+	configMu    sync.Mutex
 	log         *logrus.Entry
 
-	saver manifestsSaver
+	saver             manifestsSaver
+	kubeClientFactory k8sutil.ClientFactory
 }
 
 type kubeRouterConfig struct {
@@ -39,16 +60,27 @@ type kubeRouterConfig struct {
 	CNIImage          string
 	PeerRouterIPs     string
 	PeerRouterASNs    string
+	PeerPasswords     bool
+	MultihopTTL       uint8
+	IPIP              bool
+	OverlayType       string
+	Hairpin           bool
+	ServiceProxy      bool
+	ServiceCIDR       string
+	APIServerHost     string
+	APIServerPort     string
+	MetricsPort       int
 	PullPolicy        string
 }
 
 // NewKubeRouter creates new KubeRouter reconciler component
-func NewKubeRouter(clusterConf *config.ClusterConfig, manifestsSaver manifestsSaver) (*KubeRouter, error) {
+func NewKubeRouter(clusterConf *config.ClusterConfig, manifestsSaver manifestsSaver, kubeClientFactory k8sutil.ClientFactory) (*KubeRouter, error) {
 	log := logrus.WithFields(logrus.Fields{"component": "kube-router"})
 	return &KubeRouter{
-		clusterConf: clusterConf,
-		saver:       manifestsSaver,
-		log:         log,
+		clusterConf:       clusterConf,
+		saver:             manifestsSaver,
+		kubeClientFactory: kubeClientFactory,
+		log:               log,
 	}, nil
 }
 
@@ -58,28 +90,158 @@ func (c *KubeRouter) Init() error { return nil }
 // Healthy is a no-op check
 func (c *KubeRouter) Healthy() error { return nil }
 
-// Stop no-op as nothing running
+// Stop does nothing, the peer passwords are only reconciled by the leader
 func (c *KubeRouter) Stop() error { return nil }
 
 // Run runs the kube-router reconciler
 func (c *KubeRouter) Run() error {
-	c.log.Info("starting to dump manifests")
+	c.configMu.Lock()
+	defer c.configMu.Unlock()
+	return c.writeManifests()
+}
+
+// RunLeader reconciles the peer passwords every 10 seconds for as long as we're the leader. The passwords
+// are written to the secret through the API, so that they don't end up in the manifest files. The references
+// are resolved again on every round to pick up the changed secrets.
+func (c *KubeRouter) RunLeader(ctx context.Context) error {
+	ticker := time.NewTicker(10 * time.Second)
+	defer ticker.Stop()
+	for {
+		if err := c.syncPeerPasswords(ctx); err != nil {
+			c.log.Warnf("failed to reconcile the BGP peer passwords, will retry: %s", err.Error())
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
 
+// Reconcile re-renders the manifests with the changed cluster config
+func (c *KubeRouter) Reconcile(cfg *config.ClusterConfig) error {
+	c.configMu.Lock()
+	defer c.configMu.Unlock()
+	c.clusterConf = cfg
+	return c.writeManifests()
+}
+
+func (c *KubeRouter) getConfig() kubeRouterConfig {
+	kubeRouter := c.clusterConf.Spec.Network.KubeRouter
 	cfg := kubeRouterConfig{
-		AutoMTU:           c.clusterConf.Spec.Network.KubeRouter.AutoMTU,
-		MTU:               c.clusterConf.Spec.Network.KubeRouter.MTU,
-		PeerRouterIPs:     c.clusterConf.Spec.Network.KubeRouter.PeerRouterIPs,
-		PeerRouterASNs:    c.clusterConf.Spec.Network.KubeRouter.PeerRouterASNs,
+		AutoMTU:           kubeRouter.AutoMTU,
+		MTU:               kubeRouter.MTU,
+		PeerRouterIPs:     kubeRouter.PeerRouterIPs,
+		PeerRouterASNs:    kubeRouter.PeerRouterASNs,
+		MultihopTTL:       kubeRouter.MultihopTTL(),
+		IPIP:              kubeRouter.IPIP,
+		OverlayType:       kubeRouter.OverlayType,
+		Hairpin:           kubeRouter.Hairpin,
+		ServiceProxy:      kubeRouter.ServiceProxy,
+		ServiceCIDR:       c.clusterConf.Spec.Network.ServiceCIDR,
+		APIServerHost:     c.clusterConf.Spec.API.APIAddress(),
+		APIServerPort:     strconv.Itoa(c.clusterConf.Spec.API.Port),
+		MetricsPort:       kubeRouter.MetricsPort,
 		CNIImage:          c.clusterConf.Spec.Images.KubeRouter.CNI.URI(),
 		CNIInstallerImage: c.clusterConf.Spec.Images.KubeRouter.CNIInstaller.URI(),
 		PullPolicy:        c.clusterConf.Spec.Images.DefaultPullPolicy,
 	}
 
+	if len(kubeRouter.Peers) > 0 {
+		var ips, asns []string
+		for i, peer := range kubeRouter.Peers {
+			ips = append(ips, peer.IP)
+			asns = append(asns, strconv.FormatUint(uint64(peer.ASN), 10))
+			if peer.Password != "" {
+				c.log.Warnf("spec.network.kuberouter.peers[%d].password is deprecated, use passwordFrom to keep the password out of the config", i)
+			}
+			cfg.PeerPasswords = cfg.PeerPasswords || peer.Password != "" || peer.PasswordFrom != nil
+		}
+		cfg.PeerRouterIPs = strings.Join(ips, ",")
+		cfg.PeerRouterASNs = strings.Join(asns, ",")
+	}
+	return cfg
+}
+
+// peerPasswords returns the passwords of the peers in the format of the kube-router passwords file, or an
+// empty string if no peer has a password
+func peerPasswords(ctx context.Context, peers []config.KubeRouterPeer, client kubernetes.Interface) (string, error) {
+	var passwords []string
+	var withPasswords bool
+	for _, peer := range peers {
+		password, err := secretref.ValueOrRef(ctx, peer.Password, peer.PasswordFrom, client)
+		if err != nil {
+			return "", fmt.Errorf("can't get the password of the peer %s: %w", peer.IP, err)
+		}
+		// kube-router expects the passwords base64 encoded, an empty one disables the authentication
+		passwords = append(passwords, base64.StdEncoding.EncodeToString([]byte(password)))
+		withPasswords = withPasswords || password != ""
+	}
+	if !withPasswords {
+		return "", nil
+	}
+	return strings.Join(passwords, ","), nil
+}
+
+// syncPeerPasswords creates or updates the secret holding the peer passwords, and removes it if no peer has a
+// password. The secret isn't part of the kube-router stack, the label of the stack is removed from the secrets
+// created by the previous versions so that the applier doesn't prune it.
+func (c *KubeRouter) syncPeerPasswords(ctx context.Context) error {
+	c.configMu.Lock()
+	peers := c.clusterConf.Spec.Network.KubeRouter.Peers
+	c.configMu.Unlock()
+
+	client, err := c.kubeClientFactory.GetClient()
+	if err != nil {
+		return err
+	}
+	passwords, err := peerPasswords(ctx, peers, client)
+	if err != nil {
+		return err
+	}
+
+	secrets := client.CoreV1().Secrets(metav1.NamespaceSystem)
+	secret, err := secrets.Get(ctx, kubeRouterPeerPasswordsSecret, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		if passwords == "" {
+			return nil
+		}
+		_, err = secrets.Create(ctx, &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: kubeRouterPeerPasswordsSecret, Namespace: metav1.NamespaceSystem},
+			Type:       corev1.SecretTypeOpaque,
+			Data:       map[string][]byte{kubeRouterPeerPasswordsKey: []byte(passwords)},
+		}, metav1.CreateOptions{})
+		return err
+	}
+	if err != nil {
+		return err
+	}
+
+	if passwords == "" {
+		err = secrets.Delete(ctx, kubeRouterPeerPasswordsSecret, metav1.DeleteOptions{})
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	if _, stacked := secret.Labels[applier.NameLabel]; !stacked && string(secret.Data[kubeRouterPeerPasswordsKey]) == passwords {
+		return nil
+	}
+	delete(secret.Labels, applier.NameLabel)
+	secret.Data = map[string][]byte{kubeRouterPeerPasswordsKey: []byte(passwords)}
+	_, err = secrets.Update(ctx, secret, metav1.UpdateOptions{})
+	return err
+}
+
+// writeManifests renders and saves the manifests, the caller must hold the config lock
+func (c *KubeRouter) writeManifests() error {
+	c.log.Info("starting to dump manifests")
+
 	output := bytes.NewBuffer([]byte{})
 	tw := util.TemplateWriter{
 		Name:     "kube-router",
 		Template: kubeRouterTemplate,
-		Data:     cfg,
+		Data:     c.getConfig(),
 	}
 
 	err := tw.WriteToBuffer(output)
@@ -96,7 +258,8 @@ func (c *KubeRouter) Run() error {
 	return nil
 }
 
-const kubeRouterTemplate = `---
+const kubeRouterTemplate = `
+---
 apiVersion: v1
 kind: ConfigMap
 metadata:
@@ -118,6 +281,7 @@ data:
              "mtu": {{ .MTU }},
              {{- end }}
              "auto-mtu": {{ .AutoMTU }},
+             "hairpinMode": {{ .Hairpin }},
              "bridge":"kube-bridge",
              "isDefaultGateway":true,
              "ipam":{
@@ -152,9 +316,11 @@ spec:
       labels:
         k8s-app: kube-router
         tier: node
+      {{- if .MetricsPort }}
       annotations:
         prometheus.io/scrape: "true"
-        prometheus.io/port: "8080"
+        prometheus.io/port: "{{ .MetricsPort }}"
+      {{- end }}
     spec:
       priorityClassName: system-node-critical
       serviceAccountName: kube-router
@@ -213,6 +379,11 @@ spec:
         hostPath:
           path: /run/xtables.lock
           type: FileOrCreate
+      {{- if .PeerPasswords }}
+      - name: peer-passwords
+        secret:
+          secretName: kube-router-peer-passwords
+      {{- end }}
       containers:
       - name: kube-router
         image: {{ .CNIImage }}
@@ -220,15 +391,29 @@ spec:
         args:
         - "--run-router=true"
         - "--run-firewall=true"
-        - "--run-service-proxy=false"
+        - "--run-service-proxy={{ .ServiceProxy }}"
+        {{- if .ServiceProxy }}
+        - "--service-cluster-ip-range={{ .ServiceCIDR }}"
+        {{- end }}
         - "--bgp-graceful-restart=true"
-        - "--metrics-port=8080"
+        - "--metrics-port={{ .MetricsPort }}"
+        - "--enable-overlay={{ .IPIP }}"
+        {{- if .IPIP }}
+        - "--overlay-type={{ .OverlayType }}"
+        {{- end }}
+        - "--hairpin-mode={{ .Hairpin }}"
         {{- if .PeerRouterIPs }}
         - "--peer-router-ips={{ .PeerRouterIPs }}"
         {{- end }}
         {{- if .PeerRouterASNs }}
         - "--peer-router-asns={{ .PeerRouterASNs }}"
         {{- end }}
+        {{- if .PeerPasswords }}
+        - "--peer-router-passwords-file=/etc/kube-router-secrets/peer-passwords"
+        {{- end }}
+        {{- if .MultihopTTL }}
+        - "--peer-router-multihop-ttl={{ .MultihopTTL }}"
+        {{- end }}
         env:
         - name: NODE_NAME
           valueFrom:
@@ -236,6 +421,12 @@ spec:
               fieldPath: spec.nodeName
         - name: KUBE_ROUTER_CNI_CONF_FILE
           value: /etc/cni/net.d/10-kuberouter.conflist
+        {{- if .ServiceProxy }}
+        - name: KUBERNETES_SERVICE_HOST
+          value: "{{ .APIServerHost }}"
+        - name: KUBERNETES_SERVICE_PORT
+          value: "{{ .APIServerPort }}"
+        {{- end }}
         livenessProbe:
           httpGet:
             path: /healthz
@@ -257,6 +448,11 @@ spec:
         - name: xtables-lock
           mountPath: /run/xtables.lock
           readOnly: false
+        {{- if .PeerPasswords }}
+        - name: peer-passwords
+          mountPath: /etc/kube-router-secrets
+          readOnly: true
+        {{- end }}
 
 ---
 apiVersion: v1
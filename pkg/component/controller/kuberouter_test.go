@@ -1,6 +1,7 @@
 package controller
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"testing"
@@ -8,10 +9,13 @@ import (
 	"github.com/k0sproject/dig"
 	"github.com/k0sproject/k0s/internal/testutil"
 	"github.com/k0sproject/k0s/pkg/apis/v1beta1"
+	"github.com/k0sproject/k0s/pkg/applier"
 	"github.com/k0sproject/k0s/pkg/constant"
 	"github.com/stretchr/testify/require"
 	v1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 )
@@ -27,7 +31,7 @@ func TestKubeRouterConfig(t *testing.T) {
 	cfg.Spec.Network.KubeRouter.PeerRouterIPs = "1.2.3.4,4.3.2.1"
 
 	saver := inMemorySaver{}
-	kr, err := NewKubeRouter(cfg, saver)
+	kr, err := NewKubeRouter(cfg, saver, testutil.NewFakeClientFactory())
 	require.NoError(t, err)
 	require.NoError(t, kr.Run())
 	require.NoError(t, kr.Stop())
@@ -60,7 +64,7 @@ func TestKubeRouterDefaultManifests(t *testing.T) {
 	cfg.Spec.Network.Provider = "kuberouter"
 	cfg.Spec.Network.KubeRouter = v1beta1.DefaultKubeRouter()
 	saver := inMemorySaver{}
-	kr, err := NewKubeRouter(cfg, saver)
+	kr, err := NewKubeRouter(cfg, saver, testutil.NewFakeClientFactory())
 	require.NoError(t, err)
 	require.NoError(t, kr.Run())
 	require.NoError(t, kr.Stop())
@@ -82,6 +86,154 @@ func TestKubeRouterDefaultManifests(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, true, p.Dig("auto-mtu"))
 	require.Nil(t, p.Dig("mtu"))
+
+	args := ds.Spec.Template.Spec.Containers[0].Args
+	require.Contains(t, args, "--run-service-proxy=false")
+	require.Contains(t, args, "--enable-overlay=true")
+	require.Contains(t, args, "--overlay-type=subnet")
+	require.Contains(t, args, "--metrics-port=8080")
+	require.Equal(t, "8080", ds.Spec.Template.Annotations["prometheus.io/port"])
+	for _, r := range resources {
+		require.NotEqual(t, "Secret", r.GetKind())
+	}
+}
+
+func TestKubeRouterPeers(t *testing.T) {
+	cfg := v1beta1.DefaultClusterConfig(constant.CfgVars{})
+	cfg.Spec.Network.Calico = nil
+	cfg.Spec.Network.Provider = "kuberouter"
+	cfg.Spec.Network.KubeRouter = v1beta1.DefaultKubeRouter()
+	cfg.Spec.Network.KubeRouter.Peers = []v1beta1.KubeRouterPeer{
+		{IP: "10.0.1.1", ASN: 65001, Password: "secret", MultihopTTL: 3},
+		{IP: "10.0.2.1", ASN: 65002},
+	}
+
+	saver := inMemorySaver{}
+	kr, err := NewKubeRouter(cfg, saver, testutil.NewFakeClientFactory())
+	require.NoError(t, err)
+	require.NoError(t, kr.Run())
+	defer func() { require.NoError(t, kr.Stop()) }()
+
+	resources, err := testutil.ParseManifests(saver["kube-router.yaml"])
+	require.NoError(t, err)
+	ds, err := findDaemonset(resources)
+	require.NoError(t, err)
+	args := ds.Spec.Template.Spec.Containers[0].Args
+	require.Contains(t, args, "--peer-router-ips=10.0.1.1,10.0.2.1")
+	require.Contains(t, args, "--peer-router-asns=65001,65002")
+	require.Contains(t, args, "--peer-router-passwords-file=/etc/kube-router-secrets/peer-passwords")
+	require.Contains(t, args, "--peer-router-multihop-ttl=3")
+
+	for _, r := range resources {
+		require.NotEqual(t, "Secret", r.GetKind(), "the passwords must not be written to the manifests")
+	}
+}
+
+func TestKubeRouterPeerPasswords(t *testing.T) {
+	cfg := v1beta1.DefaultClusterConfig(constant.CfgVars{})
+	cfg.Spec.Network.KubeRouter = v1beta1.DefaultKubeRouter()
+	cfg.Spec.Network.KubeRouter.Peers = []v1beta1.KubeRouterPeer{
+		{IP: "10.0.1.1", ASN: 65001, PasswordFrom: &v1beta1.SecretRef{Secret: &v1beta1.SecretKeyRef{Name: "bgp", Key: "password"}}},
+		{IP: "10.0.2.1", ASN: 65002},
+	}
+	ctx := context.Background()
+
+	// the secret written to the manifests by the previous versions
+	stacked := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      kubeRouterPeerPasswordsSecret,
+			Namespace: metav1.NamespaceSystem,
+			Labels:    map[string]string{applier.NameLabel: "kuberouter"},
+		},
+		Data: map[string][]byte{kubeRouterPeerPasswordsKey: []byte("b2xk,")},
+	}
+	bgp := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "bgp", Namespace: metav1.NamespaceSystem},
+		Data:       map[string][]byte{"password": []byte("secret")},
+	}
+	clients := testutil.NewFakeClientFactory(stacked, bgp)
+	secrets := clients.Client.CoreV1().Secrets(metav1.NamespaceSystem)
+	kr, err := NewKubeRouter(cfg, inMemorySaver{}, clients)
+	require.NoError(t, err)
+
+	require.NoError(t, kr.syncPeerPasswords(ctx))
+	secret, err := secrets.Get(ctx, kubeRouterPeerPasswordsSecret, metav1.GetOptions{})
+	require.NoError(t, err)
+	require.Equal(t, "c2VjcmV0,", string(secret.Data[kubeRouterPeerPasswordsKey]))
+	require.NotContains(t, secret.Labels, applier.NameLabel)
+
+	t.Run("changed reference", func(t *testing.T) {
+		bgp.Data["password"] = []byte("rotated")
+		_, err := secrets.Update(ctx, bgp, metav1.UpdateOptions{})
+		require.NoError(t, err)
+
+		require.NoError(t, kr.syncPeerPasswords(ctx))
+		secret, err := secrets.Get(ctx, kubeRouterPeerPasswordsSecret, metav1.GetOptions{})
+		require.NoError(t, err)
+		require.Equal(t, "cm90YXRlZA==,", string(secret.Data[kubeRouterPeerPasswordsKey]))
+	})
+
+	t.Run("unresolvable reference", func(t *testing.T) {
+		cfg.Spec.Network.KubeRouter.Peers = []v1beta1.KubeRouterPeer{
+			{IP: "10.0.1.1", ASN: 65001, PasswordFrom: &v1beta1.SecretRef{Secret: &v1beta1.SecretKeyRef{Name: "missing", Key: "password"}}},
+		}
+		require.NoError(t, kr.Reconcile(cfg))
+
+		err := kr.syncPeerPasswords(ctx)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "10.0.1.1")
+		_, err = secrets.Get(ctx, kubeRouterPeerPasswordsSecret, metav1.GetOptions{})
+		require.NoError(t, err, "the secret must be kept")
+	})
+
+	t.Run("no passwords", func(t *testing.T) {
+		cfg.Spec.Network.KubeRouter.Peers = []v1beta1.KubeRouterPeer{{IP: "10.0.2.1", ASN: 65002}}
+		require.NoError(t, kr.Reconcile(cfg))
+
+		require.NoError(t, kr.syncPeerPasswords(ctx))
+		_, err := secrets.Get(ctx, kubeRouterPeerPasswordsSecret, metav1.GetOptions{})
+		require.True(t, apierrors.IsNotFound(err), "the secret must be removed, got %v", err)
+	})
+}
+
+func TestKubeRouterServiceProxyAndOverlay(t *testing.T) {
+	cfg := v1beta1.DefaultClusterConfig(constant.CfgVars{})
+	cfg.Spec.API.Address = "10.0.0.10"
+	cfg.Spec.Network.Calico = nil
+	cfg.Spec.Network.Provider = "kuberouter"
+	cfg.Spec.Network.KubeRouter = v1beta1.DefaultKubeRouter()
+	cfg.Spec.Network.KubeRouter.ServiceProxy = true
+	cfg.Spec.Network.KubeRouter.Hairpin = true
+	cfg.Spec.Network.KubeRouter.IPIP = false
+	cfg.Spec.Network.KubeRouter.MetricsPort = 0
+
+	saver := inMemorySaver{}
+	kr, err := NewKubeRouter(cfg, saver, testutil.NewFakeClientFactory())
+	require.NoError(t, err)
+	require.NoError(t, kr.Run())
+	defer func() { require.NoError(t, kr.Stop()) }()
+
+	resources, err := testutil.ParseManifests(saver["kube-router.yaml"])
+	require.NoError(t, err)
+	ds, err := findDaemonset(resources)
+	require.NoError(t, err)
+	require.Empty(t, ds.Spec.Template.Annotations)
+
+	container := ds.Spec.Template.Spec.Containers[0]
+	require.Contains(t, container.Args, "--run-service-proxy=true")
+	require.Contains(t, container.Args, "--service-cluster-ip-range=10.96.0.0/12")
+	require.Contains(t, container.Args, "--enable-overlay=false")
+	require.Contains(t, container.Args, "--hairpin-mode=true")
+	require.Contains(t, container.Args, "--metrics-port=0")
+	require.NotContains(t, container.Args, "--overlay-type=subnet")
+	require.Contains(t, container.Env, corev1.EnvVar{Name: "KUBERNETES_SERVICE_HOST", Value: "10.0.0.10"})
+	require.Contains(t, container.Env, corev1.EnvVar{Name: "KUBERNETES_SERVICE_PORT", Value: "6443"})
+
+	cm, err := findConfig(resources)
+	require.NoError(t, err)
+	p, err := getKubeRouterPlugin(cm, "bridge")
+	require.NoError(t, err)
+	require.Equal(t, true, p.Dig("hairpinMode"))
 }
 
 func findConfig(resources []*unstructured.Unstructured) (corev1.ConfigMap, error) {
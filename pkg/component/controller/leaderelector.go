@@ -18,8 +18,11 @@ package controller
 import (
 	"context"
 	"fmt"
+	"os"
+	"sync"
 	"sync/atomic"
 
+	"github.com/k0sproject/k0s/internal/util"
 	config "github.com/k0sproject/k0s/pkg/apis/v1beta1"
 	k0sv1beta1 "github.com/k0sproject/k0s/pkg/apis/v1beta1"
 	"github.com/k0sproject/k0s/pkg/component"
@@ -28,11 +31,16 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
+const (
+	// LeaderLeaseName is the name of the lease electing the leading controller
+	LeaderLeaseName = "k0s-endpoint-reconciler"
+	// LeaderLeaseNamespace is the namespace of the leader lease
+	LeaderLeaseNamespace = "kube-node-lease"
+)
+
 // LeaderElector is the common leader elector component to manage each controller leader status
 type LeaderElector interface {
-	IsLeader() bool
-	AddAcquiredLeaseCallback(fn func())
-	AddLostLeaseCallback(fn func())
+	component.LeaderElection
 	component.Component
 }
 
@@ -46,6 +54,7 @@ type leaderElector struct {
 	kubeClientFactory kubeutil.ClientFactory
 	leaseCancel       context.CancelFunc
 
+	callbacksMutex         sync.Mutex
 	acquiredLeaseCallbacks []func()
 	lostLeaseCallbacks     []func()
 }
@@ -58,7 +67,7 @@ func NewLeaderElector(c *k0sv1beta1.ClusterConfig, kubeClientFactory kubeutil.Cl
 		ClusterConfig:     c,
 		stopCh:            make(chan struct{}),
 		kubeClientFactory: kubeClientFactory,
-		L:                 logrus.WithFields(logrus.Fields{"component": "endpointreconciler"}),
+		L:                 logrus.WithFields(logrus.Fields{"component": "leaderelector"}),
 		leaderStatus:      d,
 	}
 }
@@ -72,7 +81,14 @@ func (l *leaderElector) Run() error {
 	if err != nil {
 		return fmt.Errorf("can't create kubernetes rest client for lease pool: %v", err)
 	}
-	leasePool, err := leaderelection.NewLeasePool(client, "k0s-endpoint-reconciler", leaderelection.WithLogger(l.L))
+	identity, err := LeaderIdentity()
+	if err != nil {
+		return err
+	}
+	leasePool, err := leaderelection.NewLeasePool(client, LeaderLeaseName,
+		leaderelection.WithIdentity(identity),
+		leaderelection.WithNamespace(LeaderLeaseNamespace),
+		leaderelection.WithLogger(l.L))
 
 	if err != nil {
 		return err
@@ -89,19 +105,36 @@ func (l *leaderElector) Run() error {
 			case <-events.AcquiredLease:
 				l.L.Info("acquired leader lease")
 				l.leaderStatus.Store(true)
-				runCallbacks(l.acquiredLeaseCallbacks)
+				l.runCallbacks(&l.acquiredLeaseCallbacks)
 			case <-events.LostLease:
 				l.L.Info("lost leader lease")
 				l.leaderStatus.Store(false)
-				runCallbacks(l.lostLeaseCallbacks)
+				l.runCallbacks(&l.lostLeaseCallbacks)
 			}
 		}
 	}()
 	return nil
 }
 
-func runCallbacks(callbacks []func()) {
-	for _, fn := range callbacks {
+// LeaderIdentity returns the identity this controller holds the leader lease with, the hostname makes
+// it possible to tell which controller is the leader
+func LeaderIdentity() (string, error) {
+	hostname, err := os.Hostname()
+	if err != nil {
+		return "", err
+	}
+	machineID, err := util.MachineID()
+	if err != nil {
+		return "", err
+	}
+	return hostname + "_" + machineID, nil
+}
+
+func (l *leaderElector) runCallbacks(callbacks *[]func()) {
+	l.callbacksMutex.Lock()
+	fns := append([]func(){}, *callbacks...)
+	l.callbacksMutex.Unlock()
+	for _, fn := range fns {
 		if fn != nil {
 			fn()
 		}
@@ -109,10 +142,14 @@ func runCallbacks(callbacks []func()) {
 }
 
 func (l *leaderElector) AddAcquiredLeaseCallback(fn func()) {
+	l.callbacksMutex.Lock()
+	defer l.callbacksMutex.Unlock()
 	l.acquiredLeaseCallbacks = append(l.acquiredLeaseCallbacks, fn)
 }
 
 func (l *leaderElector) AddLostLeaseCallback(fn func()) {
+	l.callbacksMutex.Lock()
+	defer l.callbacksMutex.Unlock()
 	l.lostLeaseCallbacks = append(l.lostLeaseCallbacks, fn)
 }
 
@@ -21,6 +21,7 @@ import (
 	"math"
 	"path"
 	"path/filepath"
+	"sync"
 	"time"
 
 	"github.com/sirupsen/logrus"
@@ -206,6 +207,7 @@ spec:
 type MetricServer struct {
 	log               *logrus.Entry
 	clusterConfig     *config.ClusterConfig
+	configMu          sync.Mutex
 	tickerDone        chan struct{}
 	K0sVars           constant.CfgVars
 	kubeClientFactory k8sutil.ClientFactory
@@ -280,6 +282,14 @@ func (m *MetricServer) Run() error {
 	return nil
 }
 
+// Reconcile applies the changed cluster config on the next reconciliation round
+func (m *MetricServer) Reconcile(cfg *config.ClusterConfig) error {
+	m.configMu.Lock()
+	defer m.configMu.Unlock()
+	m.clusterConfig = cfg
+	return nil
+}
+
 // Stop stops the reconciler
 func (m *MetricServer) Stop() error {
 	if m.tickerDone != nil {
@@ -297,6 +307,9 @@ func (m *MetricServer) Healthy() error { return nil }
 // - 300MiB of memory
 // So that's 10m CPU and 30MiB mem per 10 nodes
 func (m *MetricServer) getConfig() (metricsConfig, error) {
+	m.configMu.Lock()
+	defer m.configMu.Unlock()
+
 	cfg := metricsConfig{
 		Image:      m.clusterConfig.Spec.Images.MetricsServer.URI(),
 		PullPolicy: m.clusterConfig.Spec.Images.DefaultPullPolicy,
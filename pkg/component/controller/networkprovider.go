@@ -0,0 +1,199 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package controller
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/k0sproject/k0s/internal/util"
+	config "github.com/k0sproject/k0s/pkg/apis/v1beta1"
+	"github.com/k0sproject/k0s/pkg/component"
+	"github.com/k0sproject/k0s/pkg/constant"
+	k8sutil "github.com/k0sproject/k0s/pkg/kubernetes"
+)
+
+// networkProviderManifestDirs are the manifest stacks written by the reconciler of each network provider
+var networkProviderManifestDirs = map[string][]string{
+	"calico":     {"calico", "calico_init"},
+	"cilium":     {"cilium"},
+	"kuberouter": {"kuberouter"},
+}
+
+// NetworkProvider runs the reconciler of the configured network provider. When the provider is changed
+// in the cluster config, the reconciler of the previous provider is stopped and its manifests are removed,
+// so that the leading controller deletes the previous stack, before the new provider is deployed. The leader
+// only work of the reconcilers is run under the leader lease.
+type NetworkProvider struct {
+	k0sVars           constant.CfgVars
+	kubeClientFactory k8sutil.ClientFactory
+	leaderElection    component.LeaderElection
+	log               *logrus.Entry
+
+	mu            sync.Mutex
+	clusterConf   *config.ClusterConfig
+	provider      string
+	reconciler    component.Component
+	newReconciler func(*config.ClusterConfig) (component.Component, error)
+}
+
+var _ component.Component = (*NetworkProvider)(nil)
+var _ component.ConfigReconciler = (*NetworkProvider)(nil)
+
+// NewNetworkProvider creates new NetworkProvider reconciler component
+func NewNetworkProvider(clusterConf *config.ClusterConfig, k0sVars constant.CfgVars, kubeClientFactory k8sutil.ClientFactory, leaderElection component.LeaderElection) *NetworkProvider {
+	n := &NetworkProvider{
+		clusterConf:       clusterConf,
+		k0sVars:           k0sVars,
+		kubeClientFactory: kubeClientFactory,
+		leaderElection:    leaderElection,
+		log:               logrus.WithFields(logrus.Fields{"component": "network-provider"}),
+	}
+	n.newReconciler = n.createReconciler
+	return n
+}
+
+// Init does nothing
+func (n *NetworkProvider) Init() error { return nil }
+
+// Run starts the reconciler of the configured network provider
+func (n *NetworkProvider) Run() error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.start()
+}
+
+// Reconcile passes the changed cluster config to the reconciler of the network provider, or replaces
+// the reconciler if the network provider was changed
+func (n *NetworkProvider) Reconcile(cfg *config.ClusterConfig) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.clusterConf = cfg
+
+	if cfg.Spec.Network.Provider == n.provider {
+		if r, ok := component.Unwrap(n.reconciler).(component.ConfigReconciler); ok {
+			return r.Reconcile(cfg)
+		}
+		return nil
+	}
+
+	n.log.Infof("switching the network provider from %s to %s", n.provider, cfg.Spec.Network.Provider)
+	if n.reconciler != nil {
+		if err := n.reconciler.Stop(); err != nil {
+			return fmt.Errorf("failed to stop the %s reconciler: %w", n.provider, err)
+		}
+		n.reconciler = nil
+	}
+	return n.start()
+}
+
+// Stop stops the reconciler of the network provider
+func (n *NetworkProvider) Stop() error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.reconciler == nil {
+		return nil
+	}
+	return n.reconciler.Stop()
+}
+
+// Healthy is a no-op check
+func (n *NetworkProvider) Healthy() error { return nil }
+
+func (n *NetworkProvider) start() error {
+	provider := n.clusterConf.Spec.Network.Provider
+
+	// the manifests of the previous provider are left behind on the controllers that were
+	// offline while the provider was migrated
+	if err := n.removeManifests(provider); err != nil {
+		return err
+	}
+
+	reconciler, err := n.newReconciler(n.clusterConf)
+	if err != nil {
+		return fmt.Errorf("failed to create the %s reconciler: %w", provider, err)
+	}
+	n.provider = provider
+	if reconciler == nil {
+		n.log.Infof("network provider %s is not managed by k0s", provider)
+		return nil
+	}
+	if lc, ok := reconciler.(component.LeaderComponent); ok {
+		reconciler = component.LeaderScoped(n.leaderElection, lc)
+	}
+
+	if err := reconciler.Init(); err != nil {
+		return fmt.Errorf("failed to initialize the %s reconciler: %w", provider, err)
+	}
+	if err := reconciler.Run(); err != nil {
+		return fmt.Errorf("failed to run the %s reconciler: %w", provider, err)
+	}
+	n.reconciler = reconciler
+	return nil
+}
+
+// removeManifests removes the manifests of all the network providers but the given one
+func (n *NetworkProvider) removeManifests(keep string) error {
+	for provider, dirs := range networkProviderManifestDirs {
+		if provider == keep {
+			continue
+		}
+		for _, dir := range dirs {
+			manifestDir := filepath.Join(n.k0sVars.ManifestsDir, dir)
+			if !util.IsDirectory(manifestDir) {
+				continue
+			}
+			n.log.Infof("removing the %s manifests from %s", provider, manifestDir)
+			if err := os.RemoveAll(manifestDir); err != nil {
+				return fmt.Errorf("failed to remove the %s manifests: %w", provider, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (n *NetworkProvider) createReconciler(cfg *config.ClusterConfig) (component.Component, error) {
+	switch cfg.Spec.Network.Provider {
+	case "calico":
+		calicoSaver, err := NewManifestsSaver("calico", n.k0sVars.DataDir)
+		if err != nil {
+			return nil, err
+		}
+		calicoInitSaver, err := NewManifestsSaver("calico_init", n.k0sVars.DataDir)
+		if err != nil {
+			return nil, err
+		}
+		return NewCalico(cfg, calicoInitSaver, calicoSaver)
+	case "cilium":
+		saver, err := NewManifestsSaver("cilium", n.k0sVars.DataDir)
+		if err != nil {
+			return nil, err
+		}
+		return NewCilium(cfg, saver)
+	case "kuberouter":
+		saver, err := NewManifestsSaver("kuberouter", n.k0sVars.DataDir)
+		if err != nil {
+			return nil, err
+		}
+		return NewKubeRouter(cfg, saver, n.kubeClientFactory)
+	default:
+		return nil, nil
+	}
+}
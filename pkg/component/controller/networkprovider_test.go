@@ -0,0 +1,107 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package controller
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/k0sproject/k0s/internal/testutil"
+	"github.com/k0sproject/k0s/internal/util"
+	"github.com/k0sproject/k0s/pkg/apis/v1beta1"
+	"github.com/k0sproject/k0s/pkg/component"
+	"github.com/k0sproject/k0s/pkg/constant"
+)
+
+func TestNetworkProviderSwitch(t *testing.T) {
+	dataDir, err := ioutil.TempDir("", "k0s-network-provider")
+	require.NoError(t, err)
+	defer os.RemoveAll(dataDir)
+	k0sVars := constant.CfgVars{DataDir: dataDir, ManifestsDir: filepath.Join(dataDir, "manifests")}
+
+	// leftovers of a migration that happened while the controller was offline
+	require.NoError(t, os.MkdirAll(filepath.Join(k0sVars.ManifestsDir, "calico"), 0700))
+
+	cfg := v1beta1.DefaultClusterConfig(k0sVars)
+	n := NewNetworkProvider(cfg, k0sVars, testutil.NewFakeClientFactory(), &DummyLeaderElector{Leader: true})
+	require.NoError(t, n.Run())
+	defer func() { assert.NoError(t, n.Stop()) }()
+
+	assert.False(t, util.IsDirectory(filepath.Join(k0sVars.ManifestsDir, "calico")))
+	assert.FileExists(t, filepath.Join(k0sVars.ManifestsDir, "kuberouter", "kube-router.yaml"))
+	assert.IsType(t, &KubeRouter{}, component.Unwrap(n.reconciler), "the kube-router reconciler runs under the leader lease")
+
+	calicoCfg := v1beta1.DefaultClusterConfig(k0sVars)
+	calicoCfg.Spec.Network.Provider = "calico"
+	calicoCfg.Spec.Network.KubeRouter = nil
+	calicoCfg.Spec.Network.Calico = v1beta1.DefaultCalico()
+	require.NoError(t, n.Reconcile(calicoCfg))
+
+	assert.False(t, util.IsDirectory(filepath.Join(k0sVars.ManifestsDir, "kuberouter")))
+	assert.True(t, util.IsDirectory(filepath.Join(k0sVars.ManifestsDir, "calico")))
+	assert.FileExists(t, filepath.Join(k0sVars.ManifestsDir, "calico_init", "calico-crd-ippools.crd.projectcalico.org.yaml"))
+	assert.IsType(t, &Calico{}, n.reconciler)
+}
+
+func TestNetworkProviderCustom(t *testing.T) {
+	dataDir, err := ioutil.TempDir("", "k0s-network-provider")
+	require.NoError(t, err)
+	defer os.RemoveAll(dataDir)
+	k0sVars := constant.CfgVars{DataDir: dataDir, ManifestsDir: filepath.Join(dataDir, "manifests")}
+
+	cfg := v1beta1.DefaultClusterConfig(k0sVars)
+	cfg.Spec.Network.Provider = "custom"
+	n := NewNetworkProvider(cfg, k0sVars, testutil.NewFakeClientFactory(), &DummyLeaderElector{Leader: true})
+	require.NoError(t, n.Run())
+	assert.Nil(t, n.reconciler)
+	assert.NoError(t, n.Reconcile(cfg))
+	assert.NoError(t, n.Stop())
+	assert.False(t, util.IsDirectory(filepath.Join(k0sVars.ManifestsDir, "kuberouter")))
+}
+
+func TestNetworkProviderLeaderOnlyWork(t *testing.T) {
+	k0sVars := constant.CfgVars{DataDir: t.TempDir()}
+	k0sVars.ManifestsDir = filepath.Join(k0sVars.DataDir, "manifests")
+	cfg := v1beta1.DefaultClusterConfig(k0sVars)
+	cfg.Spec.Network.KubeRouter.Peers = []v1beta1.KubeRouterPeer{{IP: "10.0.1.1", ASN: 65001, Password: "secret"}}
+
+	for _, leader := range []bool{false, true} {
+		clients := testutil.NewFakeClientFactory()
+		secrets := clients.Client.CoreV1().Secrets(metav1.NamespaceSystem)
+		n := NewNetworkProvider(cfg, k0sVars, clients, &DummyLeaderElector{Leader: leader})
+		require.NoError(t, n.Run())
+		if leader {
+			assert.Eventually(t, func() bool {
+				_, err := secrets.Get(context.Background(), kubeRouterPeerPasswordsSecret, metav1.GetOptions{})
+				return err == nil
+			}, 5*time.Second, 100*time.Millisecond, "the leader writes the peer passwords")
+		}
+		require.NoError(t, n.Stop())
+		if !leader {
+			_, err := secrets.Get(context.Background(), kubeRouterPeerPasswordsSecret, metav1.GetOptions{})
+			assert.True(t, apierrors.IsNotFound(err), "only the leader writes the peer passwords, got %v", err)
+		}
+	}
+}
@@ -25,6 +25,7 @@ import (
 	config "github.com/k0sproject/k0s/pkg/apis/v1beta1"
 	"github.com/k0sproject/k0s/pkg/assets"
 	"github.com/k0sproject/k0s/pkg/constant"
+	"github.com/k0sproject/k0s/pkg/flags"
 	"github.com/k0sproject/k0s/pkg/supervisor"
 )
 
@@ -48,11 +49,17 @@ func (a *Scheduler) Init() error {
 	return assets.Stage(a.K0sVars.BinDir, "kube-scheduler", constant.BinDirMode)
 }
 
-// Run runs kube scheduler
-func (a *Scheduler) Run() error {
-	logrus.Info("Starting kube-scheduler")
+// schedulerPolicy lists the flags k0s sets that can be replaced with the extra args and the deprecated ones
+var schedulerPolicy = flags.Policy{
+	Component:   "kube-scheduler",
+	Replaceable: []string{"bind-address", "profiling", "v"},
+	Deprecated:  map[string]string{"feature-gates": "spec.featureGates"},
+}
+
+// Args returns the flags kube-scheduler is run with
+func (a *Scheduler) Args() (flags.Args, error) {
 	schedulerAuthConf := filepath.Join(a.K0sVars.CertRootDir, "scheduler.conf")
-	args := map[string]string{
+	args := flags.Args{
 		"authentication-kubeconfig": schedulerAuthConf,
 		"authorization-kubeconfig":  schedulerAuthConf,
 		"kubeconfig":                schedulerAuthConf,
@@ -61,18 +68,24 @@ func (a *Scheduler) Run() error {
 		"profiling":                 "false",
 		"v":                         a.LogLevel,
 	}
-	for name, value := range a.ClusterConfig.Spec.Scheduler.ExtraArgs {
-		if args[name] != "" && name != "profiling" {
-			return fmt.Errorf("cannot override kube-scheduler flag: %s", name)
-		}
-		args[name] = value
+	if a.ClusterConfig.Spec.API.ExternalAddress == "" {
+		args["leader-elect"] = "false"
 	}
-	var schedulerArgs []string
-	for name, value := range args {
-		schedulerArgs = append(schedulerArgs, fmt.Sprintf("--%s=%s", name, value))
+	if gates := a.ClusterConfig.Spec.EffectiveFeatureGates(); len(gates) > 0 {
+		args["feature-gates"] = gates.String()
 	}
-	if a.ClusterConfig.Spec.API.ExternalAddress == "" {
-		schedulerArgs = append(schedulerArgs, "--leader-elect=false")
+	return schedulerPolicy.Merge(args, a.ClusterConfig.Spec.Scheduler.ExtraArgs)
+}
+
+// Run runs kube scheduler
+func (a *Scheduler) Run() error {
+	logrus.Info("Starting kube-scheduler")
+	args, err := a.Args()
+	if err != nil {
+		return err
+	}
+	if err := schedulerPolicy.Validate(assets.BinPath("kube-scheduler", a.K0sVars.BinDir), a.ClusterConfig.Spec.Scheduler.ExtraArgs); err != nil {
+		return err
 	}
 
 	a.supervisor = supervisor.Supervisor{
@@ -80,7 +93,7 @@ func (a *Scheduler) Run() error {
 		BinPath: assets.BinPath("kube-scheduler", a.K0sVars.BinDir),
 		RunDir:  a.K0sVars.RunDir,
 		DataDir: a.K0sVars.DataDir,
-		Args:    schedulerArgs,
+		Args:    args.ToArgs(),
 		UID:     a.uid,
 		GID:     a.gid,
 	}
@@ -67,6 +67,8 @@ func (s *SystemRBAC) Stop() error {
 	return nil
 }
 
+// bootstrapRBACTemplate doesn't let kube-controller-manager auto approve the kubelet client certificates of
+// bootstrapping nodes, the CSRApprover approves them so that node scoped tokens only get a cert for their node
 const bootstrapRBACTemplate = `
 apiVersion: rbac.authorization.k8s.io/v1
 kind: ClusterRoleBinding
@@ -83,19 +85,6 @@ subjects:
 ---
 apiVersion: rbac.authorization.k8s.io/v1
 kind: ClusterRoleBinding
-metadata:
-  name: node-autoapprove-bootstrap
-roleRef:
-  apiGroup: rbac.authorization.k8s.io
-  kind: ClusterRole
-  name: system:certificates.k8s.io:certificatesigningrequests:nodeclient
-subjects:
-- apiGroup: rbac.authorization.k8s.io
-  kind: Group
-  name: system:bootstrappers
----
-apiVersion: rbac.authorization.k8s.io/v1
-kind: ClusterRoleBinding
 metadata:
   name: node-autoapprove-certificate-rotation
 roleRef:
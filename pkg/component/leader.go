@@ -0,0 +1,148 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package component
+
+import (
+	"context"
+	"reflect"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// LeaderElection tells whether this controller is the cluster wide leader and notifies about lease transitions
+type LeaderElection interface {
+	IsLeader() bool
+	AddAcquiredLeaseCallback(fn func())
+	AddLostLeaseCallback(fn func())
+}
+
+// LeaderComponent is a component doing work only one controller in the cluster may do at a time.
+// Init, Run and Stop are called on every controller, RunLeader is called each time this controller
+// acquires the leader lease. The context given to RunLeader is cancelled when the lease is lost or the
+// component is stopped, and RunLeader is expected to return once it's done.
+type LeaderComponent interface {
+	Component
+	RunLeader(ctx context.Context) error
+}
+
+// LeaderScoped wraps the component so that its leader only work is started and stopped following the lease
+// transitions. The component manager does this by itself, it's only needed for components managed otherwise.
+func LeaderScoped(le LeaderElection, comp LeaderComponent) Component {
+	return &leaderScoped{
+		LeaderComponent: comp,
+		leaderElection:  le,
+		name:            componentName(comp),
+	}
+}
+
+type leaderScoped struct {
+	LeaderComponent
+	leaderElection LeaderElection
+	name           string
+
+	mu         sync.Mutex
+	registered bool
+	running    bool
+	leading    bool
+	cancel     context.CancelFunc
+	done       chan struct{}
+}
+
+// Run runs the component and starts the leader only work if we're already leading
+func (l *leaderScoped) Run() error {
+	if err := l.LeaderComponent.Run(); err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !l.registered {
+		l.leaderElection.AddAcquiredLeaseCallback(func() { l.setLeading(true) })
+		l.leaderElection.AddLostLeaseCallback(func() { l.setLeading(false) })
+		l.registered = true
+	}
+	l.running = true
+	// the lease might have been acquired before the callbacks got registered
+	l.leading = l.leading || l.leaderElection.IsLeader()
+	l.reconcile()
+	return nil
+}
+
+// Stop stops the leader only work before stopping the component
+func (l *leaderScoped) Stop() error {
+	l.mu.Lock()
+	l.running = false
+	l.reconcile()
+	l.mu.Unlock()
+
+	return l.LeaderComponent.Stop()
+}
+
+func (l *leaderScoped) setLeading(leading bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.leading = leading
+	l.reconcile()
+}
+
+// reconcile starts or stops the leader only work, the caller must hold the lock
+func (l *leaderScoped) reconcile() {
+	log := logrus.WithField("component", l.name)
+	shouldLead := l.running && l.leading
+
+	if shouldLead && l.cancel == nil {
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+		l.cancel, l.done = cancel, done
+		log.Info("acquired leader lease, starting leader only work")
+		go func() {
+			defer close(done)
+			if err := l.LeaderComponent.RunLeader(ctx); err != nil {
+				log.WithError(err).Error("leader only work failed")
+			}
+		}()
+		return
+	}
+
+	if !shouldLead && l.cancel != nil {
+		log.Info("stopping leader only work")
+		l.cancel()
+		// wait for it to finish so that we never run two instances at once
+		<-l.done
+		l.cancel, l.done = nil, nil
+	}
+}
+
+// Unwrap returns the component wrapped with LeaderScoped, or the component itself if it's not wrapped
+func Unwrap(comp Component) Component {
+	if l, ok := comp.(*leaderScoped); ok {
+		return l.LeaderComponent
+	}
+	return comp
+}
+
+// componentName returns the name of the component's type, looking through the leader scoped wrapper
+func componentName(comp Component) string {
+	if l, ok := comp.(*leaderScoped); ok {
+		return l.name
+	}
+	t := reflect.TypeOf(comp)
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.Name()
+}
@@ -0,0 +1,130 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package component
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeLeaderElection struct {
+	mu       sync.Mutex
+	leader   bool
+	acquired []func()
+	lost     []func()
+}
+
+func (f *fakeLeaderElection) IsLeader() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.leader
+}
+
+func (f *fakeLeaderElection) AddAcquiredLeaseCallback(fn func()) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.acquired = append(f.acquired, fn)
+}
+
+func (f *fakeLeaderElection) AddLostLeaseCallback(fn func()) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.lost = append(f.lost, fn)
+}
+
+func (f *fakeLeaderElection) setLeader(leader bool) {
+	f.mu.Lock()
+	f.leader = leader
+	callbacks := f.lost
+	if leader {
+		callbacks = f.acquired
+	}
+	f.mu.Unlock()
+	for _, fn := range callbacks {
+		fn()
+	}
+}
+
+type fakeLeaderComponent struct {
+	leading int32
+	runs    int32
+}
+
+func (f *fakeLeaderComponent) Init() error    { return nil }
+func (f *fakeLeaderComponent) Run() error     { return nil }
+func (f *fakeLeaderComponent) Stop() error    { return nil }
+func (f *fakeLeaderComponent) Healthy() error { return nil }
+
+func (f *fakeLeaderComponent) RunLeader(ctx context.Context) error {
+	atomic.AddInt32(&f.runs, 1)
+	atomic.StoreInt32(&f.leading, 1)
+	<-ctx.Done()
+	atomic.StoreInt32(&f.leading, 0)
+	return nil
+}
+
+func (f *fakeLeaderComponent) isLeading() bool {
+	return atomic.LoadInt32(&f.leading) == 1
+}
+
+func TestManagerRunsLeaderComponentsOnlyWhileLeading(t *testing.T) {
+	le := &fakeLeaderElection{}
+	comp := &fakeLeaderComponent{}
+
+	m := NewManager()
+	m.SetLeaderElection(le)
+	m.Add(comp)
+	assert.NoError(t, m.Init())
+	assert.NoError(t, m.Start(context.Background()))
+	assert.False(t, comp.isLeading())
+
+	le.setLeader(true)
+	assert.Eventually(t, comp.isLeading, time.Second, 10*time.Millisecond)
+
+	// lost lease callbacks return once the leader only work has stopped
+	le.setLeader(false)
+	assert.False(t, comp.isLeading())
+
+	le.setLeader(true)
+	assert.Eventually(t, comp.isLeading, time.Second, 10*time.Millisecond)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&comp.runs))
+
+	assert.NoError(t, m.Stop())
+	assert.False(t, comp.isLeading())
+}
+
+func TestLeaderComponentStartsWhenAlreadyLeading(t *testing.T) {
+	le := &fakeLeaderElection{leader: true}
+	comp := &fakeLeaderComponent{}
+
+	c := LeaderScoped(le, comp)
+	assert.NoError(t, c.Init())
+	assert.NoError(t, c.Run())
+	assert.Eventually(t, comp.isLeading, time.Second, 10*time.Millisecond)
+	assert.NoError(t, c.Stop())
+	assert.False(t, comp.isLeading())
+}
+
+func TestManagerRequiresLeaderElectionForLeaderComponents(t *testing.T) {
+	m := NewManager()
+	m.Add(&fakeLeaderComponent{})
+	assert.Error(t, m.Init())
+}
@@ -18,7 +18,6 @@ package component
 import (
 	"context"
 	"fmt"
-	"reflect"
 	"time"
 
 	"github.com/k0sproject/k0s/pkg/performance"
@@ -28,8 +27,9 @@ import (
 
 // Manager manages components
 type Manager struct {
-	components []Component
-	sync       map[string]struct{}
+	components     []Component
+	sync           map[string]struct{}
+	leaderElection LeaderElection
 }
 
 // NewManager creates a manager
@@ -48,16 +48,27 @@ func (m *Manager) Add(component Component) {
 // AddSync adds a component to the manager that should be initialized synchronously
 func (m *Manager) AddSync(component Component) {
 	m.components = append(m.components, component)
-	compName := reflect.TypeOf(component).Elem().Name()
-	m.sync[compName] = struct{}{}
+	m.sync[componentName(component)] = struct{}{}
+}
+
+// SetLeaderElection sets the leader election driving the components implementing LeaderComponent
+func (m *Manager) SetLeaderElection(le LeaderElection) {
+	m.leaderElection = le
 }
 
 // Init initializes all managed components
 func (m *Manager) Init() error {
 	var g errgroup.Group
 
-	for _, comp := range m.components {
-		compName := reflect.TypeOf(comp).Elem().Name()
+	for i, comp := range m.components {
+		compName := componentName(comp)
+		lc, isLeaderComponent := comp.(LeaderComponent)
+		if _, wrapped := comp.(*leaderScoped); isLeaderComponent && !wrapped {
+			if m.leaderElection == nil {
+				return fmt.Errorf("%s needs leader election but none is set", compName)
+			}
+			m.components[i] = LeaderScoped(m.leaderElection, lc)
+		}
 		logrus.Infof("initializing %v\n", compName)
 		c := comp
 		if _, found := m.sync[compName]; found {
@@ -78,7 +89,7 @@ func (m *Manager) Start(ctx context.Context) error {
 
 	perfTimer := performance.NewTimer("component-start").Buffer().Start()
 	for _, comp := range m.components {
-		compName := reflect.TypeOf(comp).Elem().Name()
+		compName := componentName(comp)
 		perfTimer.Checkpoint(fmt.Sprintf("running-%s", compName))
 		logrus.Infof("starting %v", compName)
 
@@ -110,24 +121,36 @@ func (m *Manager) Stop() error {
 
 // waitForHealthy waits until the component is healthy and returns true upon success. If a timeout occurs, it returns false
 func waitForHealthy(ctx context.Context, comp Component, name string) error {
-	ctx, cancelFunction := context.WithTimeout(ctx, 2*time.Minute)
+	timeout := 2 * time.Minute
+	if s, ok := comp.(SlowStarter); ok && s.HealthTimeout() > timeout {
+		timeout = s.HealthTimeout()
+	}
+	ctx, cancelFunction := context.WithTimeout(ctx, timeout)
 
 	// clear up context after timeout
 	defer cancelFunction()
 
 	// loop forever, until the context is canceled or until etcd is healthy
 	ticker := time.NewTicker(100 * time.Millisecond)
+	var lastErr string
 	for {
 		select {
 		case <-ticker.C:
 			logrus.Debugf("checking %s for health", name)
 			if err := comp.Healthy(); err != nil {
-				logrus.Errorf("health-check: %s might be down: %v", name, err)
+				// a component waiting for a long time would flood the log with the same error
+				if err.Error() != lastErr {
+					logrus.Errorf("health-check: %s might be down: %v", name, err)
+					lastErr = err.Error()
+				}
 				continue
 			}
 			logrus.Debugf("%s is healthy. closing check", name)
 			return nil
 		case <-ctx.Done():
+			if lastErr != "" {
+				return fmt.Errorf("%s health-check timed out after %s: %s", name, timeout, lastErr)
+			}
 			return fmt.Errorf("%s health-check timed out", name)
 		}
 	}
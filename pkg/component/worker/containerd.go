@@ -16,7 +16,6 @@ limitations under the License.
 package worker
 
 import (
-	"fmt"
 	"path/filepath"
 
 	"github.com/sirupsen/logrus"
@@ -24,6 +23,7 @@ import (
 
 	"github.com/k0sproject/k0s/pkg/assets"
 	"github.com/k0sproject/k0s/pkg/constant"
+	"github.com/k0sproject/k0s/pkg/flags"
 	"github.com/k0sproject/k0s/pkg/supervisor"
 )
 
@@ -34,6 +34,8 @@ type ContainerD struct {
 	K0sVars    constant.CfgVars
 
 	OCIBundlePath string
+	// ExtraArgs are the extra containerd flags given on the command line
+	ExtraArgs string
 }
 
 // Init extracts the needed binaries
@@ -49,21 +51,39 @@ func (c *ContainerD) Init() error {
 	return g.Wait()
 }
 
+// containerdPolicy lists the flags k0s sets that can be replaced with the extra args
+var containerdPolicy = flags.Policy{
+	Component:   "containerd",
+	Replaceable: []string{"config", "log-level"},
+}
+
+// Args returns the flags containerd is run with
+func (c *ContainerD) Args() (flags.Args, error) {
+	return containerdPolicy.Merge(flags.Args{
+		"root":      filepath.Join(c.K0sVars.DataDir, "containerd"),
+		"state":     filepath.Join(c.K0sVars.RunDir, "containerd"),
+		"address":   filepath.Join(c.K0sVars.RunDir, "containerd.sock"),
+		"log-level": c.LogLevel,
+		"config":    "/etc/k0s/containerd.toml",
+	}, flags.ParseExtraArgs(c.ExtraArgs))
+}
+
 // Run runs containerD
 func (c *ContainerD) Run() error {
 	logrus.Info("Starting containerD")
+	args, err := c.Args()
+	if err != nil {
+		return err
+	}
+	if err := containerdPolicy.Validate(assets.BinPath("containerd", c.K0sVars.BinDir), flags.ParseExtraArgs(c.ExtraArgs)); err != nil {
+		return err
+	}
 	c.supervisor = supervisor.Supervisor{
 		Name:    "containerd",
 		BinPath: assets.BinPath("containerd", c.K0sVars.BinDir),
 		RunDir:  c.K0sVars.RunDir,
 		DataDir: c.K0sVars.DataDir,
-		Args: []string{
-			fmt.Sprintf("--root=%s", filepath.Join(c.K0sVars.DataDir, "containerd")),
-			fmt.Sprintf("--state=%s", filepath.Join(c.K0sVars.RunDir, "containerd")),
-			fmt.Sprintf("--address=%s", filepath.Join(c.K0sVars.RunDir, "containerd.sock")),
-			fmt.Sprintf("--log-level=%s", c.LogLevel),
-			"--config=/etc/k0s/containerd.toml",
-		},
+		Args:    args.ToArgs(),
 	}
 	// TODO We need to dump the config file suited for k0s use
 
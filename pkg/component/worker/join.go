@@ -0,0 +1,147 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package worker
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/k0sproject/k0s/internal/util"
+	"github.com/k0sproject/k0s/pkg/apis/v1beta1"
+	"github.com/k0sproject/k0s/pkg/constant"
+	"github.com/k0sproject/k0s/pkg/token"
+)
+
+// JoinInfo holds the cluster settings the worker received when joining over the k0s api
+type JoinInfo struct {
+	// K0sAPIAddress is the address of the k0s api the worker joined through
+	K0sAPIAddress string `json:"k0sApiAddress"`
+	Profile       string `json:"profile"`
+	APIAddress    string `json:"apiAddress"`
+	ClusterDNS    string `json:"clusterDNS"`
+	ClusterDomain string `json:"clusterDomain"`
+	ServiceCIDR   string `json:"serviceCIDR"`
+	PodCIDR       string `json:"podCIDR"`
+
+	// KubeletConfig is the resolved worker profile at join time, it's not persisted
+	KubeletConfig string `json:"-"`
+}
+
+func joinInfoPath(k0sVars constant.CfgVars) string {
+	return filepath.Join(k0sVars.DataDir, "worker-join.json")
+}
+
+// JoinWorker joins the node over the k0s api and writes the node scoped kubelet bootstrap config.
+// Tokens and controllers not supporting the join api fall back to using the token itself as the
+// kubelet bootstrap config, in which case the returned JoinInfo is nil.
+func JoinWorker(encodedToken string, k0sVars constant.CfgVars, profile string, labels []string) (*JoinInfo, error) {
+	joinClient, err := token.WorkerJoinClientFromToken(encodedToken)
+	if err == token.ErrNoJoinAPI {
+		logrus.Info("join token does not support joining over the k0s api, using it as kubelet bootstrap config")
+		return nil, HandleKubeletBootstrapToken(encodedToken, k0sVars)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		return nil, err
+	}
+	joinReq := v1beta1.WorkerJoinRequest{
+		Hostname: hostname,
+		Arch:     runtime.GOARCH,
+		OS:       runtime.GOOS,
+		Profile:  profile,
+		Labels:   map[string]string{},
+	}
+	for _, l := range labels {
+		kv := strings.SplitN(l, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("invalid label %q, expected key=value", l)
+		}
+		joinReq.Labels[kv[0]] = kv[1]
+	}
+
+	joinResp, err := joinClient.JoinWorker(joinReq)
+	if err != nil {
+		// only controllers predating the join api fall back to the cluster wide token, failing to reach
+		// the k0s api must not bypass the node name policy enforced by the join api
+		var notServed *token.NotServedError
+		if errors.As(err, &notServed) {
+			logrus.Warnf("the controller at %s does not support joining over the k0s api, using the token as kubelet bootstrap config: %s", joinClient.Address(), err.Error())
+			return nil, HandleKubeletBootstrapToken(encodedToken, k0sVars)
+		}
+		return nil, err
+	}
+
+	if err := util.InitDirectory(k0sVars.CertRootDir, constant.CertRootDirMode); err != nil {
+		return nil, fmt.Errorf("failed to initialize directory '%s': %w", k0sVars.CertRootDir, err)
+	}
+	kubeletCAPath := path.Join(k0sVars.CertRootDir, "ca.crt")
+	if err := ioutil.WriteFile(kubeletCAPath, joinResp.CACert, constant.CertMode); err != nil {
+		return nil, fmt.Errorf("failed to write ca client cert: %w", err)
+	}
+	if err := ioutil.WriteFile(k0sVars.KubeletBootstrapConfigPath, joinResp.Kubeconfig, constant.CertSecureMode); err != nil {
+		return nil, fmt.Errorf("failed writing kubelet bootstrap auth config: %w", err)
+	}
+
+	info := &JoinInfo{
+		K0sAPIAddress: joinClient.Address(),
+		Profile:       joinResp.Profile,
+		APIAddress:    joinResp.APIAddress,
+		ClusterDNS:    joinResp.ClusterDNS,
+		ClusterDomain: joinResp.ClusterDomain,
+		ServiceCIDR:   joinResp.ServiceCIDR,
+		PodCIDR:       joinResp.PodCIDR,
+		KubeletConfig: joinResp.KubeletConfig,
+	}
+	data, err := json.Marshal(info)
+	if err != nil {
+		return nil, err
+	}
+	if err := ioutil.WriteFile(joinInfoPath(k0sVars), data, constant.CertMode); err != nil {
+		return nil, fmt.Errorf("failed to write worker join info: %w", err)
+	}
+	logrus.Infof("joined the cluster over the k0s api at %s using worker profile %s", info.K0sAPIAddress, info.Profile)
+
+	return info, nil
+}
+
+// LoadJoinInfo loads the join info persisted when the worker joined, or nil if the worker did not join over the k0s api
+func LoadJoinInfo(k0sVars constant.CfgVars) (*JoinInfo, error) {
+	data, err := ioutil.ReadFile(joinInfoPath(k0sVars))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	info := &JoinInfo{}
+	if err := json.Unmarshal(data, info); err != nil {
+		return nil, fmt.Errorf("failed to parse worker join info: %w", err)
+	}
+	return info, nil
+}
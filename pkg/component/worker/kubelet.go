@@ -31,8 +31,10 @@ import (
 	"github.com/sirupsen/logrus"
 
 	"github.com/k0sproject/k0s/internal/util"
+	"github.com/k0sproject/k0s/pkg/apis/v1beta1"
 	"github.com/k0sproject/k0s/pkg/assets"
 	"github.com/k0sproject/k0s/pkg/constant"
+	"github.com/k0sproject/k0s/pkg/flags"
 	"github.com/k0sproject/k0s/pkg/supervisor"
 )
 
@@ -42,13 +44,17 @@ type Kubelet struct {
 	EnableCloudProvider bool
 	K0sVars             constant.CfgVars
 	KubeletConfigClient *KubeletConfigClient
-	LogLevel            string
-	Profile             string
-	dataDir             string
-	supervisor          supervisor.Supervisor
-	ClusterDNS          string
-	Labels              []string
-	ExtraArgs           string
+	// InitialConfig is the kubelet config received when joining, used instead of fetching it on the first start
+	InitialConfig string
+	LogLevel      string
+	Profile       string
+	dataDir       string
+	supervisor    supervisor.Supervisor
+	ClusterDNS    string
+	// ClusterDomain is the cluster domain received when joining, used for windows kubelets
+	ClusterDomain string
+	Labels        []string
+	ExtraArgs     string
 }
 
 type kubeletConfig struct {
@@ -76,90 +82,123 @@ func (k *Kubelet) Init() error {
 	return nil
 }
 
-// Run runs kubelet
-func (k *Kubelet) Run() error {
-	cmd := "kubelet"
-
-	if runtime.GOOS == "windows" {
-		cmd = "kubelet.exe"
-	}
+// kubeletPolicy lists the flags k0s sets that can be replaced with the extra args
+var kubeletPolicy = flags.Policy{
+	Component: "kubelet",
+	Replaceable: []string{
+		"cgroups-per-qos",
+		"cluster-domain",
+		"cni-bin-dir",
+		"cni-conf-dir",
+		"enforce-node-allocatable",
+		"hairpin-mode",
+		"hostname-override",
+		"kube-reserved-cgroup",
+		"kubelet-cgroups",
+		"node-labels",
+		"pod-infra-container-image",
+		"resolv-conf",
+		"runtime-cgroups",
+		"v",
+	},
+}
 
-	logrus.Info("Starting kubelet")
+// Args returns the flags kubelet is run with
+func (k *Kubelet) Args() (flags.Args, error) {
 	kubeletConfigPath := filepath.Join(k.K0sVars.DataDir, "kubelet-config.yaml")
 	// get the "real" resolv.conf file (in systemd-resolvd bases system,
 	// this will return /run/systemd/resolve/resolv.conf
 	resolvConfPath := resolvconf.Path()
+	dataDir := filepath.Join(k.K0sVars.DataDir, "kubelet")
 
-	args := util.MappedArgs{
-		"--root-dir":             k.dataDir,
-		"--config":               kubeletConfigPath,
-		"--bootstrap-kubeconfig": k.K0sVars.KubeletBootstrapConfigPath,
-		"--kubeconfig":           k.K0sVars.KubeletAuthConfigPath,
-		"--v":                    k.LogLevel,
-		"--kube-reserved-cgroup": "system.slice",
-		"--runtime-cgroups":      "/system.slice/containerd.service",
-		"--kubelet-cgroups":      "/system.slice/containerd.service",
-		"--cert-dir":             filepath.Join(k.dataDir, "pki"),
+	args := flags.Args{
+		"root-dir":             dataDir,
+		"config":               kubeletConfigPath,
+		"bootstrap-kubeconfig": k.K0sVars.KubeletBootstrapConfigPath,
+		"kubeconfig":           k.K0sVars.KubeletAuthConfigPath,
+		"v":                    k.LogLevel,
+		"kube-reserved-cgroup": "system.slice",
+		"runtime-cgroups":      "/system.slice/containerd.service",
+		"kubelet-cgroups":      "/system.slice/containerd.service",
+		"cert-dir":             filepath.Join(dataDir, "pki"),
 	}
 
 	if len(k.Labels) > 0 {
-		args["--node-labels"] = strings.Join(k.Labels, ",")
+		args["node-labels"] = strings.Join(k.Labels, ",")
 	}
 
 	if runtime.GOOS == "windows" {
 		node, err := getNodeName()
 		if err != nil {
-			return fmt.Errorf("can't get hostname: %v", err)
+			return nil, fmt.Errorf("can't get hostname: %v", err)
+		}
+		args["cgroups-per-qos"] = "false"
+		args["enforce-node-allocatable"] = ""
+		args["pod-infra-container-image"] = "mcr.microsoft.com/oss/kubernetes/pause:1.4.1"
+		args["network-plugin"] = "cni"
+		args["cni-bin-dir"] = "C:\\k\\cni"
+		args["cni-conf-dir"] = "C:\\k\\cni\\config"
+		args["hostname-override"] = node
+		args["resolv-conf"] = ""
+		args["cluster-domain"] = v1beta1.DefaultClusterDomain
+		if k.ClusterDomain != "" {
+			args["cluster-domain"] = k.ClusterDomain
 		}
-		args["--cgroups-per-qos"] = "false"
-		args["--enforce-node-allocatable"] = ""
-		args["--pod-infra-container-image"] = "mcr.microsoft.com/oss/kubernetes/pause:1.4.1"
-		args["--network-plugin"] = "cni"
-		args["--cni-bin-dir"] = "C:\\k\\cni"
-		args["--cni-conf-dir"] = "C:\\k\\cni\\config"
-		args["--hostname-override"] = node
-		args["--resolv-conf"] = ""
-		args["--cluster-domain"] = "cluster.local"
-		args["--hairpin-mode"] = "promiscuous-bridge"
-		args["--cert-dir"] = "C:\\var\\lib\\k0s\\kubelet_certs"
+		args["hairpin-mode"] = "promiscuous-bridge"
+		args["cert-dir"] = "C:\\var\\lib\\k0s\\kubelet_certs"
 	} else {
-		args["--cgroups-per-qos"] = "true"
-		args["--resolv-conf"] = resolvConfPath
+		args["cgroups-per-qos"] = "true"
+		args["resolv-conf"] = resolvConfPath
 	}
 
 	if k.CRISocket != "" {
 		rtType, rtSock, err := SplitRuntimeConfig(k.CRISocket)
 		if err != nil {
-			return err
+			return nil, err
 		}
-		args["--container-runtime"] = rtType
+		args["container-runtime"] = rtType
 		shimPath := "unix:///var/run/dockershim.sock"
 		if runtime.GOOS == "windows" {
 			shimPath = "npipe:////./pipe/dockershim"
 		}
 		if rtType == "docker" {
-			args["--docker-endpoint"] = rtSock
+			args["docker-endpoint"] = rtSock
 			// this endpoint is actually pointing to the one kubelet itself creates as the cri shim between itself and docker
-			args["--container-runtime-endpoint"] = shimPath
+			args["container-runtime-endpoint"] = shimPath
 		} else {
-			args["--container-runtime-endpoint"] = rtSock
+			args["container-runtime-endpoint"] = rtSock
 		}
 	} else {
 		sockPath := path.Join(k.K0sVars.RunDir, "containerd.sock")
-		args["--container-runtime"] = "remote"
-		args["--container-runtime-endpoint"] = fmt.Sprintf("unix://%s", sockPath)
-		args["--containerd"] = sockPath
+		args["container-runtime"] = "remote"
+		args["container-runtime-endpoint"] = fmt.Sprintf("unix://%s", sockPath)
+		args["containerd"] = sockPath
 	}
 
 	// We only support external providers
 	if k.EnableCloudProvider {
-		args["--cloud-provider"] = "external"
+		args["cloud-provider"] = "external"
+	}
+
+	return kubeletPolicy.Merge(args, flags.ParseExtraArgs(k.ExtraArgs))
+}
+
+// Run runs kubelet
+func (k *Kubelet) Run() error {
+	cmd := "kubelet"
+
+	if runtime.GOOS == "windows" {
+		cmd = "kubelet.exe"
 	}
 
-	// Handle the extra args as last so they can be used to overrride some k0s "hardcodings"
-	if k.ExtraArgs != "" {
-		extras := util.SplitFlags(k.ExtraArgs)
-		args.Merge(extras)
+	logrus.Info("Starting kubelet")
+	kubeletConfigPath := filepath.Join(k.K0sVars.DataDir, "kubelet-config.yaml")
+	args, err := k.Args()
+	if err != nil {
+		return err
+	}
+	if err := kubeletPolicy.Validate(assets.BinPath(cmd, k.K0sVars.BinDir), flags.ParseExtraArgs(k.ExtraArgs)); err != nil {
+		return err
 	}
 
 	logrus.Infof("starting kubelet with args: %v", args)
@@ -171,11 +210,15 @@ func (k *Kubelet) Run() error {
 		Args:    args.ToArgs(),
 	}
 
-	err := retry.Do(func() error {
-		kubeletconfig, err := k.KubeletConfigClient.Get(k.Profile)
-		if err != nil {
-			logrus.Warnf("failed to get initial kubelet config with join token: %s", err.Error())
-			return err
+	err = retry.Do(func() error {
+		kubeletconfig := k.InitialConfig
+		if kubeletconfig == "" {
+			var err error
+			kubeletconfig, err = k.KubeletConfigClient.Get(k.Profile)
+			if err != nil {
+				logrus.Warnf("failed to get initial kubelet config with join token: %s", err.Error())
+				return err
+			}
 		}
 		tw := util.TemplateWriter{
 			Name:     "kubelet-config",
@@ -186,7 +229,7 @@ func (k *Kubelet) Run() error {
 			},
 			Path: kubeletConfigPath,
 		}
-		err = tw.Write()
+		err := tw.Write()
 		if err != nil {
 			return fmt.Errorf("failed to write kubelet config: %w", err)
 		}
@@ -0,0 +1,255 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package worker
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+
+	"github.com/k0sproject/k0s/internal/util"
+	"github.com/k0sproject/k0s/pkg/constant"
+)
+
+// NetworkCleanupAnnotation is set on a node by `k0s network offline-migrate` to request the removal of the
+// node local state of the network provider given as the value. The worker removes the annotation
+// once it's done.
+const NetworkCleanupAnnotation = "k0s.k0sproject.io/network-cleanup"
+
+var (
+	// cniConfigFiles are the CNI configs written on the nodes by each network provider
+	cniConfigFiles = map[string][]string{
+		"calico":     {"/etc/cni/net.d/10-calico.conflist", "/etc/cni/net.d/calico-kubeconfig"},
+		"cilium":     {"/etc/cni/net.d/05-cilium.conf"},
+		"kuberouter": {"/etc/cni/net.d/10-kuberouter.conflist"},
+	}
+	// networkStateDirs are the directories each network provider keeps its node local state in
+	networkStateDirs = map[string][]string{
+		"calico": {"/var/lib/calico", "/var/run/calico"},
+		"cilium": {"/var/run/cilium"},
+	}
+	// iptablesChainPrefixes are the prefixes of the iptables chains each network provider creates
+	iptablesChainPrefixes = map[string][]string{
+		"calico":     {"cali-"},
+		"cilium":     {"CILIUM_", "OLD_CILIUM_"},
+		"kuberouter": {"KUBE-ROUTER-", "KUBE-POD-FW-", "KUBE-NWPLCY-"},
+	}
+	// ipsetPrefixes are the prefixes of the ipsets each network provider creates, the iptables rules
+	// matching them are removed along with the chains
+	ipsetPrefixes = map[string][]string{
+		"calico":     {"cali40", "cali60"},
+		"cilium":     {"cilium_"},
+		"kuberouter": {"kube-router-", "KUBE-SRC-", "KUBE-DST-", "inet6:kube-router-", "inet6:KUBE-SRC-", "inet6:KUBE-DST-"},
+	}
+)
+
+// CNIConfigFiles returns the CNI configs written on the nodes by the network provider
+func CNIConfigFiles(provider string) []string {
+	return cniConfigFiles[provider]
+}
+
+// CleanupNetworkProvider removes the CNI config, the state directories, the network interfaces and the
+// iptables rules and ipsets the network provider leaves behind on the node
+func CleanupNetworkProvider(provider string) error {
+	var errs []string
+	for _, file := range cniConfigFiles[provider] {
+		if err := os.Remove(file); err != nil && !os.IsNotExist(err) {
+			errs = append(errs, err.Error())
+		}
+	}
+	for _, dir := range networkStateDirs[provider] {
+		if err := os.RemoveAll(dir); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if err := removeNetworkLinks(provider); err != nil {
+		errs = append(errs, err.Error())
+	}
+	// the rules go first, an ipset can't be destroyed while a rule matches it
+	if err := removeNetworkRules(provider); err != nil {
+		errs = append(errs, err.Error())
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("failed to clean up %s: %s", provider, strings.Join(errs, ", "))
+	}
+	return nil
+}
+
+// filterIptablesRules removes the chains with the prefixes, and the rules jumping to them or matching the
+// ipsets with the ipset prefixes, from the output of iptables-save. It tells if anything was removed.
+func filterIptablesRules(saved string, chainPrefixes, ipsetPrefixes []string) (string, bool) {
+	hasPrefix := func(s string, prefixes []string) bool {
+		for _, p := range prefixes {
+			if strings.HasPrefix(s, p) {
+				return true
+			}
+		}
+		return false
+	}
+
+	var out strings.Builder
+	removed := false
+	for _, line := range strings.SplitAfter(saved, "\n") {
+		drop := false
+		switch {
+		case strings.HasPrefix(line, ":"):
+			drop = hasPrefix(line[1:], chainPrefixes)
+		case strings.HasPrefix(line, "-A "):
+			// the chain of the rule, the chain it jumps to and the ipsets it matches are all single words
+			for _, word := range strings.Fields(line) {
+				word = strings.Trim(word, `"`)
+				if hasPrefix(word, chainPrefixes) || hasPrefix(word, ipsetPrefixes) {
+					drop = true
+					break
+				}
+			}
+		}
+		if drop {
+			removed = true
+			continue
+		}
+		out.WriteString(line)
+	}
+	return out.String(), removed
+}
+
+// NetworkCleanup watches the node for the NetworkCleanupAnnotation and removes the node local state
+// of the previous network provider when the node is migrated to another provider
+type NetworkCleanup struct {
+	K0sVars constant.CfgVars
+
+	client   kubernetes.Interface
+	nodeName string
+	log      *logrus.Entry
+	stopCh   chan struct{}
+}
+
+// Init does nothing
+func (n *NetworkCleanup) Init() error {
+	n.log = logrus.WithField("component", "network-cleanup")
+	return nil
+}
+
+// Run starts watching the node for cleanup requests
+func (n *NetworkCleanup) Run() error {
+	n.stopCh = make(chan struct{})
+	go wait.Until(n.check, 10*time.Second, n.stopCh)
+	return nil
+}
+
+// Stop stops watching the node
+func (n *NetworkCleanup) Stop() error {
+	if n.stopCh != nil {
+		close(n.stopCh)
+	}
+	return nil
+}
+
+// Healthy is a no-op check
+func (n *NetworkCleanup) Healthy() error { return nil }
+
+func (n *NetworkCleanup) check() {
+	// the kubelet kubeconfig only exists once the kubelet has bootstrapped
+	if n.client == nil {
+		if !util.FileExists(n.K0sVars.KubeletAuthConfigPath) {
+			return
+		}
+		client, nodeName, err := nodeClient(n.K0sVars.KubeletAuthConfigPath)
+		if err != nil {
+			n.log.WithError(err).Warn("failed to create the node client")
+			return
+		}
+		n.client, n.nodeName = client, nodeName
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	node, err := n.client.CoreV1().Nodes().Get(ctx, n.nodeName, metav1.GetOptions{})
+	if err != nil {
+		n.log.WithError(err).Debug("failed to get the node")
+		return
+	}
+	provider, ok := node.Annotations[NetworkCleanupAnnotation]
+	if !ok {
+		return
+	}
+
+	n.log.Infof("removing the node local state of the network provider %s", provider)
+	if err := CleanupNetworkProvider(provider); err != nil {
+		n.log.WithError(err).Warn("network cleanup failed, will retry")
+		return
+	}
+
+	patch := fmt.Sprintf(`{"metadata":{"annotations":{%q:null}}}`, NetworkCleanupAnnotation)
+	if _, err := n.client.CoreV1().Nodes().Patch(ctx, n.nodeName, types.MergePatchType, []byte(patch), metav1.PatchOptions{}); err != nil {
+		n.log.WithError(err).Warn("failed to remove the network cleanup annotation")
+		return
+	}
+	n.log.Infof("network provider %s cleaned up", provider)
+}
+
+// nodeClient creates a client using the kubelet kubeconfig, the node name is read from the kubelet client certificate
+func nodeClient(kubeconfigPath string) (kubernetes.Interface, string, error) {
+	restConfig, err := clientcmd.BuildConfigFromFlags("", kubeconfigPath)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to load kubeconfig: %w", err)
+	}
+
+	certData := restConfig.CertData
+	if len(certData) == 0 {
+		certData, err = ioutil.ReadFile(restConfig.CertFile)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to read the kubelet client certificate: %w", err)
+		}
+	}
+	nodeName, err := nodeNameFromCert(certData)
+	if err != nil {
+		return nil, "", err
+	}
+
+	client, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		return nil, "", err
+	}
+	return client, nodeName, nil
+}
+
+func nodeNameFromCert(certData []byte) (string, error) {
+	block, _ := pem.Decode(certData)
+	if block == nil {
+		return "", fmt.Errorf("no PEM data found in the kubelet client certificate")
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse the kubelet client certificate: %w", err)
+	}
+	if !strings.HasPrefix(cert.Subject.CommonName, "system:node:") {
+		return "", fmt.Errorf("unexpected kubelet client certificate subject %s", cert.Subject.CommonName)
+	}
+	return strings.TrimPrefix(cert.Subject.CommonName, "system:node:"), nil
+}
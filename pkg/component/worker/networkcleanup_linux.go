@@ -0,0 +1,111 @@
+// +build linux
+
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package worker
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"github.com/vishvananda/netlink"
+)
+
+// networkLinkPrefixes are the prefixes of the network interfaces created on the nodes by each network provider
+var networkLinkPrefixes = map[string][]string{
+	"calico":     {"cali", "vxlan.calico"},
+	"cilium":     {"cilium_", "lxc"},
+	"kuberouter": {"kube-bridge", "kube-dummy-if", "tun-"},
+}
+
+func removeNetworkLinks(provider string) error {
+	links, err := netlink.LinkList()
+	if err != nil {
+		return fmt.Errorf("failed to list the network interfaces: %w", err)
+	}
+
+	var errs []string
+	for _, link := range links {
+		name := link.Attrs().Name
+		for _, prefix := range networkLinkPrefixes[provider] {
+			if !strings.HasPrefix(name, prefix) {
+				continue
+			}
+			if err := netlink.LinkDel(link); err != nil {
+				errs = append(errs, fmt.Sprintf("failed to delete %s: %v", name, err))
+			}
+			break
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%s", strings.Join(errs, ", "))
+	}
+	return nil
+}
+
+// removeNetworkRules removes the iptables chains, rules and ipsets of the network provider. The tools run by
+// the providers are part of their images, the ones missing on the node are skipped.
+func removeNetworkRules(provider string) error {
+	var errs []string
+	for _, tool := range []string{"iptables", "ip6tables"} {
+		if _, err := exec.LookPath(tool + "-save"); err != nil {
+			logrus.Debugf("%s-save not found, not removing the %s rules of %s", tool, tool, provider)
+			continue
+		}
+		saved, err := exec.Command(tool + "-save").Output()
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s-save failed: %v", tool, err))
+			continue
+		}
+		filtered, removed := filterIptablesRules(string(saved), iptablesChainPrefixes[provider], ipsetPrefixes[provider])
+		if !removed {
+			continue
+		}
+		// the tables are restored as a whole, the rules of the other chains are kept as they were saved
+		restore := exec.Command(tool + "-restore")
+		restore.Stdin = strings.NewReader(filtered)
+		if out, err := restore.CombinedOutput(); err != nil {
+			errs = append(errs, fmt.Sprintf("%s-restore failed: %v: %s", tool, err, bytes.TrimSpace(out)))
+		}
+	}
+
+	if _, err := exec.LookPath("ipset"); err != nil {
+		logrus.Debugf("ipset not found, not removing the ipsets of %s", provider)
+	} else if out, err := exec.Command("ipset", "list", "-n").Output(); err != nil {
+		errs = append(errs, fmt.Sprintf("ipset list failed: %v", err))
+	} else {
+		for _, name := range strings.Fields(string(out)) {
+			for _, prefix := range ipsetPrefixes[provider] {
+				if !strings.HasPrefix(name, prefix) {
+					continue
+				}
+				if out, err := exec.Command("ipset", "destroy", name).CombinedOutput(); err != nil {
+					errs = append(errs, fmt.Sprintf("failed to destroy ipset %s: %v: %s", name, err, bytes.TrimSpace(out)))
+				}
+				break
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("%s", strings.Join(errs, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package worker
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNodeNameFromCert(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	certPEM := func(cn string) []byte {
+		tmpl := &x509.Certificate{
+			SerialNumber: big.NewInt(1),
+			Subject:      pkix.Name{CommonName: cn, Organization: []string{"system:nodes"}},
+			NotBefore:    time.Now(),
+			NotAfter:     time.Now().Add(time.Hour),
+		}
+		der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+		require.NoError(t, err)
+		return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	}
+
+	name, err := nodeNameFromCert(certPEM("system:node:worker-0"))
+	assert.NoError(t, err)
+	assert.Equal(t, "worker-0", name)
+
+	_, err = nodeNameFromCert(certPEM("admin"))
+	assert.Error(t, err)
+
+	_, err = nodeNameFromCert([]byte("not a certificate"))
+	assert.Error(t, err)
+}
+
+func TestCNIConfigFiles(t *testing.T) {
+	assert.Equal(t, []string{"/etc/cni/net.d/10-kuberouter.conflist"}, CNIConfigFiles("kuberouter"))
+	assert.Empty(t, CNIConfigFiles("custom"))
+}
+
+func TestFilterIptablesRules(t *testing.T) {
+	saved := `# Generated by iptables-save
+*filter
+:INPUT ACCEPT [0:0]
+:FORWARD ACCEPT [0:0]
+:KUBE-FORWARD - [0:0]
+:KUBE-ROUTER-FORWARD - [0:0]
+:KUBE-POD-FW-ABCDEF - [0:0]
+-A INPUT -m comment --comment "kube-router netpol" -j KUBE-ROUTER-INPUT
+-A FORWARD -m comment --comment "kubernetes forwarding rules" -j KUBE-FORWARD
+-A FORWARD -j KUBE-ROUTER-FORWARD
+-A KUBE-ROUTER-FORWARD -j KUBE-POD-FW-ABCDEF
+-A KUBE-POD-FW-ABCDEF -j ACCEPT
+COMMIT
+*nat
+:POSTROUTING ACCEPT [0:0]
+-A POSTROUTING -m set --match-set kube-router-pod-subnets src -m set ! --match-set kube-router-pod-subnets dst -j MASQUERADE
+-A POSTROUTING -s 10.244.0.0/16 -j MASQUERADE
+COMMIT
+`
+	filtered, removed := filterIptablesRules(saved, iptablesChainPrefixes["kuberouter"], ipsetPrefixes["kuberouter"])
+	assert.True(t, removed)
+	assert.Equal(t, `# Generated by iptables-save
+*filter
+:INPUT ACCEPT [0:0]
+:FORWARD ACCEPT [0:0]
+:KUBE-FORWARD - [0:0]
+-A FORWARD -m comment --comment "kubernetes forwarding rules" -j KUBE-FORWARD
+COMMIT
+*nat
+:POSTROUTING ACCEPT [0:0]
+-A POSTROUTING -s 10.244.0.0/16 -j MASQUERADE
+COMMIT
+`, filtered)
+
+	_, removed = filterIptablesRules(filtered, iptablesChainPrefixes["calico"], ipsetPrefixes["calico"])
+	assert.False(t, removed)
+}
@@ -28,6 +28,7 @@ import (
 
 var (
 	CfgFile        string
+	CfgDir         string
 	DataDir        string
 	Debug          bool
 	DebugListenOn  string
@@ -42,6 +43,7 @@ type CLIOptions struct {
 	WorkerOptions
 	ControllerOptions
 	CfgFile          string
+	CfgDir           string
 	ClusterConfig    *v1beta1.ClusterConfig
 	Debug            bool
 	DebugListenOn    string
@@ -53,8 +55,9 @@ type CLIOptions struct {
 
 // Shared controller cli flags
 type ControllerOptions struct {
-	EnableWorker bool
-	SingleNode   bool
+	EnableWorker        bool
+	SingleNode          bool
+	EnableDynamicConfig bool
 
 	EnableK0sCloudProvider          bool
 	K0sCloudProviderUpdateFrequency time.Duration
@@ -63,17 +66,15 @@ type ControllerOptions struct {
 
 // Shared worker cli flags
 type WorkerOptions struct {
-	APIServer        string
-	CIDRRange        string
-	CloudProvider    bool
-	ClusterDNS       string
-	CmdLogLevels     map[string]string
-	CriSocket        string
-	KubeletExtraArgs string
-	Labels           []string
-	TokenFile        string
-	TokenArg         string
-	WorkerProfile    string
+	CloudProvider       bool
+	CmdLogLevels        map[string]string
+	ContainerdExtraArgs string
+	CriSocket           string
+	KubeletExtraArgs    string
+	Labels              []string
+	TokenFile           string
+	TokenArg            string
+	WorkerProfile       string
 }
 
 func DefaultLogLevels() map[string]string {
@@ -92,6 +93,7 @@ func DefaultLogLevels() map[string]string {
 func GetPersistentFlagSet() *pflag.FlagSet {
 	flagset := &pflag.FlagSet{}
 	flagset.StringVarP(&CfgFile, "config", "c", "", "config file, use '-' to read the config from stdin")
+	flagset.StringVar(&CfgDir, "config-dir", "", "directory of config fragments (*.yaml) merged over the config file in lexical order")
 	flagset.BoolVarP(&Debug, "debug", "d", false, "Debug logging (default: false)")
 	flagset.StringVar(&DataDir, "data-dir", "", "Data Directory for k0s (default: /var/lib/k0s). DO NOT CHANGE for an existing setup, things will break!")
 	flagset.StringVar(&DebugListenOn, "debugListenOn", ":6060", "Http listenOn for Debug pprof handler")
@@ -117,16 +119,25 @@ func GetWorkerFlags() *pflag.FlagSet {
 	flagset := &pflag.FlagSet{}
 
 	flagset.StringVar(&workerOpts.WorkerProfile, "profile", "default", "worker profile to use on the node")
-	flagset.StringVar(&workerOpts.APIServer, "api-server", "", "HACK: api-server for the windows worker node")
-	flagset.StringVar(&workerOpts.CIDRRange, "cidr-range", "10.96.0.0/12", "HACK: cidr range for the windows worker node")
-	flagset.StringVar(&workerOpts.ClusterDNS, "cluster-dns", "10.96.0.10", "HACK: cluster dns for the windows worker node")
 	flagset.BoolVar(&workerOpts.CloudProvider, "enable-cloud-provider", false, "Whether or not to enable cloud provider support in kubelet")
 	flagset.StringVar(&workerOpts.TokenFile, "token-file", "", "Path to the file containing token.")
 	flagset.StringToStringVarP(&workerOpts.CmdLogLevels, "logging", "l", DefaultLogLevels(), "Logging Levels for the different components")
 	flagset.StringSliceVarP(&workerOpts.Labels, "labels", "", []string{}, "Node labels, list of key=value pairs")
 	flagset.StringVar(&workerOpts.KubeletExtraArgs, "kubelet-extra-args", "", "extra args for kubelet")
+	flagset.StringVar(&workerOpts.ContainerdExtraArgs, "containerd-extra-args", "", "extra args for containerd")
 	flagset.AddFlagSet(GetCriSocketFlag())
 
+	// the windows worker gets the values from the join now, the flags are kept so that the existing
+	// setups don't fail to start, MarkDeprecated also hides them
+	var apiServer, cidrRange, clusterDNS string
+	flagset.StringVar(&apiServer, "api-server", "", "HACK: api-server for the windows worker node")
+	flagset.StringVar(&cidrRange, "cidr-range", "10.96.0.0/12", "HACK: cidr range for the windows worker node")
+	flagset.StringVar(&clusterDNS, "cluster-dns", "10.96.0.10", "HACK: cluster dns for the windows worker node")
+	for _, name := range []string{"api-server", "cidr-range", "cluster-dns"} {
+		_ = flagset.MarkDeprecated(name, "it has no effect, the worker gets the value when joining the cluster")
+		_ = flagset.MarkHidden(name)
+	}
+
 	return flagset
 }
 
@@ -141,6 +152,7 @@ func GetControllerFlags() *pflag.FlagSet {
 	flagset.BoolVar(&controllerOpts.EnableK0sCloudProvider, "enable-k0s-cloud-provider", false, "enables the k0s-cloud-provider (default false)")
 	flagset.DurationVar(&controllerOpts.K0sCloudProviderUpdateFrequency, "k0s-cloud-provider-update-frequency", 2*time.Minute, "the frequency of k0s-cloud-provider node updates")
 	flagset.IntVar(&controllerOpts.K0sCloudProviderPort, "k0s-cloud-provider-port", cloudprovider.CloudControllerManagerPort, "the port that k0s-cloud-provider binds on")
+	flagset.BoolVar(&controllerOpts.EnableDynamicConfig, "enable-dynamic-config", false, "store the cluster wide config in the cluster and reconcile changes at runtime (default false)")
 	flagset.AddFlagSet(GetCriSocketFlag())
 
 	return flagset
@@ -154,6 +166,7 @@ func GetCmdOpts() CLIOptions {
 		WorkerOptions:     workerOpts,
 
 		CfgFile:          CfgFile,
+		CfgDir:           CfgDir,
 		Debug:            Debug,
 		DefaultLogLevels: DefaultLogLevels(),
 		K0sVars:          K0sVars,
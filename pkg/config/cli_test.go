@@ -0,0 +1,34 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWorkerFlagsRemovedFlags(t *testing.T) {
+	flags := GetWorkerFlags()
+	require.NoError(t, flags.Parse([]string{"--api-server", "https://10.0.0.1:6443", "--cidr-range=10.96.0.0/16", "--cluster-dns=10.96.0.11"}))
+	for _, name := range []string{"api-server", "cidr-range", "cluster-dns"} {
+		flag := flags.Lookup(name)
+		require.NotNil(t, flag, name)
+		assert.True(t, flag.Hidden, name)
+		assert.NotEmpty(t, flag.Deprecated, name)
+	}
+}
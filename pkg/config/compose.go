@@ -0,0 +1,389 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v2"
+
+	"github.com/k0sproject/k0s/pkg/apis/v1beta1"
+	"github.com/k0sproject/k0s/pkg/apis/v1beta2"
+	"github.com/k0sproject/k0s/pkg/jsonschema"
+)
+
+// EnvOverridePrefix is the prefix of the environment variables overriding scalar config fields. The rest
+// of the variable name is the upper cased path of the field joined with underscores, e.g.
+// K0S_CONFIG_SPEC_API_ADDRESS overrides spec.api.address.
+const EnvOverridePrefix = "K0S_CONFIG_"
+
+// OriginDefault is the origin of the config values not set by any of the config sources
+const OriginDefault = "default"
+
+// Source is a config document and the name of where it was read from
+type Source struct {
+	Name string
+	Data []byte
+}
+
+// ComposedConfig is the config document merged from the config file, the drop-in directory fragments
+// and the environment overrides, along with the origin of each value
+type ComposedConfig struct {
+	APIVersion string
+	doc        yaml.MapSlice
+	// origins maps the dotted paths of the scalar values and lists to the name of the source that set them
+	origins map[string]string
+}
+
+// ReadSources reads the config file and the *.yaml and *.yml fragments of the drop-in directory,
+// the fragments in lexical order. A missing drop-in directory is not an error.
+func ReadSources(cfgPath string, cfgDir string) ([]Source, error) {
+	var sources []Source
+	switch cfgPath {
+	case "":
+	case "-":
+		data, err := ioutil.ReadAll(os.Stdin)
+		if err != nil {
+			return nil, fmt.Errorf("can't read configration from stdin: %w", err)
+		}
+		sources = append(sources, Source{Name: "stdin", Data: data})
+	default:
+		data, err := ioutil.ReadFile(cfgPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read config file at %s: %w", cfgPath, err)
+		}
+		sources = append(sources, Source{Name: cfgPath, Data: data})
+	}
+
+	if cfgDir == "" {
+		return sources, nil
+	}
+	entries, err := ioutil.ReadDir(cfgDir)
+	if os.IsNotExist(err) {
+		return sources, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config directory %s: %w", cfgDir, err)
+	}
+	// ReadDir sorts the entries by name
+	for _, e := range entries {
+		ext := filepath.Ext(e.Name())
+		if e.IsDir() || (ext != ".yaml" && ext != ".yml") {
+			continue
+		}
+		path := filepath.Join(cfgDir, e.Name())
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read config fragment at %s: %w", path, err)
+		}
+		sources = append(sources, Source{Name: path, Data: data})
+	}
+	return sources, nil
+}
+
+// Compose merges the sources in order and applies the environment overrides found in environ. Maps are
+// merged key by key, lists and scalars of the later sources replace the earlier ones and a null value
+// removes the key. All the sources must be of the same apiVersion. Returns nil if there's nothing to compose.
+func Compose(sources []Source, environ []string) (*ComposedConfig, error) {
+	c := &ComposedConfig{origins: map[string]string{}}
+	for _, s := range sources {
+		var doc yaml.MapSlice
+		if err := yaml.Unmarshal(s.Data, &doc); err != nil {
+			return nil, fmt.Errorf("can't parse %s: %w", s.Name, err)
+		}
+		for _, item := range doc {
+			if item.Key != "apiVersion" {
+				continue
+			}
+			apiVersion := fmt.Sprint(item.Value)
+			if c.APIVersion != "" && apiVersion != c.APIVersion {
+				return nil, fmt.Errorf("%s: apiVersion %s doesn't match the apiVersion %s of the previous config sources", s.Name, apiVersion, c.APIVersion)
+			}
+			c.APIVersion = apiVersion
+		}
+		c.doc = c.merge(c.doc, doc, "", s.Name)
+	}
+
+	overrides, err := envOverrides(c.APIVersion, environ)
+	if err != nil {
+		return nil, err
+	}
+	for _, o := range overrides {
+		c.doc = c.set(c.doc, o.path, o.value, "env "+o.env)
+	}
+
+	if len(sources) == 0 && len(overrides) == 0 {
+		return nil, nil
+	}
+	return c, nil
+}
+
+// Data returns the composed config document
+func (c *ComposedConfig) Data() ([]byte, error) {
+	return yaml.Marshal(c.doc)
+}
+
+// Origin returns the name of the source that set the value at the dotted path, OriginDefault if none did
+func (c *ComposedConfig) Origin(path string) string {
+	if o, ok := c.origins[path]; ok {
+		return o
+	}
+	return OriginDefault
+}
+
+// Origins returns the names of the sources that set the value at the dotted path or anything below it, sorted
+func (c *ComposedConfig) Origins(path string) []string {
+	set := map[string]bool{}
+	for p, o := range c.origins {
+		if p == path || strings.HasPrefix(p, path+".") {
+			set[o] = true
+		}
+	}
+	origins := make([]string, 0, len(set))
+	for o := range set {
+		origins = append(origins, o)
+	}
+	sort.Strings(origins)
+	return origins
+}
+
+// Annotated returns the composed config document with the origin of each value as a comment
+func (c *ComposedConfig) Annotated() ([]byte, error) {
+	return c.annotate(c.doc)
+}
+
+// AnnotatedEffective returns the effective config, i.e. the composed config with the defaults applied, in the
+// apiVersion of the config sources, with the origin of each value as a comment
+func (c *ComposedConfig) AnnotatedEffective(cfg *v1beta1.ClusterConfig) ([]byte, error) {
+	var out interface{} = cfg
+	if c.APIVersion == v1beta2.ClusterConfigAPIVersion {
+		out = v1beta2.FromV1beta1(cfg)
+	}
+	data, err := yaml.Marshal(out)
+	if err != nil {
+		return nil, err
+	}
+	var doc yaml.MapSlice
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	return c.annotate(doc)
+}
+
+func (c *ComposedConfig) merge(dst yaml.MapSlice, src yaml.MapSlice, prefix string, origin string) yaml.MapSlice {
+	for _, item := range src {
+		key := fmt.Sprint(item.Key)
+		path := joinPath(prefix, key)
+		i := indexOf(dst, key)
+
+		if item.Value == nil {
+			c.clearOrigins(path)
+			if i >= 0 {
+				dst = append(dst[:i], dst[i+1:]...)
+			}
+			continue
+		}
+
+		srcMap, srcIsMap := item.Value.(yaml.MapSlice)
+		if i >= 0 {
+			if dstMap, ok := dst[i].Value.(yaml.MapSlice); ok && srcIsMap {
+				dst[i].Value = c.merge(dstMap, srcMap, path, origin)
+				continue
+			}
+		}
+
+		c.clearOrigins(path)
+		if srcIsMap {
+			item.Value = c.merge(nil, srcMap, path, origin)
+		} else {
+			c.origins[path] = origin
+		}
+		if i >= 0 {
+			dst[i].Value = item.Value
+		} else {
+			dst = append(dst, yaml.MapItem{Key: key, Value: item.Value})
+		}
+	}
+	return dst
+}
+
+// set sets the value at the path, creating the maps on the way
+func (c *ComposedConfig) set(dst yaml.MapSlice, path []string, value interface{}, origin string) yaml.MapSlice {
+	src := yaml.MapSlice{{Key: path[len(path)-1], Value: value}}
+	for i := len(path) - 2; i >= 0; i-- {
+		src = yaml.MapSlice{{Key: path[i], Value: src}}
+	}
+	return c.merge(dst, src, "", origin)
+}
+
+// clearOrigins forgets the origins of the value at the path and everything below it
+func (c *ComposedConfig) clearOrigins(path string) {
+	for p := range c.origins {
+		if p == path || strings.HasPrefix(p, path+".") {
+			delete(c.origins, p)
+		}
+	}
+}
+
+func (c *ComposedConfig) annotate(doc yaml.MapSlice) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := c.writeAnnotated(&buf, doc, "", 0); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (c *ComposedConfig) writeAnnotated(buf *bytes.Buffer, doc yaml.MapSlice, prefix string, depth int) error {
+	indent := strings.Repeat("  ", depth)
+	for _, item := range doc {
+		key := fmt.Sprint(item.Key)
+		path := joinPath(prefix, key)
+		keyData, err := yaml.Marshal(key)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(buf, "%s%s:", indent, strings.TrimSuffix(string(keyData), "\n"))
+
+		if m, ok := item.Value.(yaml.MapSlice); ok && len(m) > 0 {
+			buf.WriteString("\n")
+			if err := c.writeAnnotated(buf, m, path, depth+1); err != nil {
+				return err
+			}
+			continue
+		}
+
+		data, err := yaml.Marshal(item.Value)
+		if err != nil {
+			return err
+		}
+		lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+		comment := " # " + c.Origin(path)
+		if l, isList := item.Value.([]interface{}); isList && len(l) > 0 {
+			// block lists start on the next line
+			buf.WriteString(comment + "\n")
+		} else {
+			// inline values and the header of block scalars stay on the key line
+			buf.WriteString(" " + lines[0] + comment + "\n")
+			lines = lines[1:]
+		}
+		for _, l := range lines {
+			buf.WriteString(indent + "  " + l + "\n")
+		}
+	}
+	return nil
+}
+
+type envOverride struct {
+	env   string
+	path  []string
+	value interface{}
+}
+
+// envOverrides returns the overrides set in the environment, ordered by the variable name. Variables not
+// matching any field are ignored, they might be meant for another k0s version.
+func envOverrides(apiVersion string, environ []string) ([]envOverride, error) {
+	var fields map[string]fieldSchema
+	var overrides []envOverride
+	for _, kv := range environ {
+		if !strings.HasPrefix(kv, EnvOverridePrefix) {
+			continue
+		}
+		parts := strings.SplitN(kv, "=", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		if fields == nil {
+			fields = overridableFields(apiVersion)
+		}
+		f, ok := fields[strings.TrimPrefix(parts[0], EnvOverridePrefix)]
+		if !ok {
+			logrus.Warnf("ignoring %s, it doesn't match any scalar config field", parts[0])
+			continue
+		}
+		value, err := parseScalar(f.schema.Type, parts[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid value for %s: %w", parts[0], err)
+		}
+		overrides = append(overrides, envOverride{env: parts[0], path: f.path, value: value})
+	}
+	sort.Slice(overrides, func(i, j int) bool { return overrides[i].env < overrides[j].env })
+	return overrides, nil
+}
+
+type fieldSchema struct {
+	path   []string
+	schema *jsonschema.Schema
+}
+
+// overridableFields returns the scalar fields of the config by their environment override name
+func overridableFields(apiVersion string) map[string]fieldSchema {
+	var s *jsonschema.Schema
+	if apiVersion == v1beta2.ClusterConfigAPIVersion {
+		s = jsonschema.Reflect(v1beta2.ClusterConfig{}, "yaml")
+	} else {
+		s = v1beta1.ClusterConfigSchema()
+	}
+	fields := map[string]fieldSchema{}
+	collectScalarFields(s, nil, fields)
+	return fields
+}
+
+func collectScalarFields(s *jsonschema.Schema, path []string, fields map[string]fieldSchema) {
+	switch s.Type {
+	case "object":
+		for name, p := range s.Properties {
+			collectScalarFields(p, append(append([]string{}, path...), name), fields)
+		}
+	case "string", "integer", "number", "boolean":
+		fields[strings.ToUpper(strings.Join(path, "_"))] = fieldSchema{path: path, schema: s}
+	}
+}
+
+func parseScalar(typ string, value string) (interface{}, error) {
+	switch typ {
+	case "integer":
+		return strconv.Atoi(value)
+	case "number":
+		return strconv.ParseFloat(value, 64)
+	case "boolean":
+		return strconv.ParseBool(value)
+	}
+	return value, nil
+}
+
+func indexOf(m yaml.MapSlice, key string) int {
+	for i, item := range m {
+		if fmt.Sprint(item.Key) == key {
+			return i
+		}
+	}
+	return -1
+}
+
+func joinPath(prefix string, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + "." + key
+}
@@ -0,0 +1,169 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/k0sproject/k0s/pkg/constant"
+)
+
+const baseConfig = `apiVersion: k0s.k0sproject.io/v1beta1
+kind: Cluster
+spec:
+  api:
+    port: 6443
+  network:
+    podCIDR: 10.244.0.0/16
+  workerProfiles:
+  - name: a
+  images:
+    coredns:
+      version: 1.6.0
+`
+
+func TestCompose(t *testing.T) {
+	c, err := Compose([]Source{
+		{Name: "base", Data: []byte(baseConfig)},
+		{Name: "cluster", Data: []byte("spec:\n  network:\n    provider: kuberouter\n  workerProfiles:\n  - name: b\n")},
+		{Name: "node", Data: []byte("spec:\n  images:\n    coredns: null\n")},
+	}, []string{"K0S_CONFIG_SPEC_API_ADDRESS=10.0.0.1", "K0S_CONFIG_SPEC_API_PORT=7443", "PATH=/bin"})
+	require.NoError(t, err)
+
+	assert.Equal(t, "base", c.Origin("apiVersion"))
+	assert.Equal(t, "base", c.Origin("spec.network.podCIDR"))
+	assert.Equal(t, "cluster", c.Origin("spec.network.provider"))
+	assert.Equal(t, "cluster", c.Origin("spec.workerProfiles"))
+	assert.Equal(t, OriginDefault, c.Origin("spec.images.coredns.version"))
+	assert.Equal(t, "env K0S_CONFIG_SPEC_API_ADDRESS", c.Origin("spec.api.address"))
+	assert.Equal(t, "env K0S_CONFIG_SPEC_API_PORT", c.Origin("spec.api.port"))
+	assert.Equal(t, []string{"base", "cluster"}, c.Origins("spec.network"))
+	assert.Empty(t, c.Origins("spec.storage"))
+
+	cfg, err := ParseClusterConfig(mustData(t, c), constant.CfgVars{})
+	require.NoError(t, err)
+	assert.Equal(t, "10.0.0.1", cfg.Spec.API.Address)
+	assert.Equal(t, 7443, cfg.Spec.API.Port)
+	assert.Equal(t, "kuberouter", cfg.Spec.Network.Provider)
+	assert.Equal(t, "10.244.0.0/16", cfg.Spec.Network.PodCIDR)
+	if assert.Len(t, cfg.Spec.WorkerProfiles, 1) {
+		assert.Equal(t, "b", cfg.Spec.WorkerProfiles[0].Name)
+	}
+	assert.NotEqual(t, "1.6.0", cfg.Spec.Images.CoreDNS.Version)
+}
+
+func TestComposeAnnotated(t *testing.T) {
+	c, err := Compose([]Source{
+		{Name: "base", Data: []byte(baseConfig)},
+	}, []string{"K0S_CONFIG_SPEC_TELEMETRY_ENABLED=false"})
+	require.NoError(t, err)
+
+	data, err := c.Annotated()
+	require.NoError(t, err)
+	assert.Equal(t, `apiVersion: k0s.k0sproject.io/v1beta1 # base
+kind: Cluster # base
+spec:
+  api:
+    port: 6443 # base
+  network:
+    podCIDR: 10.244.0.0/16 # base
+  workerProfiles: # base
+    - name: a
+  images:
+    coredns:
+      version: 1.6.0 # base
+  telemetry:
+    enabled: false # env K0S_CONFIG_SPEC_TELEMETRY_ENABLED
+`, string(data))
+	// the annotations are comments, the annotated config is the same config
+	cfg, err := ParseClusterConfig(data, constant.CfgVars{})
+	require.NoError(t, err)
+	assert.False(t, cfg.Spec.Telemetry.Enabled)
+}
+
+func TestComposeErrors(t *testing.T) {
+	_, err := Compose([]Source{
+		{Name: "base", Data: []byte(baseConfig)},
+		{Name: "fragment", Data: []byte("apiVersion: k0s.k0sproject.io/v1beta2\n")},
+	}, nil)
+	assert.EqualError(t, err, "fragment: apiVersion k0s.k0sproject.io/v1beta2 doesn't match the apiVersion k0s.k0sproject.io/v1beta1 of the previous config sources")
+
+	_, err = Compose(nil, []string{"K0S_CONFIG_SPEC_API_PORT=foo"})
+	assert.Error(t, err)
+}
+
+func TestComposeIgnoresUnknownEnv(t *testing.T) {
+	c, err := Compose([]Source{
+		{Name: "base", Data: []byte(baseConfig)},
+	}, []string{"K0S_CONFIG_SPEC_FOO=bar", "K0S_CONFIG_SPEC_API_PORT=7443"})
+	require.NoError(t, err)
+
+	assert.Equal(t, "env K0S_CONFIG_SPEC_API_PORT", c.Origin("spec.api.port"))
+	data, err := c.Data()
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "foo")
+}
+
+func TestComposeNothing(t *testing.T) {
+	c, err := Compose(nil, []string{"PATH=/bin"})
+	assert.NoError(t, err)
+	assert.Nil(t, c)
+}
+
+func TestEnvOverridesFollowTheAPIVersion(t *testing.T) {
+	c, err := Compose([]Source{
+		{Name: "base", Data: []byte("apiVersion: k0s.k0sproject.io/v1beta2\n")},
+	}, []string{"K0S_CONFIG_SPEC_IMAGES_DEFAULTPULLPOLICY=Never"})
+	require.NoError(t, err)
+	assert.Equal(t, "env K0S_CONFIG_SPEC_IMAGES_DEFAULTPULLPOLICY", c.Origin("spec.images.defaultPullPolicy"))
+}
+
+func TestReadSources(t *testing.T) {
+	dir, err := ioutil.TempDir("", "k0s-config")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	cfgFile := filepath.Join(dir, "k0s.yaml")
+	cfgDir := filepath.Join(dir, "conf.d")
+	require.NoError(t, os.Mkdir(cfgDir, 0755))
+	for _, name := range []string{"k0s.yaml", "conf.d/20-node.yml", "conf.d/10-cluster.yaml", "conf.d/README.md"} {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, name), []byte("{}"), 0644))
+	}
+
+	sources, err := ReadSources(cfgFile, cfgDir)
+	require.NoError(t, err)
+	var names []string
+	for _, s := range sources {
+		names = append(names, s.Name)
+	}
+	assert.Equal(t, []string{cfgFile, filepath.Join(cfgDir, "10-cluster.yaml"), filepath.Join(cfgDir, "20-node.yml")}, names)
+
+	sources, err = ReadSources("", filepath.Join(dir, "missing"))
+	assert.NoError(t, err)
+	assert.Empty(t, sources)
+}
+
+func mustData(t *testing.T, c *ComposedConfig) []byte {
+	data, err := c.Data()
+	require.NoError(t, err)
+	return data
+}
@@ -17,37 +17,61 @@ package config
 
 import (
 	"fmt"
+	"os"
 	"strings"
 
 	"github.com/k0sproject/k0s/pkg/apis/v1beta1"
+	"github.com/k0sproject/k0s/pkg/apis/v1beta2"
 	"github.com/k0sproject/k0s/pkg/constant"
 
 	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v2"
 )
 
-func GetYamlFromFile(cfgPath string, k0sVars constant.CfgVars) (clusterConfig *v1beta1.ClusterConfig, err error) {
-	if cfgPath == "" {
+// GetYamlFromFile loads the config composed of the config file, the fragments of the drop-in directory cfgDir
+// and the environment overrides
+func GetYamlFromFile(cfgPath string, cfgDir string, k0sVars constant.CfgVars) (clusterConfig *v1beta1.ClusterConfig, err error) {
+	if cfgPath == "" && cfgDir == "" {
 		// no config file exists, using defaults
 		logrus.Info("no config file given, using defaults")
 	}
-	cfg, err := ValidateYaml(cfgPath, k0sVars)
+	cfg, err := ValidateYaml(cfgPath, cfgDir, k0sVars)
 	if err != nil {
 		return nil, err
 	}
 	return cfg, nil
 }
 
-func ValidateYaml(cfgPath string, k0sVars constant.CfgVars) (clusterConfig *v1beta1.ClusterConfig, err error) {
-	switch cfgPath {
-	case "-":
-		clusterConfig, err = v1beta1.ConfigFromStdin(k0sVars)
-	case "":
-		clusterConfig = v1beta1.DefaultClusterConfig(k0sVars)
-	default:
-		clusterConfig, err = v1beta1.ConfigFromFile(cfgPath, k0sVars)
+// ValidateYaml loads the config composed of the config file, the fragments of the drop-in directory cfgDir
+// and the environment overrides, and validates it
+func ValidateYaml(cfgPath string, cfgDir string, k0sVars constant.CfgVars) (clusterConfig *v1beta1.ClusterConfig, err error) {
+	_, clusterConfig, err = LoadComposed(cfgPath, cfgDir, k0sVars)
+	return clusterConfig, err
+}
+
+// LoadComposed composes, parses and validates the config, the composed config is nil if there were no config sources
+func LoadComposed(cfgPath string, cfgDir string, k0sVars constant.CfgVars) (*ComposedConfig, *v1beta1.ClusterConfig, error) {
+	sources, err := ReadSources(cfgPath, cfgDir)
+	if err != nil {
+		return nil, nil, err
 	}
+	composed, err := Compose(sources, os.Environ())
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+
+	var clusterConfig *v1beta1.ClusterConfig
+	if composed == nil {
+		clusterConfig = v1beta1.DefaultClusterConfig(k0sVars)
+	} else {
+		data, err := composed.Data()
+		if err != nil {
+			return nil, nil, err
+		}
+		clusterConfig, err = ParseClusterConfig(data, k0sVars)
+		if err != nil {
+			return nil, nil, err
+		}
 	}
 
 	if clusterConfig.Spec.Storage.Type == v1beta1.KineStorageType && clusterConfig.Spec.Storage.Kine == nil {
@@ -59,11 +83,33 @@ func ValidateYaml(cfgPath string, k0sVars constant.CfgVars) (clusterConfig *v1be
 
 	errors := clusterConfig.Validate()
 	if len(errors) > 0 {
-		messages := make([]string, len(errors))
+		messages := make([]string, 0, len(errors))
 		for _, e := range errors {
 			messages = append(messages, e.Error())
 		}
-		return nil, fmt.Errorf(strings.Join(messages, "\n"))
+		return nil, nil, fmt.Errorf("%s", strings.Join(messages, "\n"))
+	}
+	return composed, clusterConfig, nil
+}
+
+// ParseClusterConfig parses the config of any supported version, converting it to the v1beta1 config used by the components
+func ParseClusterConfig(data []byte, k0sVars constant.CfgVars) (*v1beta1.ClusterConfig, error) {
+	var versioned struct {
+		APIVersion string `yaml:"apiVersion"`
+	}
+	if err := yaml.Unmarshal(data, &versioned); err != nil {
+		return nil, err
+	}
+
+	switch versioned.APIVersion {
+	case v1beta1.ClusterConfigAPIVersion, "":
+		return v1beta1.ConfigFromString(string(data), k0sVars)
+	case v1beta2.ClusterConfigAPIVersion:
+		cfg, err := v1beta2.ConfigFromString(string(data), k0sVars)
+		if err != nil {
+			return nil, err
+		}
+		return cfg.ToV1beta1(), nil
 	}
-	return clusterConfig, nil
+	return nil, fmt.Errorf("unsupported apiVersion %s, supported versions are %s and %s", versioned.APIVersion, v1beta1.ClusterConfigAPIVersion, v1beta2.ClusterConfigAPIVersion)
 }
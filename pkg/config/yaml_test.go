@@ -0,0 +1,61 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package config
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/k0sproject/k0s/pkg/apis/v1beta1"
+	"github.com/k0sproject/k0s/pkg/constant"
+)
+
+// the flags configured with the extra args before they got their config fields keep working
+func TestLoadComposedLegacyExtraArgs(t *testing.T) {
+	dir := t.TempDir()
+	cfgPath := filepath.Join(dir, "k0s.yaml")
+	require.NoError(t, ioutil.WriteFile(cfgPath, []byte(`apiVersion: k0s.k0sproject.io/v1beta1
+kind: Cluster
+spec:
+  api:
+    address: 10.0.0.1
+    extraArgs:
+      feature-gates: EphemeralContainers=true,CSIMigration=false
+      oidc-issuer-url: https://dex.example.com
+      oidc-client-id: k0s
+      audit-log-path: /var/log/k0s/audit.log
+  controllerManager:
+    extraArgs:
+      feature-gates: EphemeralContainers=true
+  scheduler:
+    extraArgs:
+      feature-gates: EphemeralContainers=true
+`), 0600))
+
+	_, cfg, err := LoadComposed(cfgPath, "", constant.GetConfig(dir))
+	require.NoError(t, err)
+	assert.Equal(t, v1beta1.FeatureGates{"EphemeralContainers": true, "CSIMigration": false}, cfg.Spec.EffectiveFeatureGates())
+	assert.Equal(t, "https://dex.example.com", cfg.Spec.API.ExtraArgs["oidc-issuer-url"].Value)
+
+	t.Run("spec.featureGates wins", func(t *testing.T) {
+		cfg.Spec.FeatureGates = v1beta1.FeatureGates{"EphemeralContainers": false}
+		assert.Equal(t, v1beta1.FeatureGates{"EphemeralContainers": false, "CSIMigration": false}, cfg.Spec.EffectiveFeatureGates())
+	})
+}
@@ -36,6 +36,10 @@ const (
 
 	// KineDBDirMode is the expected directory permissions for the Kine DB
 	KineDBDirMode = 0750
+	// AuditLogDirMode is the expected directory permissions for the kube-apiserver audit log
+	AuditLogDirMode = 0700
+	// EncryptionKeysDirMode is the expected directory permissions for the encryption at rest keys
+	EncryptionKeysDirMode = 0700
 
 	// User accounts for services
 
@@ -70,6 +74,10 @@ const (
 	KubeRouterCNIImageVersion          = "v1.2.1"
 	KubeRouterCNIInstallerImage        = "quay.io/k0sproject/cni-node"
 	KubeRouterCNIInstallerImageVersion = "0.1.0"
+	CiliumImage                        = "quay.io/cilium/cilium"
+	CiliumOperatorImage                = "quay.io/cilium/operator-generic"
+	CiliumHubbleRelayImage             = "quay.io/cilium/hubble-relay"
+	CiliumImageVersion                 = "v1.10.2"
 )
 
 // CfgVars is a struct that holds all the config variables required for K0s
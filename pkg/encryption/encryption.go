@@ -0,0 +1,347 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+// Package encryption manages the keys kube-apiserver encrypts the resources at rest with
+package encryption
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/k0sproject/k0s/pkg/apis/v1beta1"
+	"github.com/k0sproject/k0s/pkg/constant"
+)
+
+// keySize is the size of the generated keys in bytes, valid for all the supported providers
+const keySize = 32
+
+// KeysPath returns the path of the encryption keys under the cert root dir
+func KeysPath(certRootDir string) string {
+	return filepath.Join(certRootDir, "encryption", "keys.yaml")
+}
+
+// ConfigPath returns the path of the EncryptionConfiguration kube-apiserver is run with
+func ConfigPath(dataDir string) string {
+	return filepath.Join(dataDir, "encryption-config.yaml")
+}
+
+// Key is a single encryption key
+type Key struct {
+	Name     string `yaml:"name"`
+	Provider string `yaml:"provider"`
+	// Secret is the base64 encoded key
+	Secret string `yaml:"secret"`
+	// Pending is set on a rotated key until all the controllers have it, it only decrypts until promoted
+	Pending bool `yaml:"pending,omitempty"`
+}
+
+// Keys holds the encryption keys, the first one encrypts the resources and all of them decrypt. The
+// resources are all the resources ever encrypted with the keys, they're kept readable even when they
+// are no longer encrypted. The generation is increased on every change of the keys made by a controller
+// generating them or by a rotation, the other controllers take the keys of a newer generation.
+type Keys struct {
+	Generation int      `yaml:"generation,omitempty"`
+	Resources  []string `yaml:"resources"`
+	Keys       []Key    `yaml:"keys"`
+}
+
+// LoadKeys loads the keys from the file, no keys are returned if the file doesn't exist
+func LoadKeys(path string) (*Keys, error) {
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return &Keys{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the encryption keys: %w", err)
+	}
+	return ParseKeys(data)
+}
+
+// ParseKeys parses the keys file contents
+func ParseKeys(data []byte) (*Keys, error) {
+	keys := &Keys{}
+	if err := yaml.Unmarshal(data, keys); err != nil {
+		return nil, fmt.Errorf("failed to parse the encryption keys: %w", err)
+	}
+	return keys, nil
+}
+
+// Save writes the keys to the file. The file is replaced atomically so that the controllers watching
+// it never see a partially written file.
+func (k *Keys) Save(path string) error {
+	data, err := yaml.Marshal(k)
+	if err != nil {
+		return err
+	}
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, constant.EncryptionKeysDirMode); err != nil {
+		return fmt.Errorf("failed to create the encryption keys dir: %w", err)
+	}
+	tmp, err := ioutil.TempFile(dir, ".keys-*.yaml")
+	if err != nil {
+		return fmt.Errorf("failed to write the encryption keys: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write the encryption keys: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write the encryption keys: %w", err)
+	}
+	if err := os.Chmod(tmp.Name(), constant.CertSecureMode); err != nil {
+		return fmt.Errorf("failed to write the encryption keys: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to write the encryption keys: %w", err)
+	}
+	return nil
+}
+
+// Empty tells if there are no keys, i.e. nothing has ever been encrypted
+func (k *Keys) Empty() bool {
+	return len(k.Keys) == 0
+}
+
+// ErrKeyMissing is returned by Reconcile when a key is needed but generating it isn't allowed
+var ErrKeyMissing = errors.New("no encryption key for the configured provider")
+
+// Reconcile adds the resources of the config and, when generateKey is set, generates a new key if there
+// are no keys yet or the provider has changed. The keys must be generated by a single controller and
+// shared with the others, the rest of the controllers get ErrKeyMissing instead. It tells if the keys
+// have been changed and need to be saved.
+func (k *Keys) Reconcile(spec *v1beta1.EncryptionSpec, generateKey bool) (bool, error) {
+	if spec == nil {
+		return false, nil
+	}
+	changed := false
+	if k.Empty() || k.Keys[0].Provider != spec.Provider {
+		if !generateKey {
+			return false, ErrKeyMissing
+		}
+		key, err := newKey(k.lastKeyIndex()+1, spec.Provider)
+		if err != nil {
+			return false, err
+		}
+		k.Keys = append([]Key{key}, k.Keys...)
+		changed = true
+	}
+	for _, r := range spec.Resources {
+		if !contains(k.Resources, r) {
+			k.Resources = append(k.Resources, r)
+			changed = true
+		}
+	}
+	sort.Strings(k.Resources)
+	// only the changes of the controller generating the keys are shared with the others
+	if changed && generateKey {
+		k.Generation++
+	}
+	return changed, nil
+}
+
+// Rotate generates a new key. The key only decrypts until it's promoted, so that the resources encrypted
+// with it stay readable on the controllers that don't have it yet. The first key is used right away.
+func (k *Keys) Rotate(provider string) (Key, error) {
+	if pending := k.Pending(); pending != nil {
+		return Key{}, fmt.Errorf("the encryption key %s hasn't been promoted yet", pending.Name)
+	}
+	key, err := newKey(k.lastKeyIndex()+1, provider)
+	if err != nil {
+		return Key{}, err
+	}
+	if k.Empty() {
+		k.Keys = []Key{key}
+	} else {
+		key.Pending = true
+		k.Keys = append([]Key{k.Keys[0], key}, k.Keys[1:]...)
+	}
+	k.Generation++
+	return key, nil
+}
+
+// Pending returns the rotated key that hasn't been promoted yet, nil if there's none
+func (k *Keys) Pending() *Key {
+	for i := range k.Keys {
+		if k.Keys[i].Pending {
+			return &k.Keys[i]
+		}
+	}
+	return nil
+}
+
+// Promote makes the pending key the one the resources are encrypted with
+func (k *Keys) Promote() (Key, error) {
+	for i, key := range k.Keys {
+		if !key.Pending {
+			continue
+		}
+		key.Pending = false
+		rest := append(append([]Key{}, k.Keys[:i]...), k.Keys[i+1:]...)
+		k.Keys = append([]Key{key}, rest...)
+		k.Generation++
+		return key, nil
+	}
+	return Key{}, errors.New("no pending encryption key to promote")
+}
+
+// Names returns the names of the keys in the order kube-apiserver tries them
+func (k *Keys) Names() []string {
+	names := make([]string, len(k.Keys))
+	for i, key := range k.Keys {
+		names[i] = key.Name
+	}
+	return names
+}
+
+func newKey(index int, provider string) (Key, error) {
+	secret := make([]byte, keySize)
+	if _, err := rand.Read(secret); err != nil {
+		return Key{}, fmt.Errorf("failed to generate the encryption key: %w", err)
+	}
+	return Key{
+		Name:     fmt.Sprintf("key%d", index),
+		Provider: provider,
+		Secret:   base64.StdEncoding.EncodeToString(secret),
+	}, nil
+}
+
+func (k *Keys) lastKeyIndex() int {
+	last := 0
+	for _, key := range k.Keys {
+		if i, err := strconv.Atoi(strings.TrimPrefix(key.Name, "key")); err == nil && i > last {
+			last = i
+		}
+	}
+	return last
+}
+
+type keyConfig struct {
+	Name   string `yaml:"name"`
+	Secret string `yaml:"secret"`
+}
+
+type keysConfig struct {
+	Keys []keyConfig `yaml:"keys"`
+}
+
+type providerConfig struct {
+	AESCBC    *keysConfig `yaml:"aescbc,omitempty"`
+	AESGCM    *keysConfig `yaml:"aesgcm,omitempty"`
+	Secretbox *keysConfig `yaml:"secretbox,omitempty"`
+	Identity  *struct{}   `yaml:"identity,omitempty"`
+}
+
+type resourceConfig struct {
+	Resources []string         `yaml:"resources"`
+	Providers []providerConfig `yaml:"providers"`
+}
+
+type encryptionConfiguration struct {
+	APIVersion string           `yaml:"apiVersion"`
+	Kind       string           `yaml:"kind"`
+	Resources  []resourceConfig `yaml:"resources"`
+}
+
+// Config renders the EncryptionConfiguration of the keys. The resources of the config are encrypted
+// with the first key, the rest of the resources are stored in plaintext. Every key and the plaintext
+// are accepted when reading, so that the resources stored before a rotation stay readable.
+func (k *Keys) Config(spec *v1beta1.EncryptionSpec) ([]byte, error) {
+	var keyProviders []providerConfig
+	for _, key := range k.Keys {
+		keys := &keysConfig{Keys: []keyConfig{{Name: key.Name, Secret: key.Secret}}}
+		switch key.Provider {
+		case v1beta1.EncryptionProviderAESCBC:
+			keyProviders = append(keyProviders, providerConfig{AESCBC: keys})
+		case v1beta1.EncryptionProviderAESGCM:
+			keyProviders = append(keyProviders, providerConfig{AESGCM: keys})
+		case v1beta1.EncryptionProviderSecretbox:
+			keyProviders = append(keyProviders, providerConfig{Secretbox: keys})
+		default:
+			return nil, fmt.Errorf("unsupported provider %q for the encryption key %s", key.Provider, key.Name)
+		}
+	}
+	identity := providerConfig{Identity: &struct{}{}}
+
+	var encrypted, plaintext []string
+	for _, r := range k.Resources {
+		if spec != nil && contains(spec.Resources, r) {
+			encrypted = append(encrypted, r)
+		} else {
+			plaintext = append(plaintext, r)
+		}
+	}
+
+	cfg := encryptionConfiguration{
+		APIVersion: "apiserver.config.k8s.io/v1",
+		Kind:       "EncryptionConfiguration",
+	}
+	if len(encrypted) > 0 {
+		cfg.Resources = append(cfg.Resources, resourceConfig{
+			Resources: encrypted,
+			Providers: append(keyProviders, identity),
+		})
+	}
+	if len(plaintext) > 0 {
+		cfg.Resources = append(cfg.Resources, resourceConfig{
+			Resources: plaintext,
+			Providers: append([]providerConfig{identity}, keyProviders...),
+		})
+	}
+	return yaml.Marshal(cfg)
+}
+
+// ConfigKeyNames returns the names of the keys of an EncryptionConfiguration in the order kube-apiserver
+// tries them, the first one encrypting the resources
+func ConfigKeyNames(data []byte) ([]string, error) {
+	var cfg encryptionConfiguration
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse the encryption config: %w", err)
+	}
+	var names []string
+	if len(cfg.Resources) == 0 {
+		return names, nil
+	}
+	for _, p := range cfg.Resources[0].Providers {
+		for _, keys := range []*keysConfig{p.AESCBC, p.AESGCM, p.Secretbox} {
+			if keys == nil {
+				continue
+			}
+			for _, key := range keys.Keys {
+				names = append(names, key.Name)
+			}
+		}
+	}
+	return names, nil
+}
+
+func contains(items []string, item string) bool {
+	for _, i := range items {
+		if i == item {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,277 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package encryption
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	fakedynamic "k8s.io/client-go/dynamic/fake"
+	kubetesting "k8s.io/client-go/testing"
+
+	"github.com/k0sproject/k0s/pkg/apis/v1beta1"
+)
+
+func TestKeysReconcile(t *testing.T) {
+	keys := &Keys{}
+	changed, err := keys.Reconcile(nil, true)
+	require.NoError(t, err)
+	assert.False(t, changed)
+	assert.True(t, keys.Empty())
+
+	spec := v1beta1.DefaultEncryptionSpec()
+	changed, err = keys.Reconcile(spec, true)
+	require.NoError(t, err)
+	assert.True(t, changed)
+	assert.Equal(t, []string{"secrets"}, keys.Resources)
+	require.Len(t, keys.Keys, 1)
+	assert.Equal(t, "key1", keys.Keys[0].Name)
+	assert.Equal(t, v1beta1.EncryptionProviderAESCBC, keys.Keys[0].Provider)
+
+	changed, err = keys.Reconcile(spec, true)
+	require.NoError(t, err)
+	assert.False(t, changed)
+
+	spec.Provider = v1beta1.EncryptionProviderSecretbox
+	spec.Resources = []string{"configmaps", "secrets"}
+	changed, err = keys.Reconcile(spec, true)
+	require.NoError(t, err)
+	assert.True(t, changed)
+	assert.Equal(t, []string{"configmaps", "secrets"}, keys.Resources)
+	require.Len(t, keys.Keys, 2)
+	assert.Equal(t, "key2", keys.Keys[0].Name)
+	assert.Equal(t, v1beta1.EncryptionProviderSecretbox, keys.Keys[0].Provider)
+	assert.Equal(t, "key1", keys.Keys[1].Name)
+}
+
+func TestKeysReconcileWithoutGenerating(t *testing.T) {
+	spec := v1beta1.DefaultEncryptionSpec()
+	keys := &Keys{}
+	_, err := keys.Reconcile(spec, false)
+	assert.Equal(t, ErrKeyMissing, err)
+	assert.True(t, keys.Empty())
+
+	_, err = keys.Rotate(spec.Provider)
+	require.NoError(t, err)
+	changed, err := keys.Reconcile(spec, false)
+	require.NoError(t, err)
+	assert.True(t, changed, "the resources are reconciled with the shared keys")
+	assert.Equal(t, []string{"secrets"}, keys.Resources)
+
+	spec.Provider = v1beta1.EncryptionProviderSecretbox
+	_, err = keys.Reconcile(spec, false)
+	assert.Equal(t, ErrKeyMissing, err)
+	assert.Len(t, keys.Keys, 1)
+}
+
+func TestKeysRotateAndPromote(t *testing.T) {
+	keys := &Keys{}
+	first, err := keys.Rotate(v1beta1.EncryptionProviderAESCBC)
+	require.NoError(t, err)
+	assert.False(t, first.Pending, "the first key is used right away")
+	assert.Nil(t, keys.Pending())
+	assert.Equal(t, 1, keys.Generation)
+
+	key, err := keys.Rotate(v1beta1.EncryptionProviderSecretbox)
+	require.NoError(t, err)
+	assert.Equal(t, "key2", key.Name)
+	assert.Equal(t, []string{"key1", "key2"}, keys.Names(), "the pending key only decrypts")
+	require.NotNil(t, keys.Pending())
+	assert.Equal(t, "key2", keys.Pending().Name)
+	assert.Equal(t, 2, keys.Generation)
+
+	_, err = keys.Rotate(v1beta1.EncryptionProviderSecretbox)
+	assert.Error(t, err, "a pending key must be promoted before the next rotation")
+
+	promoted, err := keys.Promote()
+	require.NoError(t, err)
+	assert.Equal(t, "key2", promoted.Name)
+	assert.False(t, promoted.Pending)
+	assert.Equal(t, []string{"key2", "key1"}, keys.Names())
+	assert.Nil(t, keys.Pending())
+	assert.Equal(t, 3, keys.Generation)
+
+	_, err = keys.Promote()
+	assert.Error(t, err)
+
+	key, err = keys.Rotate(v1beta1.EncryptionProviderSecretbox)
+	require.NoError(t, err)
+	assert.Equal(t, "key3", key.Name)
+	assert.Equal(t, []string{"key2", "key3", "key1"}, keys.Names())
+}
+
+func TestKeysReconcileGeneration(t *testing.T) {
+	spec := v1beta1.DefaultEncryptionSpec()
+	keys := &Keys{}
+	_, err := keys.Reconcile(spec, true)
+	require.NoError(t, err)
+	assert.Equal(t, 1, keys.Generation)
+
+	_, err = keys.Reconcile(spec, true)
+	require.NoError(t, err)
+	assert.Equal(t, 1, keys.Generation, "unchanged keys keep their generation")
+
+	spec.Resources = []string{"configmaps", "secrets"}
+	_, err = keys.Reconcile(spec, false)
+	require.NoError(t, err)
+	assert.Equal(t, 1, keys.Generation, "the local changes of a joined controller aren't shared")
+}
+
+func TestKeysSaveAndLoad(t *testing.T) {
+	path := KeysPath(t.TempDir())
+
+	keys, err := LoadKeys(path)
+	require.NoError(t, err)
+	assert.True(t, keys.Empty())
+
+	_, err = keys.Reconcile(v1beta1.DefaultEncryptionSpec(), true)
+	require.NoError(t, err)
+	require.NoError(t, keys.Save(path))
+
+	loaded, err := LoadKeys(path)
+	require.NoError(t, err)
+	assert.Equal(t, keys, loaded)
+
+	matches, err := filepath.Glob(filepath.Join(filepath.Dir(path), "*"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{path}, matches)
+}
+
+func TestKeysConfig(t *testing.T) {
+	keys := &Keys{
+		Resources: []string{"configmaps", "secrets"},
+		Keys: []Key{
+			{Name: "key2", Provider: v1beta1.EncryptionProviderSecretbox, Secret: "c2Vjb25k"},
+			{Name: "key1", Provider: v1beta1.EncryptionProviderAESCBC, Secret: "Zmlyc3Q="},
+		},
+	}
+
+	t.Run("encrypted", func(t *testing.T) {
+		data, err := keys.Config(&v1beta1.EncryptionSpec{Provider: v1beta1.EncryptionProviderSecretbox, Resources: []string{"secrets"}})
+		require.NoError(t, err)
+		assert.Equal(t, `apiVersion: apiserver.config.k8s.io/v1
+kind: EncryptionConfiguration
+resources:
+- resources:
+  - secrets
+  providers:
+  - secretbox:
+      keys:
+      - name: key2
+        secret: c2Vjb25k
+  - aescbc:
+      keys:
+      - name: key1
+        secret: Zmlyc3Q=
+  - identity: {}
+- resources:
+  - configmaps
+  providers:
+  - identity: {}
+  - secretbox:
+      keys:
+      - name: key2
+        secret: c2Vjb25k
+  - aescbc:
+      keys:
+      - name: key1
+        secret: Zmlyc3Q=
+`, string(data))
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		data, err := keys.Config(nil)
+		require.NoError(t, err)
+		var cfg encryptionConfiguration
+		require.NoError(t, yaml.Unmarshal(data, &cfg))
+		require.Len(t, cfg.Resources, 1)
+		assert.Equal(t, []string{"configmaps", "secrets"}, cfg.Resources[0].Resources)
+		assert.NotNil(t, cfg.Resources[0].Providers[0].Identity)
+	})
+
+	t.Run("unsupported provider", func(t *testing.T) {
+		_, err := (&Keys{Keys: []Key{{Name: "key1", Provider: "kms"}}}).Config(nil)
+		assert.Error(t, err)
+	})
+}
+
+func TestConfigKeyNames(t *testing.T) {
+	keys := &Keys{
+		Resources: []string{"configmaps", "secrets"},
+		Keys: []Key{
+			{Name: "key2", Provider: v1beta1.EncryptionProviderSecretbox, Secret: "c2Vjb25k"},
+			{Name: "key3", Provider: v1beta1.EncryptionProviderAESGCM, Secret: "dGhpcmQ=", Pending: true},
+			{Name: "key1", Provider: v1beta1.EncryptionProviderAESCBC, Secret: "Zmlyc3Q="},
+		},
+	}
+
+	data, err := keys.Config(v1beta1.DefaultEncryptionSpec())
+	require.NoError(t, err)
+	names, err := ConfigKeyNames(data)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"key2", "key3", "key1"}, names)
+
+	data, err = keys.Config(nil)
+	require.NoError(t, err)
+	names, err = ConfigKeyNames(data)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"key2", "key3", "key1"}, names, "the identity provider isn't a key")
+
+	_, err = ConfigKeyNames([]byte("resources: {"))
+	assert.Error(t, err)
+}
+
+func TestRewrite(t *testing.T) {
+	disco := &fakediscovery.FakeDiscovery{Fake: &kubetesting.Fake{}}
+	disco.Resources = []*metav1.APIResourceList{{
+		GroupVersion: "v1",
+		APIResources: []metav1.APIResource{
+			{Name: "secrets", Namespaced: true, Kind: "Secret"},
+			{Name: "configmaps", Namespaced: true, Kind: "ConfigMap"},
+		},
+	}}
+
+	scheme := runtime.NewScheme()
+	require.NoError(t, corev1.AddToScheme(scheme))
+	client := fakedynamic.NewSimpleDynamicClient(scheme,
+		&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "a"}},
+		&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: "kube-system", Name: "b"}},
+		&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "c"}},
+	)
+
+	count, err := Rewrite(context.Background(), disco, client, []string{"secrets"})
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+
+	var updated []string
+	for _, action := range client.Actions() {
+		if update, ok := action.(kubetesting.UpdateAction); ok {
+			updated = append(updated, update.GetResource().Resource+"/"+update.GetNamespace())
+		}
+	}
+	assert.ElementsMatch(t, []string{"secrets/default", "secrets/kube-system"}, updated)
+
+	_, err = Rewrite(context.Background(), disco, client, []string{"widgets.example.com"})
+	assert.EqualError(t, err, `resource "widgets.example.com" is not served by the API`)
+}
@@ -0,0 +1,104 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package encryption
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/dynamic"
+)
+
+// rewritePageSize is the number of objects listed at once when rewriting
+const rewritePageSize = 500
+
+// Rewrite updates all the objects of the resources as is, so that kube-apiserver stores them again
+// encrypted with the current key. The resources are given in the EncryptionConfiguration format, i.e.
+// "secrets" or "deployments.apps". It returns the number of objects rewritten.
+func Rewrite(ctx context.Context, disco discovery.DiscoveryInterface, client dynamic.Interface, resources []string) (int, error) {
+	gvrs, err := resolveResources(disco, resources)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, gvr := range gvrs {
+		opts := metav1.ListOptions{Limit: rewritePageSize}
+		for {
+			list, err := client.Resource(gvr).List(ctx, opts)
+			if err != nil {
+				return count, fmt.Errorf("failed to list %s: %w", gvr.Resource, err)
+			}
+			for i := range list.Items {
+				item := &list.Items[i]
+				_, err := client.Resource(gvr).Namespace(item.GetNamespace()).Update(ctx, item, metav1.UpdateOptions{})
+				// the object has been changed or deleted meanwhile, it has been written with the current key anyway
+				if apierrors.IsConflict(err) || apierrors.IsNotFound(err) {
+					continue
+				}
+				if err != nil {
+					return count, fmt.Errorf("failed to rewrite %s %s/%s: %w", gvr.Resource, item.GetNamespace(), item.GetName(), err)
+				}
+				count++
+			}
+			if list.GetContinue() == "" {
+				break
+			}
+			opts.Continue = list.GetContinue()
+		}
+	}
+	return count, nil
+}
+
+// resolveResources maps the resource names to the preferred versions the API serves them with
+func resolveResources(disco discovery.DiscoveryInterface, resources []string) ([]schema.GroupVersionResource, error) {
+	lists, err := discovery.ServerPreferredResources(disco)
+	if err != nil && len(lists) == 0 {
+		return nil, fmt.Errorf("failed to discover the API resources: %w", err)
+	}
+	served := map[string]schema.GroupVersionResource{}
+	for _, list := range lists {
+		gv, err := schema.ParseGroupVersion(list.GroupVersion)
+		if err != nil {
+			continue
+		}
+		for _, r := range list.APIResources {
+			if strings.Contains(r.Name, "/") {
+				continue
+			}
+			name := r.Name
+			if gv.Group != "" {
+				name += "." + gv.Group
+			}
+			served[name] = gv.WithResource(r.Name)
+		}
+	}
+
+	var gvrs []schema.GroupVersionResource
+	for _, r := range resources {
+		gvr, ok := served[r]
+		if !ok {
+			return nil, fmt.Errorf("resource %q is not served by the API", r)
+		}
+		gvrs = append(gvrs, gvr)
+	}
+	return gvrs, nil
+}
@@ -0,0 +1,75 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package encryption
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+)
+
+// StatusConfigMap is the ConfigMap in kube-system the controllers report the names of the keys their
+// kube-apiserver runs with to, keyed by the controller name. It holds no key material.
+const StatusConfigMap = "k0s-encryption-keys-status"
+
+// ReportKeyNames records the names of the keys the kube-apiserver of the controller runs with
+func ReportKeyNames(ctx context.Context, client kubernetes.Interface, controller string, names []string) error {
+	configMaps := client.CoreV1().ConfigMaps(metav1.NamespaceSystem)
+	value := strings.Join(names, ",")
+	patch, err := json.Marshal(map[string]interface{}{"data": map[string]string{controller: value}})
+	if err != nil {
+		return err
+	}
+	_, err = configMaps.Patch(ctx, StatusConfigMap, types.MergePatchType, patch, metav1.PatchOptions{})
+	if !apierrors.IsNotFound(err) {
+		return err
+	}
+	_, err = configMaps.Create(ctx, &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: StatusConfigMap, Namespace: metav1.NamespaceSystem},
+		Data:       map[string]string{controller: value},
+	}, metav1.CreateOptions{})
+	if apierrors.IsAlreadyExists(err) {
+		// created by another controller in the meantime
+		_, err = configMaps.Patch(ctx, StatusConfigMap, types.MergePatchType, patch, metav1.PatchOptions{})
+	}
+	return err
+}
+
+// ReportedKeyNames returns the names of the keys the controllers reported, keyed by the controller name
+func ReportedKeyNames(ctx context.Context, client kubernetes.Interface) (map[string][]string, error) {
+	cm, err := client.CoreV1().ConfigMaps(metav1.NamespaceSystem).Get(ctx, StatusConfigMap, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return map[string][]string{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	reported := make(map[string][]string, len(cm.Data))
+	for controller, value := range cm.Data {
+		if value == "" {
+			reported[controller] = nil
+			continue
+		}
+		reported[controller] = strings.Split(value, ",")
+	}
+	return reported, nil
+}
@@ -0,0 +1,47 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package encryption
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestReportKeyNames(t *testing.T) {
+	ctx := context.Background()
+	client := fake.NewSimpleClientset()
+
+	reported, err := ReportedKeyNames(ctx, client)
+	require.NoError(t, err)
+	assert.Empty(t, reported)
+
+	require.NoError(t, ReportKeyNames(ctx, client, "controller-0", []string{"key1"}))
+	require.NoError(t, ReportKeyNames(ctx, client, "controller-1", []string{"key1", "key2"}))
+	require.NoError(t, ReportKeyNames(ctx, client, "controller-2", nil))
+	require.NoError(t, ReportKeyNames(ctx, client, "controller-0", []string{"key2", "key1"}))
+
+	reported, err = ReportedKeyNames(ctx, client)
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]string{
+		"controller-0": {"key2", "key1"},
+		"controller-1": {"key1", "key2"},
+		"controller-2": nil,
+	}, reported)
+}
@@ -23,6 +23,8 @@ import (
 	"go.etcd.io/etcd/clientv3"
 	"go.etcd.io/etcd/etcdserver/api/v3rpc/rpctypes"
 	"go.etcd.io/etcd/pkg/transport"
+
+	"github.com/k0sproject/k0s/pkg/apis/v1beta1"
 )
 
 // Client is our internal helper to access some of the etcd APIs
@@ -32,22 +34,34 @@ type Client struct {
 	tlsInfo transport.TLSInfo
 }
 
-// NewClient creates new Client
-func NewClient(certDir string, etcdCertDir string) (*Client, error) {
+// NewClient creates new Client, connected to the external cluster if the config has one or else to the
+// etcd run by the controller
+func NewClient(certDir string, etcdCertDir string, etcdConf *v1beta1.EtcdConfig) (*Client, error) {
 	client := &Client{}
+	endpoints := []string{etcdConf.ClientURL()}
 	client.tlsInfo = transport.TLSInfo{
 		CertFile:      filepath.Join(certDir, "apiserver-etcd-client.crt"),
 		KeyFile:       filepath.Join(certDir, "apiserver-etcd-client.key"),
 		TrustedCAFile: filepath.Join(etcdCertDir, "ca.crt"),
 	}
+	if etcdConf.IsExternalClusterUsed() {
+		external := etcdConf.ExternalCluster
+		endpoints = external.Endpoints
+		client.tlsInfo = transport.TLSInfo{
+			CertFile:      external.ClientCertFile,
+			KeyFile:       external.ClientKeyFile,
+			TrustedCAFile: external.CaFile,
+		}
+	}
 
+	// without a CA nor a client certificate, the system CAs are trusted, plain http endpoints don't use TLS
 	tlsConfig, err := client.tlsInfo.ClientConfig()
 	if err != nil {
 		return nil, err
 	}
 
 	cfg := clientv3.Config{
-		Endpoints: []string{"https://127.0.0.1:2379"},
+		Endpoints: endpoints,
 		TLS:       tlsConfig,
 	}
 	cli, _ := clientv3.New(cfg)
@@ -71,10 +85,40 @@ func (c *Client) ListMembers(ctx context.Context) (map[string]string, error) {
 	return memberList, nil
 }
 
-// AddMember add new member to etcd cluster
-func (c *Client) AddMember(ctx context.Context, name, peerAddress string) ([]string, error) {
+// Member is an etcd cluster member
+type Member struct {
+	ID         uint64
+	Name       string
+	PeerURL    string
+	ClientURLs []string
+	// IsLearner tells if the member is a learner, which doesn't vote until promoted
+	IsLearner bool
+}
 
-	addResp, err := c.client.MemberAdd(ctx, []string{peerAddress})
+// Members gets the current etcd members
+func (c *Client) Members(ctx context.Context) ([]Member, error) {
+	resp, err := c.client.MemberList(ctx)
+	if err != nil {
+		return nil, err
+	}
+	members := make([]Member, 0, len(resp.Members))
+	for _, m := range resp.Members {
+		member := Member{ID: m.ID, Name: m.Name, ClientURLs: m.ClientURLs, IsLearner: m.IsLearner}
+		if len(m.PeerURLs) > 0 {
+			member.PeerURL = m.PeerURLs[0]
+		}
+		members = append(members, member)
+	}
+	return members, nil
+}
+
+// AddMember add new member to etcd cluster, a learner doesn't vote until it's promoted
+func (c *Client) AddMember(ctx context.Context, name, peerAddress string, learner bool) ([]string, error) {
+	add := c.client.MemberAdd
+	if learner {
+		add = c.client.MemberAddAsLearner
+	}
+	addResp, err := add(ctx, []string{peerAddress})
 	if err != nil {
 		// TODO we should try to detect possible double add for a peer
 		// Not sure though if we can return correct initial-cluster as the order
@@ -112,12 +156,24 @@ func (c *Client) GetPeerIDByAddress(ctx context.Context, peerAddress string) (ui
 	return 0, fmt.Errorf("peer not found: %s", peerAddress)
 }
 
+// PromoteMember promotes the learner to a voting member, it fails with rpctypes.ErrMemberLearnerNotReady
+// until the learner has caught up with the leader
+func (c *Client) PromoteMember(ctx context.Context, peerID uint64) error {
+	_, err := c.client.MemberPromote(ctx, peerID)
+	return err
+}
+
 // DeleteMember deletes member by peer name
 func (c *Client) DeleteMember(ctx context.Context, peerID uint64) error {
 	_, err := c.client.MemberRemove(ctx, peerID)
 	return err
 }
 
+// KV returns the key-value API of the cluster
+func (c *Client) KV() clientv3.KV {
+	return c.client
+}
+
 // Close closes the etcd client
 func (c *Client) Close() {
 	c.client.Close()
@@ -2,13 +2,20 @@ package etcd
 
 import (
 	"context"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strings"
 
 	"github.com/sirupsen/logrus"
+	"go.etcd.io/etcd/pkg/transport"
+
+	"github.com/k0sproject/k0s/pkg/apis/v1beta1"
 )
 
 // CheckEtcdReady returns true if etcd responds to the metrics endpoint with a status code of 200
-func CheckEtcdReady(ctx context.Context, certDir string, etcdCertDir string) error {
-	c, err := NewClient(certDir, etcdCertDir)
+func CheckEtcdReady(ctx context.Context, certDir string, etcdCertDir string, etcdConf *v1beta1.EtcdConfig) error {
+	c, err := NewClient(certDir, etcdCertDir, etcdConf)
 	if err != nil {
 		logrus.Errorf("failed to initialize etcd client: %v", err)
 		return err
@@ -16,3 +23,32 @@ func CheckEtcdReady(ctx context.Context, certDir string, etcdCertDir string) err
 
 	return c.Health(ctx)
 }
+
+// CheckPeerReachable returns an error if the etcd member doesn't answer on its peer URL
+func CheckPeerReachable(ctx context.Context, etcdCertDir string, peerURL string) error {
+	tlsInfo := transport.TLSInfo{
+		CertFile:      filepath.Join(etcdCertDir, "peer.crt"),
+		KeyFile:       filepath.Join(etcdCertDir, "peer.key"),
+		TrustedCAFile: filepath.Join(etcdCertDir, "ca.crt"),
+	}
+	tlsConfig, err := tlsInfo.ClientConfig()
+	if err != nil {
+		return err
+	}
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: tlsConfig}}
+	defer client.CloseIdleConnections()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(peerURL, "/")+"/version", nil)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %s from %s", resp.Status, peerURL)
+	}
+	return nil
+}
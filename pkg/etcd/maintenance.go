@@ -0,0 +1,106 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package etcd
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.etcd.io/etcd/clientv3"
+	"go.etcd.io/etcd/clientv3/concurrency"
+	"go.etcd.io/etcd/etcdserver/etcdserverpb"
+)
+
+// Status is the status of the member the client is connected to
+type Status struct {
+	MemberID uint64
+	// DBSize is the size of the database file, DBSizeInUse the part of it not freed by compaction
+	DBSize      int64
+	DBSizeInUse int64
+	Leader      uint64
+	RaftTerm    uint64
+	RaftIndex   uint64
+	IsLearner   bool
+}
+
+// Status gets the status of the member the client is connected to
+func (c *Client) Status(ctx context.Context) (*Status, error) {
+	resp, err := c.client.Status(ctx, c.Config.Endpoints[0])
+	if err != nil {
+		return nil, err
+	}
+	return newStatus(resp), nil
+}
+
+func newStatus(resp *clientv3.StatusResponse) *Status {
+	return &Status{
+		MemberID:    resp.Header.MemberId,
+		DBSize:      resp.DbSize,
+		DBSizeInUse: resp.DbSizeInUse,
+		Leader:      resp.Leader,
+		RaftTerm:    resp.RaftTerm,
+		RaftIndex:   resp.RaftIndex,
+		IsLearner:   resp.IsLearner,
+	}
+}
+
+// NoSpaceAlarms returns the IDs of the members that raised the NOSPACE alarm
+func (c *Client) NoSpaceAlarms(ctx context.Context) ([]uint64, error) {
+	resp, err := c.client.AlarmList(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var members []uint64
+	for _, a := range resp.Alarms {
+		if a.Alarm == etcdserverpb.AlarmType_NOSPACE {
+			members = append(members, a.MemberID)
+		}
+	}
+	return members, nil
+}
+
+// DisarmNoSpaceAlarm clears the NOSPACE alarm of the member
+func (c *Client) DisarmNoSpaceAlarm(ctx context.Context, memberID uint64) error {
+	_, err := c.client.AlarmDisarm(ctx, &clientv3.AlarmMember{MemberID: memberID, Alarm: etcdserverpb.AlarmType_NOSPACE})
+	return err
+}
+
+// Defragment defragments the database of the member the client is connected to, the member doesn't
+// serve requests meanwhile
+func (c *Client) Defragment(ctx context.Context) error {
+	_, err := c.client.Defragment(ctx, c.Config.Endpoints[0])
+	return err
+}
+
+// Lock acquires the cluster wide lock of the given name, the returned func releases it
+func (c *Client) Lock(ctx context.Context, name string) (func(), error) {
+	session, err := concurrency.NewSession(c.client)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create etcd session: %w", err)
+	}
+	mutex := concurrency.NewMutex(session, name)
+	if err := mutex.Lock(ctx); err != nil {
+		session.Close()
+		return nil, err
+	}
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		_ = mutex.Unlock(ctx)
+		session.Close()
+	}, nil
+}
@@ -0,0 +1,61 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package etcd
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"go.etcd.io/etcd/clientv3/snapshot"
+	"go.uber.org/zap"
+)
+
+// SaveSnapshot saves a snapshot of the database to the path. The snapshot is taken from a single member, the
+// next endpoints of an external cluster are tried if it fails.
+func (c *Client) SaveSnapshot(ctx context.Context, path string) error {
+	// disable etcd's logging
+	m := snapshot.NewV3(zap.NewNop())
+
+	var err error
+	for _, endpoint := range c.Config.Endpoints {
+		// the snapshot retries until the context is done, an unreachable endpoint would use up all of it
+		if err = c.checkEndpoint(ctx, endpoint); err != nil {
+			logrus.Warnf("skipping the etcd endpoint %s for the snapshot: %v", endpoint, err)
+			continue
+		}
+		cfg := *c.Config
+		cfg.Endpoints = []string{endpoint}
+		if err = m.Save(ctx, cfg, path); err == nil {
+			return nil
+		}
+		logrus.Warnf("failed to save the etcd snapshot from %s: %v", endpoint, err)
+	}
+	return err
+}
+
+// checkEndpoint tells if the endpoint answers in time
+func (c *Client) checkEndpoint(ctx context.Context, endpoint string) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	_, err := c.client.Status(ctx, endpoint)
+	return err
+}
+
+// SnapshotStatus reads the hash, revision, key count and size of the snapshot
+func SnapshotStatus(path string) (snapshot.Status, error) {
+	return snapshot.NewV3(zap.NewNop()).Status(path)
+}
@@ -0,0 +1,158 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package etcd
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"time"
+
+	"go.etcd.io/etcd/clientv3"
+	"go.etcd.io/etcd/etcdserver/api/v3rpc/rpctypes"
+)
+
+// MemberStatus is the status of an etcd member as seen from this controller
+type MemberStatus struct {
+	ID        string `json:"id" yaml:"id"`
+	Name      string `json:"name" yaml:"name"`
+	PeerURL   string `json:"peerURL" yaml:"peerURL"`
+	IsLeader  bool   `json:"isLeader" yaml:"isLeader"`
+	IsLearner bool   `json:"isLearner" yaml:"isLearner"`
+	RaftTerm  uint64 `json:"raftTerm,omitempty" yaml:"raftTerm,omitempty"`
+	RaftIndex uint64 `json:"raftIndex,omitempty" yaml:"raftIndex,omitempty"`
+	DBSize    int64  `json:"dbSize,omitempty" yaml:"dbSize,omitempty"`
+	// DBSizeInUse is the part of the database not freed by compaction, defragmentation shrinks DBSize to it
+	DBSizeInUse int64 `json:"dbSizeInUse,omitempty" yaml:"dbSizeInUse,omitempty"`
+	// QuotaPercent is how much of the quota DBSize takes, the member raises the NOSPACE alarm at 100
+	QuotaPercent int `json:"quotaPercent,omitempty" yaml:"quotaPercent,omitempty"`
+	// NoSpaceAlarm tells if the member raised the NOSPACE alarm, the cluster only accepts reads and deletes then
+	NoSpaceAlarm bool `json:"noSpaceAlarm,omitempty" yaml:"noSpaceAlarm,omitempty"`
+	Healthy      bool `json:"healthy" yaml:"healthy"`
+	// HealthLatency is how long the member took to answer the health check
+	HealthLatency string `json:"healthLatency,omitempty" yaml:"healthLatency,omitempty"`
+	// Error tells why the member status couldn't be read
+	Error string `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// MemberStatuses gets the status of every member. The members only listening on the loopback interface of
+// other controllers can't be reached, only their membership is known then. The quota usage isn't set without
+// a quota, the quota of an external cluster isn't known.
+func (c *Client) MemberStatuses(ctx context.Context, quotaBackendBytes int64) ([]MemberStatus, error) {
+	members, err := c.Members(ctx)
+	if err != nil {
+		return nil, err
+	}
+	local, err := c.Status(ctx)
+	if err != nil {
+		return nil, err
+	}
+	alarms, err := c.NoSpaceAlarms(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MemberStatus, 0, len(members))
+	for _, m := range members {
+		status := MemberStatus{
+			ID:        strconv.FormatUint(m.ID, 16),
+			Name:      m.Name,
+			PeerURL:   m.PeerURL,
+			IsLeader:  m.ID == local.Leader,
+			IsLearner: m.IsLearner,
+		}
+		for _, id := range alarms {
+			status.NoSpaceAlarm = status.NoSpaceAlarm || id == m.ID
+		}
+		statuses = append(statuses, status)
+		s := &statuses[len(statuses)-1]
+
+		if len(m.ClientURLs) == 0 {
+			s.Error = "the member hasn't started yet"
+			continue
+		}
+		endpoint := m.ClientURLs[0]
+		if isLoopbackURL(endpoint) {
+			if m.ID != local.MemberID {
+				s.Error = fmt.Sprintf("the client URL %s isn't reachable from this controller", endpoint)
+				continue
+			}
+			endpoint = c.Config.Endpoints[0]
+		}
+
+		resp, err := c.client.Status(ctx, endpoint)
+		if err != nil {
+			s.Error = err.Error()
+			continue
+		}
+		s.RaftTerm = resp.RaftTerm
+		s.RaftIndex = resp.RaftIndex
+		s.DBSize = resp.DbSize
+		s.DBSizeInUse = resp.DbSizeInUse
+		if quotaBackendBytes > 0 {
+			s.QuotaPercent = int(resp.DbSize * 100 / quotaBackendBytes)
+		}
+
+		latency, err := c.endpointHealth(ctx, endpoint, m.IsLearner)
+		if err != nil {
+			s.Error = err.Error()
+			continue
+		}
+		s.Healthy = true
+		s.HealthLatency = latency.Round(time.Microsecond).String()
+	}
+	return statuses, nil
+}
+
+// endpointHealth reads the health key from the endpoint and returns how long it took, learners only serve
+// serializable reads
+func (c *Client) endpointHealth(ctx context.Context, endpoint string, learner bool) (time.Duration, error) {
+	cli, err := clientv3.New(clientv3.Config{
+		Endpoints:   []string{endpoint},
+		TLS:         c.Config.TLS,
+		DialTimeout: 5 * time.Second,
+	})
+	if err != nil {
+		return 0, err
+	}
+	defer cli.Close()
+
+	var opts []clientv3.OpOption
+	if learner {
+		opts = append(opts, clientv3.WithSerializable())
+	}
+	start := time.Now()
+	_, err = cli.Get(ctx, "health", opts...)
+	// permission denied is OK since proposal goes through consensus to get it
+	if err != nil && err != rpctypes.ErrPermissionDenied {
+		return 0, err
+	}
+	return time.Since(start), nil
+}
+
+func isLoopbackURL(u string) bool {
+	parsed, err := url.Parse(u)
+	if err != nil {
+		return false
+	}
+	if parsed.Hostname() == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(parsed.Hostname())
+	return ip != nil && ip.IsLoopback()
+}
@@ -0,0 +1,114 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package etcd
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/k0sproject/k0s/internal/testutil"
+	"github.com/k0sproject/k0s/pkg/apis/v1beta1"
+)
+
+func newTestClient(t *testing.T, endpoints ...string) *Client {
+	client, err := NewClient("", "", &v1beta1.EtcdConfig{ExternalCluster: &v1beta1.ExternalCluster{Endpoints: endpoints}})
+	require.NoError(t, err)
+	client.Config.DialTimeout = 5 * time.Second
+	t.Cleanup(client.Close)
+	return client
+}
+
+func TestMemberStatuses(t *testing.T) {
+	clientURL, peerURL := testutil.StartEtcd(t)
+	client := newTestClient(t, clientURL)
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	// a learner which never started is only known by its membership
+	_, err := client.AddMember(ctx, "learner", "https://127.0.0.2:2380", true)
+	require.NoError(t, err)
+
+	local, err := client.Status(ctx)
+	require.NoError(t, err)
+	statuses, err := client.MemberStatuses(ctx, v1beta1.DefaultEtcdQuotaBackendBytes)
+	require.NoError(t, err)
+	require.Len(t, statuses, 2)
+
+	var member, learner MemberStatus
+	for _, s := range statuses {
+		if s.PeerURL == peerURL {
+			member = s
+		} else {
+			learner = s
+		}
+	}
+
+	assert.Equal(t, strconv.FormatUint(local.MemberID, 16), member.ID)
+	assert.Equal(t, "test", member.Name)
+	assert.True(t, member.IsLeader)
+	assert.False(t, member.IsLearner)
+	assert.True(t, member.Healthy, member.Error)
+	assert.NotEmpty(t, member.HealthLatency)
+	assert.NotZero(t, member.RaftTerm)
+	assert.NotZero(t, member.DBSize)
+	assert.NotZero(t, member.DBSizeInUse)
+	assert.Equal(t, int(member.DBSize*100/v1beta1.DefaultEtcdQuotaBackendBytes), member.QuotaPercent)
+	assert.False(t, member.NoSpaceAlarm)
+
+	assert.Equal(t, "https://127.0.0.2:2380", learner.PeerURL)
+	assert.True(t, learner.IsLearner)
+	assert.False(t, learner.IsLeader)
+	assert.False(t, learner.Healthy)
+	assert.Equal(t, "the member hasn't started yet", learner.Error)
+
+	statuses, err = client.MemberStatuses(ctx, 0)
+	require.NoError(t, err)
+	for _, s := range statuses {
+		assert.Zero(t, s.QuotaPercent, "quota usage set without a quota")
+	}
+}
+
+func TestSaveSnapshot(t *testing.T) {
+	clientURL, _ := testutil.StartEtcd(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	// the endpoints of an external cluster are tried in order
+	client := newTestClient(t, "http://127.0.0.1:1", clientURL)
+	for i := 0; i < 10; i++ {
+		_, err := client.KV().Put(ctx, fmt.Sprintf("/test/%d", i), "value")
+		require.NoError(t, err)
+	}
+
+	path := filepath.Join(t.TempDir(), "snapshot.db")
+	require.NoError(t, client.SaveSnapshot(ctx, path))
+
+	status, err := SnapshotStatus(path)
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, status.TotalKey, 10)
+	assert.NotZero(t, status.Revision)
+	assert.NotZero(t, status.TotalSize)
+
+	_, err = SnapshotStatus(filepath.Join(t.TempDir(), "missing.db"))
+	assert.Error(t, err)
+}
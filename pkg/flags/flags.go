@@ -0,0 +1,172 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package flags
+
+import (
+	"fmt"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/k0sproject/k0s/pkg/apis/v1beta1"
+)
+
+// Args are the flags of a component keyed by the flag name without the leading dashes
+type Args map[string]string
+
+// ToArgs renders the flags as --name=value sorted by the name
+func (a Args) ToArgs() []string {
+	names := make([]string, 0, len(a))
+	for name := range a {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	args := make([]string, len(names))
+	for i, name := range names {
+		args[i] = fmt.Sprintf("--%s=%s", name, a[name])
+	}
+	return args
+}
+
+// Policy is the override policy of the flags of a component
+type Policy struct {
+	// Component is the name of the component binary
+	Component string
+	// Replaceable lists the flags k0s sets that the extra args may replace, the rest of them are managed by k0s
+	Replaceable []string
+	// Deprecated lists the flags replaced by config fields, keyed by the flag name. They're accepted in the extra
+	// args with a warning. The value k0s sets for them takes the extra arg into account, e.g. the feature gates,
+	// so the value k0s sets wins.
+	Deprecated map[string]string
+}
+
+// Merge merges the extra args into the flags k0s sets. Flags k0s doesn't set are added. Flags k0s sets can only
+// be replaced if they're replaceable. Replacing them without marking the extra arg to replace them is deprecated,
+// it's accepted with a warning as the extra args used to override the flags k0s sets without it.
+func (p Policy) Merge(defaults Args, extra v1beta1.ExtraArgs) (Args, error) {
+	args := make(Args, len(defaults)+len(extra))
+	for name, value := range defaults {
+		args[name] = value
+	}
+
+	var conflicts []string
+	for _, name := range extra.Names() {
+		arg := extra[name]
+		_, set := defaults[name]
+		field, deprecated := p.Deprecated[name]
+		switch {
+		case deprecated:
+			logrus.Warnf("%s flag %s is deprecated in the extra args and will be rejected in a future release, use %s instead", p.Component, name, field)
+			if !set {
+				args[name] = arg.Value
+			}
+		case set && !p.replaceable(name):
+			conflicts = append(conflicts, fmt.Sprintf("%s is managed by k0s", name))
+		case set && !arg.Replace:
+			logrus.Warnf("%s flag %s is set by k0s, set replace to override it, overriding it without replace is deprecated and will be rejected in a future release", p.Component, name)
+			args[name] = arg.Value
+		default:
+			args[name] = arg.Value
+		}
+	}
+	if len(conflicts) > 0 {
+		return nil, fmt.Errorf("can't override %s flags: %s", p.Component, strings.Join(conflicts, ", "))
+	}
+	return args, nil
+}
+
+func (p Policy) replaceable(name string) bool {
+	for _, r := range p.Replaceable {
+		if r == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate checks that the binary knows the extra flags, the known flags being the ones it lists in its help
+func (p Policy) Validate(binPath string, extra v1beta1.ExtraArgs) error {
+	if len(extra) == 0 {
+		return nil
+	}
+	known, err := KnownFlags(binPath)
+	if err != nil {
+		return err
+	}
+	var unknown []string
+	for _, name := range extra.Names() {
+		if !known[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) > 0 {
+		return fmt.Errorf("unknown %s flags: %s", p.Component, strings.Join(unknown, ", "))
+	}
+	return nil
+}
+
+var flagPattern = regexp.MustCompile(`(?m)^\s*(?:-\w,\s+)?--([a-zA-Z0-9][a-zA-Z0-9._-]*)`)
+
+var knownFlags = struct {
+	sync.Mutex
+	byBinary map[string]map[string]bool
+}{byBinary: map[string]map[string]bool{}}
+
+// KnownFlags runs the binary with --help and returns the flags it lists. The flags are cached per binary,
+// the callers must not modify the returned map.
+func KnownFlags(binPath string) (map[string]bool, error) {
+	knownFlags.Lock()
+	defer knownFlags.Unlock()
+	if known, ok := knownFlags.byBinary[binPath]; ok {
+		return known, nil
+	}
+
+	// some binaries exit with a non-zero code after printing the help
+	out, err := exec.Command(binPath, "--help").CombinedOutput()
+	if len(out) == 0 && err != nil {
+		return nil, fmt.Errorf("can't list the flags of %s: %w", binPath, err)
+	}
+	known := parseFlags(string(out))
+	knownFlags.byBinary[binPath] = known
+	return known, nil
+}
+
+func parseFlags(help string) map[string]bool {
+	known := map[string]bool{}
+	for _, m := range flagPattern.FindAllStringSubmatch(help, -1) {
+		known[m[1]] = true
+	}
+	return known
+}
+
+// ParseExtraArgs parses the extra args given on the command line as a single string, e.g. "--v=4 --node-ip=10.0.0.1".
+// Giving the flags on the command line is explicit enough, they replace the flags k0s sets if the policy allows it.
+func ParseExtraArgs(input string) v1beta1.ExtraArgs {
+	extra := v1beta1.ExtraArgs{}
+	for _, a := range strings.Fields(input) {
+		nv := strings.SplitN(strings.TrimLeft(a, "-"), "=", 2)
+		arg := v1beta1.ExtraArg{Replace: true}
+		if len(nv) == 2 {
+			arg.Value = nv[1]
+		}
+		extra[nv[0]] = arg
+	}
+	return extra
+}
@@ -0,0 +1,131 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package flags
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/k0sproject/k0s/pkg/apis/v1beta1"
+)
+
+func TestMerge(t *testing.T) {
+	policy := Policy{Component: "kube-scheduler", Replaceable: []string{"v"}}
+	defaults := Args{"bind-address": "127.0.0.1", "v": "1"}
+
+	t.Run("adds flags k0s doesn't set", func(t *testing.T) {
+		args, err := policy.Merge(defaults, v1beta1.ExtraArgs{"tls-cert-file": {Value: "/etc/tls.crt"}})
+		require.NoError(t, err)
+		assert.Equal(t, Args{"bind-address": "127.0.0.1", "v": "1", "tls-cert-file": "/etc/tls.crt"}, args)
+		assert.Equal(t, "1", defaults["v"])
+		assert.NotContains(t, defaults, "tls-cert-file")
+	})
+
+	t.Run("replaces replaceable flags", func(t *testing.T) {
+		args, err := policy.Merge(defaults, v1beta1.ExtraArgs{"v": {Value: "4", Replace: true}})
+		require.NoError(t, err)
+		assert.Equal(t, "4", args["v"])
+	})
+
+	t.Run("replaces replaceable flags without replace", func(t *testing.T) {
+		args, err := policy.Merge(defaults, v1beta1.ExtraArgs{"v": {Value: "4"}})
+		require.NoError(t, err)
+		assert.Equal(t, "4", args["v"])
+	})
+
+	t.Run("accepts deprecated flags", func(t *testing.T) {
+		policy := Policy{Component: "kube-scheduler", Deprecated: map[string]string{"feature-gates": "spec.featureGates"}}
+		args, err := policy.Merge(defaults, v1beta1.ExtraArgs{"feature-gates": {Value: "Foo=true"}})
+		require.NoError(t, err)
+		assert.Equal(t, "Foo=true", args["feature-gates"])
+
+		// k0s takes the deprecated flag into account in the value it sets
+		args, err = policy.Merge(Args{"feature-gates": "Bar=true,Foo=true"}, v1beta1.ExtraArgs{"feature-gates": {Value: "Foo=true"}})
+		require.NoError(t, err)
+		assert.Equal(t, "Bar=true,Foo=true", args["feature-gates"])
+	})
+
+	t.Run("reports all the conflicts", func(t *testing.T) {
+		_, err := policy.Merge(Args{"bind-address": "127.0.0.1", "secure-port": "10259", "v": "1"}, v1beta1.ExtraArgs{
+			"bind-address": {Value: "0.0.0.0", Replace: true},
+			"secure-port":  {Value: "10260"},
+			"v":            {Value: "4"},
+		})
+		assert.EqualError(t, err, "can't override kube-scheduler flags: bind-address is managed by k0s, secure-port is managed by k0s")
+	})
+}
+
+func TestToArgs(t *testing.T) {
+	args := Args{"v": "1", "bind-address": "127.0.0.1", "enforce-node-allocatable": ""}
+	assert.Equal(t, []string{"--bind-address=127.0.0.1", "--enforce-node-allocatable=", "--v=1"}, args.ToArgs())
+}
+
+func TestParseFlags(t *testing.T) {
+	help := `Usage:
+  kube-scheduler [flags]
+
+Secure serving flags:
+
+      --bind-address ip        The IP address on which to listen for the --secure-port port.
+      --cert-dir string        The directory where the TLS certs are located.
+      --tls-cipher-suites strings   Comma-separated list of cipher suites for the server.
+
+Misc flags:
+
+  -v, --v Level                number for the log level verbosity
+      --log_dir string         If non-empty, write log files in this directory
+  --data-dir string            Path to the data directory.
+`
+	assert.Equal(t, map[string]bool{
+		"bind-address":      true,
+		"cert-dir":          true,
+		"tls-cipher-suites": true,
+		"v":                 true,
+		"log_dir":           true,
+		"data-dir":          true,
+	}, parseFlags(help))
+}
+
+func TestKnownFlagsCached(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("needs a shell script as the binary")
+	}
+	binPath := filepath.Join(t.TempDir(), "component")
+	require.NoError(t, ioutil.WriteFile(binPath, []byte("#!/bin/sh\necho '      --foo string'\n"), 0700))
+
+	known, err := KnownFlags(binPath)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]bool{"foo": true}, known)
+
+	require.NoError(t, ioutil.WriteFile(binPath, []byte("#!/bin/sh\necho '      --bar string'\n"), 0700))
+	known, err = KnownFlags(binPath)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]bool{"foo": true}, known, "the help of a binary must only be run once")
+}
+
+func TestParseExtraArgs(t *testing.T) {
+	assert.Equal(t, v1beta1.ExtraArgs{
+		"v":                        {Value: "4", Replace: true},
+		"node-ip":                  {Value: "10.0.0.1", Replace: true},
+		"enforce-node-allocatable": {Value: "", Replace: true},
+	}, ParseExtraArgs("--v=4  --node-ip=10.0.0.1 --enforce-node-allocatable"))
+	assert.Empty(t, ParseExtraArgs(""))
+}
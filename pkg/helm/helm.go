@@ -100,7 +100,8 @@ func (hc *Commands) AddRepository(repoCfg k0sv1beta1.Repository) error {
 		return fmt.Errorf("can't add repository: %q is not a valid chart repository or cannot be reached: %v", "repo", err)
 	}
 	f.Update(&c)
-	if err := f.WriteFile(hc.repoFile, 0644); err != nil {
+	// the file holds the repository credentials
+	if err := f.WriteFile(hc.repoFile, 0600); err != nil {
 		return fmt.Errorf("can't add repository to %s: %v", hc.repoFile, err)
 	}
 
@@ -22,6 +22,7 @@ import (
 	"os/exec"
 	"runtime"
 	"strings"
+	"time"
 
 	"github.com/k0sproject/k0s/internal/util"
 	"github.com/mitchellh/go-ps"
@@ -36,6 +37,16 @@ type K0sStatus struct {
 	SysInit  string
 	StubFile string
 	Output   string
+	Leader   *LeaderStatus `json:",omitempty" yaml:",omitempty"`
+}
+
+// LeaderStatus describes the controller currently holding the leader lease
+type LeaderStatus struct {
+	Identity string
+	// IsSelf tells if this controller is the leader
+	IsSelf      bool
+	AcquireTime time.Time
+	Transitions int32
 }
 
 func GetPid() (status *K0sStatus, err error) {
@@ -132,6 +143,17 @@ func (s K0sStatus) String() {
 		if s.StubFile != "" {
 			fmt.Println("Service file:", s.StubFile)
 		}
+		if s.Leader != nil {
+			leader := s.Leader.Identity
+			if s.Leader.IsSelf {
+				leader += " (this controller)"
+			}
+			fmt.Println("Leader:", leader)
+			if !s.Leader.AcquireTime.IsZero() {
+				fmt.Println("Leader since:", s.Leader.AcquireTime.Format(time.RFC3339), "("+time.Since(s.Leader.AcquireTime).Round(time.Second).String()+" ago)")
+			}
+			fmt.Println("Leader transitions:", s.Leader.Transitions)
+		}
 	}
 }
 
@@ -0,0 +1,128 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package jsonschema
+
+import (
+	"reflect"
+	"strings"
+)
+
+// Schema is a (subset of a) JSON Schema document, compatible with the OpenAPI v3 schema object
+type Schema struct {
+	Schema               string             `json:"$schema,omitempty"`
+	Title                string             `json:"title,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Enum                 []interface{}      `json:"enum,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+	AnyOf                []*Schema          `json:"anyOf,omitempty"`
+}
+
+// Reflect builds the schema of the given value's type, naming the properties
+// after the given struct tag (e.g. "json" or "yaml")
+func Reflect(v interface{}, tagName string) *Schema {
+	return reflectType(reflect.TypeOf(v), tagName, map[reflect.Type]bool{})
+}
+
+func reflectType(t reflect.Type, tagName string, seen map[reflect.Type]bool) *Schema {
+	if t == nil {
+		return &Schema{}
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			// encoding/json marshals byte slices as base64 strings
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: reflectType(t.Elem(), tagName, seen)}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: reflectType(t.Elem(), tagName, seen)}
+	case reflect.Struct:
+		if seen[t] {
+			// recursive types are not expanded any further
+			return &Schema{Type: "object"}
+		}
+		seen[t] = true
+		defer delete(seen, t)
+		s := &Schema{Type: "object", Properties: map[string]*Schema{}}
+		reflectFields(s, t, tagName, seen)
+		return s
+	}
+	// interface{} and anything else we can't describe accepts any value
+	return &Schema{}
+}
+
+func reflectFields(s *Schema, t reflect.Type, tagName string, seen map[reflect.Type]bool) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" && !f.Anonymous {
+			// unexported
+			continue
+		}
+		name, opts := parseTag(f.Tag.Get(tagName))
+		if name == "-" {
+			continue
+		}
+		// encoding/json inlines untagged embedded structs, yaml.v2 only does with the inline option
+		if (f.Anonymous && name == "" && tagName != "yaml") || opts["inline"] {
+			ft := f.Type
+			for ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				reflectFields(s, ft, tagName, seen)
+				continue
+			}
+		}
+		if name == "" {
+			name = f.Name
+			if tagName == "yaml" {
+				// yaml.v2 lowercases untagged field names
+				name = strings.ToLower(name)
+			}
+		}
+		s.Properties[name] = reflectType(f.Type, tagName, seen)
+	}
+}
+
+func parseTag(tag string) (string, map[string]bool) {
+	parts := strings.Split(tag, ",")
+	opts := make(map[string]bool, len(parts)-1)
+	for _, o := range parts[1:] {
+		opts[o] = true
+	}
+	return parts[0], opts
+}
@@ -0,0 +1,70 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package jsonschema
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type embedded struct {
+	Inlined string `json:"inlined"`
+}
+
+type testType struct {
+	embedded
+	Name     string            `json:"name" yaml:"name"`
+	Count    *int              `json:"count,omitempty" yaml:"count"`
+	Data     []byte            `json:"data"`
+	Items    []testItem        `json:"items"`
+	Labels   map[string]string `json:"labels"`
+	Any      interface{}       `json:"any"`
+	Skipped  string            `json:"-"`
+	Untagged bool
+	private  string
+}
+
+type testItem struct {
+	Value float64 `json:"value"`
+	Next  *testItem
+}
+
+func TestReflect(t *testing.T) {
+	s := Reflect(testType{}, "json")
+
+	assert.Equal(t, "object", s.Type)
+	assert.Equal(t, "string", s.Properties["inlined"].Type)
+	assert.Equal(t, "string", s.Properties["name"].Type)
+	assert.Equal(t, "integer", s.Properties["count"].Type)
+	assert.Equal(t, "byte", s.Properties["data"].Format)
+	assert.Equal(t, "array", s.Properties["items"].Type)
+	assert.Equal(t, "number", s.Properties["items"].Items.Properties["value"].Type)
+	assert.Equal(t, "object", s.Properties["items"].Items.Properties["Next"].Type)
+	assert.Equal(t, "string", s.Properties["labels"].AdditionalProperties.Type)
+	assert.Equal(t, &Schema{}, s.Properties["any"])
+	assert.Equal(t, "boolean", s.Properties["Untagged"].Type)
+	assert.NotContains(t, s.Properties, "Skipped")
+	assert.NotContains(t, s.Properties, "private")
+}
+
+func TestReflectYamlNames(t *testing.T) {
+	s := Reflect(&testType{}, "yaml")
+
+	assert.Contains(t, s.Properties, "name")
+	assert.Contains(t, s.Properties, "untagged")
+	assert.Contains(t, s.Properties, "embedded")
+}
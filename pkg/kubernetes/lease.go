@@ -16,9 +16,13 @@ limitations under the License.
 package kubernetes
 
 import (
+	"context"
+	"strings"
 	"time"
 
 	coordinationv1 "k8s.io/api/coordination/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
 )
 
 // IsValidLease check whether or not the lease is expired
@@ -29,3 +33,34 @@ func IsValidLease(lease coordinationv1.Lease) bool {
 
 	return leaseExpiry.After(time.Now())
 }
+
+// CountActiveControllerLeases counts the valid leases of the controllers, the controllers hold them
+// when running behind an external address
+func CountActiveControllerLeases(ctx context.Context, client kubernetes.Interface) (int, error) {
+	leases, err := client.CoordinationV1().Leases("kube-node-lease").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return 0, err
+	}
+	count := 0
+	for _, l := range leases.Items {
+		if strings.HasPrefix(l.ObjectMeta.Name, "k0s-ctrl") && IsValidLease(l) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// ActiveControllers returns the names of the controllers holding a valid lease
+func ActiveControllers(ctx context.Context, client kubernetes.Interface) ([]string, error) {
+	leases, err := client.CoordinationV1().Leases("kube-node-lease").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, l := range leases.Items {
+		if strings.HasPrefix(l.ObjectMeta.Name, "k0s-ctrl-") && IsValidLease(l) {
+			names = append(names, strings.TrimPrefix(l.ObjectMeta.Name, "k0s-ctrl-"))
+		}
+	}
+	return names, nil
+}
@@ -207,7 +207,9 @@ func (p *LeasePool) Watch(opts ...WatchOpt) (*LeaseEvents, context.CancelFunc, e
 				log.Info("lost leader lease")
 				p.events.LostLease <- struct{}{}
 			},
-			OnNewLeader: nil,
+			OnNewLeader: func(identity string) {
+				p.config.log.Infof("current leader is %s", identity)
+			},
 		},
 	}
 	le, err := leaderelection.NewLeaderElector(lec)
@@ -19,6 +19,7 @@ import (
 	"context"
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	coordinationv1 "k8s.io/api/coordination/v1"
@@ -219,3 +220,31 @@ leaseEventLoop:
 	assert.Equal(t, "node1-lost", receivedEvents[1])
 	assert.Equal(t, "node2-acquired", receivedEvents[2])
 }
+
+func TestGetLeaseStatus(t *testing.T) {
+	fakeClient := fake.NewSimpleClientset()
+
+	identity := "test-node"
+	transitions := int32(3)
+	acquireTime := metav1.NewMicroTime(time.Now().Add(-time.Minute))
+	_, err := fakeClient.CoordinationV1().Leases("test").Create(context.TODO(), &coordinationv1.Lease{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "test",
+		},
+		Spec: coordinationv1.LeaseSpec{
+			HolderIdentity:   &identity,
+			AcquireTime:      &acquireTime,
+			LeaseTransitions: &transitions,
+		},
+	}, metav1.CreateOptions{})
+	assert.NoError(t, err)
+
+	status, err := GetLeaseStatus(context.TODO(), fakeClient, "test", "test")
+	assert.NoError(t, err)
+	assert.Equal(t, identity, status.HolderIdentity)
+	assert.Equal(t, transitions, status.Transitions)
+	assert.True(t, acquireTime.Time.Equal(status.AcquireTime))
+
+	_, err = GetLeaseStatus(context.TODO(), fakeClient, "test", "missing")
+	assert.Error(t, err)
+}
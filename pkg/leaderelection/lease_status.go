@@ -0,0 +1,56 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package leaderelection
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// LeaseStatus describes who currently holds a lease and since when
+type LeaseStatus struct {
+	HolderIdentity string
+	AcquireTime    time.Time
+	RenewTime      time.Time
+	Transitions    int32
+}
+
+// GetLeaseStatus reads the current status of the given lease
+func GetLeaseStatus(ctx context.Context, client kubernetes.Interface, namespace, name string) (*LeaseStatus, error) {
+	lease, err := client.CoordinationV1().Leases(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("can't get lease %s/%s: %w", namespace, name, err)
+	}
+
+	status := &LeaseStatus{}
+	if lease.Spec.HolderIdentity != nil {
+		status.HolderIdentity = *lease.Spec.HolderIdentity
+	}
+	if lease.Spec.AcquireTime != nil {
+		status.AcquireTime = lease.Spec.AcquireTime.Time
+	}
+	if lease.Spec.RenewTime != nil {
+		status.RenewTime = lease.Spec.RenewTime.Time
+	}
+	if lease.Spec.LeaseTransitions != nil {
+		status.Transitions = *lease.Spec.LeaseTransitions
+	}
+	return status, nil
+}
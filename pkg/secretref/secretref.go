@@ -0,0 +1,76 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package secretref
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/k0sproject/k0s/pkg/apis/v1beta1"
+)
+
+// Resolve returns the value the reference points to. The client is only needed for the Kubernetes Secret
+// references. The errors never include the value.
+func Resolve(ctx context.Context, ref *v1beta1.SecretRef, client kubernetes.Interface) (string, error) {
+	switch {
+	case ref == nil:
+		return "", fmt.Errorf("no secret reference")
+	case ref.File != "":
+		data, err := ioutil.ReadFile(ref.File)
+		if err != nil {
+			return "", fmt.Errorf("can't read secret file: %w", err)
+		}
+		return strings.TrimSpace(string(data)), nil
+	case ref.Env != "":
+		value, ok := os.LookupEnv(ref.Env)
+		if !ok {
+			return "", fmt.Errorf("environment variable %s is not set", ref.Env)
+		}
+		return value, nil
+	case ref.Secret != nil:
+		if client == nil {
+			return "", fmt.Errorf("kubernetes secrets can't be used here")
+		}
+		ns := ref.Secret.Namespace
+		if ns == "" {
+			ns = v1beta1.SecretNamespace
+		}
+		secret, err := client.CoreV1().Secrets(ns).Get(ctx, ref.Secret.Name, metav1.GetOptions{})
+		if err != nil {
+			return "", fmt.Errorf("can't get secret %s/%s: %w", ns, ref.Secret.Name, err)
+		}
+		value, ok := secret.Data[ref.Secret.Key]
+		if !ok {
+			return "", fmt.Errorf("secret %s/%s has no key %s", ns, ref.Secret.Name, ref.Secret.Key)
+		}
+		return string(value), nil
+	}
+	return "", fmt.Errorf("empty secret reference")
+}
+
+// ValueOrRef returns the inline value if the reference is not set, the resolved reference otherwise
+func ValueOrRef(ctx context.Context, inline string, ref *v1beta1.SecretRef, client kubernetes.Interface) (string, error) {
+	if ref == nil {
+		return inline, nil
+	}
+	return Resolve(ctx, ref, client)
+}
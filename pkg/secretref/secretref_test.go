@@ -0,0 +1,83 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package secretref
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/k0sproject/k0s/pkg/apis/v1beta1"
+)
+
+func TestResolve(t *testing.T) {
+	f, err := ioutil.TempFile("", "secret")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+	_, err = f.WriteString("from-file\n")
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	os.Setenv("K0S_TEST_SECRET", "from-env")
+	defer os.Unsetenv("K0S_TEST_SECRET")
+
+	client := fake.NewSimpleClientset(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "kube-system", Name: "helm"},
+		Data:       map[string][]byte{"password": []byte("from-secret")},
+	})
+
+	tests := []struct {
+		name  string
+		ref   *v1beta1.SecretRef
+		value string
+		err   string
+	}{
+		{"file", &v1beta1.SecretRef{File: f.Name()}, "from-file", ""},
+		{"env", &v1beta1.SecretRef{Env: "K0S_TEST_SECRET"}, "from-env", ""},
+		{"secret", &v1beta1.SecretRef{Secret: &v1beta1.SecretKeyRef{Name: "helm", Key: "password"}}, "from-secret", ""},
+		{"unset env", &v1beta1.SecretRef{Env: "K0S_TEST_UNSET"}, "", "environment variable K0S_TEST_UNSET is not set"},
+		{"missing key", &v1beta1.SecretRef{Secret: &v1beta1.SecretKeyRef{Name: "helm", Key: "username"}}, "", "secret kube-system/helm has no key username"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			value, err := Resolve(context.Background(), tt.ref, client)
+			if tt.err != "" {
+				assert.EqualError(t, err, tt.err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.value, value)
+		})
+	}
+}
+
+func TestResolveSecretWithoutClient(t *testing.T) {
+	_, err := Resolve(context.Background(), &v1beta1.SecretRef{Secret: &v1beta1.SecretKeyRef{Name: "helm", Key: "password"}}, nil)
+	assert.Error(t, err)
+}
+
+func TestValueOrRef(t *testing.T) {
+	value, err := ValueOrRef(context.Background(), "inline", nil, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "inline", value)
+}
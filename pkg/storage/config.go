@@ -0,0 +1,91 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package storage
+
+import (
+	"fmt"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/k0sproject/k0s/pkg/apis/v1beta1"
+)
+
+// SetStorage replaces spec.storage of the config file with the storage migrated to, keeping the rest of
+// the file. All the settings of the storage are written, such as the TLS, pool and password reference of
+// kine. An empty file gives a config with only the storage.
+func SetStorage(data []byte, storage *v1beta1.StorageSpec) ([]byte, error) {
+	var doc yaml.MapSlice
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	if len(doc) == 0 {
+		doc = yaml.MapSlice{
+			{Key: "apiVersion", Value: v1beta1.ClusterConfigAPIVersion},
+			{Key: "kind", Value: v1beta1.ClusterConfigKind},
+		}
+	}
+
+	var settings interface{}
+	switch storage.Type {
+	case v1beta1.EtcdStorageType:
+		settings = storage.Etcd
+	case v1beta1.KineStorageType:
+		settings = storage.Kine
+	default:
+		return nil, fmt.Errorf("unsupported storage type %s", storage.Type)
+	}
+	settingsData, err := yaml.Marshal(settings)
+	if err != nil {
+		return nil, err
+	}
+	var settingsItem yaml.MapSlice
+	if err := yaml.Unmarshal(settingsData, &settingsItem); err != nil {
+		return nil, err
+	}
+	storageItem := yaml.MapSlice{{Key: "type", Value: storage.Type}, {Key: storage.Type, Value: settingsItem}}
+
+	spec, err := mapItem(doc, "spec")
+	if err != nil {
+		return nil, err
+	}
+	spec = replaceItem(spec, "storage", storageItem)
+	return yaml.Marshal(replaceItem(doc, "spec", spec))
+}
+
+// mapItem returns the map under the key, an empty one if the key isn't there
+func mapItem(doc yaml.MapSlice, key string) (yaml.MapSlice, error) {
+	for _, item := range doc {
+		if item.Key != key || item.Value == nil {
+			continue
+		}
+		m, ok := item.Value.(yaml.MapSlice)
+		if !ok {
+			return nil, fmt.Errorf("%s is not a map", key)
+		}
+		return m, nil
+	}
+	return yaml.MapSlice{}, nil
+}
+
+func replaceItem(doc yaml.MapSlice, key string, value interface{}) yaml.MapSlice {
+	for i := range doc {
+		if doc[i].Key == key {
+			doc[i].Value = value
+			return doc
+		}
+	}
+	return append(doc, yaml.MapItem{Key: key, Value: value})
+}
@@ -0,0 +1,109 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package storage
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"go.etcd.io/etcd/clientv3"
+	"go.etcd.io/etcd/mvcc/mvccpb"
+)
+
+// pageSize is how many keys are read from the source at once
+const pageSize = 500
+
+// CopyResult tells what got copied
+type CopyResult struct {
+	// Keys is the number of keys copied
+	Keys int
+	// LeasedKeys is the number of keys left out because they're attached to a lease, such as the events
+	LeasedKeys int
+}
+
+// Copy copies the keys under srcPrefix of the source to dstPrefix of the target. The source is read at a
+// single revision, and the keys are written in the order they were last modified so that the target
+// keeps their relative order. The revisions themselves are assigned by the target. The keys attached to
+// a lease expire anyway and are left out, since the leases can't be copied.
+func Copy(ctx context.Context, src, dst clientv3.KV, srcPrefix, dstPrefix string) (CopyResult, error) {
+	var result CopyResult
+	kvs, err := list(ctx, src, srcPrefix)
+	if err != nil {
+		return result, fmt.Errorf("failed to read the source: %w", err)
+	}
+	sort.SliceStable(kvs, func(i, j int) bool { return kvs[i].ModRevision < kvs[j].ModRevision })
+
+	for _, kv := range kvs {
+		if kv.Lease != 0 {
+			result.LeasedKeys++
+			continue
+		}
+		key := dstPrefix + strings.TrimPrefix(string(kv.Key), srcPrefix)
+		// kine doesn't support plain puts, create the key the way kube-apiserver does
+		resp, err := dst.Txn(ctx).
+			If(clientv3.Compare(clientv3.ModRevision(key), "=", 0)).
+			Then(clientv3.OpPut(key, string(kv.Value))).
+			Commit()
+		if err != nil {
+			return result, fmt.Errorf("failed to write %s: %w", key, err)
+		}
+		if !resp.Succeeded {
+			return result, fmt.Errorf("%s already exists in the target", key)
+		}
+		result.Keys++
+	}
+	return result, nil
+}
+
+// Count returns the number of keys under the prefix
+func Count(ctx context.Context, kv clientv3.KV, prefix string) (int64, error) {
+	resp, err := kv.Get(ctx, prefix, clientv3.WithRange(clientv3.GetPrefixRangeEnd(prefix)), clientv3.WithCountOnly())
+	if err != nil {
+		return 0, err
+	}
+	return resp.Count, nil
+}
+
+// list reads the keys under the prefix page by page at the revision of the first page
+func list(ctx context.Context, kv clientv3.KV, prefix string) ([]*mvccpb.KeyValue, error) {
+	var (
+		kvs []*mvccpb.KeyValue
+		rev int64
+	)
+	end := clientv3.GetPrefixRangeEnd(prefix)
+	key := prefix
+	for {
+		// kine only supports the prefix ranges kube-apiserver uses, continuing from the last key
+		opts := []clientv3.OpOption{clientv3.WithRange(end), clientv3.WithLimit(pageSize)}
+		if rev > 0 {
+			opts = append(opts, clientv3.WithRev(rev))
+		}
+		resp, err := kv.Get(ctx, key, opts...)
+		if err != nil {
+			return nil, err
+		}
+		if rev == 0 {
+			rev = resp.Header.Revision
+		}
+		kvs = append(kvs, resp.Kvs...)
+		if !resp.More || len(resp.Kvs) == 0 {
+			return kvs, nil
+		}
+		key = string(resp.Kvs[len(resp.Kvs)-1].Key) + "\x00"
+	}
+}
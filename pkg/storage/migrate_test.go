@@ -0,0 +1,173 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package storage
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.etcd.io/etcd/clientv3"
+	"go.etcd.io/etcd/etcdserver/etcdserverpb"
+	"go.etcd.io/etcd/mvcc/mvccpb"
+
+	"github.com/k0sproject/k0s/pkg/apis/v1beta1"
+	"github.com/k0sproject/k0s/pkg/constant"
+)
+
+// fakeKV keeps the keys in memory, serving the ranges and the create transactions Copy uses
+type fakeKV struct {
+	clientv3.KV
+	rev  int64
+	kvs  map[string]*mvccpb.KeyValue
+	puts []string
+}
+
+func newFakeKV() *fakeKV {
+	return &fakeKV{kvs: map[string]*mvccpb.KeyValue{}}
+}
+
+func (f *fakeKV) set(key, value string, lease int64) {
+	f.rev++
+	f.kvs[key] = &mvccpb.KeyValue{Key: []byte(key), Value: []byte(value), ModRevision: f.rev, Lease: lease}
+	f.puts = append(f.puts, key)
+}
+
+func (f *fakeKV) Get(_ context.Context, key string, opts ...clientv3.OpOption) (*clientv3.GetResponse, error) {
+	op := clientv3.OpGet(key, opts...)
+	end := string(op.RangeBytes())
+	var keys []string
+	for k := range f.kvs {
+		if k >= key && k < end {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	resp := &clientv3.GetResponse{Header: &etcdserverpb.ResponseHeader{Revision: f.rev}, Count: int64(len(keys))}
+	if op.IsCountOnly() {
+		return resp, nil
+	}
+	for _, k := range keys {
+		resp.Kvs = append(resp.Kvs, f.kvs[k])
+	}
+	return resp, nil
+}
+
+func (f *fakeKV) Txn(context.Context) clientv3.Txn {
+	return &fakeTxn{kv: f}
+}
+
+type fakeTxn struct {
+	clientv3.Txn
+	kv  *fakeKV
+	cmp clientv3.Cmp
+	put clientv3.Op
+}
+
+func (t *fakeTxn) If(cs ...clientv3.Cmp) clientv3.Txn {
+	t.cmp = cs[0]
+	return t
+}
+
+func (t *fakeTxn) Then(ops ...clientv3.Op) clientv3.Txn {
+	t.put = ops[0]
+	return t
+}
+
+func (t *fakeTxn) Commit() (*clientv3.TxnResponse, error) {
+	if _, ok := t.kv.kvs[string(t.cmp.KeyBytes())]; ok {
+		return &clientv3.TxnResponse{Succeeded: false}, nil
+	}
+	t.kv.set(string(t.put.KeyBytes()), string(t.put.ValueBytes()), 0)
+	return &clientv3.TxnResponse{Succeeded: true}, nil
+}
+
+func TestCopy(t *testing.T) {
+	src := newFakeKV()
+	src.set("/registry/pods/default/b", "b", 0)
+	src.set("/registry/events/default/e", "e", 7)
+	src.set("/registry/pods/default/a", "a", 0)
+	src.set("/k0s/etcd-defrag/lock", "", 0)
+	src.set("/registry/pods/default/b", "b2", 0)
+
+	dst := newFakeKV()
+	result, err := Copy(context.Background(), src, dst, "/registry/", "/registry/")
+	require.NoError(t, err)
+	assert.Equal(t, CopyResult{Keys: 2, LeasedKeys: 1}, result)
+	assert.Equal(t, []string{"/registry/pods/default/a", "/registry/pods/default/b"}, dst.puts, "keys are written in the order they were modified")
+	assert.Equal(t, "b2", string(dst.kvs["/registry/pods/default/b"].Value))
+
+	count, err := Count(context.Background(), dst, "/registry/")
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, count)
+
+	t.Run("existing keys", func(t *testing.T) {
+		_, err := Copy(context.Background(), src, dst, "/registry/", "/registry/")
+		if assert.Error(t, err) {
+			assert.Contains(t, err.Error(), "already exists in the target")
+		}
+	})
+
+	t.Run("prefix", func(t *testing.T) {
+		dst := newFakeKV()
+		_, err := Copy(context.Background(), src, dst, "/registry/", "/tenant-1/")
+		require.NoError(t, err)
+		for _, k := range dst.puts {
+			assert.True(t, strings.HasPrefix(k, "/tenant-1/pods/"), k)
+		}
+	})
+}
+
+func TestSetStorage(t *testing.T) {
+	cfg := `apiVersion: k0s.k0sproject.io/v1beta1
+kind: ClusterConfig
+spec:
+  api:
+    address: 10.0.0.1
+  storage:
+    type: kine
+    kine:
+      dataSource: sqlite:///var/lib/k0s/db/state.db
+`
+	out, err := SetStorage([]byte(cfg), &v1beta1.StorageSpec{Type: v1beta1.EtcdStorageType, Etcd: &v1beta1.EtcdConfig{PeerAddress: "10.0.0.1"}})
+	require.NoError(t, err)
+	assert.Equal(t, `apiVersion: k0s.k0sproject.io/v1beta1
+kind: ClusterConfig
+spec:
+  api:
+    address: 10.0.0.1
+  storage:
+    type: etcd
+    etcd:
+      peerAddress: 10.0.0.1
+`, string(out))
+
+	kine := &v1beta1.KineConfig{
+		DataSource:   "postgres://k0s@db/k0s",
+		PasswordFrom: &v1beta1.SecretRef{File: "/etc/k0s/db-password"},
+		TLS:          &v1beta1.KineTLS{CAFile: "/etc/k0s/db-ca.crt"},
+		Pool:         &v1beta1.KinePool{MaxOpenConnections: 20},
+	}
+	out, err = SetStorage(nil, &v1beta1.StorageSpec{Type: v1beta1.KineStorageType, Kine: kine})
+	require.NoError(t, err)
+	parsed, err := v1beta1.ConfigFromString(string(out), constant.CfgVars{})
+	require.NoError(t, err)
+	assert.Equal(t, kine, parsed.Spec.Storage.Kine)
+	assert.NotContains(t, string(out), "etcd")
+}
@@ -34,11 +34,13 @@ import (
 
 // Supervisor is dead simple and stupid process supervisor, just tries to keep the process running in a while-true loop
 type Supervisor struct {
-	Name           string
-	BinPath        string
-	RunDir         string
-	DataDir        string
-	Args           []string
+	Name    string
+	BinPath string
+	RunDir  string
+	DataDir string
+	Args    []string
+	// Env is added to the environment inherited from k0s
+	Env            []string
 	PidFile        string
 	UID            int
 	GID            int
@@ -113,7 +115,7 @@ func (s *Supervisor) Supervise() error {
 		for {
 			s.cmd = exec.Command(s.BinPath, s.Args...)
 			s.cmd.Dir = s.DataDir
-			s.cmd.Env = getEnv(s.DataDir)
+			s.cmd.Env = append(getEnv(s.DataDir), s.Env...)
 
 			// detach from the process group so children don't
 			// get signals sent directly to parent.
@@ -166,6 +168,8 @@ func (s *Supervisor) Stop() error {
 	if s.quit != nil {
 		s.quit <- true
 		<-s.done
+		// allow supervising the process again
+		s.quit = nil
 	}
 	return nil
 }
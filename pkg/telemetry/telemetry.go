@@ -12,6 +12,7 @@ import (
 	"github.com/k0sproject/k0s/internal/util"
 	config "github.com/k0sproject/k0s/pkg/apis/v1beta1"
 	"github.com/k0sproject/k0s/pkg/etcd"
+	kubeutil "github.com/k0sproject/k0s/pkg/kubernetes"
 )
 
 type telemetryData struct {
@@ -117,7 +118,10 @@ func (c Component) getWorkerData() ([]workerData, workerSums, error) {
 func (c Component) getControlPlaneNodeCount() (int, error) {
 	switch c.ClusterConfig.Spec.Storage.Type {
 	case config.EtcdStorageType:
-		cl, err := etcd.NewClient(c.K0sVars.CertRootDir, c.K0sVars.EtcdCertDir)
+		if c.ClusterConfig.Spec.Storage.Etcd.IsExternalClusterUsed() {
+			return c.countAPIServers()
+		}
+		cl, err := etcd.NewClient(c.K0sVars.CertRootDir, c.K0sVars.EtcdCertDir, c.ClusterConfig.Spec.Storage.Etcd)
 		if err != nil {
 			return 0, fmt.Errorf("can't get etcd client: %v", err)
 		}
@@ -132,6 +136,27 @@ func (c Component) getControlPlaneNodeCount() (int, error) {
 	}
 }
 
+// countAPIServers counts the controllers sharing an external etcd cluster, the members of which aren't
+// controllers. They're counted by their leases, or else by the addresses of the kubernetes service.
+func (c Component) countAPIServers() (int, error) {
+	ctx := context.Background()
+	count, err := kubeutil.CountActiveControllerLeases(ctx, c.kubernetesClient)
+	if err != nil {
+		return 0, fmt.Errorf("can't count controller leases: %v", err)
+	}
+	if count > 0 {
+		return count, nil
+	}
+	endpoints, err := c.kubernetesClient.CoreV1().Endpoints("default").Get(ctx, "kubernetes", metav1.GetOptions{})
+	if err != nil {
+		return 0, fmt.Errorf("can't get the kubernetes endpoints: %v", err)
+	}
+	for _, subset := range endpoints.Subsets {
+		count += len(subset.Addresses)
+	}
+	return count, nil
+}
+
 func (c Component) sendTelemetry() {
 	data, err := c.collectTelemetry()
 	if err != nil {
@@ -17,7 +17,9 @@ import (
 	"crypto/tls"
 	"crypto/x509"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"os"
@@ -32,8 +34,16 @@ type JoinClient struct {
 	joinAddress string
 	httpClient  http.Client
 	bearerToken string
+
+	// apiVersion is the control API version negotiated with the controller
+	apiVersion string
+	// legacy is set if the controller predates the version discovery endpoint
+	legacy bool
 }
 
+// ErrNoJoinAPI is returned for worker tokens created by k0s versions not supporting joining workers over the k0s api
+var ErrNoJoinAPI = errors.New("token does not contain the k0s api address")
+
 // JoinClientFromToken creates a new join api client from a token
 func JoinClientFromToken(encodedToken string) (*JoinClient, error) {
 	tokenBytes, err := DecodeJoinToken(encodedToken)
@@ -50,8 +60,35 @@ func JoinClientFromToken(encodedToken string) (*JoinClient, error) {
 		return nil, err
 	}
 
+	return newJoinClient(config.Host, config.CAData, config.BearerToken), nil
+}
+
+// WorkerJoinClientFromToken creates a new join api client from a worker token
+func WorkerJoinClientFromToken(encodedToken string) (*JoinClient, error) {
+	tokenBytes, err := DecodeJoinToken(encodedToken)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode token: %w", err)
+	}
+
+	kubeconfig, err := clientcmd.Load(tokenBytes)
+	if err != nil {
+		return nil, err
+	}
+	cluster, ok := kubeconfig.Clusters[k0sAPICluster]
+	if !ok {
+		return nil, ErrNoJoinAPI
+	}
+	var bearerToken string
+	for _, auth := range kubeconfig.AuthInfos {
+		bearerToken = auth.Token
+	}
+
+	return newJoinClient(cluster.Server, cluster.CertificateAuthorityData, bearerToken), nil
+}
+
+func newJoinClient(host string, caData []byte, bearerToken string) *JoinClient {
 	ca := x509.NewCertPool()
-	ca.AppendCertsFromPEM(config.CAData)
+	ca.AppendCertsFromPEM(caData)
 	tlsConfig := &tls.Config{
 		InsecureSkipVerify: false,
 		RootCAs:            ca,
@@ -59,51 +96,34 @@ func JoinClientFromToken(encodedToken string) (*JoinClient, error) {
 	tr := &http.Transport{TLSClientConfig: tlsConfig}
 	c := &JoinClient{
 		httpClient:  http.Client{Transport: tr},
-		bearerToken: config.BearerToken,
+		bearerToken: bearerToken,
 	}
-	c.joinAddress = config.Host
+	c.joinAddress = host
 	logrus.Info("initialized join client successfully")
-	return c, nil
+	return c
+}
+
+// Address returns the address of the k0s api the client talks to
+func (j *JoinClient) Address() string {
+	return j.joinAddress
 }
 
 // GetCA calls the CA sync API
 func (j *JoinClient) GetCA() (v1beta1.CaResponse, error) {
 	var caData v1beta1.CaResponse
-	req, err := http.NewRequest(http.MethodGet, j.joinAddress+"/v1beta1/ca", nil)
-	if err != nil {
+	if err := j.call(http.MethodGet, "/ca", nil, &caData); err != nil {
 		return caData, err
 	}
-	req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", j.bearerToken))
-
-	resp, err := j.httpClient.Do(req)
-	if err != nil {
-		return caData, err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return caData, fmt.Errorf("unexpected response status: %s", resp.Status)
-	}
 	logrus.Info("got valid CA response")
-	if resp.Body == nil {
-		return caData, fmt.Errorf("response body is nil")
-	}
-	b, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return caData, err
-	}
-	err = json.Unmarshal(b, &caData)
-	if err != nil {
-		return caData, err
-	}
 	return caData, nil
 }
 
-// JoinEtcd calls the etcd join API
-func (j *JoinClient) JoinEtcd(peerAddress string) (v1beta1.EtcdResponse, error) {
+// JoinEtcd calls the etcd join API, a learner doesn't vote until it's promoted
+func (j *JoinClient) JoinEtcd(peerAddress string, learner bool) (v1beta1.EtcdResponse, error) {
 	var etcdResponse v1beta1.EtcdResponse
 	etcdRequest := v1beta1.EtcdRequest{
 		PeerAddress: peerAddress,
+		Learner:     learner,
 	}
 	name, err := os.Hostname()
 	if err != nil {
@@ -111,33 +131,124 @@ func (j *JoinClient) JoinEtcd(peerAddress string) (v1beta1.EtcdResponse, error)
 	}
 	etcdRequest.Node = name
 
-	buf := new(bytes.Buffer)
-	if err := json.NewEncoder(buf).Encode(etcdRequest); err != nil {
-		return etcdResponse, err
+	if err := j.call(http.MethodPost, "/etcd/members", etcdRequest, &etcdResponse); err != nil {
+		return etcdResponse, fmt.Errorf("failed to join etcd cluster: %w", err)
 	}
 
-	req, err := http.NewRequest(http.MethodPost, j.joinAddress+"/v1beta1/etcd/members", buf)
+	return etcdResponse, nil
+}
+
+// JoinWorker calls the worker join API
+func (j *JoinClient) JoinWorker(joinRequest v1beta1.WorkerJoinRequest) (v1beta1.WorkerJoinResponse, error) {
+	var joinResponse v1beta1.WorkerJoinResponse
+	if err := j.call(http.MethodPost, "/worker/join", joinRequest, &joinResponse); err != nil {
+		return joinResponse, fmt.Errorf("failed to join worker: %w", err)
+	}
+	return joinResponse, nil
+}
+
+// negotiateVersion picks the control API version to talk to the controller with
+func (j *JoinClient) negotiateVersion() error {
+	if j.apiVersion != "" {
+		return nil
+	}
+
+	var versions v1beta1.VersionResponse
+	err := j.do(http.MethodGet, "/version", nil, &versions)
 	if err != nil {
-		return etcdResponse, err
+		if legacyErr, ok := err.(*unexpectedStatusError); ok && legacyErr.code == http.StatusNotFound {
+			// controllers without version discovery only ever served v1beta1
+			logrus.Warnf("controller at %s does not support k0s API version discovery, assuming %s", j.joinAddress, v1beta1.ControlAPIVersion)
+			j.apiVersion = v1beta1.ControlAPIVersion
+			j.legacy = true
+			return nil
+		}
+		return fmt.Errorf("failed to negotiate k0s API version with %s: %w", j.joinAddress, err)
+	}
+
+	for _, v := range versions.APIVersions {
+		if v == v1beta1.ControlAPIVersion {
+			j.apiVersion = v
+			return nil
+		}
+	}
+	return fmt.Errorf("controller at %s (k0s %s) serves k0s API versions %v, but this k0s version requires %s: make sure all controllers run compatible k0s versions",
+		j.joinAddress, versions.K0sVersion, versions.APIVersions, v1beta1.ControlAPIVersion)
+}
+
+// call calls the given path of the negotiated API version
+func (j *JoinClient) call(method string, path string, in interface{}, out interface{}) error {
+	if err := j.negotiateVersion(); err != nil {
+		return err
+	}
+	err := j.do(method, "/"+j.apiVersion+path, in, out)
+	if statusErr, ok := err.(*unexpectedStatusError); ok && statusErr.code == http.StatusNotFound && j.legacy {
+		return fmt.Errorf("controller at %s predates k0s API discovery, make sure all controllers are upgraded: %w", j.joinAddress, &NotServedError{Method: method, Path: path})
+	}
+	if apiErr, ok := err.(*v1beta1.ErrorResponse); ok && apiErr.Code == http.StatusNotFound {
+		return fmt.Errorf("%s: %w", apiErr.Message, &NotServedError{Method: method, Path: path})
+	}
+	return err
+}
+
+// do executes the request and decodes the JSON response into out
+func (j *JoinClient) do(method string, path string, in interface{}, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		buf := new(bytes.Buffer)
+		if err := json.NewEncoder(buf).Encode(in); err != nil {
+			return err
+		}
+		body = buf
+	}
+
+	req, err := http.NewRequest(method, j.joinAddress+path, body)
+	if err != nil {
+		return err
 	}
 	req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", j.bearerToken))
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
 	resp, err := j.httpClient.Do(req)
 	if err != nil {
-		return etcdResponse, err
+		return err
 	}
 	defer resp.Body.Close()
-	if resp.StatusCode != http.StatusOK {
-		return etcdResponse, fmt.Errorf("unexpected response status when trying to join etcd cluster: %s", resp.Status)
-	}
 
 	b, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
-		return etcdResponse, err
+		return err
 	}
-	err = json.Unmarshal(b, &etcdResponse)
-	if err != nil {
-		return etcdResponse, err
+
+	if resp.StatusCode != http.StatusOK {
+		apiErr := &v1beta1.ErrorResponse{}
+		if json.Unmarshal(b, apiErr) == nil && apiErr.Code != 0 {
+			return apiErr
+		}
+		return &unexpectedStatusError{code: resp.StatusCode, status: resp.Status}
 	}
 
-	return etcdResponse, nil
+	return json.Unmarshal(b, out)
+}
+
+// NotServedError is returned when the controller does not serve the called endpoint
+type NotServedError struct {
+	Method string
+	Path   string
+}
+
+func (e *NotServedError) Error() string {
+	return fmt.Sprintf("%s %s is not served by the controller", e.Method, e.Path)
+}
+
+// unexpectedStatusError is returned for failed calls without a k0s API error body
+type unexpectedStatusError struct {
+	code   int
+	status string
+}
+
+func (e *unexpectedStatusError) Error() string {
+	return fmt.Sprintf("unexpected response status: %s", e.status)
 }
@@ -0,0 +1,107 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package token
+
+import (
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/k0sproject/k0s/pkg/apis/v1beta1"
+)
+
+func newTestJoinClient(t *testing.T, handler http.Handler) *JoinClient {
+	server := httptest.NewTLSServer(handler)
+	t.Cleanup(server.Close)
+	ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	return newJoinClient(server.URL, ca, "abcdef.0123456789abcdef")
+}
+
+func writeJSON(w http.ResponseWriter, code int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+func TestNegotiateVersionLegacyController(t *testing.T) {
+	// controllers predating the discovery endpoint answer unknown paths with a plain 404
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1beta1/ca", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer abcdef.0123456789abcdef", r.Header.Get("Authorization"))
+		writeJSON(w, http.StatusOK, v1beta1.CaResponse{Cert: []byte("cert")})
+	})
+	c := newTestJoinClient(t, mux)
+
+	ca, err := c.GetCA()
+	require.NoError(t, err)
+	assert.Equal(t, []byte("cert"), ca.Cert)
+	assert.Equal(t, v1beta1.ControlAPIVersion, c.apiVersion)
+	assert.True(t, c.legacy)
+
+	_, err = c.JoinWorker(v1beta1.WorkerJoinRequest{})
+	var notServed *NotServedError
+	require.True(t, errors.As(err, &notServed), "expected NotServedError, got %v", err)
+	assert.Equal(t, "/worker/join", notServed.Path)
+}
+
+func TestNegotiateVersion(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/version", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, v1beta1.VersionResponse{K0sVersion: "v1.21.2+k0s.0", APIVersions: []string{v1beta1.ControlAPIVersion}})
+	})
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusNotFound, v1beta1.ErrorResponse{Code: http.StatusNotFound, Message: r.URL.Path + " is not served by this controller"})
+	})
+	c := newTestJoinClient(t, mux)
+
+	_, err := c.GetCA()
+	var notServed *NotServedError
+	require.True(t, errors.As(err, &notServed), "expected NotServedError, got %v", err)
+	assert.Contains(t, err.Error(), "/v1beta1/ca is not served by this controller")
+	assert.Equal(t, v1beta1.ControlAPIVersion, c.apiVersion)
+	assert.False(t, c.legacy)
+}
+
+func TestNegotiateVersionUnsupported(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/version", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, v1beta1.VersionResponse{K0sVersion: "v2.0.0+k0s.0", APIVersions: []string{"v2"}})
+	})
+	c := newTestJoinClient(t, mux)
+
+	_, err := c.GetCA()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "serves k0s API versions [v2]")
+}
+
+func TestNegotiateVersionServerError(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/version", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	c := newTestJoinClient(t, mux)
+
+	_, err := c.GetCA()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to negotiate k0s API version")
+	assert.Empty(t, c.apiVersion, "must not assume a version on other errors")
+}
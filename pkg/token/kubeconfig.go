@@ -41,6 +41,12 @@ clusters:
     server: {{.JoinURL}}
     certificate-authority-data: {{.CACert}}
   name: k0s
+{{- if .K0sAPIURL }}
+- cluster:
+    server: {{.K0sAPIURL}}
+    certificate-authority-data: {{.CACert}}
+  name: {{.K0sAPICluster}}
+{{- end }}
 contexts:
 - context:
     cluster: k0s
@@ -56,6 +62,33 @@ users:
 `))
 )
 
+// k0sAPICluster is the name of the kubeconfig cluster pointing to the k0s api in worker tokens
+const k0sAPICluster = "k0s-api"
+
+type kubeconfigData struct {
+	CACert        string
+	Token         string
+	User          string
+	JoinURL       string
+	K0sAPIURL     string
+	K0sAPICluster string
+}
+
+// NodeBootstrapKubeconfig renders the kubelet bootstrap kubeconfig for the given node scoped token
+func NodeBootstrapKubeconfig(apiURL string, caCert []byte, tokenString string) ([]byte, error) {
+	var buf bytes.Buffer
+	err := kubeconfigTemplate.Execute(&buf, &kubeconfigData{
+		CACert:  base64.StdEncoding.EncodeToString(caCert),
+		Token:   tokenString,
+		User:    "kubelet-bootstrap",
+		JoinURL: apiURL,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
 func CreateKubeletBootstrapConfig(clusterConfig *config.ClusterConfig, k0sVars constant.CfgVars, role string, expiry time.Duration) (string, error) {
 	crtFile := filepath.Join(k0sVars.CertRootDir, "ca.crt")
 	caCert, err := ioutil.ReadFile(crtFile)
@@ -70,19 +103,16 @@ func CreateKubeletBootstrapConfig(clusterConfig *config.ClusterConfig, k0sVars c
 	if err != nil {
 		return "", err
 	}
-	data := struct {
-		CACert  string
-		Token   string
-		User    string
-		JoinURL string
-		APIUrl  string
-	}{
+	data := kubeconfigData{
 		CACert: base64.StdEncoding.EncodeToString(caCert),
 		Token:  tokenString,
 	}
 	if role == workerRole {
 		data.User = "kubelet-bootstrap"
 		data.JoinURL = clusterConfig.Spec.API.APIAddressURL()
+		// lets workers join over the k0s api, older workers just ignore the extra cluster
+		data.K0sAPIURL = clusterConfig.Spec.API.K0sControlPlaneAPIAddress()
+		data.K0sAPICluster = k0sAPICluster
 	} else if role == controllerRole {
 		data.User = "controller-bootstrap"
 		data.JoinURL = clusterConfig.Spec.API.K0sControlPlaneAPIAddress()
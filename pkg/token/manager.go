@@ -18,6 +18,7 @@ package token
 import (
 	"context"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/sirupsen/logrus"
@@ -52,6 +53,13 @@ func NewManager(kubeconfig string) (*Manager, error) {
 	}, nil
 }
 
+// NewManagerForClient creates a new token manager using the given kube client
+func NewManagerForClient(client kubernetes.Interface) *Manager {
+	return &Manager{
+		client: client,
+	}
+}
+
 // Manager is responsible to manage the join tokens in kube API as secrets in kube-system namespace
 type Manager struct {
 	client kubernetes.Interface
@@ -59,18 +67,7 @@ type Manager struct {
 
 // Create creates a new bootstrap token
 func (m *Manager) Create(valid time.Duration, role string) (string, error) {
-	tokenID := util.RandomString(6)
-	tokenSecret := util.RandomString(16)
-
-	token := fmt.Sprintf("%s.%s", tokenID, tokenSecret)
-
 	data := make(map[string]string)
-	data["token-id"] = tokenID
-	data["token-secret"] = tokenSecret
-	if valid != 0 {
-		data["expiration"] = time.Now().Add(valid).UTC().Format(time.RFC3339)
-		logrus.Debugf("Set expiry to %s", data["expiration"])
-	}
 
 	// This "usage-" is shared for both roles of the token
 	// which allows both roles execute calls to the k0s api.
@@ -89,10 +86,51 @@ func (m *Manager) Create(valid time.Duration, role string) (string, error) {
 		data["usage-controller-join"] = "true"
 	}
 
+	return m.create(valid, data, nil)
+}
+
+// NodeNameLabel is the label set on node scoped bootstrap token secrets
+const NodeNameLabel = "k0s.k0sproject.io/node-name"
+
+// CreateForNode creates a new kubelet bootstrap token usable only for bootstrapping the given node.
+// The token is not allowed to call the k0s api.
+func (m *Manager) CreateForNode(valid time.Duration, nodeName string) (string, error) {
+	data := map[string]string{
+		"description":                    fmt.Sprintf("Bootstrap token for node %s generated by k0s", nodeName),
+		"usage-bootstrap-authentication": "true",
+		"auth-extra-groups":              NodeBootstrapGroup(nodeName),
+	}
+	return m.create(valid, data, map[string]string{NodeNameLabel: nodeName})
+}
+
+// NodeBootstrapGroupPrefix is the prefix of the extra group node scoped bootstrap tokens authenticate with
+const NodeBootstrapGroupPrefix = "system:bootstrappers:k0s-node:"
+
+// NodeBootstrapGroup returns the extra group the node scoped bootstrap token of the given node authenticates with.
+// Bootstrap token groups can't contain dots, thus the group alone doesn't identify the node, the kubelet client
+// CSRs of node scoped tokens are checked against the NodeNameLabel of the token secret.
+func NodeBootstrapGroup(nodeName string) string {
+	return NodeBootstrapGroupPrefix + strings.ReplaceAll(nodeName, ".", "-")
+}
+
+func (m *Manager) create(valid time.Duration, data map[string]string, labels map[string]string) (string, error) {
+	tokenID := util.RandomString(6)
+	tokenSecret := util.RandomString(16)
+
+	token := fmt.Sprintf("%s.%s", tokenID, tokenSecret)
+
+	data["token-id"] = tokenID
+	data["token-secret"] = tokenSecret
+	if valid != 0 {
+		data["expiration"] = time.Now().Add(valid).UTC().Format(time.RFC3339)
+		logrus.Debugf("Set expiry to %s", data["expiration"])
+	}
+
 	secret := &v1.Secret{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      fmt.Sprintf("bootstrap-token-%s", tokenID),
 			Namespace: "kube-system",
+			Labels:    labels,
 		},
 		Type:       v1.SecretTypeBootstrapToken,
 		StringData: data,